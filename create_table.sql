@@ -1,3 +1,32 @@
+-- Creates the schema from scratch. Databases created with an earlier version
+-- of this script are upgraded by running the scripts in migrations/ in order.
+
+-- Create the 'project' table (every task belongs to exactly one project)
+CREATE TABLE project (
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    owner_user_id INTEGER NOT NULL,
+    created_at TIMESTAMP,
+    updated_at TIMESTAMP
+);
+
+-- Create the 'project_member' table (the owner is also recorded as a member)
+CREATE TABLE project_member (
+    project_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    role TEXT NOT NULL CHECK (role IN ('owner', 'member')),
+    created_at TIMESTAMP,
+    PRIMARY KEY (project_id, user_id),
+    FOREIGN KEY (project_id) REFERENCES project (id) ON DELETE CASCADE
+);
+
+CREATE INDEX project_member_user_id_idx ON project_member (user_id);
+
+-- The default project (id 1) holds tasks created without a project
+INSERT INTO project (name, description, owner_user_id, created_at, updated_at)
+VALUES ('Default', 'Tasks not filed under any other project.', 0, NOW(), NOW());
+
 -- Create the 'task' table
 CREATE TABLE task (
     id SERIAL PRIMARY KEY,
@@ -5,9 +34,22 @@ CREATE TABLE task (
     description TEXT,
     completed BOOLEAN NOT NULL,
     created_at TIMESTAMP,
-    updated_at TIMESTAMP
+    updated_at TIMESTAMP,
+    assigned_user_id INTEGER NOT NULL DEFAULT 0,
+    parent_task_id INTEGER,
+    project_id INTEGER NOT NULL DEFAULT 1,
+    deleted_at TIMESTAMP,
+    FOREIGN KEY (parent_task_id) REFERENCES task (id) ON DELETE CASCADE,
+    FOREIGN KEY (project_id) REFERENCES project (id),
+    CHECK (parent_task_id <> id)
 );
 
+CREATE INDEX task_parent_task_id_idx ON task (parent_task_id);
+CREATE INDEX task_project_id_idx ON task (project_id);
+CREATE INDEX task_deleted_at_idx ON task (deleted_at) WHERE deleted_at IS NOT NULL;
+-- Task lists are paged in this order, by (updated_at, id) after a cursor
+CREATE INDEX task_updated_at_id_idx ON task (updated_at, id) WHERE deleted_at IS NULL;
+
 -- Create the 'task_item' table (to store the list items associated with each task)
 CREATE TABLE task_item (
     id SERIAL PRIMARY KEY,
@@ -15,3 +57,164 @@ CREATE TABLE task_item (
     item TEXT NOT NULL,
     FOREIGN KEY (task_id) REFERENCES task (id) ON DELETE CASCADE
 );
+
+-- Create the 'task_comment' table
+CREATE TABLE task_comment (
+    id SERIAL PRIMARY KEY,
+    task_id INTEGER NOT NULL,
+    comment TEXT NOT NULL,
+    created_at TIMESTAMP,
+    FOREIGN KEY (task_id) REFERENCES task (id) ON DELETE CASCADE
+);
+
+CREATE INDEX task_comment_task_id_idx ON task_comment (task_id, created_at, id);
+
+-- Create the 'task_dependency' table (a 'blocks' row means task_id blocks related_task_id)
+CREATE TABLE task_dependency (
+    id SERIAL PRIMARY KEY,
+    task_id INTEGER NOT NULL,
+    related_task_id INTEGER NOT NULL,
+    type TEXT NOT NULL CHECK (type IN ('blocks', 'relates_to')),
+    created_at TIMESTAMP,
+    FOREIGN KEY (task_id) REFERENCES task (id) ON DELETE CASCADE,
+    FOREIGN KEY (related_task_id) REFERENCES task (id) ON DELETE CASCADE,
+    UNIQUE (task_id, related_task_id, type),
+    CHECK (task_id <> related_task_id)
+);
+
+CREATE INDEX task_dependency_related_task_id_idx ON task_dependency (related_task_id);
+
+-- Create the 'board' table (kanban boards belong to a project)
+CREATE TABLE board (
+    id SERIAL PRIMARY KEY,
+    project_id INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    created_at TIMESTAMP,
+    FOREIGN KEY (project_id) REFERENCES project (id) ON DELETE CASCADE
+);
+
+-- Create the 'board_column' table (each column maps to a task state)
+CREATE TABLE board_column (
+    id SERIAL PRIMARY KEY,
+    board_id INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    position INTEGER NOT NULL,
+    state TEXT NOT NULL CHECK (state IN ('open', 'completed')),
+    FOREIGN KEY (board_id) REFERENCES board (id) ON DELETE CASCADE,
+    UNIQUE (board_id, position)
+);
+
+-- Create the 'board_card' table (ranks sort byte-wise, hence COLLATE "C")
+CREATE TABLE board_card (
+    board_id INTEGER NOT NULL,
+    task_id INTEGER NOT NULL,
+    column_id INTEGER NOT NULL,
+    rank TEXT COLLATE "C" NOT NULL,
+    PRIMARY KEY (board_id, task_id),
+    FOREIGN KEY (board_id) REFERENCES board (id) ON DELETE CASCADE,
+    FOREIGN KEY (task_id) REFERENCES task (id) ON DELETE CASCADE,
+    FOREIGN KEY (column_id) REFERENCES board_column (id) ON DELETE CASCADE,
+    UNIQUE (column_id, rank)
+);
+
+-- Create the 'task_recurrence' table (an RRULE schedule attached to a template task)
+CREATE TABLE task_recurrence (
+    id SERIAL PRIMARY KEY,
+    task_id INTEGER NOT NULL UNIQUE,
+    rrule TEXT NOT NULL,
+    dtstart TIMESTAMP NOT NULL,
+    next_run_at TIMESTAMP,
+    last_run_at TIMESTAMP,
+    created_at TIMESTAMP,
+    FOREIGN KEY (task_id) REFERENCES task (id) ON DELETE CASCADE
+);
+
+CREATE INDEX task_recurrence_next_run_at_idx ON task_recurrence (next_run_at);
+
+-- Create the 'task_recurrence_instance' table (one row per generated occurrence, which keeps generation idempotent)
+CREATE TABLE task_recurrence_instance (
+    recurrence_id INTEGER NOT NULL,
+    occurrence_at TIMESTAMP NOT NULL,
+    task_id INTEGER,
+    PRIMARY KEY (recurrence_id, occurrence_at),
+    FOREIGN KEY (recurrence_id) REFERENCES task_recurrence (id) ON DELETE CASCADE,
+    FOREIGN KEY (task_id) REFERENCES task (id) ON DELETE SET NULL
+);
+
+-- Create the 'task_event' table (the audit trail; rows outlive the task they describe)
+CREATE TABLE task_event (
+    id SERIAL PRIMARY KEY,
+    task_id INTEGER NOT NULL,
+    actor_id INTEGER NOT NULL DEFAULT 0,
+    action TEXT NOT NULL,
+    changes JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX task_event_task_id_idx ON task_event (task_id, created_at DESC, id DESC);
+
+-- Create the 'task_attachment' table (content lives in the blob store, keyed by sha256)
+CREATE TABLE task_attachment (
+    id SERIAL PRIMARY KEY,
+    task_id INTEGER NOT NULL,
+    filename TEXT NOT NULL,
+    content_type TEXT NOT NULL,
+    size BIGINT NOT NULL,
+    sha256 CHAR(64) NOT NULL,
+    uploaded_by INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (task_id) REFERENCES task (id) ON DELETE CASCADE
+);
+
+CREATE INDEX task_attachment_task_id_idx ON task_attachment (task_id);
+CREATE INDEX task_attachment_sha256_idx ON task_attachment (sha256);
+
+-- Create the 'webhook' table (outbound subscriptions to the events of one project's tasks)
+CREATE TABLE webhook (
+    id SERIAL PRIMARY KEY,
+    project_id INTEGER NOT NULL,
+    url TEXT NOT NULL,
+    secret TEXT NOT NULL,
+    events TEXT[] NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT true,
+    created_by INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (project_id) REFERENCES project (id) ON DELETE CASCADE
+);
+
+CREATE INDEX webhook_project_id_idx ON webhook (project_id);
+
+-- Create the 'webhook_delivery' table (the outbox and delivery log; rows are queued with the task event)
+CREATE TABLE webhook_delivery (
+    id SERIAL PRIMARY KEY,
+    webhook_id INTEGER NOT NULL,
+    event_id INTEGER NOT NULL,
+    event TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    response_status INTEGER,
+    last_error TEXT,
+    payload JSONB,
+    next_attempt_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL,
+    delivered_at TIMESTAMP,
+    FOREIGN KEY (webhook_id) REFERENCES webhook (id) ON DELETE CASCADE
+);
+
+CREATE INDEX webhook_delivery_due_idx ON webhook_delivery (status, next_attempt_at);
+CREATE INDEX webhook_delivery_webhook_id_idx ON webhook_delivery (webhook_id, created_at DESC);
+
+-- Create the 'user_email' table (where users are sent notifications; users themselves are only known by ID)
+CREATE TABLE user_email (
+    user_id INTEGER PRIMARY KEY,
+    email TEXT NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+-- Create the 'schema_migrations' table (the version of this script, checked by /readyz against model.SchemaVersion)
+CREATE TABLE schema_migrations (
+    version BIGINT PRIMARY KEY,
+    dirty BOOLEAN NOT NULL
+);
+
+INSERT INTO schema_migrations (version, dirty) VALUES (3, false);
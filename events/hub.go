@@ -0,0 +1,181 @@
+// Package events fans task changes out to live subscribers, such as the
+// Server-Sent Events stream, and keeps a bounded buffer of recent events so
+// a subscriber that reconnects can pick up where it left off.
+package events
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// Event types published by the API.
+const (
+	TaskCreated    = "task.created"
+	TaskUpdated    = "task.updated"
+	TaskAssigned   = "task.assigned"
+	TaskDeleted    = "task.deleted"
+	CommentCreated = "comment.created"
+)
+
+// subscriberBuffer is how many events a subscriber may fall behind by
+// before it is dropped.
+const subscriberBuffer = 64
+
+// Event is one published change. AssigneeID is the user the task is
+// assigned to after the change, or 0, and is only used for filtering.
+type Event struct {
+	ID         uint64
+	Type       string
+	TaskID     int
+	AssigneeID int
+	Data       json.RawMessage
+}
+
+// Filter selects the events a subscriber receives. Zero fields match
+// everything.
+type Filter struct {
+	TaskID     int
+	AssigneeID int
+}
+
+// Match reports whether the event passes the filter.
+func (filter Filter) Match(event Event) bool {
+	if filter.TaskID != 0 && event.TaskID != filter.TaskID {
+		return false
+	}
+	if filter.AssigneeID != 0 && event.AssigneeID != filter.AssigneeID {
+		return false
+	}
+	return true
+}
+
+// Hub publishes events to subscribers. It is safe for concurrent use.
+type Hub struct {
+	mu          sync.Mutex
+	replay      []Event
+	next        int
+	lastID      uint64
+	subscribers map[*Subscription]struct{}
+}
+
+// NewHub returns a hub that keeps the last replaySize events for
+// resumption. IDs start from the current time in microseconds, so they keep
+// increasing across restarts and an ID from an earlier run is never
+// mistaken for a recent one.
+func NewHub(replaySize int) *Hub {
+	if replaySize < 1 {
+		replaySize = 1
+	}
+	return &Hub{
+		replay:      make([]Event, 0, replaySize),
+		lastID:      uint64(time.Now().UnixMicro()),
+		subscribers: make(map[*Subscription]struct{}),
+	}
+}
+
+// Publish assigns the event the next ID, stores it for replay and sends it
+// to every matching subscriber. A subscriber that has fallen too far
+// behind is dropped rather than allowed to block the publisher; it can
+// reconnect and resume from the last event it saw.
+func (hub *Hub) Publish(eventType string, taskID, assigneeID int, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	hub.lastID++
+	event := Event{ID: hub.lastID, Type: eventType, TaskID: taskID, AssigneeID: assigneeID, Data: payload}
+
+	if len(hub.replay) < cap(hub.replay) {
+		hub.replay = append(hub.replay, event)
+	} else {
+		hub.replay[hub.next] = event
+		hub.next = (hub.next + 1) % len(hub.replay)
+	}
+
+	for sub := range hub.subscribers {
+		if !sub.filter.Match(event) {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+			hub.remove(sub)
+		}
+	}
+
+	return nil
+}
+
+// Subscribe registers a subscriber. If lastEventID is non-zero, the
+// matching events published after it are returned for replay, and
+// complete is false if some of them have already left the buffer.
+func (hub *Hub) Subscribe(filter Filter, lastEventID uint64) (sub *Subscription, missed []Event, complete bool) {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	sub = &Subscription{hub: hub, filter: filter, events: make(chan Event, subscriberBuffer)}
+	hub.subscribers[sub] = struct{}{}
+
+	if lastEventID == 0 {
+		return sub, nil, true
+	}
+
+	buffered := hub.buffered()
+	switch {
+	case lastEventID > hub.lastID:
+		complete = false
+	case len(buffered) == 0:
+		complete = lastEventID == hub.lastID
+	default:
+		complete = lastEventID+1 >= buffered[0].ID
+	}
+
+	for _, event := range buffered {
+		if event.ID > lastEventID && filter.Match(event) {
+			missed = append(missed, event)
+		}
+	}
+
+	return sub, missed, complete
+}
+
+// buffered returns the replay buffer oldest first.
+func (hub *Hub) buffered() []Event {
+	ordered := make([]Event, 0, len(hub.replay))
+	ordered = append(ordered, hub.replay[hub.next:]...)
+	return append(ordered, hub.replay[:hub.next]...)
+}
+
+// remove unregisters the subscriber and closes its channel. The caller
+// must hold the lock.
+func (hub *Hub) remove(sub *Subscription) {
+	if _, ok := hub.subscribers[sub]; ok {
+		delete(hub.subscribers, sub)
+		close(sub.events)
+	}
+}
+
+// Subscription receives the events that match its filter.
+type Subscription struct {
+	hub    *Hub
+	filter Filter
+	events chan Event
+}
+
+// Events returns the channel events are delivered on. It is closed when
+// the subscription is closed or dropped for falling behind.
+func (sub *Subscription) Events() <-chan Event {
+	return sub.events
+}
+
+// Close unsubscribes. It is safe to call more than once.
+func (sub *Subscription) Close() {
+	sub.hub.mu.Lock()
+	defer sub.hub.mu.Unlock()
+	sub.hub.remove(sub)
+}
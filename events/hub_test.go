@@ -0,0 +1,86 @@
+package events
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHub_PublishesToMatchingSubscribers(t *testing.T) {
+	hub := NewHub(10)
+
+	all, _, _ := hub.Subscribe(Filter{}, 0)
+	defer all.Close()
+	task2, _, _ := hub.Subscribe(Filter{TaskID: 2}, 0)
+	defer task2.Close()
+	user7, _, _ := hub.Subscribe(Filter{AssigneeID: 7}, 0)
+	defer user7.Close()
+
+	assert.NoError(t, hub.Publish(TaskCreated, 1, 0, map[string]int{"id": 1}))
+	assert.NoError(t, hub.Publish(TaskAssigned, 2, 7, map[string]int{"id": 2}))
+
+	first := <-all.Events()
+	assert.Equal(t, TaskCreated, first.Type)
+	assert.JSONEq(t, `{"id":1}`, string(first.Data))
+	second := <-all.Events()
+	assert.Equal(t, first.ID+1, second.ID)
+
+	assert.Equal(t, TaskAssigned, (<-task2.Events()).Type)
+	assert.Equal(t, TaskAssigned, (<-user7.Events()).Type)
+	assert.Len(t, task2.Events(), 0)
+	assert.Len(t, user7.Events(), 0)
+}
+
+func TestHub_SubscribeReplaysAfterLastEventID(t *testing.T) {
+	hub := NewHub(3)
+
+	first, _, _ := hub.Subscribe(Filter{}, 0)
+	for taskID := 1; taskID <= 5; taskID++ {
+		assert.NoError(t, hub.Publish(TaskUpdated, taskID, 0, taskID))
+	}
+	var ids []uint64
+	for i := 0; i < 5; i++ {
+		ids = append(ids, (<-first.Events()).ID)
+	}
+	first.Close()
+
+	// Events 3 to 5 are still buffered, so resuming after 2 misses nothing.
+	sub, missed, complete := hub.Subscribe(Filter{}, ids[1])
+	defer sub.Close()
+	assert.True(t, complete)
+	assert.Len(t, missed, 3)
+	assert.Equal(t, ids[2], missed[0].ID)
+	assert.Equal(t, ids[4], missed[2].ID)
+
+	// Event 2 has left the buffer, so resuming after 1 is incomplete.
+	_, missed, complete = hub.Subscribe(Filter{TaskID: 4}, ids[0])
+	assert.False(t, complete)
+	assert.Len(t, missed, 1)
+
+	// An ID from the future, as after a clock change, cannot be resumed.
+	_, _, complete = hub.Subscribe(Filter{}, ids[4]+100)
+	assert.False(t, complete)
+
+	// Nothing to replay when the client is up to date.
+	_, missed, complete = hub.Subscribe(Filter{}, ids[4])
+	assert.True(t, complete)
+	assert.Empty(t, missed)
+}
+
+func TestHub_DropsSlowSubscribers(t *testing.T) {
+	hub := NewHub(1)
+
+	slow, _, _ := hub.Subscribe(Filter{}, 0)
+	for i := 0; i < subscriberBuffer+1; i++ {
+		assert.NoError(t, hub.Publish(TaskUpdated, 1, 0, i))
+	}
+
+	received := 0
+	for range slow.Events() {
+		received++
+	}
+	assert.Equal(t, subscriberBuffer, received)
+
+	// Closing a dropped subscription is harmless.
+	slow.Close()
+}
@@ -0,0 +1,207 @@
+// Package mail renders notification emails from embedded templates and
+// sends them through a pluggable Mailer: SMTP in production, or a
+// directory of .eml files or memory in development and tests.
+package mail
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Message is an email with a plain text body and an optional HTML alternative.
+type Message struct {
+	To      []string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Mailer sends messages.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// Encode returns the message in RFC 5322 form, as multipart/alternative if
+// it has an HTML body.
+func (msg Message) Encode(from string, date time.Time) ([]byte, error) {
+	if len(msg.To) == 0 {
+		return nil, errors.New("mail: message has no recipients")
+	}
+
+	var buf bytes.Buffer
+	header := func(key, value string) {
+		fmt.Fprintf(&buf, "%s: %s\r\n", key, value)
+	}
+
+	header("From", from)
+	header("To", strings.Join(msg.To, ", "))
+	header("Subject", mime.QEncoding.Encode("utf-8", msg.Subject))
+	header("Date", date.Format(time.RFC1123Z))
+	header("Message-ID", messageID(from))
+	header("MIME-Version", "1.0")
+
+	if msg.HTML == "" {
+		header("Content-Type", "text/plain; charset=utf-8")
+		header("Content-Transfer-Encoding", "quoted-printable")
+		buf.WriteString("\r\n")
+		err := writeQuotedPrintable(&buf, msg.Text)
+		return buf.Bytes(), err
+	}
+
+	parts := multipart.NewWriter(&buf)
+	header("Content-Type", "multipart/alternative; boundary="+parts.Boundary())
+	buf.WriteString("\r\n")
+
+	for _, part := range []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", msg.Text},
+		{"text/html; charset=utf-8", msg.HTML},
+	} {
+		w, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		err = writeQuotedPrintable(w, part.body)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err := parts.Close()
+	return buf.Bytes(), err
+}
+
+func writeQuotedPrintable(w interface{ Write([]byte) (int, error) }, body string) error {
+	qp := quotedprintable.NewWriter(w)
+	_, err := qp.Write([]byte(body))
+	if err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+func messageID(from string) string {
+	domain := "localhost"
+	if addr, err := mail.ParseAddress(from); err == nil {
+		if _, host, ok := strings.Cut(addr.Address, "@"); ok {
+			domain = host
+		}
+	}
+
+	id := make([]byte, 16)
+	rand.Read(id)
+	return "<" + hex.EncodeToString(id) + "@" + domain + ">"
+}
+
+// SMTPMailer sends messages through an SMTP server, using STARTTLS when the
+// server offers it. Username may be empty for servers that do not need
+// authentication.
+type SMTPMailer struct {
+	Addr     string
+	Username string
+	Password string
+	From     string
+}
+
+func (mailer SMTPMailer) Send(ctx context.Context, msg Message) error {
+	body, err := msg.Encode(mailer.From, time.Now())
+	if err != nil {
+		return err
+	}
+
+	sender, err := mail.ParseAddress(mailer.From)
+	if err != nil {
+		return fmt.Errorf("mail: invalid from address: %w", err)
+	}
+
+	var auth smtp.Auth
+	if mailer.Username != "" {
+		host, _, _ := strings.Cut(mailer.Addr, ":")
+		auth = smtp.PlainAuth("", mailer.Username, mailer.Password, host)
+	}
+
+	// net/smtp has no context support, so honour cancellation while waiting.
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(mailer.Addr, auth, sender.Address, msg.To, body)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// FileMailer writes each message as a .eml file in Dir instead of sending
+// it, for development.
+type FileMailer struct {
+	Dir  string
+	From string
+
+	seq atomic.Int64
+}
+
+// NewFileMailer creates the directory if needed.
+func NewFileMailer(dir, from string) (*FileMailer, error) {
+	err := os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileMailer{Dir: dir, From: from}, nil
+}
+
+func (mailer *FileMailer) Send(ctx context.Context, msg Message) error {
+	now := time.Now()
+	body, err := msg.Encode(mailer.From, now)
+	if err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%s-%d.eml", now.Format("20060102T150405.000000000"), mailer.seq.Add(1))
+	return os.WriteFile(filepath.Join(mailer.Dir, name), body, 0o644)
+}
+
+// MemoryMailer keeps sent messages in memory, for tests.
+type MemoryMailer struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+func (mailer *MemoryMailer) Send(ctx context.Context, msg Message) error {
+	if len(msg.To) == 0 {
+		return errors.New("mail: message has no recipients")
+	}
+
+	mailer.mu.Lock()
+	defer mailer.mu.Unlock()
+	mailer.messages = append(mailer.messages, msg)
+	return nil
+}
+
+// Messages returns the messages sent so far.
+func (mailer *MemoryMailer) Messages() []Message {
+	mailer.mu.Lock()
+	defer mailer.mu.Unlock()
+	return append([]Message(nil), mailer.messages...)
+}
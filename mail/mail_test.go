@@ -0,0 +1,158 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log"
+	netmail "net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type task struct {
+	ID          int
+	Title       string
+	Description string
+	Items       []string
+}
+
+func TestRender_TaskAssigned(t *testing.T) {
+	data := map[string]any{
+		"ActorID": 3,
+		"Task":    task{ID: 12, Title: "Rotate <certs>", Description: "Before Friday", Items: []string{"staging", "prod"}},
+	}
+
+	msg, err := Render(TemplateTaskAssigned, []string{"dev@example.com"}, data)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Task #12 was assigned to you: Rotate <certs>", msg.Subject)
+	assert.Contains(t, msg.Text, `User 3 assigned you task #12, "Rotate <certs>".`)
+	assert.Contains(t, msg.Text, "  - prod")
+	assert.Contains(t, msg.HTML, "<strong>Rotate &lt;certs&gt;</strong>")
+	assert.Contains(t, msg.HTML, "<li>staging</li>")
+}
+
+func TestRender_EachNotificationHasItsOwnSubject(t *testing.T) {
+	data := map[string]any{"ActorID": 1, "Task": task{ID: 2, Title: "Ship"}, "Comment": map[string]string{"Comment": "Done?"}}
+
+	commented, err := Render(TemplateTaskCommented, []string{"a@example.com"}, data)
+	assert.NoError(t, err)
+	assert.Equal(t, "New comment on task #2: Ship", commented.Subject)
+	assert.Contains(t, commented.Text, "Done?")
+
+	completed, err := Render(TemplateTaskCompleted, []string{"a@example.com"}, data)
+	assert.NoError(t, err)
+	assert.Equal(t, "Task #2 was completed: Ship", completed.Subject)
+
+	_, err = Render("missing", nil, data)
+	assert.Error(t, err)
+}
+
+func TestMessage_EncodeMultipart(t *testing.T) {
+	msg := Message{To: []string{"dev@example.com"}, Subject: "Grüße", Text: "plain", HTML: "<p>rich</p>"}
+
+	raw, err := msg.Encode("TMS <tms@example.com>", time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+
+	parsed, err := netmail.ReadMessage(bytes.NewReader(raw))
+	assert.NoError(t, err)
+	assert.Equal(t, "dev@example.com", parsed.Header.Get("To"))
+	assert.Equal(t, "=?utf-8?q?Gr=C3=BC=C3=9Fe?=", parsed.Header.Get("Subject"))
+	assert.True(t, strings.HasSuffix(parsed.Header.Get("Message-ID"), "@example.com>"))
+	assert.True(t, strings.HasPrefix(parsed.Header.Get("Content-Type"), "multipart/alternative; boundary="))
+
+	body, _ := io.ReadAll(parsed.Body)
+	assert.Contains(t, string(body), "text/plain; charset=utf-8")
+	assert.Contains(t, string(body), "<p>rich</p>")
+
+	_, err = Message{Subject: "nobody"}.Encode("tms@example.com", time.Now())
+	assert.Error(t, err)
+}
+
+func TestFileMailer_WritesEmlFiles(t *testing.T) {
+	mailer, err := NewFileMailer(filepath.Join(t.TempDir(), "outbox"), "tms@example.com")
+	assert.NoError(t, err)
+
+	msg := Message{To: []string{"dev@example.com"}, Subject: "Hello", Text: "Hi"}
+	assert.NoError(t, mailer.Send(context.Background(), msg))
+	assert.NoError(t, mailer.Send(context.Background(), msg))
+
+	entries, err := os.ReadDir(mailer.Dir)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+	assert.True(t, strings.HasSuffix(entries[0].Name(), ".eml"))
+}
+
+// flakyMailer fails the first few sends, then hands over to a MemoryMailer.
+type flakyMailer struct {
+	failures int32
+	calls    atomic.Int32
+	MemoryMailer
+}
+
+func (mailer *flakyMailer) Send(ctx context.Context, msg Message) error {
+	if mailer.calls.Add(1) <= mailer.failures {
+		return errors.New("451 try again later")
+	}
+	return mailer.MemoryMailer.Send(ctx, msg)
+}
+
+func TestQueue_RetriesFailedSends(t *testing.T) {
+	var logs syncBuffer
+	mailer := &flakyMailer{failures: 2}
+	queue := NewQueue(mailer, log.New(&logs, "", 0), 3, 10)
+	queue.Backoff = func(int) time.Duration { return time.Millisecond }
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go queue.Run(ctx)
+
+	assert.True(t, queue.Enqueue(Message{To: []string{"dev@example.com"}, Subject: "Hello"}))
+
+	assert.Eventually(t, func() bool { return len(mailer.Messages()) == 1 }, time.Second, time.Millisecond)
+	assert.Equal(t, int32(3), mailer.calls.Load())
+	assert.Contains(t, logs.String(), "attempt 2")
+}
+
+func TestQueue_GivesUpAfterAttempts(t *testing.T) {
+	var logs syncBuffer
+	mailer := &flakyMailer{failures: 100}
+	queue := NewQueue(mailer, log.New(&logs, "", 0), 2, 10)
+	queue.Backoff = func(int) time.Duration { return time.Millisecond }
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go queue.Run(ctx)
+
+	queue.Enqueue(Message{To: []string{"dev@example.com"}, Subject: "Hello"})
+
+	assert.Eventually(t, func() bool { return strings.Contains(logs.String(), "giving up") }, time.Second, time.Millisecond)
+	assert.Equal(t, int32(2), mailer.calls.Load())
+}
+
+// syncBuffer is a bytes.Buffer that the queue can log to while the test reads it.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
@@ -0,0 +1,94 @@
+package mail
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+)
+
+// Queue sends messages in the background, so handlers never wait on the
+// mail server. A failed send is retried with exponential backoff, and
+// given up on, with a log line, after Attempts tries. Messages still
+// queued when the process exits are lost.
+type Queue struct {
+	Mailer   Mailer
+	Logger   *log.Logger
+	Attempts int
+	Timeout  time.Duration
+	// Backoff returns the delay before the given retry; 30s doubling by default.
+	Backoff func(retry int) time.Duration
+
+	jobs chan job
+}
+
+type job struct {
+	msg     Message
+	attempt int
+}
+
+// NewQueue returns a queue holding up to size messages waiting to be sent.
+// Call Run to start sending.
+func NewQueue(mailer Mailer, logger *log.Logger, attempts, size int) *Queue {
+	return &Queue{
+		Mailer:   mailer,
+		Logger:   logger,
+		Attempts: attempts,
+		Timeout:  30 * time.Second,
+		Backoff: func(retry int) time.Duration {
+			return 30 * time.Second << (retry - 1)
+		},
+		jobs: make(chan job, size),
+	}
+}
+
+// Enqueue adds the message to the queue. It reports false, and logs, if
+// the queue is full.
+func (queue *Queue) Enqueue(msg Message) bool {
+	return queue.push(job{msg: msg, attempt: 1})
+}
+
+func (queue *Queue) push(j job) bool {
+	select {
+	case queue.jobs <- j:
+		return true
+	default:
+		queue.Logger.Printf("mail queue full, dropping %q to %s", j.msg.Subject, strings.Join(j.msg.To, ", "))
+		return false
+	}
+}
+
+// Run sends queued messages until ctx is cancelled.
+func (queue *Queue) Run(ctx context.Context) {
+	for {
+		select {
+		case j := <-queue.jobs:
+			queue.send(ctx, j)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (queue *Queue) send(ctx context.Context, j job) {
+	sendCtx, cancel := context.WithTimeout(ctx, queue.Timeout)
+	err := queue.Mailer.Send(sendCtx, j.msg)
+	cancel()
+	if err == nil {
+		return
+	}
+
+	to := strings.Join(j.msg.To, ", ")
+	if j.attempt >= queue.Attempts {
+		queue.Logger.Printf("giving up on mail %q to %s after %d attempts: %v", j.msg.Subject, to, j.attempt, err)
+		return
+	}
+
+	delay := queue.Backoff(j.attempt)
+	queue.Logger.Printf("mail %q to %s failed (attempt %d), retrying in %s: %v", j.msg.Subject, to, j.attempt, delay, err)
+
+	// Wait off the worker, so one failing message does not hold up the rest.
+	time.AfterFunc(delay, func() {
+		queue.push(job{msg: j.msg, attempt: j.attempt + 1})
+	})
+}
@@ -0,0 +1,71 @@
+package mail
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+)
+
+// Template names, one per notification.
+const (
+	TemplateTaskAssigned  = "task_assigned"
+	TemplateTaskCommented = "task_commented"
+	TemplateTaskCompleted = "task_completed"
+)
+
+//go:embed templates
+var templateFS embed.FS
+
+// notification is the parsed text and HTML templates of one notification.
+// Each is parsed on its own, as every text template defines a "subject".
+type notification struct {
+	text *texttemplate.Template
+	html *htmltemplate.Template
+}
+
+var notifications = map[string]notification{
+	TemplateTaskAssigned:  mustParse(TemplateTaskAssigned),
+	TemplateTaskCommented: mustParse(TemplateTaskCommented),
+	TemplateTaskCompleted: mustParse(TemplateTaskCompleted),
+}
+
+func mustParse(name string) notification {
+	return notification{
+		text: texttemplate.Must(texttemplate.ParseFS(templateFS, "templates/"+name+".txt.tmpl")),
+		html: htmltemplate.Must(htmltemplate.ParseFS(templateFS, "templates/"+name+".html.tmpl")),
+	}
+}
+
+// Render builds a message from the named template. The subject comes from
+// the "subject" block of the text template.
+func Render(name string, to []string, data any) (Message, error) {
+	msg := Message{To: to}
+
+	tmpl, ok := notifications[name]
+	if !ok {
+		return msg, fmt.Errorf("mail: no template %q", name)
+	}
+
+	var subject, text, html bytes.Buffer
+	err := tmpl.text.ExecuteTemplate(&subject, "subject", data)
+	if err != nil {
+		return msg, err
+	}
+	err = tmpl.text.Execute(&text, data)
+	if err != nil {
+		return msg, err
+	}
+	err = tmpl.html.Execute(&html, data)
+	if err != nil {
+		return msg, err
+	}
+
+	msg.Subject = strings.TrimSpace(subject.String())
+	msg.Text = strings.TrimSpace(text.String()) + "\n"
+	msg.HTML = html.String()
+
+	return msg, nil
+}
@@ -0,0 +1,197 @@
+-- Upgrades a database created with the original create_table.sql, which only
+-- had the 'task' and 'task_item' tables, to schema version 1: projects,
+-- subtasks, comments, dependencies, boards, recurrence, the audit trail,
+-- attachments, webhooks, notification addresses and the schema_migrations
+-- table. Every statement is safe to run again on a database that already has
+-- some or all of it.
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS project (
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    owner_user_id INTEGER NOT NULL,
+    created_at TIMESTAMP,
+    updated_at TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS project_member (
+    project_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    role TEXT NOT NULL CHECK (role IN ('owner', 'member')),
+    created_at TIMESTAMP,
+    PRIMARY KEY (project_id, user_id),
+    FOREIGN KEY (project_id) REFERENCES project (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS project_member_user_id_idx ON project_member (user_id);
+
+-- Existing tasks are filed under the default project, which must be id 1
+INSERT INTO project (id, name, description, owner_user_id, created_at, updated_at)
+VALUES (1, 'Default', 'Tasks not filed under any other project.', 0, NOW(), NOW())
+ON CONFLICT (id) DO NOTHING;
+SELECT setval(pg_get_serial_sequence('project', 'id'), (SELECT MAX(id) FROM project));
+
+ALTER TABLE task ADD COLUMN IF NOT EXISTS assigned_user_id INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE task ADD COLUMN IF NOT EXISTS parent_task_id INTEGER REFERENCES task (id) ON DELETE CASCADE;
+ALTER TABLE task ADD COLUMN IF NOT EXISTS project_id INTEGER NOT NULL DEFAULT 1 REFERENCES project (id);
+ALTER TABLE task ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+
+DO $$
+BEGIN
+    ALTER TABLE task ADD CONSTRAINT task_check CHECK (parent_task_id <> id);
+EXCEPTION
+    WHEN duplicate_object THEN NULL;
+END
+$$;
+
+CREATE INDEX IF NOT EXISTS task_parent_task_id_idx ON task (parent_task_id);
+CREATE INDEX IF NOT EXISTS task_project_id_idx ON task (project_id);
+CREATE INDEX IF NOT EXISTS task_deleted_at_idx ON task (deleted_at) WHERE deleted_at IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS task_comment (
+    id SERIAL PRIMARY KEY,
+    task_id INTEGER NOT NULL,
+    comment TEXT NOT NULL,
+    created_at TIMESTAMP,
+    FOREIGN KEY (task_id) REFERENCES task (id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS task_dependency (
+    id SERIAL PRIMARY KEY,
+    task_id INTEGER NOT NULL,
+    related_task_id INTEGER NOT NULL,
+    type TEXT NOT NULL CHECK (type IN ('blocks', 'relates_to')),
+    created_at TIMESTAMP,
+    FOREIGN KEY (task_id) REFERENCES task (id) ON DELETE CASCADE,
+    FOREIGN KEY (related_task_id) REFERENCES task (id) ON DELETE CASCADE,
+    UNIQUE (task_id, related_task_id, type),
+    CHECK (task_id <> related_task_id)
+);
+
+CREATE INDEX IF NOT EXISTS task_dependency_related_task_id_idx ON task_dependency (related_task_id);
+
+CREATE TABLE IF NOT EXISTS board (
+    id SERIAL PRIMARY KEY,
+    project_id INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    created_at TIMESTAMP,
+    FOREIGN KEY (project_id) REFERENCES project (id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS board_column (
+    id SERIAL PRIMARY KEY,
+    board_id INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    position INTEGER NOT NULL,
+    state TEXT NOT NULL CHECK (state IN ('open', 'completed')),
+    FOREIGN KEY (board_id) REFERENCES board (id) ON DELETE CASCADE,
+    UNIQUE (board_id, position)
+);
+
+CREATE TABLE IF NOT EXISTS board_card (
+    board_id INTEGER NOT NULL,
+    task_id INTEGER NOT NULL,
+    column_id INTEGER NOT NULL,
+    rank TEXT COLLATE "C" NOT NULL,
+    PRIMARY KEY (board_id, task_id),
+    FOREIGN KEY (board_id) REFERENCES board (id) ON DELETE CASCADE,
+    FOREIGN KEY (task_id) REFERENCES task (id) ON DELETE CASCADE,
+    FOREIGN KEY (column_id) REFERENCES board_column (id) ON DELETE CASCADE,
+    UNIQUE (column_id, rank)
+);
+
+CREATE TABLE IF NOT EXISTS task_recurrence (
+    id SERIAL PRIMARY KEY,
+    task_id INTEGER NOT NULL UNIQUE,
+    rrule TEXT NOT NULL,
+    dtstart TIMESTAMP NOT NULL,
+    next_run_at TIMESTAMP,
+    last_run_at TIMESTAMP,
+    created_at TIMESTAMP,
+    FOREIGN KEY (task_id) REFERENCES task (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS task_recurrence_next_run_at_idx ON task_recurrence (next_run_at);
+
+CREATE TABLE IF NOT EXISTS task_recurrence_instance (
+    recurrence_id INTEGER NOT NULL,
+    occurrence_at TIMESTAMP NOT NULL,
+    task_id INTEGER,
+    PRIMARY KEY (recurrence_id, occurrence_at),
+    FOREIGN KEY (recurrence_id) REFERENCES task_recurrence (id) ON DELETE CASCADE,
+    FOREIGN KEY (task_id) REFERENCES task (id) ON DELETE SET NULL
+);
+
+CREATE TABLE IF NOT EXISTS task_event (
+    id SERIAL PRIMARY KEY,
+    task_id INTEGER NOT NULL,
+    actor_id INTEGER NOT NULL DEFAULT 0,
+    action TEXT NOT NULL,
+    changes JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS task_event_task_id_idx ON task_event (task_id, created_at DESC);
+
+CREATE TABLE IF NOT EXISTS task_attachment (
+    id SERIAL PRIMARY KEY,
+    task_id INTEGER NOT NULL,
+    filename TEXT NOT NULL,
+    content_type TEXT NOT NULL,
+    size BIGINT NOT NULL,
+    sha256 CHAR(64) NOT NULL,
+    uploaded_by INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (task_id) REFERENCES task (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS task_attachment_task_id_idx ON task_attachment (task_id);
+CREATE INDEX IF NOT EXISTS task_attachment_sha256_idx ON task_attachment (sha256);
+
+CREATE TABLE IF NOT EXISTS webhook (
+    id SERIAL PRIMARY KEY,
+    url TEXT NOT NULL,
+    secret TEXT NOT NULL,
+    events TEXT[] NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT true,
+    created_by INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS webhook_delivery (
+    id SERIAL PRIMARY KEY,
+    webhook_id INTEGER NOT NULL,
+    event_id INTEGER NOT NULL,
+    event TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    response_status INTEGER,
+    last_error TEXT,
+    payload JSONB,
+    next_attempt_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL,
+    delivered_at TIMESTAMP,
+    FOREIGN KEY (webhook_id) REFERENCES webhook (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS webhook_delivery_due_idx ON webhook_delivery (status, next_attempt_at);
+CREATE INDEX IF NOT EXISTS webhook_delivery_webhook_id_idx ON webhook_delivery (webhook_id, created_at DESC);
+
+CREATE TABLE IF NOT EXISTS user_email (
+    user_id INTEGER PRIMARY KEY,
+    email TEXT NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS schema_migrations (
+    version BIGINT PRIMARY KEY,
+    dirty BOOLEAN NOT NULL
+);
+
+INSERT INTO schema_migrations (version, dirty)
+SELECT 0, false
+WHERE NOT EXISTS (SELECT 1 FROM schema_migrations);
+UPDATE schema_migrations SET version = 1, dirty = false WHERE version < 1;
+
+COMMIT;
@@ -0,0 +1,14 @@
+-- Upgrades a version 1 database to version 2: indexes matching the keyset
+-- order of the task, comment and history lists. Safe to run again.
+BEGIN;
+
+CREATE INDEX IF NOT EXISTS task_updated_at_id_idx ON task (updated_at, id) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS task_comment_task_id_idx ON task_comment (task_id, created_at, id);
+
+-- Version 1 had this index without the id tiebreaker
+DROP INDEX IF EXISTS task_event_task_id_idx;
+CREATE INDEX task_event_task_id_idx ON task_event (task_id, created_at DESC, id DESC);
+
+UPDATE schema_migrations SET version = 2, dirty = false WHERE version < 2;
+
+COMMIT;
@@ -0,0 +1,13 @@
+-- Upgrades a version 2 database to version 3: webhooks belong to a project
+-- and are only sent its tasks' events. Existing webhooks are moved to the
+-- default project. Safe to run again.
+BEGIN;
+
+ALTER TABLE webhook ADD COLUMN IF NOT EXISTS project_id INTEGER NOT NULL DEFAULT 1 REFERENCES project (id) ON DELETE CASCADE;
+ALTER TABLE webhook ALTER COLUMN project_id DROP DEFAULT;
+
+CREATE INDEX IF NOT EXISTS webhook_project_id_idx ON webhook (project_id);
+
+UPDATE schema_migrations SET version = 3, dirty = false WHERE version < 3;
+
+COMMIT;
@@ -0,0 +1,194 @@
+package model
+
+import (
+	"time"
+)
+
+// TaskAttachment is the metadata of a file attached to a task. The content
+// itself is kept in a blob store under SHA256; attachments with identical
+// content share one blob.
+type TaskAttachment struct {
+	ID          int       `json:"id"`
+	TaskID      int       `json:"task_id"`
+	Filename    string    `json:"filename"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	SHA256      string    `json:"sha256"`
+	UploadedBy  int       `json:"uploaded_by"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func (taskDto TaskDto) InsertTaskAttachment(attachment *TaskAttachment) error {
+	tx, err := taskDto.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(`
+		INSERT INTO task_attachment (task_id, filename, content_type, size, sha256, uploaded_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id
+	`, attachment.TaskID, attachment.Filename, attachment.ContentType, attachment.Size, attachment.SHA256, attachment.UploadedBy, attachment.CreatedAt).Scan(&attachment.ID)
+	if err != nil {
+		return err
+	}
+
+	err = taskDto.recordTaskEvent(tx, attachment.TaskID, TaskEventAttachmentAdded, fieldChanges{
+		"attachment": {After: map[string]any{"id": attachment.ID, "filename": attachment.Filename, "size": attachment.Size}},
+	})
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (taskDto TaskDto) GetTaskAttachments(taskID int) ([]TaskAttachment, error) {
+	rows, err := taskDto.DB.Query(`
+		SELECT id, task_id, filename, content_type, size, sha256, uploaded_by, created_at
+		FROM task_attachment
+		WHERE task_id = $1
+		ORDER BY id
+	`, taskID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attachments := make([]TaskAttachment, 0)
+	for rows.Next() {
+		var attachment TaskAttachment
+		err := rows.Scan(
+			&attachment.ID,
+			&attachment.TaskID,
+			&attachment.Filename,
+			&attachment.ContentType,
+			&attachment.Size,
+			&attachment.SHA256,
+			&attachment.UploadedBy,
+			&attachment.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, attachment)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return attachments, nil
+}
+
+func (taskDto TaskDto) GetTaskAttachment(taskID, id int) (*TaskAttachment, error) {
+	attachment := &TaskAttachment{}
+	err := taskDto.DB.QueryRow(`
+		SELECT id, task_id, filename, content_type, size, sha256, uploaded_by, created_at
+		FROM task_attachment
+		WHERE id = $1 AND task_id = $2
+	`, id, taskID).Scan(
+		&attachment.ID,
+		&attachment.TaskID,
+		&attachment.Filename,
+		&attachment.ContentType,
+		&attachment.Size,
+		&attachment.SHA256,
+		&attachment.UploadedBy,
+		&attachment.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return attachment, nil
+}
+
+// DeleteTaskAttachment removes the attachment's metadata and returns it, so
+// the caller can release the blob. It returns sql.ErrNoRows if the task has
+// no such attachment.
+func (taskDto TaskDto) DeleteTaskAttachment(taskID, id int) (*TaskAttachment, error) {
+	tx, err := taskDto.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	attachment := &TaskAttachment{}
+	err = tx.QueryRow(`
+		DELETE FROM task_attachment
+		WHERE id = $1 AND task_id = $2
+		RETURNING id, task_id, filename, content_type, size, sha256, uploaded_by, created_at
+	`, id, taskID).Scan(
+		&attachment.ID,
+		&attachment.TaskID,
+		&attachment.Filename,
+		&attachment.ContentType,
+		&attachment.Size,
+		&attachment.SHA256,
+		&attachment.UploadedBy,
+		&attachment.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	err = taskDto.recordTaskEvent(tx, taskID, TaskEventAttachmentRemoved, fieldChanges{
+		"attachment": {Before: map[string]any{"id": attachment.ID, "filename": attachment.Filename, "size": attachment.Size}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return attachment, nil
+}
+
+// IsBlobReferenced reports whether any attachment still uses the blob.
+func (taskDto TaskDto) IsBlobReferenced(sha256 string) (bool, error) {
+	var referenced bool
+	err := taskDto.DB.QueryRow(`
+		SELECT EXISTS (SELECT 1 FROM task_attachment WHERE sha256 = $1)
+	`, sha256).Scan(&referenced)
+	if err != nil {
+		return false, err
+	}
+
+	return referenced, nil
+}
+
+// GetPurgeableAttachmentBlobs returns the blobs used by attachments of
+// tasks that PurgeDeletedTasks would remove for the same cutoff.
+func (taskDto TaskDto) GetPurgeableAttachmentBlobs(before time.Time) ([]string, error) {
+	rows, err := taskDto.DB.Query(`
+		SELECT DISTINCT a.sha256
+		FROM task_attachment a
+		JOIN task t ON t.id = a.task_id
+		WHERE t.deleted_at IS NOT NULL AND t.deleted_at < $1
+	`, before)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var blobs []string
+	for rows.Next() {
+		var sha256 string
+		err := rows.Scan(&sha256)
+		if err != nil {
+			return nil, err
+		}
+		blobs = append(blobs, sha256)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return blobs, nil
+}
@@ -0,0 +1,110 @@
+package model
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+var attachmentColumns = []string{"id", "task_id", "filename", "content_type", "size", "sha256", "uploaded_by", "created_at"}
+
+func TestInsertTaskAttachment_RecordsEvent(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	taskDto := TaskDto{DB: db, ActorID: 7}
+
+	attachment := &TaskAttachment{
+		TaskID:      1,
+		Filename:    "router.conf",
+		ContentType: "text/plain; charset=utf-8",
+		Size:        120,
+		SHA256:      "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824",
+		UploadedBy:  7,
+		CreatedAt:   time.Now(),
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO task_attachment").
+		WithArgs(1, "router.conf", attachment.ContentType, int64(120), attachment.SHA256, 7, attachment.CreatedAt).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4))
+	mock.ExpectExec("INSERT INTO task_event").
+		WithArgs(1, 7, TaskEventAttachmentAdded, []byte(`{"attachment":{"before":null,"after":{"filename":"router.conf","id":4,"size":120}}}`), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	err = taskDto.InsertTaskAttachment(attachment)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, attachment.ID)
+
+	err = mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+func TestDeleteTaskAttachment_ReturnsMetadata(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	taskDto := TaskDto{DB: db}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("DELETE FROM task_attachment WHERE id = \\$1 AND task_id = \\$2 RETURNING").
+		WithArgs(4, 1).
+		WillReturnRows(sqlmock.NewRows(attachmentColumns).
+			AddRow(4, 1, "photo.jpg", "image/jpeg", 2048, "abc", 7, time.Now()))
+	mock.ExpectExec("INSERT INTO task_event").
+		WithArgs(1, 0, TaskEventAttachmentRemoved, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	attachment, err := taskDto.DeleteTaskAttachment(1, 4)
+	assert.NoError(t, err)
+	assert.Equal(t, "abc", attachment.SHA256)
+
+	err = mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+func TestDeleteTaskAttachment_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	taskDto := TaskDto{DB: db}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("DELETE FROM task_attachment").
+		WithArgs(4, 2).
+		WillReturnRows(sqlmock.NewRows(attachmentColumns))
+	mock.ExpectRollback()
+
+	_, err = taskDto.DeleteTaskAttachment(2, 4)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	err = mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+func TestIsBlobReferenced(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	taskDto := TaskDto{DB: db}
+
+	mock.ExpectQuery("SELECT EXISTS \\(SELECT 1 FROM task_attachment WHERE sha256 = \\$1\\)").
+		WithArgs("abc").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	referenced, err := taskDto.IsBlobReferenced("abc")
+	assert.NoError(t, err)
+	assert.True(t, referenced)
+
+	err = mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
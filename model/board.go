@@ -0,0 +1,368 @@
+package model
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Column states. Moving a card into a column sets the task's completed flag
+// to match the column's state.
+const (
+	ColumnStateOpen      = "open"
+	ColumnStateCompleted = "completed"
+)
+
+// ErrCardNotOnBoard is returned when a move is positioned relative to a task
+// that has no card in the target column.
+var ErrCardNotOnBoard = errors.New("neighbouring task is not in the target column")
+
+type Board struct {
+	ID        int           `json:"id"`
+	ProjectID int           `json:"project_id"`
+	Name      string        `json:"name"`
+	CreatedAt time.Time     `json:"created_at"`
+	Columns   []BoardColumn `json:"columns"`
+}
+
+type BoardColumn struct {
+	ID       int         `json:"id"`
+	Name     string      `json:"name"`
+	Position int         `json:"position"`
+	State    string      `json:"state"`
+	Cards    []BoardCard `json:"cards"`
+}
+
+// BoardCard is a task placed in a board column. Cards in a column are
+// ordered by Rank; see RankBetween.
+type BoardCard struct {
+	Task
+	ColumnID int    `json:"column_id"`
+	Rank     string `json:"rank"`
+}
+
+// CardMove places a task in a column, directly after AfterTaskID, directly
+// before BeforeTaskID, or at the end of the column when neither is set.
+// AfterTaskID wins when both are given, so a client working from a stale
+// view of the column still lands next to the card it dropped onto.
+type CardMove struct {
+	ColumnID     int `json:"column_id"`
+	AfterTaskID  int `json:"after_task_id,omitempty"`
+	BeforeTaskID int `json:"before_task_id,omitempty"`
+}
+
+type BoardDto struct {
+	DB *sql.DB
+	// ActorID is the user recorded in the history of the tasks a change
+	// to the board completes or reopens.
+	ActorID int
+}
+
+// Insert creates the board together with its columns, positioned in the order given.
+func (boardDto BoardDto) Insert(board *Board) error {
+	tx, err := boardDto.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(`
+		INSERT INTO board (project_id, name, created_at)
+		VALUES ($1, $2, $3)
+		RETURNING id
+	`, board.ProjectID, board.Name, board.CreatedAt).Scan(&board.ID)
+	if err != nil {
+		return err
+	}
+
+	for i := range board.Columns {
+		column := &board.Columns[i]
+		column.Position = i
+		if column.Cards == nil {
+			column.Cards = make([]BoardCard, 0)
+		}
+		err = tx.QueryRow(`
+			INSERT INTO board_column (board_id, name, position, state)
+			VALUES ($1, $2, $3, $4)
+			RETURNING id
+		`, board.ID, column.Name, column.Position, column.State).Scan(&column.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetBoardsByProjectID returns the project's boards without their columns.
+func (boardDto BoardDto) GetBoardsByProjectID(projectID int) ([]Board, error) {
+	rows, err := boardDto.DB.Query(`
+		SELECT id, project_id, name, created_at
+		FROM board
+		WHERE project_id = $1
+		ORDER BY id
+	`, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	boards := make([]Board, 0)
+	for rows.Next() {
+		var board Board
+		err := rows.Scan(&board.ID, &board.ProjectID, &board.Name, &board.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		boards = append(boards, board)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return boards, nil
+}
+
+// GetBoard returns the board with its columns in position order and each
+// column's cards in rank order. Cards of tasks that are no longer in the
+// board's project are left out.
+func (boardDto BoardDto) GetBoard(id int) (*Board, error) {
+	board := &Board{Columns: make([]BoardColumn, 0)}
+	err := boardDto.DB.QueryRow(`
+		SELECT id, project_id, name, created_at
+		FROM board
+		WHERE id = $1
+	`, id).Scan(&board.ID, &board.ProjectID, &board.Name, &board.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := boardDto.DB.Query(`
+		SELECT id, name, position, state
+		FROM board_column
+		WHERE board_id = $1
+		ORDER BY position
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columnIndex := make(map[int]int)
+	for rows.Next() {
+		column := BoardColumn{Cards: make([]BoardCard, 0)}
+		err := rows.Scan(&column.ID, &column.Name, &column.Position, &column.State)
+		if err != nil {
+			return nil, err
+		}
+		columnIndex[column.ID] = len(board.Columns)
+		board.Columns = append(board.Columns, column)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	cardRows, err := boardDto.DB.Query(`
+		SELECT `+taskColumns+`, bc.column_id, bc.rank
+		FROM board_card bc
+		JOIN board b ON b.id = bc.board_id
+		JOIN task t ON t.id = bc.task_id AND t.project_id = b.project_id
+		WHERE bc.board_id = $1 AND t.deleted_at IS NULL
+		ORDER BY bc.column_id, bc.rank
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer cardRows.Close()
+
+	for cardRows.Next() {
+		var card BoardCard
+		err := scanTask(cardRows, &card.Task, &card.ColumnID, &card.Rank)
+		if err != nil {
+			return nil, err
+		}
+
+		if i, ok := columnIndex[card.ColumnID]; ok {
+			board.Columns[i].Cards = append(board.Columns[i].Cards, card)
+		}
+	}
+
+	if err = cardRows.Err(); err != nil {
+		return nil, err
+	}
+
+	return board, nil
+}
+
+// GetBoardProjectID returns the ID of the project the board belongs to.
+func (boardDto BoardDto) GetBoardProjectID(id int) (int, error) {
+	var projectID int
+	err := boardDto.DB.QueryRow(`SELECT project_id FROM board WHERE id = $1`, id).Scan(&projectID)
+	if err != nil {
+		return 0, err
+	}
+
+	return projectID, nil
+}
+
+// GetColumn returns a column of the board, without its cards.
+func (boardDto BoardDto) GetColumn(boardID, columnID int) (*BoardColumn, error) {
+	column := &BoardColumn{}
+	err := boardDto.DB.QueryRow(`
+		SELECT id, name, position, state
+		FROM board_column
+		WHERE id = $1 AND board_id = $2
+	`, columnID, boardID).Scan(&column.ID, &column.Name, &column.Position, &column.State)
+	if err != nil {
+		return nil, err
+	}
+
+	return column, nil
+}
+
+// MoveCard puts the task's card in the target column at the requested
+// position and brings the task's completed flag in line with the column,
+// all in one transaction. The column row is locked for the duration so
+// concurrent moves into the same column are applied one after the other,
+// each seeing the ranks the previous one committed. Completing the task
+// fails with ErrTaskBlocked if it has unfinished blockers. It returns the
+// card's new rank and whether the task's completed flag changed.
+func (boardDto BoardDto) MoveCard(boardID, taskID int, move CardMove, now time.Time) (string, bool, error) {
+	tx, err := boardDto.DB.Begin()
+	if err != nil {
+		return "", false, err
+	}
+	defer tx.Rollback()
+
+	var state string
+	err = tx.QueryRow(`
+		SELECT state
+		FROM board_column
+		WHERE id = $1 AND board_id = $2
+		FOR UPDATE
+	`, move.ColumnID, boardID).Scan(&state)
+	if err != nil {
+		return "", false, err
+	}
+
+	prev, next, err := neighbourRanks(tx, boardID, taskID, move)
+	if err != nil {
+		return "", false, err
+	}
+
+	rank, err := RankBetween(prev, next)
+	if err != nil {
+		return "", false, err
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO board_card (board_id, task_id, column_id, rank)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (board_id, task_id) DO UPDATE
+		SET column_id = EXCLUDED.column_id, rank = EXCLUDED.rank
+	`, boardID, taskID, move.ColumnID, rank)
+	if err != nil {
+		return "", false, err
+	}
+
+	taskDto := TaskDto{DB: boardDto.DB, ActorID: boardDto.ActorID}
+	changed, err := taskDto.setCompleted(tx, taskID, state == ColumnStateCompleted, now)
+	if err != nil {
+		return "", false, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return "", false, err
+	}
+
+	return rank, changed, nil
+}
+
+// neighbourRanks finds the ranks the moved card must sit between, ignoring
+// the card's own current position.
+func neighbourRanks(tx *sql.Tx, boardID, taskID int, move CardMove) (string, string, error) {
+	cardRank := func(neighbourID int) (string, error) {
+		var rank string
+		err := tx.QueryRow(`
+			SELECT rank
+			FROM board_card
+			WHERE board_id = $1 AND column_id = $2 AND task_id = $3
+		`, boardID, move.ColumnID, neighbourID).Scan(&rank)
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", ErrCardNotOnBoard
+		}
+		return rank, err
+	}
+
+	// adjacentRank returns the closest rank after (or before) the given one,
+	// or "" if there is none.
+	adjacentRank := func(query string, args ...any) (string, error) {
+		var rank string
+		err := tx.QueryRow(query, args...).Scan(&rank)
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", nil
+		}
+		return rank, err
+	}
+
+	switch {
+	case move.AfterTaskID != 0:
+		prev, err := cardRank(move.AfterTaskID)
+		if err != nil {
+			return "", "", err
+		}
+		next, err := adjacentRank(`
+			SELECT rank
+			FROM board_card
+			WHERE board_id = $1 AND column_id = $2 AND task_id <> $3 AND rank > $4
+			ORDER BY rank
+			LIMIT 1
+		`, boardID, move.ColumnID, taskID, prev)
+		return prev, next, err
+
+	case move.BeforeTaskID != 0:
+		next, err := cardRank(move.BeforeTaskID)
+		if err != nil {
+			return "", "", err
+		}
+		prev, err := adjacentRank(`
+			SELECT rank
+			FROM board_card
+			WHERE board_id = $1 AND column_id = $2 AND task_id <> $3 AND rank < $4
+			ORDER BY rank DESC
+			LIMIT 1
+		`, boardID, move.ColumnID, taskID, next)
+		return prev, next, err
+
+	default:
+		prev, err := adjacentRank(`
+			SELECT rank
+			FROM board_card
+			WHERE board_id = $1 AND column_id = $2 AND task_id <> $3
+			ORDER BY rank DESC
+			LIMIT 1
+		`, boardID, move.ColumnID, taskID)
+		return prev, "", err
+	}
+}
+
+// RemoveCard takes the task's card off the board. The task itself is kept.
+func (boardDto BoardDto) RemoveCard(boardID, taskID int) (bool, error) {
+	result, err := boardDto.DB.Exec(`
+		DELETE FROM board_card
+		WHERE board_id = $1 AND task_id = $2
+	`, boardID, taskID)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAffected > 0, nil
+}
@@ -0,0 +1,112 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMoveCard_AfterTask(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	boardDto := BoardDto{DB: db, ActorID: 5}
+	now := time.Now()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT state FROM board_column WHERE id = \\$1 AND board_id = \\$2 FOR UPDATE").
+		WithArgs(3, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"state"}).AddRow(ColumnStateCompleted))
+	mock.ExpectQuery("SELECT rank FROM board_card WHERE board_id = \\$1 AND column_id = \\$2 AND task_id = \\$3").
+		WithArgs(1, 3, 7).
+		WillReturnRows(sqlmock.NewRows([]string{"rank"}).AddRow("a"))
+	mock.ExpectQuery("SELECT rank FROM board_card .* rank > \\$4").
+		WithArgs(1, 3, 9, "a").
+		WillReturnRows(sqlmock.NewRows([]string{"rank"}).AddRow("c"))
+	mock.ExpectExec("INSERT INTO board_card .* ON CONFLICT \\(board_id, task_id\\) DO UPDATE").
+		WithArgs(1, 9, 3, "b").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	// Completing the task is checked against its blockers and recorded in the same transaction.
+	mock.ExpectQuery("SELECT completed FROM task WHERE id = \\$1 AND deleted_at IS NULL FOR UPDATE").
+		WithArgs(9).
+		WillReturnRows(sqlmock.NewRows([]string{"completed"}).AddRow(false))
+	mock.ExpectExec("SELECT pg_advisory_xact_lock").WithArgs(dependencyGraphLock).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT EXISTS").WithArgs(9).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectExec("UPDATE task SET completed = \\$1").
+		WithArgs(true, now, 9).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO task_event").
+		WithArgs(9, 5, TaskEventUpdated, []byte(`{"completed":{"before":false,"after":true}}`), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	rank, completed, err := boardDto.MoveCard(1, 9, CardMove{ColumnID: 3, AfterTaskID: 7}, now)
+	assert.NoError(t, err)
+	assert.Equal(t, "b", rank)
+	assert.True(t, completed)
+
+	err = mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+func TestMoveCard_NeighbourNotInColumn(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	boardDto := BoardDto{DB: db}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT state FROM board_column").
+		WithArgs(3, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"state"}).AddRow(ColumnStateOpen))
+	mock.ExpectQuery("SELECT rank FROM board_card").
+		WithArgs(1, 3, 8).
+		WillReturnRows(sqlmock.NewRows([]string{"rank"}))
+	mock.ExpectRollback()
+
+	_, _, err = boardDto.MoveCard(1, 9, CardMove{ColumnID: 3, BeforeTaskID: 8}, time.Now())
+	assert.ErrorIs(t, err, ErrCardNotOnBoard)
+
+	err = mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+func TestMoveCard_Blocked(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	boardDto := BoardDto{DB: db}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT state FROM board_column").
+		WithArgs(3, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"state"}).AddRow(ColumnStateCompleted))
+	mock.ExpectQuery("SELECT rank FROM board_card").
+		WithArgs(1, 3, 9).
+		WillReturnRows(sqlmock.NewRows([]string{"rank"}))
+	mock.ExpectExec("INSERT INTO board_card").
+		WithArgs(1, 9, 3, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT completed FROM task").
+		WithArgs(9).
+		WillReturnRows(sqlmock.NewRows([]string{"completed"}).AddRow(false))
+	mock.ExpectExec("SELECT pg_advisory_xact_lock").WithArgs(dependencyGraphLock).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT EXISTS").WithArgs(9).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectRollback()
+
+	// The card does not move either.
+	_, _, err = boardDto.MoveCard(1, 9, CardMove{ColumnID: 3}, time.Now())
+	assert.ErrorIs(t, err, ErrTaskBlocked)
+
+	err = mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
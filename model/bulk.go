@@ -0,0 +1,460 @@
+package model
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Bulk operation kinds.
+const (
+	BulkCreate   = "create"
+	BulkUpdate   = "update"
+	BulkDelete   = "delete"
+	BulkAssign   = "assign"
+	BulkComplete = "complete"
+)
+
+// Bulk operation outcomes. In an atomic batch that fails, operations that
+// had succeeded are rolled back and the ones after the failure are skipped.
+const (
+	BulkStatusOK         = "ok"
+	BulkStatusFailed     = "failed"
+	BulkStatusRolledBack = "rolled_back"
+	BulkStatusSkipped    = "skipped"
+)
+
+var (
+	ErrTaskBlocked     = errors.New("task has unfinished blockers")
+	ErrProjectNotFound = errors.New("project not found")
+	ErrParentNotFound  = errors.New("parent task not found")
+)
+
+// TaskFilter selects tasks for a bulk operation. Every given field must
+// match; at least one must be given.
+type TaskFilter struct {
+	AssignedUserID *int   `json:"assigned_user_id,omitempty"`
+	ProjectID      int    `json:"project_id,omitempty"`
+	ParentTaskID   int    `json:"parent_task_id,omitempty"`
+	Completed      *bool  `json:"completed,omitempty"`
+	TitleContains  string `json:"title_contains,omitempty"`
+	// VisibleTo, set by the server and never by clients, limits the tasks
+	// to the projects the user can access. See GetAccessRole.
+	VisibleTo int `json:"-"`
+}
+
+func (filter TaskFilter) empty() bool {
+	return filter.AssignedUserID == nil && filter.ProjectID == 0 && filter.ParentTaskID == 0 &&
+		filter.Completed == nil && filter.TitleContains == ""
+}
+
+// match reports whether the task matches every given field. The file store
+// has no project members, so VisibleTo is not checked.
+func (filter TaskFilter) match(task Task) bool {
+	return (filter.AssignedUserID == nil || task.AssignedUserID == *filter.AssignedUserID) &&
+		(filter.ProjectID == 0 || task.ProjectID == filter.ProjectID) &&
+		(filter.ParentTaskID == 0 || task.ParentTaskID == filter.ParentTaskID) &&
+		(filter.Completed == nil || task.Completed == *filter.Completed) &&
+		(filter.TitleContains == "" || strings.Contains(strings.ToLower(task.Title), strings.ToLower(filter.TitleContains)))
+}
+
+// addTo adds the filter's fields to conditions on the task table.
+func (filter TaskFilter) addTo(where *conditions) {
+	if filter.AssignedUserID != nil {
+		where.add("assigned_user_id = $%d", *filter.AssignedUserID)
+	}
+	if filter.ProjectID != 0 {
+		where.add("project_id = $%d", filter.ProjectID)
+	}
+	if filter.ParentTaskID != 0 {
+		where.add("parent_task_id = $%d", filter.ParentTaskID)
+	}
+	if filter.Completed != nil {
+		where.add("completed = $%d", *filter.Completed)
+	}
+	if filter.TitleContains != "" {
+		where.add("strpos(lower(title), lower($%d)) > 0", filter.TitleContains)
+	}
+	if filter.VisibleTo != 0 {
+		where.add(visibleTo("project_id"), filter.VisibleTo)
+	}
+}
+
+// BulkOperation is one step of a bulk request. Update, delete, assign and
+// complete target either a single task by ID or, except for update, every
+// task matching Filter.
+type BulkOperation struct {
+	Op     string      `json:"op"`
+	ID     int         `json:"id,omitempty"`
+	Filter *TaskFilter `json:"filter,omitempty"`
+	Task   *Task       `json:"task,omitempty"`
+	UserID int         `json:"user_id,omitempty"`
+}
+
+// Validate checks the operation is well formed, before anything is run.
+func (op BulkOperation) Validate() error {
+	switch op.Op {
+	case BulkCreate:
+		if op.Task == nil || op.ID != 0 || op.Filter != nil {
+			return errors.New("create takes a task and no id or filter")
+		}
+		return nil
+	case BulkUpdate:
+		if op.Task == nil || op.ID < 1 || op.Filter != nil {
+			return errors.New("update takes an id and a task")
+		}
+		return nil
+	case BulkDelete, BulkAssign, BulkComplete:
+		if (op.ID < 1) == (op.Filter == nil) {
+			return fmt.Errorf("%s takes either an id or a filter", op.Op)
+		}
+		if op.Filter != nil && op.Filter.empty() {
+			return errors.New("a filter must have at least one field")
+		}
+		if op.Op == BulkAssign && op.UserID < 0 {
+			return errors.New("invalid user_id")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown op %q", op.Op)
+	}
+}
+
+// BulkResult is the outcome of one operation. TaskIDs are the tasks it
+// applied to, and Changes what it did to each of them; Task is the created
+// task. Err is the underlying error, for logging, and Error the message safe
+// to return to the caller.
+type BulkResult struct {
+	Index   int          `json:"index"`
+	Op      string       `json:"op"`
+	Status  string       `json:"status"`
+	TaskIDs []int        `json:"task_ids,omitempty"`
+	Task    *Task        `json:"task,omitempty"`
+	Error   string       `json:"error,omitempty"`
+	Err     error        `json:"-"`
+	Changes []BulkChange `json:"-"`
+}
+
+// BulkChange is what an operation did to one task. Changed is false if the
+// task already was as the operation asked, or was already in the trash.
+// AssignedUserID is who the task was assigned to before the operation.
+type BulkChange struct {
+	TaskID         int
+	Changed        bool
+	AssignedUserID int
+}
+
+// RunBulk runs the operations in order in a single transaction. If atomic
+// is true, the first failure rolls back the whole batch; otherwise each
+// operation runs under its own savepoint, so a failed one is undone and the
+// rest carry on. It reports whether the transaction was committed.
+//
+// Operations only reach the projects the actor can access: tasks elsewhere
+// are not found. An actor of 0 reaches every project.
+func (taskDto TaskDto) RunBulk(ops []BulkOperation, atomic bool, now time.Time) ([]BulkResult, bool, error) {
+	results := make([]BulkResult, len(ops))
+	for i, op := range ops {
+		results[i] = BulkResult{Index: i, Op: op.Op, Status: BulkStatusSkipped}
+	}
+
+	tx, err := taskDto.DB.Begin()
+	if err != nil {
+		return nil, false, err
+	}
+	defer tx.Rollback()
+
+	for i, op := range ops {
+		if !atomic {
+			_, err = tx.Exec(`SAVEPOINT bulk_operation`)
+			if err != nil {
+				return nil, false, err
+			}
+		}
+
+		changes, task, opErr := taskDto.runBulkOperation(tx, op, now)
+		if opErr != nil {
+			results[i].Status = BulkStatusFailed
+			results[i].Error = bulkErrorMessage(opErr)
+			results[i].Err = opErr
+
+			if atomic {
+				for j := 0; j < i; j++ {
+					results[j].Status = BulkStatusRolledBack
+				}
+				return results, false, nil
+			}
+
+			_, err = tx.Exec(`ROLLBACK TO SAVEPOINT bulk_operation`)
+			if err != nil {
+				return nil, false, err
+			}
+			continue
+		}
+
+		if !atomic {
+			_, err = tx.Exec(`RELEASE SAVEPOINT bulk_operation`)
+			if err != nil {
+				return nil, false, err
+			}
+		}
+
+		results[i].Status = BulkStatusOK
+		results[i].TaskIDs = make([]int, len(changes))
+		for j, change := range changes {
+			results[i].TaskIDs[j] = change.TaskID
+		}
+		results[i].Task = task
+		results[i].Changes = changes
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, false, err
+	}
+
+	return results, true, nil
+}
+
+func (taskDto TaskDto) runBulkOperation(tx *sql.Tx, op BulkOperation, now time.Time) ([]BulkChange, *Task, error) {
+	if op.Op == BulkCreate {
+		task, err := taskDto.bulkCreate(tx, *op.Task, now)
+		if err != nil {
+			return nil, nil, err
+		}
+		return []BulkChange{{TaskID: task.ID, Changed: true}}, task, nil
+	}
+
+	changes, err := lockBulkTargets(tx, op, taskDto.ActorID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for i, change := range changes {
+		var changed bool
+		switch op.Op {
+		case BulkUpdate:
+			changed, err = taskDto.bulkUpdate(tx, change.TaskID, *op.Task)
+		case BulkDelete:
+			err = taskDto.deleteTask(tx, change.TaskID)
+			changed = err == nil
+			// A task deleted earlier with its parent is already in the trash.
+			if errors.Is(err, sql.ErrNoRows) && op.Filter != nil {
+				err = nil
+			}
+		case BulkAssign:
+			changed, err = taskDto.assignUserToTask(tx, change.TaskID, op.UserID, now)
+		case BulkComplete:
+			changed, err = taskDto.setCompleted(tx, change.TaskID, true, now)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		changes[i].Changed = changed
+	}
+
+	return changes, nil, nil
+}
+
+// lockBulkTargets returns the live tasks an operation applies to, with
+// their assignees, locking them for the rest of the batch, among those in
+// projects the user can access. A single missing task is sql.ErrNoRows; a
+// filter may match nothing.
+func lockBulkTargets(tx *sql.Tx, op BulkOperation, userID int) ([]BulkChange, error) {
+	var where conditions
+	where.add("deleted_at IS NULL")
+	if op.Filter == nil {
+		where.add("id = $%d", op.ID)
+	} else {
+		op.Filter.addTo(&where)
+	}
+	if userID != 0 {
+		where.add(visibleTo("project_id"), userID)
+	}
+
+	rows, err := tx.Query(`SELECT id, assigned_user_id FROM task WHERE `+where.String()+` ORDER BY id FOR UPDATE`, where.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	targets := make([]BulkChange, 0)
+	for rows.Next() {
+		var target BulkChange
+		err := rows.Scan(&target.TaskID, &target.AssignedUserID)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, target)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	if op.Filter == nil && len(targets) == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	return targets, nil
+}
+
+// bulkCreate creates a task the way POST /tasks does: unassigned, in the
+// default project unless another is given, under an existing parent.
+func (taskDto TaskDto) bulkCreate(tx *sql.Tx, task Task, now time.Time) (*Task, error) {
+	task.ID = 0
+	task.CreatedAt = now
+	task.UpdatedAt = now
+	task.AssignedUserID = 0
+	task.DeletedAt = nil
+	if task.ProjectID == 0 {
+		task.ProjectID = DefaultProjectID
+	}
+
+	err := checkProjectAndParent(tx, task, taskDto.ActorID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = taskDto.insertTask(tx, &task)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, item := range task.Items {
+		err = taskDto.insertTaskItem(tx, task.ID, item)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &task, nil
+}
+
+// bulkUpdate replaces the task's title, description, completed flag and
+// items, as PUT /tasks/{id} does, and reports whether any of them changed.
+func (taskDto TaskDto) bulkUpdate(tx *sql.Tx, taskID int, task Task) (bool, error) {
+	task.ID = taskID
+	return taskDto.updateTask(tx, taskID, &task)
+}
+
+// setCompleted sets the task's completed flag and records the change,
+// checking its blockers first if it is being completed. It reports whether
+// the flag changed. The task row stays locked until the transaction ends,
+// so concurrent changes to the flag are applied one after the other.
+func (taskDto TaskDto) setCompleted(tx *sql.Tx, taskID int, completed bool, now time.Time) (bool, error) {
+	var was bool
+	err := tx.QueryRow(`SELECT completed FROM task WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`, taskID).Scan(&was)
+	if err != nil {
+		return false, err
+	}
+	if was == completed {
+		return false, nil
+	}
+
+	if completed {
+		err = checkBlockers(tx, taskID)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	_, err = tx.Exec(`UPDATE task SET completed = $1, updated_at = $2 WHERE id = $3`, completed, now, taskID)
+	if err != nil {
+		return false, err
+	}
+
+	err = taskDto.recordTaskEvent(tx, taskID, TaskEventUpdated, fieldChanges{"completed": {Before: was, After: completed}})
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// checkProjectAndParent returns ErrProjectNotFound or ErrParentNotFound
+// unless the task's project exists and is one the user can access, and its
+// parent, if it has one, is a live task in that same project. A user of 0
+// can access every project. The parent row is locked, so it cannot move to
+// another project before tx commits.
+func checkProjectAndParent(tx *sql.Tx, task Task, userID int) error {
+	var project conditions
+	project.add("id = $%d", task.ProjectID)
+	if userID != 0 {
+		project.add(visibleTo("id"), userID)
+	}
+
+	var exists bool
+	err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM project WHERE `+project.String()+`)`, project.args...).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrProjectNotFound
+	}
+
+	if task.ParentTaskID == 0 {
+		return nil
+	}
+
+	var one int
+	err = tx.QueryRow(`
+		SELECT 1
+		FROM task
+		WHERE id = $1 AND project_id = $2 AND deleted_at IS NULL
+		FOR SHARE
+	`, task.ParentTaskID, task.ProjectID).Scan(&one)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrParentNotFound
+	}
+
+	return err
+}
+
+// checkBlockers returns ErrTaskBlocked if the task has unfinished blockers,
+// seeing the changes made earlier in tx. It takes dependencyGraphLock, so
+// no blocker can be added between the check and the commit.
+func checkBlockers(tx *sql.Tx, taskID int) error {
+	_, err := tx.Exec(`SELECT pg_advisory_xact_lock($1)`, dependencyGraphLock)
+	if err != nil {
+		return err
+	}
+
+	blocked, err := hasUnfinishedBlockers(tx, taskID)
+	if err != nil {
+		return err
+	}
+	if blocked {
+		return ErrTaskBlocked
+	}
+	return nil
+}
+
+func bulkErrorMessage(err error) string {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return "task not found"
+	case errors.Is(err, ErrTaskBlocked), errors.Is(err, ErrProjectNotFound), errors.Is(err, ErrParentNotFound):
+		return err.Error()
+	default:
+		return "the operation could not be completed"
+	}
+}
+
+// Bulk modes.
+const (
+	BulkModeAtomic     = "atomic"
+	BulkModeBestEffort = "best_effort"
+)
+
+// BulkRequest is the body of POST /tasks/bulk. Mode defaults to atomic.
+type BulkRequest struct {
+	Mode       string          `json:"mode"`
+	Operations []BulkOperation `json:"operations"`
+}
+
+// BulkResponse reports whether the batch was committed and how each operation went.
+type BulkResponse struct {
+	Mode      string       `json:"mode"`
+	Committed bool         `json:"committed"`
+	Results   []BulkResult `json:"results"`
+}
@@ -0,0 +1,126 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBulkOperation_Validate(t *testing.T) {
+	user := 5
+
+	assert.NoError(t, BulkOperation{Op: BulkCreate, Task: &Task{Title: "a"}}.Validate())
+	assert.NoError(t, BulkOperation{Op: BulkComplete, Filter: &TaskFilter{AssignedUserID: &user}}.Validate())
+	assert.NoError(t, BulkOperation{Op: BulkAssign, ID: 3, UserID: 5}.Validate())
+
+	assert.Error(t, BulkOperation{Op: BulkUpdate, Filter: &TaskFilter{ProjectID: 1}, Task: &Task{}}.Validate())
+	assert.Error(t, BulkOperation{Op: BulkDelete, ID: 3, Filter: &TaskFilter{ProjectID: 1}}.Validate())
+	assert.Error(t, BulkOperation{Op: BulkDelete, Filter: &TaskFilter{}}.Validate())
+	assert.Error(t, BulkOperation{Op: BulkComplete}.Validate())
+	assert.Error(t, BulkOperation{Op: "archive", ID: 1}.Validate())
+}
+
+func TestRunBulk_AtomicRollsBackOnFailure(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	taskDto := TaskDto{DB: db, ActorID: 2}
+	now := time.Now()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id, assigned_user_id FROM task WHERE deleted_at IS NULL AND id = \\$1 AND \\(project_id = 1 OR project_id IN .*\\$2\\)\\) ORDER BY id FOR UPDATE").
+		WithArgs(1, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "assigned_user_id"}).AddRow(1, 0))
+	mock.ExpectQuery("SELECT assigned_user_id FROM task WHERE id = \\$1 AND deleted_at IS NULL FOR UPDATE").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"assigned_user_id"}).AddRow(0))
+	mock.ExpectPrepare("UPDATE task").
+		ExpectExec().
+		WithArgs(5, now, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO task_event").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	// Missing, or in a project user 2 is not a member of.
+	mock.ExpectQuery("SELECT id, assigned_user_id FROM task WHERE deleted_at IS NULL AND id = \\$1").
+		WithArgs(99, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "assigned_user_id"}))
+	mock.ExpectRollback()
+
+	results, committed, err := taskDto.RunBulk([]BulkOperation{
+		{Op: BulkAssign, ID: 1, UserID: 5},
+		{Op: BulkDelete, ID: 99},
+		{Op: BulkComplete, ID: 2},
+	}, true, now)
+
+	assert.NoError(t, err)
+	assert.False(t, committed)
+	assert.Equal(t, BulkStatusRolledBack, results[0].Status)
+	assert.Equal(t, BulkStatusFailed, results[1].Status)
+	assert.Equal(t, "task not found", results[1].Error)
+	assert.Equal(t, BulkStatusSkipped, results[2].Status)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRunBulk_BestEffortCompletesByFilter(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	taskDto := TaskDto{DB: db}
+	now := time.Now()
+	user := 5
+
+	mock.ExpectBegin()
+
+	// Complete every open task assigned to user 5: task 3 succeeds, task 4 is blocked.
+	mock.ExpectExec("SAVEPOINT bulk_operation").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT id, assigned_user_id FROM task WHERE deleted_at IS NULL AND assigned_user_id = \\$1 AND completed = \\$2 ORDER BY id FOR UPDATE").
+		WithArgs(5, false).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "assigned_user_id"}).AddRow(3, 5).AddRow(4, 5))
+	mock.ExpectQuery("SELECT completed FROM task").WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"completed"}).AddRow(false))
+	mock.ExpectExec("SELECT pg_advisory_xact_lock").WithArgs(dependencyGraphLock).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT EXISTS").WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectExec("UPDATE task SET completed = \\$1").WithArgs(true, now, 3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO task_event").
+		WithArgs(3, 0, TaskEventUpdated, []byte(`{"completed":{"before":false,"after":true}}`), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("SELECT completed FROM task").WithArgs(4).
+		WillReturnRows(sqlmock.NewRows([]string{"completed"}).AddRow(false))
+	mock.ExpectExec("SELECT pg_advisory_xact_lock").WithArgs(dependencyGraphLock).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT EXISTS").WithArgs(4).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectExec("ROLLBACK TO SAVEPOINT bulk_operation").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	// The next operation still runs, but task 7 is already completed.
+	mock.ExpectExec("SAVEPOINT bulk_operation").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT id, assigned_user_id FROM task WHERE deleted_at IS NULL AND id = \\$1 ORDER BY id FOR UPDATE").
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "assigned_user_id"}).AddRow(7, 6))
+	mock.ExpectQuery("SELECT completed FROM task").WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"completed"}).AddRow(true))
+	mock.ExpectExec("RELEASE SAVEPOINT bulk_operation").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	completed := false
+	results, committed, err := taskDto.RunBulk([]BulkOperation{
+		{Op: BulkComplete, Filter: &TaskFilter{AssignedUserID: &user, Completed: &completed}},
+		{Op: BulkComplete, ID: 7},
+	}, false, now)
+
+	assert.NoError(t, err)
+	assert.True(t, committed)
+	assert.Equal(t, BulkStatusFailed, results[0].Status)
+	assert.Equal(t, ErrTaskBlocked.Error(), results[0].Error)
+	assert.Equal(t, BulkStatusOK, results[1].Status)
+	assert.Equal(t, []int{7}, results[1].TaskIDs)
+	assert.Equal(t, []BulkChange{{TaskID: 7, Changed: false, AssignedUserID: 6}}, results[1].Changes)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,379 @@
+package model
+
+import (
+	"container/heap"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Dependency types. A "blocks" row means TaskID blocks RelatedTaskID; the
+// same row read from the other side is reported as "blocked_by".
+const (
+	DependencyBlocks    = "blocks"
+	DependencyBlockedBy = "blocked_by"
+	DependencyRelatesTo = "relates_to"
+)
+
+var (
+	// ErrDependencyCycle is returned when a blocking link would close a loop.
+	ErrDependencyCycle = errors.New("task dependency would create a cycle")
+	// ErrDuplicateDependency is returned when the same link already exists.
+	ErrDuplicateDependency = errors.New("task dependency already exists")
+	// ErrInvalidDependency is returned for self links and unknown link types.
+	ErrInvalidDependency = errors.New("invalid task dependency")
+)
+
+// dependencyGraphLock is the advisory lock taken while a blocking link is
+// checked for cycles and added. Two links added at once could each pass the
+// check and together close a loop through any number of other tasks, so
+// locking the rows of the two tasks linked is not enough. Completing a task
+// takes it too, so a blocker cannot be added while its blockers are checked.
+const dependencyGraphLock = 0x746d735f646570 // "tms_dep"
+
+type TaskDependency struct {
+	ID            int       `json:"id"`
+	TaskID        int       `json:"task_id"`
+	RelatedTaskID int       `json:"related_task_id"`
+	Type          string    `json:"type"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// normalize rewrites a dependency into the form it is stored in: blocked_by
+// becomes blocks with the two tasks swapped, and relates_to always has the
+// lower task ID first so each pair is stored once.
+func (dep TaskDependency) normalize() (TaskDependency, error) {
+	if dep.TaskID == dep.RelatedTaskID {
+		return dep, ErrInvalidDependency
+	}
+
+	switch dep.Type {
+	case DependencyBlocks:
+	case DependencyBlockedBy:
+		dep.TaskID, dep.RelatedTaskID = dep.RelatedTaskID, dep.TaskID
+		dep.Type = DependencyBlocks
+	case DependencyRelatesTo:
+		if dep.TaskID > dep.RelatedTaskID {
+			dep.TaskID, dep.RelatedTaskID = dep.RelatedTaskID, dep.TaskID
+		}
+	default:
+		return dep, ErrInvalidDependency
+	}
+
+	return dep, nil
+}
+
+// InsertTaskDependency stores a link between two tasks. Blocking links are
+// checked for cycles inside the same transaction as the insert, which holds
+// dependencyGraphLock until it commits.
+func (taskDto TaskDto) InsertTaskDependency(dep *TaskDependency) error {
+	stored, err := dep.normalize()
+	if err != nil {
+		return err
+	}
+
+	tx, err := taskDto.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	err = tx.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1 FROM task_dependency
+			WHERE task_id = $1 AND related_task_id = $2 AND type = $3
+		)
+	`, stored.TaskID, stored.RelatedTaskID, stored.Type).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return ErrDuplicateDependency
+	}
+
+	if stored.Type == DependencyBlocks {
+		_, err = tx.Exec(`SELECT pg_advisory_xact_lock($1)`, dependencyGraphLock)
+		if err != nil {
+			return err
+		}
+
+		// Adding "A blocks B" closes a loop if B already (transitively) blocks A.
+		var cycle bool
+		err = tx.QueryRow(`
+			WITH RECURSIVE blocked AS (
+				SELECT related_task_id AS id
+				FROM task_dependency
+				WHERE task_id = $1 AND type = 'blocks'
+				UNION
+				SELECT d.related_task_id
+				FROM task_dependency d
+				JOIN blocked b ON d.task_id = b.id
+				WHERE d.type = 'blocks'
+			)
+			SELECT EXISTS (SELECT 1 FROM blocked WHERE id = $2)
+		`, stored.RelatedTaskID, stored.TaskID).Scan(&cycle)
+		if err != nil {
+			return err
+		}
+		if cycle {
+			return ErrDependencyCycle
+		}
+	}
+
+	err = tx.QueryRow(`
+		INSERT INTO task_dependency (task_id, related_task_id, type, created_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+	`, stored.TaskID, stored.RelatedTaskID, stored.Type, dep.CreatedAt).Scan(&dep.ID)
+	if err != nil {
+		return err
+	}
+
+	err = taskDto.recordTaskEvent(tx, dep.TaskID, TaskEventDependencyAdded, fieldChanges{
+		"dependency": {After: map[string]any{"id": dep.ID, "type": dep.Type, "related_task_id": dep.RelatedTaskID}},
+	})
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetTaskDependencies returns every link that involves the task, seen from
+// that task: TaskID is always the given task.
+func (taskDto TaskDto) GetTaskDependencies(taskID int) ([]TaskDependency, error) {
+	query := `
+		SELECT d.id, d.task_id, d.related_task_id, d.type, d.created_at
+		FROM task_dependency d
+		JOIN task t ON t.id = d.related_task_id
+		WHERE d.task_id = $1 AND t.deleted_at IS NULL
+		UNION ALL
+		SELECT d.id, d.related_task_id, d.task_id, CASE d.type WHEN 'blocks' THEN 'blocked_by' ELSE d.type END, d.created_at
+		FROM task_dependency d
+		JOIN task t ON t.id = d.task_id
+		WHERE d.related_task_id = $1 AND t.deleted_at IS NULL
+		ORDER BY 1
+	`
+
+	rows, err := taskDto.DB.Query(query, taskID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deps := make([]TaskDependency, 0)
+	for rows.Next() {
+		var dep TaskDependency
+		err := rows.Scan(&dep.ID, &dep.TaskID, &dep.RelatedTaskID, &dep.Type, &dep.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		deps = append(deps, dep)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return deps, nil
+}
+
+// DeleteTaskDependency removes a link, provided it involves the given task.
+func (taskDto TaskDto) DeleteTaskDependency(taskID, dependencyID int) (bool, error) {
+	tx, err := taskDto.DB.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var dep TaskDependency
+	err = tx.QueryRow(`
+		DELETE FROM task_dependency
+		WHERE id = $1 AND (task_id = $2 OR related_task_id = $2)
+		RETURNING id, task_id, related_task_id, type
+	`, dependencyID, taskID).Scan(&dep.ID, &dep.TaskID, &dep.RelatedTaskID, &dep.Type)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	// Describe the link from the side of the task it was removed from.
+	if dep.TaskID != taskID {
+		dep.TaskID, dep.RelatedTaskID = dep.RelatedTaskID, dep.TaskID
+		if dep.Type == DependencyBlocks {
+			dep.Type = DependencyBlockedBy
+		}
+	}
+
+	err = taskDto.recordTaskEvent(tx, taskID, TaskEventDependencyRemoved, fieldChanges{
+		"dependency": {Before: map[string]any{"id": dep.ID, "type": dep.Type, "related_task_id": dep.RelatedTaskID}},
+	})
+	if err != nil {
+		return false, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// HasUnfinishedBlockers reports whether any task blocking the given task is still open.
+func (taskDto TaskDto) HasUnfinishedBlockers(taskID int) (bool, error) {
+	return hasUnfinishedBlockers(taskDto.DB, taskID)
+}
+
+// hasUnfinishedBlockers runs against the pool or, to see its own changes, a transaction.
+func hasUnfinishedBlockers(q interface {
+	QueryRow(query string, args ...any) *sql.Row
+}, taskID int) (bool, error) {
+	var blocked bool
+	err := q.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1
+			FROM task_dependency d
+			JOIN task t ON t.id = d.task_id
+			WHERE d.related_task_id = $1 AND d.type = 'blocks' AND NOT t.completed AND t.deleted_at IS NULL
+		)
+	`, taskID).Scan(&blocked)
+	if err != nil {
+		return false, err
+	}
+
+	return blocked, nil
+}
+
+// GetOpenTaskOrder returns the open tasks filter matches in an order that
+// respects every blocking link between them. Ties are broken by task ID so
+// the order is stable.
+func (taskDto TaskDto) GetOpenTaskOrder(filter TaskFilter) ([]Task, error) {
+	var where conditions
+	where.add("t.deleted_at IS NULL")
+	where.add("NOT t.completed")
+	filter.addTo(&where)
+
+	tasks, err := taskDto.queryTasks(`
+		SELECT `+taskColumns+`
+		FROM task t
+		WHERE `+where.String()+`
+		ORDER BY t.id
+	`, where.args...)
+	if err != nil {
+		return nil, err
+	}
+
+	openTasks := make(map[int]Task)
+	ids := make([]int, 0, len(tasks))
+	for _, task := range tasks {
+		openTasks[task.ID] = task
+		ids = append(ids, task.ID)
+	}
+
+	rows, err := taskDto.DB.Query(`
+		SELECT d.task_id, d.related_task_id
+		FROM task_dependency d
+		JOIN task blocker ON blocker.id = d.task_id
+		JOIN task blocked ON blocked.id = d.related_task_id
+		WHERE d.type = 'blocks' AND NOT blocker.completed AND NOT blocked.completed
+			AND blocker.deleted_at IS NULL AND blocked.deleted_at IS NULL
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var edges [][2]int
+	for rows.Next() {
+		var edge [2]int
+		err := rows.Scan(&edge[0], &edge[1])
+		if err != nil {
+			return nil, err
+		}
+		edges = append(edges, edge)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	order, err := TopologicalOrder(ids, edges)
+	if err != nil {
+		return nil, err
+	}
+
+	ordered := make([]Task, 0, len(order))
+	for _, id := range order {
+		ordered = append(ordered, openTasks[id])
+	}
+
+	return ordered, nil
+}
+
+// TopologicalOrder sorts ids so that for every edge {a, b}, a comes before b,
+// using Kahn's algorithm with the smallest ready ID taken first. Edges that
+// mention unknown IDs are ignored. It returns ErrDependencyCycle if the edges
+// contain a loop.
+func TopologicalOrder(ids []int, edges [][2]int) ([]int, error) {
+	inDegree := make(map[int]int, len(ids))
+	for _, id := range ids {
+		inDegree[id] = 0
+	}
+
+	successors := make(map[int][]int)
+	for _, edge := range edges {
+		_, okFrom := inDegree[edge[0]]
+		_, okTo := inDegree[edge[1]]
+		if !okFrom || !okTo {
+			continue
+		}
+		successors[edge[0]] = append(successors[edge[0]], edge[1])
+		inDegree[edge[1]]++
+	}
+
+	ready := &intHeap{}
+	for id, degree := range inDegree {
+		if degree == 0 {
+			*ready = append(*ready, id)
+		}
+	}
+	heap.Init(ready)
+
+	order := make([]int, 0, len(inDegree))
+	for ready.Len() > 0 {
+		id := heap.Pop(ready).(int)
+		order = append(order, id)
+
+		for _, successor := range successors[id] {
+			inDegree[successor]--
+			if inDegree[successor] == 0 {
+				heap.Push(ready, successor)
+			}
+		}
+	}
+
+	if len(order) != len(inDegree) {
+		return nil, ErrDependencyCycle
+	}
+
+	return order, nil
+}
+
+// intHeap is a min-heap of task IDs.
+type intHeap []int
+
+func (h intHeap) Len() int           { return len(h) }
+func (h intHeap) Less(i, j int) bool { return h[i] < h[j] }
+func (h intHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *intHeap) Push(x any)        { *h = append(*h, x.(int)) }
+func (h *intHeap) Pop() any {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
@@ -0,0 +1,124 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTopologicalOrder(t *testing.T) {
+	// 3 blocks 1, 1 blocks 2, 4 is independent.
+	order, err := TopologicalOrder([]int{1, 2, 3, 4}, [][2]int{{3, 1}, {1, 2}})
+	assert.NoError(t, err)
+	assert.Equal(t, []int{3, 1, 2, 4}, order)
+}
+
+func TestTopologicalOrder_IgnoresUnknownTasks(t *testing.T) {
+	order, err := TopologicalOrder([]int{2, 1}, [][2]int{{9, 1}, {2, 1}})
+	assert.NoError(t, err)
+	assert.Equal(t, []int{2, 1}, order)
+}
+
+func TestTopologicalOrder_DetectsCycle(t *testing.T) {
+	_, err := TopologicalOrder([]int{1, 2, 3}, [][2]int{{1, 2}, {2, 3}, {3, 1}})
+	assert.ErrorIs(t, err, ErrDependencyCycle)
+}
+
+func TestTaskDependencyNormalize(t *testing.T) {
+	dep, err := TaskDependency{TaskID: 5, RelatedTaskID: 2, Type: DependencyBlockedBy}.normalize()
+	assert.NoError(t, err)
+	assert.Equal(t, TaskDependency{TaskID: 2, RelatedTaskID: 5, Type: DependencyBlocks}, dep)
+
+	dep, err = TaskDependency{TaskID: 5, RelatedTaskID: 2, Type: DependencyRelatesTo}.normalize()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, dep.TaskID)
+
+	_, err = TaskDependency{TaskID: 5, RelatedTaskID: 5, Type: DependencyBlocks}.normalize()
+	assert.ErrorIs(t, err, ErrInvalidDependency)
+
+	_, err = TaskDependency{TaskID: 5, RelatedTaskID: 6, Type: "duplicates"}.normalize()
+	assert.ErrorIs(t, err, ErrInvalidDependency)
+}
+
+func TestInsertTaskDependency_SuccessfulInsert(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	taskDto := TaskDto{DB: db}
+
+	dep := &TaskDependency{TaskID: 2, RelatedTaskID: 1, Type: DependencyBlockedBy, CreatedAt: time.Now()}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT EXISTS").
+		WithArgs(1, 2, DependencyBlocks).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectExec("SELECT pg_advisory_xact_lock").
+		WithArgs(dependencyGraphLock).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("WITH RECURSIVE blocked AS").
+		WithArgs(2, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectQuery("INSERT INTO task_dependency").
+		WithArgs(1, 2, DependencyBlocks, dep.CreatedAt).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+	mock.ExpectExec("INSERT INTO task_event").
+		WithArgs(2, 0, TaskEventDependencyAdded, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	err = taskDto.InsertTaskDependency(dep)
+	assert.NoError(t, err)
+	assert.Equal(t, 7, dep.ID)
+	assert.Equal(t, DependencyBlockedBy, dep.Type) // the caller's view is kept
+
+	err = mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+func TestInsertTaskDependency_RejectsCycle(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	taskDto := TaskDto{DB: db}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT EXISTS").
+		WithArgs(1, 2, DependencyBlocks).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectExec("SELECT pg_advisory_xact_lock").
+		WithArgs(dependencyGraphLock).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("WITH RECURSIVE blocked AS").
+		WithArgs(2, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectRollback()
+
+	err = taskDto.InsertTaskDependency(&TaskDependency{TaskID: 1, RelatedTaskID: 2, Type: DependencyBlocks})
+	assert.ErrorIs(t, err, ErrDependencyCycle)
+
+	err = mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+func TestHasUnfinishedBlockers(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	taskDto := TaskDto{DB: db}
+
+	mock.ExpectQuery("SELECT EXISTS").
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	blocked, err := taskDto.HasUnfinishedBlockers(3)
+	assert.NoError(t, err)
+	assert.True(t, blocked)
+
+	err = mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
@@ -0,0 +1,84 @@
+package model
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// TaskCSVHeader is the column order of CSV exports. Imports accept the
+// columns in any order. Items and comments are JSON arrays of strings.
+var TaskCSVHeader = []string{
+	"id", "title", "description", "completed", "created_at", "updated_at",
+	"assigned_user_id", "parent_task_id", "project_id", "items", "comments",
+}
+
+// StreamTasks calls fn for every task filter matches that is not in the
+// trash, in ID order, with its items and comments. Rows are read one at a
+// time, so the whole table is never held in memory.
+func (taskDto TaskDto) StreamTasks(filter TaskFilter, fn func(Task) error) error {
+	var where conditions
+	where.add("t.deleted_at IS NULL")
+	filter.addTo(&where)
+
+	rows, err := taskDto.DB.Query(`
+		SELECT `+taskColumns+`, `+taskCommentsColumn+`
+		FROM task t
+		WHERE `+where.String()+`
+		ORDER BY t.id
+	`, where.args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var task Task
+		err := scanTask(rows, &task, pq.Array(&task.Comments))
+		if err != nil {
+			return err
+		}
+
+		err = fn(task)
+		if err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// TaskCSVRecord returns the task as a CSV row in TaskCSVHeader order.
+func TaskCSVRecord(task Task) ([]string, error) {
+	items, err := json.Marshal(nonNil(task.Items))
+	if err != nil {
+		return nil, err
+	}
+	comments, err := json.Marshal(nonNil(task.Comments))
+	if err != nil {
+		return nil, err
+	}
+
+	return []string{
+		strconv.Itoa(task.ID),
+		task.Title,
+		task.Description,
+		strconv.FormatBool(task.Completed),
+		task.CreatedAt.UTC().Format(time.RFC3339),
+		task.UpdatedAt.UTC().Format(time.RFC3339),
+		strconv.Itoa(task.AssignedUserID),
+		strconv.Itoa(task.ParentTaskID),
+		strconv.Itoa(task.ProjectID),
+		string(items),
+		string(comments),
+	}, nil
+}
+
+func nonNil(values []string) []string {
+	if values == nil {
+		return make([]string, 0)
+	}
+	return values
+}
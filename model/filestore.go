@@ -0,0 +1,438 @@
+package model
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// FileTaskStore keeps tasks and their comments in a single JSON file, for
+// running without a database. The whole file is held in memory and
+// rewritten on every change, through a temporary file renamed over the old
+// one, so a crash never leaves it half written. It has only the default
+// project, no dependencies and no history, and deleted tasks are removed
+// rather than moved to the trash.
+type FileTaskStore struct {
+	path string
+	mu   sync.RWMutex
+	data fileTaskData
+}
+
+// fileTaskData is the content of the file. IDs are never reused, even
+// after the task or comment holding the highest one is deleted.
+type fileTaskData struct {
+	NextTaskID    int           `json:"next_task_id"`
+	NextCommentID int           `json:"next_comment_id"`
+	Tasks         []Task        `json:"tasks"`
+	Comments      []TaskComment `json:"comments"`
+}
+
+// OpenFileTaskStore loads the store kept at path, creating the file if it
+// does not exist. The file may also hold a plain JSON array of tasks, such
+// as tasks.json or an export, which is taken as the starting data and
+// rewritten in the store's own format on the first change.
+func OpenFileTaskStore(path string) (*FileTaskStore, error) {
+	store := &FileTaskStore{path: path}
+
+	content, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		store.data = fileTaskData{NextTaskID: 1, NextCommentID: 1}
+		err = writeJSONFile(path, store.data)
+		if err != nil {
+			return nil, err
+		}
+		return store, nil
+	case err != nil:
+		return nil, err
+	}
+
+	content = bytes.TrimSpace(content)
+	if bytes.HasPrefix(content, []byte("[")) {
+		var tasks []Task
+		err = json.Unmarshal(content, &tasks)
+		if err != nil {
+			return nil, err
+		}
+		store.data = seedFileTaskData(tasks)
+		return store, nil
+	}
+
+	err = json.Unmarshal(content, &store.data)
+	if err != nil {
+		return nil, err
+	}
+	store.data.normalize()
+
+	return store, nil
+}
+
+// seedFileTaskData turns a list of tasks into store data, numbering tasks
+// without an ID and keeping each task's comments as comment records.
+func seedFileTaskData(tasks []Task) fileTaskData {
+	nextID := 1
+	for _, task := range tasks {
+		if task.ID >= nextID {
+			nextID = task.ID + 1
+		}
+	}
+
+	data := fileTaskData{Tasks: make([]Task, 0, len(tasks))}
+	for _, task := range tasks {
+		if task.DeletedAt != nil {
+			continue
+		}
+		if task.ID == 0 {
+			task.ID = nextID
+			nextID++
+		}
+		for _, comment := range task.Comments {
+			data.Comments = append(data.Comments, TaskComment{
+				ID:        len(data.Comments) + 1,
+				TaskID:    task.ID,
+				Comment:   comment,
+				CreatedAt: task.UpdatedAt,
+			})
+		}
+		task.Comments = nil
+		data.Tasks = append(data.Tasks, task)
+	}
+	data.normalize()
+
+	return data
+}
+
+// normalize fills in defaults and makes the next IDs follow the highest in use.
+func (data *fileTaskData) normalize() {
+	if data.NextTaskID < 1 {
+		data.NextTaskID = 1
+	}
+	if data.NextCommentID < 1 {
+		data.NextCommentID = 1
+	}
+
+	for i := range data.Tasks {
+		task := &data.Tasks[i]
+		if task.ProjectID == 0 {
+			task.ProjectID = DefaultProjectID
+		}
+		if task.Items == nil {
+			task.Items = make([]string, 0)
+		}
+		if task.ID >= data.NextTaskID {
+			data.NextTaskID = task.ID + 1
+		}
+	}
+	for _, comment := range data.Comments {
+		if comment.ID >= data.NextCommentID {
+			data.NextCommentID = comment.ID + 1
+		}
+	}
+
+	sort.SliceStable(data.Tasks, func(i, j int) bool { return data.Tasks[i].ID < data.Tasks[j].ID })
+}
+
+// clone returns a copy that can be changed without touching data.
+func (data fileTaskData) clone() fileTaskData {
+	tasks := make([]Task, len(data.Tasks))
+	for i, task := range data.Tasks {
+		tasks[i] = copyTask(task)
+	}
+
+	comments := make([]TaskComment, len(data.Comments))
+	copy(comments, data.Comments)
+
+	data.Tasks = tasks
+	data.Comments = comments
+	return data
+}
+
+func (data *fileTaskData) find(id int) *Task {
+	i := sort.Search(len(data.Tasks), func(i int) bool { return data.Tasks[i].ID >= id })
+	if i < len(data.Tasks) && data.Tasks[i].ID == id {
+		return &data.Tasks[i]
+	}
+	return nil
+}
+
+// remove drops the tasks with the given IDs and their comments.
+func (data *fileTaskData) remove(ids map[int]bool) {
+	tasks := data.Tasks[:0]
+	for _, task := range data.Tasks {
+		if !ids[task.ID] {
+			tasks = append(tasks, task)
+		}
+	}
+	data.Tasks = tasks
+
+	comments := data.Comments[:0]
+	for _, comment := range data.Comments {
+		if !ids[comment.TaskID] {
+			comments = append(comments, comment)
+		}
+	}
+	data.Comments = comments
+}
+
+func copyTask(task Task) Task {
+	items := make([]string, len(task.Items))
+	copy(items, task.Items)
+	task.Items = items
+	return task
+}
+
+// update applies fn to a copy of the data and, if it succeeds, writes the
+// copy to the file before making it current.
+func (store *FileTaskStore) update(fn func(data *fileTaskData) error) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	data := store.data.clone()
+	err := fn(&data)
+	if err != nil {
+		return err
+	}
+
+	err = writeJSONFile(store.path, data)
+	if err != nil {
+		return err
+	}
+
+	store.data = data
+	return nil
+}
+
+// writeJSONFile writes v to a temporary file next to path and renames it
+// over path once it is safely on disk.
+func writeJSONFile(path string, v any) error {
+	file, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	// Once renamed there is nothing left to remove.
+	defer os.Remove(file.Name())
+
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	err = encoder.Encode(v)
+	if err == nil {
+		err = file.Sync()
+	}
+	closeErr := file.Close()
+	if err != nil {
+		return err
+	}
+	if closeErr != nil {
+		return closeErr
+	}
+
+	return os.Rename(file.Name(), path)
+}
+
+func (store *FileTaskStore) GetAllTasks() ([]Task, error) {
+	return store.filter(func(Task) bool { return true }), nil
+}
+
+func (store *FileTaskStore) GetAllTaskByAssignedUserID(userID int) ([]Task, error) {
+	return store.filter(func(task Task) bool { return task.AssignedUserID == userID }), nil
+}
+
+func (store *FileTaskStore) filter(match func(Task) bool) []Task {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	tasks := make([]Task, 0)
+	for _, task := range store.data.Tasks {
+		if match(task) {
+			tasks = append(tasks, copyTask(task))
+		}
+	}
+	return tasks
+}
+
+func (store *FileTaskStore) GetTask(id int) (*Task, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	task := store.data.find(id)
+	if task == nil {
+		return nil, sql.ErrNoRows
+	}
+
+	found := copyTask(*task)
+	return &found, nil
+}
+
+// Insert adds the task without its items, which are added one at a time
+// with InsertTaskItem, and sets its ID.
+func (store *FileTaskStore) Insert(task *Task) error {
+	return store.update(func(data *fileTaskData) error {
+		if task.ProjectID != DefaultProjectID {
+			return ErrProjectNotFound
+		}
+		if task.ParentTaskID != 0 && data.find(task.ParentTaskID) == nil {
+			return ErrParentNotFound
+		}
+
+		task.ID = data.NextTaskID
+		data.NextTaskID++
+
+		stored := *task
+		stored.Items = make([]string, 0)
+		stored.Comments = nil
+		stored.DeletedAt = nil
+		data.Tasks = append(data.Tasks, stored)
+		return nil
+	})
+}
+
+func (store *FileTaskStore) InsertTaskItem(taskID int, item string) error {
+	return store.update(func(data *fileTaskData) error {
+		task := data.find(taskID)
+		if task == nil {
+			return sql.ErrNoRows
+		}
+		task.Items = append(task.Items, item)
+		return nil
+	})
+}
+
+// UpdateTask replaces the task's title, description, completed flag and items.
+func (store *FileTaskStore) UpdateTask(id int, task *Task) error {
+	return store.update(func(data *fileTaskData) error {
+		stored := data.find(id)
+		if stored == nil {
+			return sql.ErrNoRows
+		}
+
+		stored.Title = task.Title
+		stored.Description = task.Description
+		stored.Completed = task.Completed
+		stored.UpdatedAt = time.Now()
+		stored.Items = copyTask(*task).Items
+		return nil
+	})
+}
+
+func (store *FileTaskStore) AssignUserToTask(id, userID int, updatedAt time.Time) error {
+	return store.update(func(data *fileTaskData) error {
+		task := data.find(id)
+		if task == nil {
+			return sql.ErrNoRows
+		}
+		task.AssignedUserID = userID
+		task.UpdatedAt = updatedAt
+		return nil
+	})
+}
+
+// DeleteTask removes the task, all of its subtasks and their comments.
+func (store *FileTaskStore) DeleteTask(id int) error {
+	return store.update(func(data *fileTaskData) error {
+		if data.find(id) == nil {
+			return sql.ErrNoRows
+		}
+
+		ids := map[int]bool{id: true}
+		// Tasks are in ID order but a subtask can be older than its parent,
+		// so keep sweeping until no more descendants turn up.
+		for found := true; found; {
+			found = false
+			for _, task := range data.Tasks {
+				if !ids[task.ID] && ids[task.ParentTaskID] {
+					ids[task.ID] = true
+					found = true
+				}
+			}
+		}
+
+		data.remove(ids)
+		return nil
+	})
+}
+
+// DeleteTaskReparent removes the task and its comments and moves its
+// direct subtasks up to its parent.
+func (store *FileTaskStore) DeleteTaskReparent(id int) error {
+	return store.update(func(data *fileTaskData) error {
+		task := data.find(id)
+		if task == nil {
+			return sql.ErrNoRows
+		}
+
+		parentID, now := task.ParentTaskID, time.Now()
+		for i := range data.Tasks {
+			if data.Tasks[i].ParentTaskID == id {
+				data.Tasks[i].ParentTaskID = parentID
+				data.Tasks[i].UpdatedAt = now
+			}
+		}
+
+		data.remove(map[int]bool{id: true})
+		return nil
+	})
+}
+
+// InsertTaskComment adds the comment and sets its ID.
+func (store *FileTaskStore) InsertTaskComment(taskComment *TaskComment) error {
+	return store.update(func(data *fileTaskData) error {
+		if data.find(taskComment.TaskID) == nil {
+			return sql.ErrNoRows
+		}
+
+		taskComment.ID = data.NextCommentID
+		data.NextCommentID++
+		data.Comments = append(data.Comments, *taskComment)
+		return nil
+	})
+}
+
+func (store *FileTaskStore) GetAllTaskCommentsByTaskID(taskID int) ([]TaskComment, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	if store.data.find(taskID) == nil {
+		return nil, sql.ErrNoRows
+	}
+
+	comments := make([]TaskComment, 0)
+	for _, comment := range store.data.Comments {
+		if comment.TaskID == taskID {
+			comments = append(comments, comment)
+		}
+	}
+	return comments, nil
+}
+
+func (store *FileTaskStore) EachTask(filter TaskFilter, page Page, fn func(Task) error) error {
+	// fn may be slow, like writing to a client, so it is called without the lock.
+	tasks := pageOf(store.filter(filter.match), page, TaskCursor)
+	for _, task := range tasks {
+		err := fn(task)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (store *FileTaskStore) EachTaskComment(taskID int, page Page, fn func(TaskComment) error) error {
+	comments, err := store.GetAllTaskCommentsByTaskID(taskID)
+	if err != nil {
+		return err
+	}
+	for _, comment := range pageOf(comments, page, TaskCommentCursor) {
+		err := fn(comment)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package model
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileTaskStore_PersistsChanges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.json")
+
+	store, err := OpenFileTaskStore(path)
+	assert.NoError(t, err)
+
+	now := time.Date(2023, 6, 10, 9, 0, 0, 0, time.UTC)
+	task := &Task{Title: "Parent", CreatedAt: now, UpdatedAt: now, ProjectID: DefaultProjectID}
+	assert.NoError(t, store.Insert(task))
+	assert.Equal(t, 1, task.ID)
+	assert.NoError(t, store.InsertTaskItem(task.ID, "first"))
+
+	child := &Task{Title: "Child", ParentTaskID: task.ID, ProjectID: DefaultProjectID}
+	assert.NoError(t, store.Insert(child))
+	assert.NoError(t, store.AssignUserToTask(child.ID, 7, now))
+
+	comment := &TaskComment{TaskID: child.ID, Comment: "On it", CreatedAt: now}
+	assert.NoError(t, store.InsertTaskComment(comment))
+	assert.Equal(t, 1, comment.ID)
+
+	reopened, err := OpenFileTaskStore(path)
+	assert.NoError(t, err)
+
+	got, err := reopened.GetTask(task.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Parent", got.Title)
+	assert.Equal(t, []string{"first"}, got.Items)
+
+	assigned, err := reopened.GetAllTaskByAssignedUserID(7)
+	assert.NoError(t, err)
+	assert.Len(t, assigned, 1)
+	assert.Equal(t, child.ID, assigned[0].ID)
+
+	comments, err := reopened.GetAllTaskCommentsByTaskID(child.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, []TaskComment{*comment}, comments)
+
+	assert.NoError(t, reopened.DeleteTask(task.ID))
+	tasks, err := reopened.GetAllTasks()
+	assert.NoError(t, err)
+	assert.Empty(t, tasks)
+	// The comments went with the task.
+	_, err = reopened.GetAllTaskCommentsByTaskID(child.ID)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	// IDs are not reused after a delete.
+	next := &Task{Title: "Next", ProjectID: DefaultProjectID}
+	assert.NoError(t, reopened.Insert(next))
+	assert.Equal(t, 3, next.ID)
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1, "temporary files are cleaned up")
+}
+
+func TestFileTaskStore_RejectsUnknownReferences(t *testing.T) {
+	store, err := OpenFileTaskStore(filepath.Join(t.TempDir(), "tasks.json"))
+	assert.NoError(t, err)
+
+	err = store.Insert(&Task{Title: "Elsewhere", ProjectID: 2})
+	assert.ErrorIs(t, err, ErrProjectNotFound)
+
+	err = store.Insert(&Task{Title: "Orphan", ParentTaskID: 9, ProjectID: DefaultProjectID})
+	assert.ErrorIs(t, err, ErrParentNotFound)
+
+	_, err = store.GetTask(9)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+	assert.ErrorIs(t, store.UpdateTask(9, &Task{Title: "Missing"}), sql.ErrNoRows)
+	assert.ErrorIs(t, store.DeleteTask(9), sql.ErrNoRows)
+	assert.ErrorIs(t, store.InsertTaskComment(&TaskComment{TaskID: 9}), sql.ErrNoRows)
+
+	// A failed change leaves nothing behind.
+	tasks, err := store.GetAllTasks()
+	assert.NoError(t, err)
+	assert.Empty(t, tasks)
+}
+
+func TestFileTaskStore_DeleteTaskReparent(t *testing.T) {
+	store, err := OpenFileTaskStore(filepath.Join(t.TempDir(), "tasks.json"))
+	assert.NoError(t, err)
+
+	root := &Task{Title: "Root", ProjectID: DefaultProjectID}
+	assert.NoError(t, store.Insert(root))
+	middle := &Task{Title: "Middle", ParentTaskID: root.ID, ProjectID: DefaultProjectID}
+	assert.NoError(t, store.Insert(middle))
+	leaf := &Task{Title: "Leaf", ParentTaskID: middle.ID, ProjectID: DefaultProjectID, UpdatedAt: time.Now().Add(-time.Hour)}
+	assert.NoError(t, store.Insert(leaf))
+
+	assert.NoError(t, store.DeleteTaskReparent(middle.ID))
+
+	got, err := store.GetTask(leaf.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, root.ID, got.ParentTaskID)
+	assert.True(t, got.UpdatedAt.After(leaf.UpdatedAt))
+	_, err = store.GetTask(middle.ID)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestOpenFileTaskStore_SeedsFromTaskArray(t *testing.T) {
+	content, err := os.ReadFile("../tasks.json")
+	assert.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "tasks.json")
+	assert.NoError(t, os.WriteFile(path, content, 0o600))
+
+	store, err := OpenFileTaskStore(path)
+	assert.NoError(t, err)
+
+	tasks, err := store.GetAllTasks()
+	assert.NoError(t, err)
+	assert.Len(t, tasks, 3)
+	assert.Equal(t, DefaultProjectID, tasks[0].ProjectID)
+
+	task := &Task{Title: "Fourth", ProjectID: DefaultProjectID}
+	assert.NoError(t, store.Insert(task))
+	assert.Equal(t, 4, task.ID)
+}
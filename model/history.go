@@ -0,0 +1,159 @@
+package model
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// Task event actions recorded in a task's history.
+const (
+	TaskEventCreated           = "created"
+	TaskEventUpdated           = "updated"
+	TaskEventAssigned          = "assigned"
+	TaskEventDeleted           = "deleted"
+	TaskEventRestored          = "restored"
+	TaskEventPurged            = "purged"
+	TaskEventItemAdded         = "item_added"
+	TaskEventCommented         = "commented"
+	TaskEventParentChanged     = "parent_changed"
+	TaskEventProjectChanged    = "project_changed"
+	TaskEventDependencyAdded   = "dependency_added"
+	TaskEventDependencyRemoved = "dependency_removed"
+	TaskEventRecurrenceSet     = "recurrence_set"
+	TaskEventRecurrenceRemoved = "recurrence_removed"
+	TaskEventAttachmentAdded   = "attachment_added"
+	TaskEventAttachmentRemoved = "attachment_removed"
+)
+
+// FieldChange is the value of one task field before and after a change.
+// Before is null for a field that was just set, After for one that was removed.
+type FieldChange struct {
+	Before any `json:"before"`
+	After  any `json:"after"`
+}
+
+// TaskEvent is one entry in a task's history. ActorID is the user who made
+// the change, or 0 for anonymous callers and the server itself.
+type TaskEvent struct {
+	ID        int                    `json:"id"`
+	TaskID    int                    `json:"task_id"`
+	ActorID   int                    `json:"actor_id"`
+	Action    string                 `json:"action"`
+	Changes   map[string]FieldChange `json:"changes"`
+	CreatedAt time.Time              `json:"created_at"`
+}
+
+// fieldChanges collects the fields a mutation changed.
+type fieldChanges map[string]FieldChange
+
+// diff records the field only if its value actually changed.
+func (changes fieldChanges) diff(field string, before, after any) {
+	if !reflect.DeepEqual(before, after) {
+		changes[field] = FieldChange{Before: before, After: after}
+	}
+}
+
+// recordTaskEvent adds an event to the task's history inside the
+// transaction of the mutation it describes, so the two commit together. The
+// same statement queues a delivery for every active webhook on the task's
+// project that is subscribed to the event, skipping webhooks whose creator
+// has since left the project; see WebhookDto.ClaimDueDeliveries.
+func (taskDto TaskDto) recordTaskEvent(tx *sql.Tx, taskID int, action string, changes fieldChanges) error {
+	if changes == nil {
+		changes = fieldChanges{}
+	}
+
+	payload, err := json.Marshal(changes)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(fmt.Sprintf(`
+		WITH event AS (
+			INSERT INTO task_event (task_id, actor_id, action, changes, created_at)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING id, created_at
+		)
+		INSERT INTO webhook_delivery (webhook_id, event_id, event, status, next_attempt_at, created_at)
+		SELECT w.id, event.id, 'task.' || $3::text, 'pending', event.created_at, event.created_at
+		FROM webhook w, event
+		WHERE w.active AND 'task.' || $3::text = ANY (w.events)
+			AND w.project_id = (SELECT project_id FROM task WHERE id = $1)
+			AND (w.project_id = %d OR EXISTS (
+				SELECT 1 FROM project_member pm WHERE pm.project_id = w.project_id AND pm.user_id = w.created_by
+			))
+	`, DefaultProjectID), taskID, taskDto.ActorID, action, payload, time.Now())
+	return err
+}
+
+// GetTaskHistory returns the task's events, newest first.
+func (taskDto TaskDto) GetTaskHistory(taskID int) ([]TaskEvent, error) {
+	rows, err := taskDto.DB.Query(`
+		SELECT id, task_id, actor_id, action, changes, created_at
+		FROM task_event
+		WHERE task_id = $1
+		ORDER BY created_at DESC, id DESC
+	`, taskID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := make([]TaskEvent, 0)
+	for rows.Next() {
+		event, err := scanTaskEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// EachTaskEvent calls fn with the page of the task's events, newest first,
+// and stops at the first error fn returns. As the list runs backwards in
+// time, the page starts at events before its cursor.
+func (taskDto TaskDto) EachTaskEvent(taskID int, page Page, fn func(TaskEvent) error) error {
+	var where conditions
+	where.add("task_id = $%d", taskID)
+	page.addTo(&where, "created_at, id", "<")
+	limit := page.limit(&where)
+
+	return eachRow(taskDto.DB, func(rows *sql.Rows) error {
+		event, err := scanTaskEvent(rows)
+		if err != nil {
+			return err
+		}
+		return fn(event)
+	}, `
+		SELECT id, task_id, actor_id, action, changes, created_at
+		FROM task_event
+		WHERE `+where.String()+`
+		ORDER BY created_at DESC, id DESC
+		`+limit, where.args...)
+}
+
+// TaskEventCursor is the event's position in the task's history.
+func TaskEventCursor(event TaskEvent) Cursor {
+	return Cursor{Time: event.CreatedAt, ID: event.ID}
+}
+
+func scanTaskEvent(rows *sql.Rows) (TaskEvent, error) {
+	var event TaskEvent
+	var payload []byte
+	err := rows.Scan(&event.ID, &event.TaskID, &event.ActorID, &event.Action, &payload, &event.CreatedAt)
+	if err != nil {
+		return event, err
+	}
+
+	err = json.Unmarshal(payload, &event.Changes)
+	return event, err
+}
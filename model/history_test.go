@@ -0,0 +1,84 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFieldChangesDiff_SkipsUnchangedFields(t *testing.T) {
+	changes := fieldChanges{}
+	changes.diff("title", "Old", "New")
+	changes.diff("completed", false, false)
+	changes.diff("items", []string{"a"}, []string{"a"})
+	changes.diff("assigned_user_id", 0, 42)
+
+	assert.Equal(t, fieldChanges{
+		"title":            {Before: "Old", After: "New"},
+		"assigned_user_id": {Before: 0, After: 42},
+	}, changes)
+}
+
+func TestUpdateTask_RecordsChangedFields(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	taskDto := TaskDto{DB: db, ActorID: 7}
+
+	task := &Task{ID: 1, Title: "New title", Description: "Same", Items: []string{"a", "b"}}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT title, description, completed, ARRAY").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"title", "description", "completed", "items"}).
+			AddRow("Old title", "Same", false, "{a}"))
+	mock.ExpectPrepare("UPDATE task SET title").ExpectExec().
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM task_item").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectPrepare("INSERT INTO task_item")
+	mock.ExpectExec("INSERT INTO task_item").WithArgs(1, "a").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO task_item").WithArgs(1, "b").WillReturnResult(sqlmock.NewResult(2, 1))
+	// Deliveries go to the webhooks on the task's project whose creator is still a member.
+	mock.ExpectExec("INSERT INTO task_event(.|\n)*w.project_id = \\(SELECT project_id FROM task WHERE id = \\$1\\)(.|\n)*pm.user_id = w.created_by").
+		WithArgs(1, 7, TaskEventUpdated,
+			[]byte(`{"items":{"before":["a"],"after":["a","b"]},"title":{"before":"Old title","after":"New title"}}`),
+			sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	err = taskDto.UpdateTask(1, task)
+	assert.NoError(t, err)
+
+	err = mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+func TestGetTaskHistory_SuccessfulGet(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	taskDto := TaskDto{DB: db}
+
+	now := time.Now()
+	rows := sqlmock.NewRows([]string{"id", "task_id", "actor_id", "action", "changes", "created_at"}).
+		AddRow(2, 1, 7, TaskEventAssigned, []byte(`{"assigned_user_id":{"before":0,"after":42}}`), now).
+		AddRow(1, 1, 7, TaskEventCreated, []byte(`{"title":{"before":null,"after":"Write report"}}`), now.Add(-time.Hour))
+	mock.ExpectQuery("SELECT id, task_id, actor_id, action, changes, created_at FROM task_event WHERE task_id = \\$1 ORDER BY created_at DESC, id DESC").
+		WithArgs(1).
+		WillReturnRows(rows)
+
+	events, err := taskDto.GetTaskHistory(1)
+	assert.NoError(t, err)
+	assert.Len(t, events, 2)
+	assert.Equal(t, TaskEventAssigned, events[0].Action)
+	assert.Equal(t, FieldChange{Before: 0.0, After: 42.0}, events[0].Changes["assigned_user_id"])
+	assert.Equal(t, FieldChange{Before: nil, After: "Write report"}, events[1].Changes["title"])
+
+	err = mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
@@ -0,0 +1,590 @@
+package model
+
+import (
+	"bufio"
+	"bytes"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// ErrTaskInTrash is returned when an import would overwrite a deleted task.
+var ErrTaskInTrash = errors.New("task is in the trash; restore it first")
+
+// ImportRow is one task read from an import file. Row is its position: the
+// line for CSV and NDJSON, the element number for a JSON array. Err is set
+// if the row could not be parsed or is invalid.
+type ImportRow struct {
+	Row  int
+	Task Task
+	Err  error
+}
+
+// ImportError reports why a row was not imported. Err is the underlying
+// error, for logging.
+type ImportError struct {
+	Row   int    `json:"row"`
+	ID    int    `json:"id,omitempty"`
+	Error string `json:"error"`
+	Err   error  `json:"-"`
+}
+
+// ImportReport is the outcome of an import. Nothing is committed if any row
+// has an error, or on a dry run.
+type ImportReport struct {
+	DryRun    bool          `json:"dry_run"`
+	Committed bool          `json:"committed"`
+	Rows      int           `json:"rows"`
+	Created   int           `json:"created"`
+	Updated   int           `json:"updated"`
+	Unchanged int           `json:"unchanged"`
+	Errors    []ImportError `json:"errors"`
+}
+
+// ParseTasksCSV reads tasks from CSV with a header row, in the format
+// written by TaskCSVRecord. Only the title column is required. It fails
+// outright only if the header is unusable.
+func ParseTasksCSV(r io.Reader) ([]ImportRow, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading CSV header: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		// Spreadsheets often save CSV with a byte order mark.
+		name = strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))
+		if !contains(TaskCSVHeader, name) {
+			return nil, fmt.Errorf("unknown CSV column %q", name)
+		}
+		if _, ok := columns[name]; ok {
+			return nil, fmt.Errorf("CSV column %q given twice", name)
+		}
+		columns[name] = i
+	}
+	if _, ok := columns["title"]; !ok {
+		return nil, errors.New("the CSV has no title column")
+	}
+
+	var rows []ImportRow
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				return nil, err
+			}
+			rows = append(rows, ImportRow{Row: parseErr.StartLine, Err: parseErr.Err})
+			continue
+		}
+
+		line, _ := reader.FieldPos(0)
+		row := ImportRow{Row: line}
+		if len(record) != len(header) {
+			row.Err = fmt.Errorf("expected %d fields, got %d", len(header), len(record))
+			rows = append(rows, row)
+			continue
+		}
+
+		row.Task, row.Err = taskFromCSV(record, columns)
+		if row.Err == nil {
+			row.Err = row.Task.validateImport()
+		}
+		rows = append(rows, row)
+	}
+
+	return checkDuplicateIDs(rows), nil
+}
+
+func taskFromCSV(record []string, columns map[string]int) (Task, error) {
+	var task Task
+	field := func(name string) string {
+		if i, ok := columns[name]; ok {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+	number := func(name string, dest *int) error {
+		value := field(name)
+		if value == "" {
+			return nil
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%s: not a number", name)
+		}
+		*dest = n
+		return nil
+	}
+	timestamp := func(name string, dest *time.Time) error {
+		value := field(name)
+		if value == "" {
+			return nil
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return fmt.Errorf("%s: expected an RFC 3339 time such as 2023-06-10T09:00:00Z", name)
+		}
+		*dest = t
+		return nil
+	}
+	list := func(name string, dest *[]string) error {
+		value := field(name)
+		if value == "" {
+			return nil
+		}
+		err := json.Unmarshal([]byte(value), dest)
+		if err != nil {
+			return fmt.Errorf("%s: expected a JSON array of strings", name)
+		}
+		return nil
+	}
+
+	task.Title = record[columns["title"]]
+	if i, ok := columns["description"]; ok {
+		task.Description = record[i]
+	}
+	if value := field("completed"); value != "" {
+		completed, err := strconv.ParseBool(value)
+		if err != nil {
+			return task, errors.New("completed: expected true or false")
+		}
+		task.Completed = completed
+	}
+
+	for _, err := range []error{
+		number("id", &task.ID),
+		number("assigned_user_id", &task.AssignedUserID),
+		number("parent_task_id", &task.ParentTaskID),
+		number("project_id", &task.ProjectID),
+		timestamp("created_at", &task.CreatedAt),
+		timestamp("updated_at", &task.UpdatedAt),
+		list("items", &task.Items),
+		list("comments", &task.Comments),
+	} {
+		if err != nil {
+			return task, err
+		}
+	}
+
+	return task, nil
+}
+
+// ParseTasksJSON reads a JSON array of tasks, as in tasks.json. An element
+// that does not decode is reported against its position rather than
+// failing the whole file.
+func ParseTasksJSON(r io.Reader) ([]ImportRow, error) {
+	decoder := json.NewDecoder(r)
+
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, fmt.Errorf("reading JSON: %w", err)
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '[' {
+		return nil, errors.New("expected a JSON array of tasks")
+	}
+
+	var rows []ImportRow
+	for n := 1; decoder.More(); n++ {
+		var raw json.RawMessage
+		err := decoder.Decode(&raw)
+		if err != nil {
+			return nil, fmt.Errorf("reading JSON element %d: %w", n, err)
+		}
+		rows = append(rows, taskFromJSON(n, raw))
+	}
+
+	_, err = decoder.Token()
+	if err != nil {
+		return nil, fmt.Errorf("reading JSON: %w", err)
+	}
+
+	return checkDuplicateIDs(rows), nil
+}
+
+// ParseTasksNDJSON reads one JSON task per line. Blank lines are skipped.
+func ParseTasksNDJSON(r io.Reader) ([]ImportRow, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16<<20)
+
+	var rows []ImportRow
+	for line := 1; scanner.Scan(); line++ {
+		raw := bytes.TrimSpace(scanner.Bytes())
+		if len(raw) == 0 {
+			continue
+		}
+		rows = append(rows, taskFromJSON(line, append([]byte(nil), raw...)))
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading NDJSON: %w", err)
+	}
+
+	return checkDuplicateIDs(rows), nil
+}
+
+func taskFromJSON(row int, raw []byte) ImportRow {
+	var task Task
+	err := json.Unmarshal(raw, &task)
+	if err != nil {
+		return ImportRow{Row: row, Err: fmt.Errorf("invalid task: %w", err)}
+	}
+
+	return ImportRow{Row: row, Task: task, Err: task.validateImport()}
+}
+
+func (task Task) validateImport() error {
+	switch {
+	case strings.TrimSpace(task.Title) == "":
+		return errors.New("title is required")
+	case task.ID < 0, task.AssignedUserID < 0, task.ParentTaskID < 0, task.ProjectID < 0:
+		return errors.New("ids cannot be negative")
+	case task.ID != 0 && task.ParentTaskID == task.ID:
+		return ErrTaskCycle
+	}
+	return nil
+}
+
+// checkDuplicateIDs marks every row after the first with the same ID.
+func checkDuplicateIDs(rows []ImportRow) []ImportRow {
+	seen := make(map[int]int)
+	for i := range rows {
+		id := rows[i].Task.ID
+		if rows[i].Err != nil || id == 0 {
+			continue
+		}
+		if first, ok := seen[id]; ok {
+			rows[i].Err = fmt.Errorf("task %d already appears in row %d", id, first)
+			continue
+		}
+		seen[id] = rows[i].Row
+	}
+	return rows
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// ImportTasks upserts the rows by ID in one transaction: a row whose ID
+// exists updates that task and replaces its items, unless it matches the
+// task already, and any other row
+// creates a task, keeping its ID if it has one. Comments are only added to
+// tasks the import creates, so importing the same file twice does not
+// duplicate them. Parents must come before their subtasks, and be in the
+// same project at the end of the import. Blockers are not checked, as an
+// import restores data rather than doing work.
+//
+// Every row is tried under its own savepoint so all errors are reported at
+// once, but the transaction is only committed if there are none and this is
+// not a dry run. As with RunBulk, rows only reach the projects the actor
+// can access.
+func (taskDto TaskDto) ImportTasks(rows []ImportRow, dryRun bool, now time.Time) (*ImportReport, error) {
+	report := &ImportReport{DryRun: dryRun, Rows: len(rows), Errors: make([]ImportError, 0)}
+
+	tx, err := taskDto.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	explicitIDs := false
+	for _, row := range rows {
+		if row.Err != nil {
+			report.Errors = append(report.Errors, ImportError{Row: row.Row, ID: row.Task.ID, Error: row.Err.Error()})
+			continue
+		}
+
+		_, err = tx.Exec(`SAVEPOINT import_row`)
+		if err != nil {
+			return nil, err
+		}
+
+		result, err := taskDto.importTask(tx, row.Task, now)
+		if err != nil {
+			report.Errors = append(report.Errors, ImportError{Row: row.Row, ID: row.Task.ID, Error: importErrorMessage(err), Err: err})
+
+			_, err = tx.Exec(`ROLLBACK TO SAVEPOINT import_row`)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		_, err = tx.Exec(`RELEASE SAVEPOINT import_row`)
+		if err != nil {
+			return nil, err
+		}
+
+		switch result {
+		case importCreated:
+			report.Created++
+			explicitIDs = explicitIDs || row.Task.ID != 0
+		case importUpdated:
+			report.Updated++
+		default:
+			report.Unchanged++
+		}
+	}
+
+	// Parents come before their subtasks, so a row moving a task to another
+	// project can leave subtasks checked earlier behind in the old one.
+	if len(report.Errors) == 0 {
+		report.Errors, err = checkImportedHierarchy(tx, rows)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(report.Errors) > 0 || dryRun {
+		return report, nil
+	}
+
+	// Tasks created with their own IDs leave the sequence behind them.
+	if explicitIDs {
+		_, err = tx.Exec(`SELECT setval(pg_get_serial_sequence('task', 'id'), (SELECT MAX(id) FROM task))`)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+	report.Committed = true
+
+	return report, nil
+}
+
+// checkImportedHierarchy reports an error for every imported task, with an
+// ID of its own, that is in another project than its parent or one of its
+// subtasks. Only such tasks can have been moved to another project.
+func checkImportedHierarchy(tx *sql.Tx, rows []ImportRow) ([]ImportError, error) {
+	rowOf := make(map[int]int)
+	ids := make([]int, 0)
+	for _, row := range rows {
+		if row.Task.ID != 0 {
+			rowOf[row.Task.ID] = row.Row
+			ids = append(ids, row.Task.ID)
+		}
+	}
+
+	importErrors := make([]ImportError, 0)
+	if len(ids) == 0 {
+		return importErrors, nil
+	}
+
+	pairs, err := tx.Query(`
+		SELECT c.id, p.id
+		FROM task c
+		JOIN task p ON p.id = c.parent_task_id
+		WHERE c.project_id <> p.project_id AND (c.id = ANY ($1) OR p.id = ANY ($1))
+		ORDER BY c.id
+	`, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer pairs.Close()
+
+	for pairs.Next() {
+		var childID, parentID int
+		err := pairs.Scan(&childID, &parentID)
+		if err != nil {
+			return nil, err
+		}
+
+		id := childID
+		if _, ok := rowOf[id]; !ok {
+			id = parentID
+		}
+		importErrors = append(importErrors, ImportError{
+			Row:   rowOf[id],
+			ID:    id,
+			Error: fmt.Sprintf("task %d is not in the project of its parent %d; move them together", childID, parentID),
+		})
+	}
+
+	if err = pairs.Err(); err != nil {
+		return nil, err
+	}
+
+	return importErrors, nil
+}
+
+// importResult is what importing a row did to its task.
+type importResult int
+
+const (
+	importUnchanged importResult = iota
+	importCreated
+	importUpdated
+)
+
+// importTask upserts one task within tx and reports what it did. A task that
+// is updated gets now as its updated_at, like any other change; the file's
+// timestamps are only kept for the tasks the import creates. A row that
+// matches its task field for field leaves it untouched.
+func (taskDto TaskDto) importTask(tx *sql.Tx, task Task, now time.Time) (importResult, error) {
+	if task.CreatedAt.IsZero() {
+		task.CreatedAt = now
+	}
+	if task.UpdatedAt.IsZero() {
+		task.UpdatedAt = now
+	}
+	if task.ProjectID == 0 {
+		task.ProjectID = DefaultProjectID
+	}
+	task.Items = nonNil(task.Items)
+
+	err := checkProjectAndParent(tx, task, taskDto.ActorID)
+	if err != nil {
+		return importUnchanged, err
+	}
+
+	before := Task{Items: make([]string, 0)}
+	var deleted bool
+	found := false
+	if task.ID != 0 {
+		err = tx.QueryRow(`
+			SELECT title, description, completed, assigned_user_id, COALESCE(parent_task_id, 0), project_id, deleted_at IS NOT NULL,
+				ARRAY(SELECT item FROM task_item WHERE task_id = $1 ORDER BY id)
+			FROM task
+			WHERE id = $1
+			FOR UPDATE
+		`, task.ID).Scan(&before.Title, &before.Description, &before.Completed, &before.AssignedUserID, &before.ParentTaskID, &before.ProjectID, &deleted, pq.Array(&before.Items))
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+		case err != nil:
+			return importUnchanged, err
+		default:
+			// A task in a project the actor cannot access is not overwritten.
+			err = checkProjectAndParent(tx, Task{ProjectID: before.ProjectID}, taskDto.ActorID)
+			if err != nil {
+				return importUnchanged, err
+			}
+			if deleted {
+				return importUnchanged, ErrTaskInTrash
+			}
+			found = true
+		}
+	}
+
+	if found && task.ParentTaskID != 0 {
+		err = lockTaskHierarchy(tx)
+		if err != nil {
+			return importUnchanged, err
+		}
+		cycle, err := wouldCycle(tx, task.ID, task.ParentTaskID)
+		if err != nil {
+			return importUnchanged, err
+		}
+		if cycle {
+			return importUnchanged, ErrTaskCycle
+		}
+	}
+
+	var changes fieldChanges
+	if found {
+		changes = fieldChanges{}
+		changes.diff("title", before.Title, task.Title)
+		changes.diff("description", before.Description, task.Description)
+		changes.diff("completed", before.Completed, task.Completed)
+		changes.diff("assigned_user_id", before.AssignedUserID, task.AssignedUserID)
+		changes.diff("parent_task_id", before.ParentTaskID, task.ParentTaskID)
+		changes.diff("project_id", before.ProjectID, task.ProjectID)
+		changes.diff("items", before.Items, task.Items)
+
+		if len(changes) == 0 {
+			return importUnchanged, nil
+		}
+	}
+
+	switch {
+	case found:
+		_, err = tx.Exec(`
+			UPDATE task
+			SET title = $1, description = $2, completed = $3, updated_at = $4, assigned_user_id = $5, parent_task_id = $6, project_id = $7
+			WHERE id = $8
+		`, task.Title, task.Description, task.Completed, now, task.AssignedUserID, nullableID(task.ParentTaskID), task.ProjectID, task.ID)
+		if err == nil {
+			_, err = tx.Exec(`DELETE FROM task_item WHERE task_id = $1`, task.ID)
+		}
+	case task.ID != 0:
+		_, err = tx.Exec(`
+			INSERT INTO task (id, title, description, completed, created_at, updated_at, assigned_user_id, parent_task_id, project_id)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		`, task.ID, task.Title, task.Description, task.Completed, task.CreatedAt, task.UpdatedAt, task.AssignedUserID, nullableID(task.ParentTaskID), task.ProjectID)
+	default:
+		err = tx.QueryRow(`
+			INSERT INTO task (title, description, completed, created_at, updated_at, assigned_user_id, parent_task_id, project_id)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			RETURNING id
+		`, task.Title, task.Description, task.Completed, task.CreatedAt, task.UpdatedAt, task.AssignedUserID, nullableID(task.ParentTaskID), task.ProjectID).Scan(&task.ID)
+	}
+	if err != nil {
+		return importUnchanged, err
+	}
+
+	for _, item := range task.Items {
+		_, err = tx.Exec(`INSERT INTO task_item (task_id, item) VALUES ($1, $2)`, task.ID, item)
+		if err != nil {
+			return importUnchanged, err
+		}
+	}
+
+	if found {
+		return importUpdated, taskDto.recordTaskEvent(tx, task.ID, TaskEventUpdated, changes)
+	}
+
+	for _, comment := range task.Comments {
+		_, err = tx.Exec(`INSERT INTO task_comment (task_id, comment, created_at) VALUES ($1, $2, $3)`, task.ID, comment, task.CreatedAt)
+		if err != nil {
+			return importUnchanged, err
+		}
+	}
+
+	changes = fieldChanges{"source": {After: "import"}}
+	changes.diff("title", nil, task.Title)
+	changes.diff("description", nil, task.Description)
+	changes.diff("completed", nil, task.Completed)
+	changes.diff("assigned_user_id", nil, task.AssignedUserID)
+	if task.ParentTaskID != 0 {
+		changes.diff("parent_task_id", nil, task.ParentTaskID)
+	}
+	changes.diff("project_id", nil, task.ProjectID)
+	if len(task.Items) > 0 {
+		changes.diff("items", nil, task.Items)
+	}
+
+	return importCreated, taskDto.recordTaskEvent(tx, task.ID, TaskEventCreated, changes)
+}
+
+func importErrorMessage(err error) string {
+	switch {
+	case errors.Is(err, ErrProjectNotFound), errors.Is(err, ErrParentNotFound), errors.Is(err, ErrTaskInTrash), errors.Is(err, ErrTaskCycle):
+		return err.Error()
+	default:
+		return "the row could not be imported"
+	}
+}
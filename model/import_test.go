@@ -0,0 +1,219 @@
+package model
+
+import (
+	"encoding/csv"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTasksJSON_Fixture(t *testing.T) {
+	file, err := os.Open("../tasks.json")
+	assert.NoError(t, err)
+	defer file.Close()
+
+	rows, err := ParseTasksJSON(file)
+
+	assert.NoError(t, err)
+	assert.Len(t, rows, 3)
+	for _, row := range rows {
+		assert.NoError(t, row.Err)
+	}
+	assert.Equal(t, "Upgrade Network Infrastructure", rows[0].Task.Title)
+	assert.Len(t, rows[0].Task.Items, 4)
+}
+
+func TestParseTasksCSV_RoundTripsExport(t *testing.T) {
+	task := Task{
+		ID:           4,
+		Title:        "Rotate certificates",
+		Description:  "Line one,\nline \"two\"",
+		Completed:    true,
+		CreatedAt:    time.Date(2023, 6, 10, 9, 0, 0, 0, time.UTC),
+		UpdatedAt:    time.Date(2023, 6, 11, 9, 0, 0, 0, time.UTC),
+		ParentTaskID: 2,
+		ProjectID:    1,
+		Items:        []string{"staging", "prod"},
+		Comments:     []string{"done"},
+	}
+
+	var out strings.Builder
+	writer := csv.NewWriter(&out)
+	record, err := TaskCSVRecord(task)
+	assert.NoError(t, err)
+	assert.NoError(t, writer.Write(TaskCSVHeader))
+	assert.NoError(t, writer.Write(record))
+	writer.Flush()
+
+	rows, err := ParseTasksCSV(strings.NewReader(out.String()))
+
+	assert.NoError(t, err)
+	assert.Len(t, rows, 1)
+	assert.NoError(t, rows[0].Err)
+	assert.Equal(t, 2, rows[0].Row)
+	assert.Equal(t, task, rows[0].Task)
+}
+
+func TestParseTasksCSV_ReportsRowErrors(t *testing.T) {
+	input := "title,id,completed\n" +
+		"Fine,1,false\n" +
+		",2,false\n" +
+		"Bad flag,3,maybe\n" +
+		"Duplicate,1,true\n"
+
+	rows, err := ParseTasksCSV(strings.NewReader(input))
+
+	assert.NoError(t, err)
+	assert.Len(t, rows, 4)
+	assert.NoError(t, rows[0].Err)
+	assert.EqualError(t, rows[1].Err, "title is required")
+	assert.EqualError(t, rows[2].Err, "completed: expected true or false")
+	assert.Equal(t, 5, rows[3].Row)
+	assert.EqualError(t, rows[3].Err, "task 1 already appears in row 2")
+
+	_, err = ParseTasksCSV(strings.NewReader("title,colour\nA,red\n"))
+	assert.EqualError(t, err, `unknown CSV column "colour"`)
+}
+
+func TestParseTasksNDJSON_SkipsBlankLines(t *testing.T) {
+	input := `{"title":"One"}` + "\n\n" + `{"title":` + "\n" + `{"title":"Three","items":["a"]}` + "\n"
+
+	rows, err := ParseTasksNDJSON(strings.NewReader(input))
+
+	assert.NoError(t, err)
+	assert.Len(t, rows, 3)
+	assert.NoError(t, rows[0].Err)
+	assert.Equal(t, 3, rows[1].Row)
+	assert.Error(t, rows[1].Err)
+	assert.Equal(t, 4, rows[2].Row)
+	assert.Equal(t, []string{"a"}, rows[2].Task.Items)
+}
+
+func TestImportTasks_DryRunRollsBack(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	taskDto := TaskDto{DB: db, ActorID: 3}
+	now := time.Now()
+
+	rows := []ImportRow{
+		{Row: 2, Task: Task{Title: "New task", Items: []string{"first"}}},
+		{Row: 3, Err: ErrTaskCycle},
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("SAVEPOINT import_row").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT EXISTS \\(SELECT 1 FROM project").
+		WithArgs(DefaultProjectID, 3).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectQuery("INSERT INTO task \\(title").
+		WithArgs("New task", "", false, now, now, 0, nil, DefaultProjectID).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(9))
+	mock.ExpectExec("INSERT INTO task_item").
+		WithArgs(9, "first").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO task_event").
+		WithArgs(9, 3, TaskEventCreated, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("RELEASE SAVEPOINT import_row").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	report, err := taskDto.ImportTasks(rows, true, now)
+
+	assert.NoError(t, err)
+	assert.False(t, report.Committed)
+	assert.Equal(t, 2, report.Rows)
+	assert.Equal(t, 1, report.Created)
+	assert.Equal(t, []ImportError{{Row: 3, Error: ErrTaskCycle.Error()}}, report.Errors)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestImportTasks_RejectsSubtasksLeftInAnotherProject(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	taskDto := TaskDto{DB: db, ActorID: 3}
+	now := time.Now()
+
+	// Task 5 moves to project 2, but its subtask 6 is not in the file.
+	rows := []ImportRow{
+		{Row: 2, Task: Task{ID: 5, Title: "Moved", ProjectID: 2, UpdatedAt: now.Add(-time.Hour)}},
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("SAVEPOINT import_row").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT EXISTS \\(SELECT 1 FROM project").
+		WithArgs(2, 3).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectQuery("SELECT title, description(.|\n)*FOR UPDATE").
+		WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"title", "description", "completed", "assigned_user_id", "parent_task_id", "project_id", "deleted", "items"}).
+			AddRow("Moved", "", false, 0, 0, DefaultProjectID, false, "{}"))
+	mock.ExpectQuery("SELECT EXISTS \\(SELECT 1 FROM project").
+		WithArgs(DefaultProjectID, 3).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectExec("UPDATE task SET title = \\$1").
+		WithArgs("Moved", "", false, now, 0, nil, 2, 5).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM task_item").WithArgs(5).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO task_event").
+		WithArgs(5, 3, TaskEventUpdated, []byte(`{"project_id":{"before":1,"after":2}}`), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("RELEASE SAVEPOINT import_row").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT c.id, p.id FROM task c JOIN task p ON p.id = c.parent_task_id WHERE c.project_id <> p.project_id").
+		WithArgs("{5}").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "id"}).AddRow(6, 5))
+	mock.ExpectRollback()
+
+	report, err := taskDto.ImportTasks(rows, false, now)
+
+	assert.NoError(t, err)
+	assert.False(t, report.Committed)
+	assert.Equal(t, []ImportError{{Row: 2, ID: 5, Error: "task 6 is not in the project of its parent 5; move them together"}}, report.Errors)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestImportTasks_LeavesMatchingTasksUntouched(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	taskDto := TaskDto{DB: db, ActorID: 3}
+	now := time.Now()
+
+	rows := []ImportRow{
+		{Row: 2, Task: Task{ID: 5, Title: "Same", Items: []string{"a"}}},
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("SAVEPOINT import_row").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT EXISTS \\(SELECT 1 FROM project").
+		WithArgs(DefaultProjectID, 3).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectQuery("SELECT title, description(.|\n)*FOR UPDATE").
+		WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"title", "description", "completed", "assigned_user_id", "parent_task_id", "project_id", "deleted", "items"}).
+			AddRow("Same", "", false, 0, 0, DefaultProjectID, false, "{a}"))
+	mock.ExpectQuery("SELECT EXISTS \\(SELECT 1 FROM project").
+		WithArgs(DefaultProjectID, 3).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectExec("RELEASE SAVEPOINT import_row").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT c.id, p.id FROM task c").
+		WithArgs("{5}").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "id"}))
+	mock.ExpectRollback()
+
+	report, err := taskDto.ImportTasks(rows, true, now)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0, report.Updated)
+	assert.Equal(t, 1, report.Unchanged)
+	assert.Empty(t, report.Errors)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
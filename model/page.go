@@ -0,0 +1,123 @@
+package model
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrInvalidCursor is returned for a cursor that was not made by Cursor.String.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor is a position in a list ordered by a timestamp and then ID: the
+// last item of the previous page. Tasks are ordered by when they were last
+// updated; comments and history events, which never change, by when they
+// were created.
+type Cursor struct {
+	Time time.Time
+	ID   int
+}
+
+// String encodes the cursor for clients, who are to treat it as opaque.
+func (cursor Cursor) String() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%d.%d", cursor.Time.UnixNano(), cursor.ID)))
+}
+
+// ParseCursor decodes a cursor made by Cursor.String.
+func ParseCursor(s string) (Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	var nanos int64
+	var id int
+	_, err = fmt.Sscanf(string(raw), "%d.%d", &nanos, &id)
+	if err != nil || id < 1 {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	// Timestamps are stored without a zone, and read back as UTC.
+	cursor := Cursor{Time: time.Unix(0, nanos).UTC(), ID: id}
+	if cursor.String() != s {
+		return Cursor{}, ErrInvalidCursor
+	}
+	return cursor, nil
+}
+
+// before reports whether the cursor comes before other in a list ordered
+// by time and then ID.
+func (cursor Cursor) before(other Cursor) bool {
+	if !cursor.Time.Equal(other.Time) {
+		return cursor.Time.Before(other.Time)
+	}
+	return cursor.ID < other.ID
+}
+
+// Page selects part of a list: at most Limit items after the cursor. The
+// zero Page is the whole list.
+type Page struct {
+	After *Cursor
+	Limit int
+}
+
+// addTo adds the condition selecting the rows after the cursor, in a list
+// ordered by the time and ID columns given. The operator is ">" for a list
+// in ascending order and "<" for one in descending order.
+func (page Page) addTo(where *conditions, columns, operator string) {
+	if page.After != nil {
+		where.add("("+columns+") "+operator+" ($%d, $%d)", page.After.Time, page.After.ID)
+	}
+}
+
+// limit returns the LIMIT clause for the page, if it has one, adding its
+// argument to where.
+func (page Page) limit(where *conditions) string {
+	if page.Limit <= 0 {
+		return ""
+	}
+	where.args = append(where.args, page.Limit)
+	return fmt.Sprintf("LIMIT $%d", len(where.args))
+}
+
+// pageOf returns the page of items kept in memory, as the file store does,
+// ordered by the position in the list each has.
+func pageOf[T any](items []T, page Page, position func(T) Cursor) []T {
+	sort.SliceStable(items, func(i, j int) bool {
+		return position(items[i]).before(position(items[j]))
+	})
+	if page.After != nil {
+		start := sort.Search(len(items), func(i int) bool {
+			return page.After.before(position(items[i]))
+		})
+		items = items[start:]
+	}
+	if page.Limit > 0 && len(items) > page.Limit {
+		items = items[:page.Limit]
+	}
+	return items
+}
+
+// conditions builds the WHERE clause of a query and its arguments.
+type conditions struct {
+	where []string
+	args  []any
+}
+
+// add adds a condition, with a $%d verb for each of its arguments, which
+// are numbered after those already added.
+func (c *conditions) add(condition string, args ...any) {
+	numbers := make([]any, len(args))
+	for i, arg := range args {
+		c.args = append(c.args, arg)
+		numbers[i] = len(c.args)
+	}
+	c.where = append(c.where, fmt.Sprintf(condition, numbers...))
+}
+
+// String joins the conditions with AND.
+func (c conditions) String() string {
+	return strings.Join(c.where, " AND ")
+}
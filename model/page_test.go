@@ -0,0 +1,79 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCursor(t *testing.T) {
+	cursor := Cursor{Time: time.Date(2024, 3, 1, 9, 30, 0, 123456789, time.UTC), ID: 42}
+
+	parsed, err := ParseCursor(cursor.String())
+	assert.NoError(t, err)
+	assert.Equal(t, cursor, parsed)
+
+	for _, s := range []string{"", "not base64!", Cursor{ID: 0}.String(), "MTIzNDU"} {
+		_, err := ParseCursor(s)
+		assert.ErrorIs(t, err, ErrInvalidCursor, s)
+	}
+}
+
+func TestPageOf(t *testing.T) {
+	now := time.Now()
+	tasks := []Task{
+		{ID: 3, UpdatedAt: now},
+		{ID: 1, UpdatedAt: now.Add(time.Minute)},
+		{ID: 2, UpdatedAt: now},
+		{ID: 4, UpdatedAt: now.Add(-time.Minute)},
+	}
+	ids := func(tasks []Task) []int {
+		var ids []int
+		for _, task := range tasks {
+			ids = append(ids, task.ID)
+		}
+		return ids
+	}
+
+	assert.Equal(t, []int{4, 2, 3, 1}, ids(pageOf(tasks, Page{}, TaskCursor)))
+	assert.Equal(t, []int{4, 2}, ids(pageOf(tasks, Page{Limit: 2}, TaskCursor)))
+
+	// Tasks updated at the same time as the cursor are ordered by ID.
+	after := Cursor{Time: now, ID: 2}
+	assert.Equal(t, []int{3, 1}, ids(pageOf(tasks, Page{After: &after, Limit: 2}, TaskCursor)))
+	after = TaskCursor(tasks[len(tasks)-1])
+	assert.Empty(t, pageOf(tasks, Page{After: &after}, TaskCursor))
+}
+
+func TestEachTask_Page(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	taskDto := TaskDto{DB: db}
+	now := time.Now().UTC()
+	userID := 7
+	after := Cursor{Time: now, ID: 5}
+
+	rows := sqlmock.NewRows([]string{"id", "title", "description", "completed", "created_at", "updated_at", "assigned_user_id", "parent_task_id", "project_id", "items"}).
+		AddRow(6, "Task 6", "", false, now, now, userID, 0, 1, `{"Item 1"}`).
+		AddRow(9, "Task 9", "", false, now, now.Add(time.Second), userID, 0, 1, `{}`)
+	mock.ExpectQuery(`WHERE t.deleted_at IS NULL AND assigned_user_id = \$1 AND \(t.updated_at, t.id\) > \(\$2, \$3\)\s+ORDER BY t.updated_at, t.id\s+LIMIT \$4`).
+		WithArgs(userID, now, 5, 3).
+		WillReturnRows(rows)
+
+	var tasks []Task
+	err = taskDto.EachTask(TaskFilter{AssignedUserID: &userID}, Page{After: &after, Limit: 3}, func(task Task) error {
+		tasks = append(tasks, task)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Len(t, tasks, 2)
+	assert.Equal(t, []string{"Item 1"}, tasks[0].Items)
+	assert.Equal(t, 9, tasks[1].ID)
+
+	err = mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
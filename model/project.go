@@ -0,0 +1,336 @@
+package model
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// DefaultProjectID is the project tasks are filed under when none is given.
+// It is created by create_table.sql.
+const DefaultProjectID = 1
+
+// Project member roles.
+const (
+	ProjectRoleOwner  = "owner"
+	ProjectRoleMember = "member"
+)
+
+type Project struct {
+	ID          int       `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	OwnerUserID int       `json:"owner_user_id"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type ProjectMember struct {
+	ProjectID int       `json:"project_id"`
+	UserID    int       `json:"user_id"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// visibleTo returns the condition that the project in column is one the
+// user, whose ID is the condition's argument, can access: see
+// GetAccessRole.
+func visibleTo(column string) string {
+	return fmt.Sprintf("(%s = %d OR %s IN (SELECT pm.project_id FROM project_member pm WHERE pm.user_id = $%%d))",
+		column, DefaultProjectID, column)
+}
+
+type ProjectDto struct {
+	DB *sql.DB
+}
+
+// Insert creates the project and records its owner as its first member.
+func (projectDto ProjectDto) Insert(project *Project) error {
+	tx, err := projectDto.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(`
+		INSERT INTO project (name, description, owner_user_id, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id
+	`, project.Name, project.Description, project.OwnerUserID, project.CreatedAt, project.UpdatedAt).Scan(&project.ID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO project_member (project_id, user_id, role, created_at)
+		VALUES ($1, $2, $3, $4)
+	`, project.ID, project.OwnerUserID, ProjectRoleOwner, project.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (projectDto ProjectDto) GetProject(id int) (*Project, error) {
+	project := &Project{}
+	err := projectDto.DB.QueryRow(`
+		SELECT id, name, description, owner_user_id, created_at, updated_at
+		FROM project
+		WHERE id = $1
+	`, id).Scan(
+		&project.ID,
+		&project.Name,
+		&project.Description,
+		&project.OwnerUserID,
+		&project.CreatedAt,
+		&project.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return project, nil
+}
+
+// GetProjectsForUser returns the projects the user is a member of.
+func (projectDto ProjectDto) GetProjectsForUser(userID int) ([]Project, error) {
+	query := `
+		SELECT p.id, p.name, p.description, p.owner_user_id, p.created_at, p.updated_at
+		FROM project p
+		JOIN project_member pm ON pm.project_id = p.id
+		WHERE pm.user_id = $1
+		ORDER BY p.id
+	`
+
+	rows, err := projectDto.DB.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	projects := make([]Project, 0)
+	for rows.Next() {
+		var project Project
+		err := rows.Scan(
+			&project.ID,
+			&project.Name,
+			&project.Description,
+			&project.OwnerUserID,
+			&project.CreatedAt,
+			&project.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		projects = append(projects, project)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return projects, nil
+}
+
+// GetMemberRole returns the user's role in the project, or an empty string
+// if the user is not a member.
+func (projectDto ProjectDto) GetMemberRole(projectID, userID int) (string, error) {
+	var role string
+	err := projectDto.DB.QueryRow(`
+		SELECT role
+		FROM project_member
+		WHERE project_id = $1 AND user_id = $2
+	`, projectID, userID).Scan(&role)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return role, nil
+}
+
+// GetAccessRole returns the role the user acts with in the project, or an
+// empty string if they cannot access it. The default project, which has no
+// members and holds the tasks filed before projects existed, is shared:
+// every user is a member of it.
+func (projectDto ProjectDto) GetAccessRole(projectID, userID int) (string, error) {
+	role, err := projectDto.GetMemberRole(projectID, userID)
+	if err == nil && role == "" && projectID == DefaultProjectID {
+		role = ProjectRoleMember
+	}
+
+	return role, err
+}
+
+func (projectDto ProjectDto) GetMembers(projectID int) ([]ProjectMember, error) {
+	query := `
+		SELECT project_id, user_id, role, created_at
+		FROM project_member
+		WHERE project_id = $1
+		ORDER BY user_id
+	`
+
+	rows, err := projectDto.DB.Query(query, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := make([]ProjectMember, 0)
+	for rows.Next() {
+		var member ProjectMember
+		err := rows.Scan(&member.ProjectID, &member.UserID, &member.Role, &member.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return members, nil
+}
+
+// AddMember adds a user to the project. Adding an existing member is a no-op.
+func (projectDto ProjectDto) AddMember(member *ProjectMember) error {
+	_, err := projectDto.DB.Exec(`
+		INSERT INTO project_member (project_id, user_id, role, created_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (project_id, user_id) DO NOTHING
+	`, member.ProjectID, member.UserID, member.Role, member.CreatedAt)
+
+	return err
+}
+
+// RemoveMember removes a user from the project. The owner cannot be removed.
+func (projectDto ProjectDto) RemoveMember(projectID, userID int) (bool, error) {
+	result, err := projectDto.DB.Exec(`
+		DELETE FROM project_member
+		WHERE project_id = $1 AND user_id = $2 AND role <> 'owner'
+	`, projectID, userID)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAffected > 0, nil
+}
+
+func (taskDto TaskDto) GetAllTasksByProjectID(projectID int) ([]Task, error) {
+	return taskDto.queryTasks(`
+		SELECT `+taskColumns+`
+		FROM task t
+		WHERE t.project_id = $1 AND t.deleted_at IS NULL
+		ORDER BY t.id
+	`, projectID)
+}
+
+// GetTaskProjectID returns the project the task is filed under. Tasks in the
+// trash are included, so their history can be read and they can be restored.
+func (taskDto TaskDto) GetTaskProjectID(id int) (int, error) {
+	var projectID int
+	err := taskDto.DB.QueryRow(`SELECT project_id FROM task WHERE id = $1`, id).Scan(&projectID)
+	if err != nil {
+		return 0, err
+	}
+
+	return projectID, nil
+}
+
+// ErrSubtaskMove is returned when a subtask is moved to another project on
+// its own. Subtasks are always in their parent's project.
+var ErrSubtaskMove = errors.New("a subtask cannot leave its parent's project")
+
+// MoveTaskToProject files the task, with all of its subtasks, under another
+// project, and takes them off the boards of the projects they left. It
+// returns ErrSubtaskMove if the task has a parent.
+func (taskDto TaskDto) MoveTaskToProject(id, projectID int, updatedAt time.Time) error {
+	tx, err := taskDto.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Keeps subtasks from being added or re-parented while the subtree moves.
+	err = lockTaskHierarchy(tx)
+	if err != nil {
+		return err
+	}
+
+	var previousProjectID, parentID int
+	err = tx.QueryRow(`SELECT project_id, COALESCE(parent_task_id, 0) FROM task WHERE id = $1 FOR UPDATE`, id).Scan(&previousProjectID, &parentID)
+	if err != nil {
+		return err
+	}
+	if parentID != 0 {
+		return ErrSubtaskMove
+	}
+	if previousProjectID == projectID {
+		return tx.Commit()
+	}
+
+	// Subtasks in the trash move too, so they can be restored under their parent.
+	rows, err := tx.Query(`
+		WITH RECURSIVE subtree AS (
+			SELECT id
+			FROM task
+			WHERE id = $1
+			UNION
+			SELECT t.id
+			FROM task t
+			JOIN subtree s ON t.parent_task_id = s.id
+		)
+		UPDATE task
+		SET project_id = $2, updated_at = $3
+		WHERE id IN (SELECT id FROM subtree)
+		RETURNING id
+	`, id, projectID, updatedAt)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var moved []int
+	for rows.Next() {
+		var taskID int
+		err := rows.Scan(&taskID)
+		if err != nil {
+			return err
+		}
+		moved = append(moved, taskID)
+	}
+
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		DELETE FROM board_card bc
+		USING board b
+		WHERE b.id = bc.board_id AND b.project_id <> $1 AND bc.task_id = ANY ($2)
+	`, projectID, pq.Array(moved))
+	if err != nil {
+		return err
+	}
+
+	for _, taskID := range moved {
+		err = taskDto.recordTaskEvent(tx, taskID, TaskEventProjectChanged, fieldChanges{"project_id": {Before: previousProjectID, After: projectID}})
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
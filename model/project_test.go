@@ -0,0 +1,185 @@
+package model
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProjectInsert_AddsOwnerAsMember(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	projectDto := ProjectDto{DB: db}
+
+	project := &Project{
+		Name:        "Network",
+		Description: "Network upgrades",
+		OwnerUserID: 5,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO project \\(name, description, owner_user_id, created_at, updated_at\\)").
+		WithArgs(project.Name, project.Description, project.OwnerUserID, project.CreatedAt, project.UpdatedAt).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+	mock.ExpectExec("INSERT INTO project_member").
+		WithArgs(2, 5, ProjectRoleOwner, project.CreatedAt).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err = projectDto.Insert(project)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, project.ID)
+
+	err = mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+func TestGetProjectsForUser_SuccessfulGet(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	projectDto := ProjectDto{DB: db}
+
+	columns := []string{"id", "name", "description", "owner_user_id", "created_at", "updated_at"}
+	mockRows := sqlmock.NewRows(columns).
+		AddRow(1, "Default", "", 0, time.Now(), time.Now()).
+		AddRow(2, "Network", "", 5, time.Now(), time.Now())
+
+	mock.ExpectQuery("SELECT p.id, p.name.*FROM project p.*JOIN project_member pm.*WHERE pm.user_id = \\$1").
+		WithArgs(5).
+		WillReturnRows(mockRows)
+
+	projects, err := projectDto.GetProjectsForUser(5)
+	assert.NoError(t, err)
+	assert.Len(t, projects, 2)
+	assert.Equal(t, "Network", projects[1].Name)
+
+	err = mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+func TestGetMemberRole_NotAMember(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	projectDto := ProjectDto{DB: db}
+
+	mock.ExpectQuery("SELECT role FROM project_member").
+		WithArgs(2, 9).
+		WillReturnError(sql.ErrNoRows)
+
+	role, err := projectDto.GetMemberRole(2, 9)
+	assert.NoError(t, err)
+	assert.Equal(t, "", role)
+
+	err = mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+func TestRemoveMember_KeepsOwner(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	projectDto := ProjectDto{DB: db}
+
+	mock.ExpectExec("DELETE FROM project_member WHERE project_id = \\$1 AND user_id = \\$2 AND role <> 'owner'").
+		WithArgs(2, 5).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	removed, err := projectDto.RemoveMember(2, 5)
+	assert.NoError(t, err)
+	assert.False(t, removed)
+
+	err = mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+func TestGetAllTasksByProjectID_SuccessfulGet(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	taskDto := TaskDto{DB: db}
+
+	columns := []string{"id", "title", "description", "completed", "created_at", "updated_at", "assigned_user_id", "parent_task_id", "project_id", "items"}
+	mockRows := sqlmock.NewRows(columns).
+		AddRow(1, "Test Title 1", "Description 1", false, time.Now(), time.Now(), 0, 0, 2, "{\"Item 1\",\"Item 2\"}").
+		AddRow(3, "Test Title 3", "Description 3", false, time.Now(), time.Now(), 0, 0, 2, "{}")
+
+	mock.ExpectQuery("^SELECT t.id, t.title.*FROM task t.*WHERE t.project_id = \\$1").
+		WithArgs(2).
+		WillReturnRows(mockRows)
+
+	tasks, err := taskDto.GetAllTasksByProjectID(2)
+	assert.NoError(t, err)
+	assert.Len(t, tasks, 2)
+	assert.Equal(t, []string{"Item 1", "Item 2"}, tasks[0].Items)
+	assert.Equal(t, 2, tasks[1].ProjectID)
+
+	err = mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+func TestMoveTaskToProject_MovesSubtasksAndDropsCards(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	taskDto := TaskDto{DB: db}
+	now := time.Now()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT pg_advisory_xact_lock").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT project_id, COALESCE\\(parent_task_id, 0\\) FROM task WHERE id = \\$1 FOR UPDATE").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"project_id", "parent_task_id"}).AddRow(2, 0))
+	mock.ExpectQuery("WITH RECURSIVE subtree AS(.|\n)*UPDATE task SET project_id = \\$2, updated_at = \\$3").
+		WithArgs(1, 3, now).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(4))
+	mock.ExpectExec("DELETE FROM board_card bc USING board b WHERE b.id = bc.board_id AND b.project_id <> \\$1").
+		WithArgs(3, "{1,4}").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	for _, id := range []int{1, 4} {
+		mock.ExpectExec("INSERT INTO task_event").
+			WithArgs(id, 0, TaskEventProjectChanged, []byte(`{"project_id":{"before":2,"after":3}}`), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+	}
+	mock.ExpectCommit()
+
+	err = taskDto.MoveTaskToProject(1, 3, now)
+	assert.NoError(t, err)
+
+	err = mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+func TestMoveTaskToProject_RejectsSubtask(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	taskDto := TaskDto{DB: db}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT pg_advisory_xact_lock").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT project_id, COALESCE\\(parent_task_id, 0\\) FROM task WHERE id = \\$1 FOR UPDATE").
+		WithArgs(4).
+		WillReturnRows(sqlmock.NewRows([]string{"project_id", "parent_task_id"}).AddRow(2, 1))
+	mock.ExpectRollback()
+
+	err = taskDto.MoveTaskToProject(4, 3, time.Now())
+	assert.ErrorIs(t, err, ErrSubtaskMove)
+
+	err = mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
@@ -0,0 +1,66 @@
+package model
+
+import (
+	"errors"
+	"strings"
+)
+
+// rankDigits are the characters card ranks are built from, in sort order.
+// Ranks compare byte-wise, so the board_card.rank column uses COLLATE "C".
+const rankDigits = "0123456789abcdefghijklmnopqrstuvwxyz"
+
+// ErrInvalidRank is returned when RankBetween is given ranks it could not have produced.
+var ErrInvalidRank = errors.New("invalid rank")
+
+// RankBetween returns a rank that sorts strictly between prev and next. An
+// empty prev means the start of the column and an empty next means its end.
+// Only the moved card gets a new rank, so a move never renumbers the column.
+// Generated ranks never end in '0', which guarantees there is always room
+// for another rank below them.
+func RankBetween(prev, next string) (string, error) {
+	if !validRank(prev) || !validRank(next) {
+		return "", ErrInvalidRank
+	}
+	if next != "" && prev >= next {
+		return "", ErrInvalidRank
+	}
+
+	var rank strings.Builder
+	upperBound := next != ""
+
+	for i := 0; ; i++ {
+		lo := 0
+		if i < len(prev) {
+			lo = strings.IndexByte(rankDigits, prev[i])
+		}
+
+		hi := len(rankDigits)
+		if upperBound {
+			hi = strings.IndexByte(rankDigits, next[i])
+		}
+
+		if hi-lo > 1 {
+			rank.WriteByte(rankDigits[(lo+hi)/2])
+			return rank.String(), nil
+		}
+
+		// No room at this position: copy the lower digit and look further
+		// along. Once we sit below next's digit, next no longer constrains us.
+		rank.WriteByte(rankDigits[lo])
+		if hi > lo {
+			upperBound = false
+		}
+	}
+}
+
+func validRank(rank string) bool {
+	if strings.HasSuffix(rank, "0") {
+		return false
+	}
+	for i := 0; i < len(rank); i++ {
+		if strings.IndexByte(rankDigits, rank[i]) < 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,65 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRankBetween(t *testing.T) {
+	tests := []struct {
+		prev, next string
+	}{
+		{"", ""},
+		{"", "i"},
+		{"i", ""},
+		{"a", "b"},
+		{"a", "a1"},
+		{"a", "a01"},
+		{"az", "b"},
+		{"", "1"},
+		{"zz", ""},
+	}
+
+	for _, tt := range tests {
+		rank, err := RankBetween(tt.prev, tt.next)
+		assert.NoError(t, err)
+		assert.Greater(t, rank, tt.prev, "prev=%q next=%q", tt.prev, tt.next)
+		if tt.next != "" {
+			assert.Less(t, rank, tt.next, "prev=%q next=%q", tt.prev, tt.next)
+		}
+		assert.NotEqual(t, byte('0'), rank[len(rank)-1])
+	}
+}
+
+func TestRankBetween_RepeatedInsertsStayOrdered(t *testing.T) {
+	// Keep dropping cards directly after the first card, the worst case for
+	// rank growth, and check the column never needs renumbering.
+	first, err := RankBetween("", "")
+	assert.NoError(t, err)
+
+	next := ""
+	for i := 0; i < 200; i++ {
+		rank, err := RankBetween(first, next)
+		assert.NoError(t, err)
+		assert.Greater(t, rank, first)
+		if next != "" {
+			assert.Less(t, rank, next)
+		}
+		next = rank
+	}
+}
+
+func TestRankBetween_RejectsInvalidInput(t *testing.T) {
+	_, err := RankBetween("b", "a")
+	assert.ErrorIs(t, err, ErrInvalidRank)
+
+	_, err = RankBetween("a", "a")
+	assert.ErrorIs(t, err, ErrInvalidRank)
+
+	_, err = RankBetween("a0", "")
+	assert.ErrorIs(t, err, ErrInvalidRank)
+
+	_, err = RankBetween("A", "")
+	assert.ErrorIs(t, err, ErrInvalidRank)
+}
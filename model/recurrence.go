@@ -0,0 +1,255 @@
+package model
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// TaskRecurrence attaches an RRULE schedule to a template task. When
+// NextRunAt falls due, a copy of the template and its checklist items is
+// created as a new task.
+type TaskRecurrence struct {
+	ID        int        `json:"id"`
+	TaskID    int        `json:"task_id"`
+	RRule     string     `json:"rrule"`
+	DTStart   time.Time  `json:"dtstart"`
+	NextRunAt *time.Time `json:"next_run_at"`
+	LastRunAt *time.Time `json:"last_run_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// FirstRun returns the first occurrence of the schedule that is not in the
+// past, so attaching a rule never back-fills old instances.
+func (rec *TaskRecurrence) FirstRun(rule *RRule, now time.Time) *time.Time {
+	after := now
+	if rec.DTStart.After(now) {
+		after = rec.DTStart.Add(-time.Nanosecond)
+	}
+
+	next, ok := rule.Next(rec.DTStart, after)
+	if !ok {
+		return nil
+	}
+	return &next
+}
+
+// UpsertTaskRecurrence attaches the schedule to the task, replacing any schedule it already had.
+func (taskDto TaskDto) UpsertTaskRecurrence(rec *TaskRecurrence) error {
+	tx, err := taskDto.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var previousRRule sql.NullString
+	err = tx.QueryRow(`SELECT rrule FROM task_recurrence WHERE task_id = $1 FOR UPDATE`, rec.TaskID).Scan(&previousRRule)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	err = tx.QueryRow(`
+		INSERT INTO task_recurrence (task_id, rrule, dtstart, next_run_at, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (task_id) DO UPDATE
+		SET rrule = EXCLUDED.rrule, dtstart = EXCLUDED.dtstart, next_run_at = EXCLUDED.next_run_at
+		RETURNING id, created_at
+	`, rec.TaskID, rec.RRule, rec.DTStart, rec.NextRunAt, rec.CreatedAt).Scan(&rec.ID, &rec.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	changes := fieldChanges{"rrule": {After: rec.RRule}, "dtstart": {After: rec.DTStart}}
+	if previousRRule.Valid {
+		changes["rrule"] = FieldChange{Before: previousRRule.String, After: rec.RRule}
+	}
+
+	err = taskDto.recordTaskEvent(tx, rec.TaskID, TaskEventRecurrenceSet, changes)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (taskDto TaskDto) GetTaskRecurrence(taskID int) (*TaskRecurrence, error) {
+	rec := &TaskRecurrence{}
+	var nextRunAt, lastRunAt sql.NullTime
+	err := taskDto.DB.QueryRow(`
+		SELECT id, task_id, rrule, dtstart, next_run_at, last_run_at, created_at
+		FROM task_recurrence
+		WHERE task_id = $1
+	`, taskID).Scan(&rec.ID, &rec.TaskID, &rec.RRule, &rec.DTStart, &nextRunAt, &lastRunAt, &rec.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if nextRunAt.Valid {
+		rec.NextRunAt = &nextRunAt.Time
+	}
+	if lastRunAt.Valid {
+		rec.LastRunAt = &lastRunAt.Time
+	}
+
+	return rec, nil
+}
+
+func (taskDto TaskDto) DeleteTaskRecurrence(taskID int) (bool, error) {
+	tx, err := taskDto.DB.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var rrule string
+	err = tx.QueryRow(`DELETE FROM task_recurrence WHERE task_id = $1 RETURNING rrule`, taskID).Scan(&rrule)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	err = taskDto.recordTaskEvent(tx, taskID, TaskEventRecurrenceRemoved, fieldChanges{"rrule": {Before: rrule}})
+	if err != nil {
+		return false, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// GenerateDueRecurrences creates one task instance for every schedule whose
+// next run is at or before now, and moves each schedule on to its next
+// occurrence after now. Occurrences missed while the API was down are
+// collapsed into that single instance.
+//
+// It is safe to run from several processes and across restarts: due rows are
+// locked with SKIP LOCKED, every occurrence is recorded in
+// task_recurrence_instance under a unique key, and next_run_at is advanced
+// in the same transaction as the instance is created.
+func (taskDto TaskDto) GenerateDueRecurrences(now time.Time) (int, error) {
+	tx, err := taskDto.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`
+		SELECT id, task_id, rrule, dtstart, next_run_at
+		FROM task_recurrence
+		WHERE next_run_at <= $1 AND task_id IN (SELECT id FROM task WHERE deleted_at IS NULL)
+		ORDER BY next_run_at
+		FOR UPDATE SKIP LOCKED
+	`, now)
+	if err != nil {
+		return 0, err
+	}
+
+	var due []TaskRecurrence
+	for rows.Next() {
+		var rec TaskRecurrence
+		var nextRunAt time.Time
+		err := rows.Scan(&rec.ID, &rec.TaskID, &rec.RRule, &rec.DTStart, &nextRunAt)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+		rec.NextRunAt = &nextRunAt
+		due = append(due, rec)
+	}
+	rows.Close()
+
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+
+	created := 0
+	for _, rec := range due {
+		occurrence := *rec.NextRunAt
+
+		result, err := tx.Exec(`
+			INSERT INTO task_recurrence_instance (recurrence_id, occurrence_at)
+			VALUES ($1, $2)
+			ON CONFLICT (recurrence_id, occurrence_at) DO NOTHING
+		`, rec.ID, occurrence)
+		if err != nil {
+			return created, err
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return created, err
+		}
+
+		// Only create the task if this occurrence has not been generated before.
+		if rowsAffected > 0 {
+			var taskID int
+			err = tx.QueryRow(`
+				INSERT INTO task (title, description, completed, created_at, updated_at, assigned_user_id, parent_task_id, project_id)
+				SELECT title, description, false, $2, $2, assigned_user_id, parent_task_id, project_id
+				FROM task
+				WHERE id = $1
+				RETURNING id
+			`, rec.TaskID, now).Scan(&taskID)
+			if err != nil {
+				return created, err
+			}
+
+			_, err = tx.Exec(`
+				INSERT INTO task_item (task_id, item)
+				SELECT $1, item FROM task_item WHERE task_id = $2 ORDER BY id
+			`, taskID, rec.TaskID)
+			if err != nil {
+				return created, err
+			}
+
+			_, err = tx.Exec(`
+				UPDATE task_recurrence_instance
+				SET task_id = $1
+				WHERE recurrence_id = $2 AND occurrence_at = $3
+			`, taskID, rec.ID, occurrence)
+			if err != nil {
+				return created, err
+			}
+
+			err = taskDto.recordTaskEvent(tx, taskID, TaskEventCreated, fieldChanges{
+				"recurrence_of": {After: rec.TaskID},
+				"occurrence_at": {After: occurrence},
+			})
+			if err != nil {
+				return created, err
+			}
+
+			created++
+		}
+
+		var nextRunAt *time.Time
+		rule, err := ParseRRule(rec.RRule)
+		if err == nil {
+			if next, ok := rule.Next(rec.DTStart, now); ok {
+				nextRunAt = &next
+			}
+		}
+
+		_, err = tx.Exec(`
+			UPDATE task_recurrence
+			SET next_run_at = $1, last_run_at = $2
+			WHERE id = $3
+		`, nextRunAt, occurrence, rec.ID)
+		if err != nil {
+			return created, err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+
+	return created, nil
+}
@@ -0,0 +1,99 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTaskRecurrenceFirstRun_SkipsPastOccurrences(t *testing.T) {
+	rule, err := ParseRRule("FREQ=MONTHLY")
+	assert.NoError(t, err)
+
+	rec := &TaskRecurrence{DTStart: time.Date(2023, 1, 15, 9, 0, 0, 0, time.UTC)}
+	next := rec.FirstRun(rule, time.Date(2023, 6, 20, 0, 0, 0, 0, time.UTC))
+	assert.Equal(t, time.Date(2023, 7, 15, 9, 0, 0, 0, time.UTC), *next)
+
+	rec.DTStart = time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)
+	next = rec.FirstRun(rule, time.Date(2023, 6, 20, 0, 0, 0, 0, time.UTC))
+	assert.Equal(t, rec.DTStart, *next)
+}
+
+func TestGenerateDueRecurrences_CreatesInstance(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	taskDto := TaskDto{DB: db}
+
+	dtstart := time.Date(2023, 6, 1, 9, 0, 0, 0, time.UTC)
+	due := time.Date(2023, 7, 1, 9, 0, 0, 0, time.UTC)
+	now := time.Date(2023, 7, 1, 9, 0, 30, 0, time.UTC)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id, task_id, rrule, dtstart, next_run_at FROM task_recurrence .* FOR UPDATE SKIP LOCKED").
+		WithArgs(now).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "task_id", "rrule", "dtstart", "next_run_at"}).
+			AddRow(3, 1, "FREQ=MONTHLY", dtstart, due))
+	mock.ExpectExec("INSERT INTO task_recurrence_instance .* ON CONFLICT").
+		WithArgs(3, due).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("INSERT INTO task .* SELECT title, description, false").
+		WithArgs(1, now).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(42))
+	mock.ExpectExec("INSERT INTO task_item \\(task_id, item\\) SELECT").
+		WithArgs(42, 1).
+		WillReturnResult(sqlmock.NewResult(0, 4))
+	mock.ExpectExec("UPDATE task_recurrence_instance SET task_id").
+		WithArgs(42, 3, due).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO task_event").
+		WithArgs(42, 0, TaskEventCreated, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("UPDATE task_recurrence SET next_run_at").
+		WithArgs(time.Date(2023, 8, 1, 9, 0, 0, 0, time.UTC), due, 3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	created, err := taskDto.GenerateDueRecurrences(now)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, created)
+
+	err = mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+func TestGenerateDueRecurrences_SkipsGeneratedOccurrence(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	taskDto := TaskDto{DB: db}
+
+	dtstart := time.Date(2023, 6, 1, 9, 0, 0, 0, time.UTC)
+	due := time.Date(2023, 7, 1, 9, 0, 0, 0, time.UTC)
+	now := time.Date(2023, 7, 1, 9, 0, 30, 0, time.UTC)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id, task_id, rrule, dtstart, next_run_at FROM task_recurrence").
+		WithArgs(now).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "task_id", "rrule", "dtstart", "next_run_at"}).
+			AddRow(3, 1, "FREQ=MONTHLY;COUNT=2", dtstart, due))
+	mock.ExpectExec("INSERT INTO task_recurrence_instance").
+		WithArgs(3, due).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	// The series is over after two occurrences, so next_run_at is cleared.
+	mock.ExpectExec("UPDATE task_recurrence SET next_run_at").
+		WithArgs(nil, due, 3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	created, err := taskDto.GenerateDueRecurrences(now)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, created)
+
+	err = mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
@@ -0,0 +1,53 @@
+package model
+
+import "encoding/json"
+
+// RPCVersion is the only JSON-RPC version accepted.
+const RPCVersion = "2.0"
+
+// JSON-RPC error codes. The first five are defined by the specification;
+// the rest are in the range it reserves for the server.
+const (
+	RPCParseError     = -32700
+	RPCInvalidRequest = -32600
+	RPCMethodNotFound = -32601
+	RPCInvalidParams  = -32602
+	RPCInternalError  = -32603
+	RPCNotFound       = -32001
+	RPCConflict       = -32002
+)
+
+// RPCRequest is a JSON-RPC 2.0 call. A request without an id is a
+// notification: it is run, but no response is sent for it.
+type RPCRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	ID      json.RawMessage `json:"id,omitempty"`
+}
+
+// IsNotification reports whether the request has no id.
+func (req RPCRequest) IsNotification() bool {
+	return req.ID == nil
+}
+
+// RPCResponse is the reply to a JSON-RPC call. Exactly one of Result and
+// Error is set; Result is kept encoded so an empty list is still sent. ID
+// is null when the request's id could not be read.
+type RPCResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *RPCError       `json:"error,omitempty"`
+	ID      json.RawMessage `json:"id"`
+}
+
+// RPCError is a failed JSON-RPC call.
+type RPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    any    `json:"data,omitempty"`
+}
+
+func (e *RPCError) Error() string {
+	return e.Message
+}
@@ -0,0 +1,264 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Supported RRULE frequencies.
+const (
+	FreqDaily   = "DAILY"
+	FreqWeekly  = "WEEKLY"
+	FreqMonthly = "MONTHLY"
+)
+
+// maxRRulePeriods bounds how many days, weeks or months Next will walk
+// through, so a rule that can never match again cannot loop forever.
+const maxRRulePeriods = 100000
+
+var ErrInvalidRRule = errors.New("invalid recurrence rule")
+
+var weekdayCodes = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+// ByDay is one BYDAY entry. Ordinal is 0 for "every such weekday", or the
+// nth (1MO) or nth-from-last (-1FR) such weekday of the month.
+type ByDay struct {
+	Ordinal int
+	Weekday time.Weekday
+}
+
+// RRule is the subset of an RFC 5545 recurrence rule that the scheduler
+// understands: FREQ (DAILY, WEEKLY or MONTHLY), INTERVAL, BYDAY, COUNT and
+// UNTIL. Weeks start on Monday.
+type RRule struct {
+	Freq     string
+	Interval int
+	ByDay    []ByDay
+	Count    int
+	Until    time.Time
+}
+
+// ParseRRule parses a rule such as "FREQ=MONTHLY;BYDAY=1MO;COUNT=12". A
+// leading "RRULE:" is accepted.
+func ParseRRule(value string) (*RRule, error) {
+	value = strings.TrimPrefix(strings.TrimSpace(value), "RRULE:")
+	if value == "" {
+		return nil, fmt.Errorf("%w: empty rule", ErrInvalidRRule)
+	}
+
+	rule := &RRule{Interval: 1}
+	seen := make(map[string]bool)
+
+	for _, part := range strings.Split(value, ";") {
+		key, val, ok := strings.Cut(part, "=")
+		key = strings.ToUpper(key)
+		if !ok || val == "" {
+			return nil, fmt.Errorf("%w: malformed part %q", ErrInvalidRRule, part)
+		}
+		if seen[key] {
+			return nil, fmt.Errorf("%w: %s given twice", ErrInvalidRRule, key)
+		}
+		seen[key] = true
+
+		switch key {
+		case "FREQ":
+			rule.Freq = strings.ToUpper(val)
+			if rule.Freq != FreqDaily && rule.Freq != FreqWeekly && rule.Freq != FreqMonthly {
+				return nil, fmt.Errorf("%w: unsupported FREQ %q", ErrInvalidRRule, val)
+			}
+		case "INTERVAL":
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("%w: INTERVAL must be a positive integer", ErrInvalidRRule)
+			}
+			rule.Interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("%w: COUNT must be a positive integer", ErrInvalidRRule)
+			}
+			rule.Count = n
+		case "UNTIL":
+			until, err := parseUntil(val)
+			if err != nil {
+				return nil, err
+			}
+			rule.Until = until
+		case "BYDAY":
+			for _, code := range strings.Split(strings.ToUpper(val), ",") {
+				day, err := parseByDay(code)
+				if err != nil {
+					return nil, err
+				}
+				rule.ByDay = append(rule.ByDay, day)
+			}
+		default:
+			return nil, fmt.Errorf("%w: unsupported part %s", ErrInvalidRRule, key)
+		}
+	}
+
+	if rule.Freq == "" {
+		return nil, fmt.Errorf("%w: FREQ is required", ErrInvalidRRule)
+	}
+	if rule.Count > 0 && !rule.Until.IsZero() {
+		return nil, fmt.Errorf("%w: COUNT and UNTIL cannot both be given", ErrInvalidRRule)
+	}
+	for _, day := range rule.ByDay {
+		if day.Ordinal != 0 && rule.Freq != FreqMonthly {
+			return nil, fmt.Errorf("%w: BYDAY ordinals are only allowed with FREQ=MONTHLY", ErrInvalidRRule)
+		}
+	}
+
+	return rule, nil
+}
+
+// parseUntil accepts the UTC date-time form (20240131T090000Z) or a plain
+// date, which includes the whole of that day.
+func parseUntil(val string) (time.Time, error) {
+	if until, err := time.Parse("20060102T150405Z", val); err == nil {
+		return until, nil
+	}
+	if until, err := time.Parse("20060102", val); err == nil {
+		return until.Add(24*time.Hour - time.Nanosecond), nil
+	}
+	return time.Time{}, fmt.Errorf("%w: UNTIL must look like 20240131 or 20240131T090000Z", ErrInvalidRRule)
+}
+
+func parseByDay(code string) (ByDay, error) {
+	if len(code) < 2 {
+		return ByDay{}, fmt.Errorf("%w: bad BYDAY value %q", ErrInvalidRRule, code)
+	}
+
+	weekday, ok := weekdayCodes[code[len(code)-2:]]
+	if !ok {
+		return ByDay{}, fmt.Errorf("%w: bad BYDAY value %q", ErrInvalidRRule, code)
+	}
+
+	day := ByDay{Weekday: weekday}
+	if prefix := code[:len(code)-2]; prefix != "" {
+		n, err := strconv.Atoi(prefix)
+		if err != nil || n == 0 || n < -5 || n > 5 {
+			return ByDay{}, fmt.Errorf("%w: bad BYDAY value %q", ErrInvalidRRule, code)
+		}
+		day.Ordinal = n
+	}
+
+	return day, nil
+}
+
+// Next returns the first occurrence of the rule strictly after the given
+// time, for a series starting at dtstart. It returns false once the series
+// has ended through COUNT or UNTIL.
+func (rule *RRule) Next(dtstart, after time.Time) (time.Time, bool) {
+	count := 0
+	for period := 0; period < maxRRulePeriods; period++ {
+		for _, occurrence := range rule.periodOccurrences(dtstart, period) {
+			if occurrence.Before(dtstart) {
+				continue
+			}
+			if !rule.Until.IsZero() && occurrence.After(rule.Until) {
+				return time.Time{}, false
+			}
+			count++
+			if rule.Count > 0 && count > rule.Count {
+				return time.Time{}, false
+			}
+			if occurrence.After(after) {
+				return occurrence, true
+			}
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// periodOccurrences returns the candidate occurrences, in order, of the
+// n-th day, week or month of the series.
+func (rule *RRule) periodOccurrences(dtstart time.Time, n int) []time.Time {
+	step := n * rule.Interval
+
+	switch rule.Freq {
+	case FreqDaily:
+		day := dtstart.AddDate(0, 0, step)
+		if len(rule.ByDay) == 0 || rule.matchesWeekday(day.Weekday()) {
+			return []time.Time{day}
+		}
+		return nil
+
+	case FreqWeekly:
+		monday := dtstart.AddDate(0, 0, -daysSinceMonday(dtstart.Weekday())+7*step)
+		if len(rule.ByDay) == 0 {
+			return []time.Time{monday.AddDate(0, 0, daysSinceMonday(dtstart.Weekday()))}
+		}
+		offsets := make([]int, 0, len(rule.ByDay))
+		for _, day := range rule.ByDay {
+			offsets = append(offsets, daysSinceMonday(day.Weekday))
+		}
+		sort.Ints(offsets)
+		occurrences := make([]time.Time, 0, len(offsets))
+		for i, offset := range offsets {
+			if i > 0 && offset == offsets[i-1] {
+				continue
+			}
+			occurrences = append(occurrences, monday.AddDate(0, 0, offset))
+		}
+		return occurrences
+
+	case FreqMonthly:
+		first := time.Date(dtstart.Year(), dtstart.Month()+time.Month(step), 1,
+			dtstart.Hour(), dtstart.Minute(), dtstart.Second(), dtstart.Nanosecond(), dtstart.Location())
+		daysInMonth := first.AddDate(0, 1, -1).Day()
+
+		if len(rule.ByDay) == 0 {
+			// Months without dtstart's day of the month are skipped, as RFC 5545 requires.
+			if dtstart.Day() > daysInMonth {
+				return nil
+			}
+			return []time.Time{first.AddDate(0, 0, dtstart.Day()-1)}
+		}
+
+		var occurrences []time.Time
+		for d := 1; d <= daysInMonth; d++ {
+			day := first.AddDate(0, 0, d-1)
+			for _, byDay := range rule.ByDay {
+				if byDay.Weekday != day.Weekday() {
+					continue
+				}
+				fromStart := (d-1)/7 + 1
+				fromEnd := -((daysInMonth-d)/7 + 1)
+				if byDay.Ordinal == 0 || byDay.Ordinal == fromStart || byDay.Ordinal == fromEnd {
+					occurrences = append(occurrences, day)
+					break
+				}
+			}
+		}
+		return occurrences
+	}
+
+	return nil
+}
+
+func (rule *RRule) matchesWeekday(weekday time.Weekday) bool {
+	for _, day := range rule.ByDay {
+		if day.Weekday == weekday {
+			return true
+		}
+	}
+	return false
+}
+
+func daysSinceMonday(weekday time.Weekday) int {
+	return (int(weekday) + 6) % 7
+}
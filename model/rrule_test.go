@@ -0,0 +1,93 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// occurrences lists the first n occurrences of the rule from dtstart.
+func occurrences(t *testing.T, value string, dtstart time.Time, n int) []string {
+	rule, err := ParseRRule(value)
+	assert.NoError(t, err)
+
+	var dates []string
+	after := dtstart.Add(-time.Nanosecond)
+	for len(dates) < n {
+		next, ok := rule.Next(dtstart, after)
+		if !ok {
+			break
+		}
+		dates = append(dates, next.Format("2006-01-02 Mon"))
+		after = next
+	}
+	return dates
+}
+
+func TestParseRRule(t *testing.T) {
+	rule, err := ParseRRule("RRULE:FREQ=MONTHLY;INTERVAL=2;BYDAY=1MO,-1FR;COUNT=6")
+	assert.NoError(t, err)
+	assert.Equal(t, FreqMonthly, rule.Freq)
+	assert.Equal(t, 2, rule.Interval)
+	assert.Equal(t, []ByDay{{1, time.Monday}, {-1, time.Friday}}, rule.ByDay)
+	assert.Equal(t, 6, rule.Count)
+
+	rule, err = ParseRRule("FREQ=WEEKLY;UNTIL=20230701")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2023, 7, 1, 23, 59, 59, 999999999, time.UTC), rule.Until)
+}
+
+func TestParseRRule_Invalid(t *testing.T) {
+	for _, value := range []string{
+		"",
+		"INTERVAL=2",
+		"FREQ=YEARLY",
+		"FREQ=DAILY;INTERVAL=0",
+		"FREQ=DAILY;COUNT=3;UNTIL=20230701",
+		"FREQ=WEEKLY;BYDAY=1MO",
+		"FREQ=MONTHLY;BYDAY=XX",
+		"FREQ=MONTHLY;BYMONTH=3",
+		"FREQ=DAILY;FREQ=WEEKLY",
+	} {
+		_, err := ParseRRule(value)
+		assert.ErrorIs(t, err, ErrInvalidRRule, value)
+	}
+}
+
+func TestRRuleNext_Daily(t *testing.T) {
+	dtstart := time.Date(2023, 6, 10, 9, 0, 0, 0, time.UTC)
+	assert.Equal(t,
+		[]string{"2023-06-10 Sat", "2023-06-13 Tue", "2023-06-16 Fri"},
+		occurrences(t, "FREQ=DAILY;INTERVAL=3;COUNT=3", dtstart, 10))
+}
+
+func TestRRuleNext_WeeklyByDay(t *testing.T) {
+	// Starts on a Saturday, so the Monday and Wednesday of that week are
+	// skipped and the series continues two weeks later.
+	dtstart := time.Date(2023, 6, 10, 9, 0, 0, 0, time.UTC)
+	assert.Equal(t,
+		[]string{"2023-06-19 Mon", "2023-06-21 Wed", "2023-07-03 Mon", "2023-07-05 Wed"},
+		occurrences(t, "FREQ=WEEKLY;INTERVAL=2;BYDAY=WE,MO", dtstart, 4))
+}
+
+func TestRRuleNext_MonthlyByDay(t *testing.T) {
+	dtstart := time.Date(2023, 6, 1, 9, 0, 0, 0, time.UTC)
+	assert.Equal(t,
+		[]string{"2023-06-05 Mon", "2023-06-30 Fri", "2023-07-03 Mon", "2023-07-28 Fri"},
+		occurrences(t, "FREQ=MONTHLY;BYDAY=1MO,-1FR", dtstart, 4))
+}
+
+func TestRRuleNext_MonthlySkipsShortMonths(t *testing.T) {
+	dtstart := time.Date(2023, 1, 31, 9, 0, 0, 0, time.UTC)
+	assert.Equal(t,
+		[]string{"2023-01-31 Tue", "2023-03-31 Fri", "2023-05-31 Wed"},
+		occurrences(t, "FREQ=MONTHLY", dtstart, 3))
+}
+
+func TestRRuleNext_Until(t *testing.T) {
+	dtstart := time.Date(2023, 6, 10, 9, 0, 0, 0, time.UTC)
+	assert.Equal(t,
+		[]string{"2023-06-10 Sat", "2023-06-17 Sat", "2023-06-24 Sat"},
+		occurrences(t, "FREQ=WEEKLY;UNTIL=20230624", dtstart, 10))
+}
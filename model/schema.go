@@ -0,0 +1,25 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+)
+
+// SchemaVersion is the version of create_table.sql this code expects to
+// find recorded in the schema_migrations table. Every change to the schema
+// bumps it, along with the row the script inserts, and ships as an
+// idempotent upgrade script migrations/NNN_*.sql numbered with the new
+// version.
+const SchemaVersion = 3
+
+type SchemaDto struct {
+	DB *sql.DB
+}
+
+// GetSchemaVersion returns the schema version recorded in the database, and
+// whether a migration to it was left unfinished.
+func (schemaDto SchemaDto) GetSchemaVersion(ctx context.Context) (version int, dirty bool, err error) {
+	err = schemaDto.DB.QueryRowContext(ctx, `SELECT version, dirty FROM schema_migrations ORDER BY version DESC LIMIT 1`).
+		Scan(&version, &dirty)
+	return version, dirty, err
+}
@@ -0,0 +1,53 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetSchemaVersion(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	schemaDto := SchemaDto{DB: db}
+
+	mock.ExpectQuery("SELECT version, dirty FROM schema_migrations").
+		WillReturnRows(sqlmock.NewRows([]string{"version", "dirty"}).AddRow(SchemaVersion, false))
+	version, dirty, err := schemaDto.GetSchemaVersion(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, SchemaVersion, version)
+	assert.False(t, dirty)
+
+	mock.ExpectQuery("SELECT version, dirty FROM schema_migrations").
+		WillReturnRows(sqlmock.NewRows([]string{"version", "dirty"}))
+	_, _, err = schemaDto.GetSchemaVersion(context.Background())
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigrations(t *testing.T) {
+	script, err := os.ReadFile("../create_table.sql")
+	assert.NoError(t, err)
+	assert.Contains(t, string(script), fmt.Sprintf("VALUES (%d, false);", SchemaVersion))
+
+	// There is one upgrade script per version, and the last one ends at SchemaVersion.
+	names, err := filepath.Glob("../migrations/*.sql")
+	assert.NoError(t, err)
+	assert.Len(t, names, SchemaVersion)
+	for i, name := range names {
+		version := i + 1
+		assert.True(t, strings.HasPrefix(filepath.Base(name), fmt.Sprintf("%03d_", version)), name)
+		migration, err := os.ReadFile(name)
+		assert.NoError(t, err)
+		assert.Contains(t, string(migration), fmt.Sprintf("SET version = %d, dirty = false WHERE version < %d;", version, version), name)
+	}
+}
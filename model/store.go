@@ -0,0 +1,44 @@
+package model
+
+import "time"
+
+// TaskStore is the core task API: tasks, their items, assignment and
+// comments. TaskDto keeps them in PostgreSQL; FileTaskStore keeps them in a
+// JSON file. Missing tasks are reported as sql.ErrNoRows by both.
+type TaskStore interface {
+	GetAllTasks() ([]Task, error)
+	GetTask(id int) (*Task, error)
+	GetAllTaskByAssignedUserID(userID int) ([]Task, error)
+	Insert(task *Task) error
+	InsertTaskItem(taskID int, item string) error
+	// UpdateTask returns ErrTaskBlocked if it would complete a task that
+	// has unfinished blockers.
+	UpdateTask(id int, task *Task) error
+	AssignUserToTask(id, userID int, updatedAt time.Time) error
+	DeleteTask(id int) error
+	DeleteTaskReparent(id int) error
+	InsertTaskComment(taskComment *TaskComment) error
+	GetAllTaskCommentsByTaskID(taskID int) ([]TaskComment, error)
+	// EachTask calls fn with the page of tasks filter matches, ordered by
+	// when they were last updated, and stops at the first error fn returns.
+	// The zero filter matches every task.
+	EachTask(filter TaskFilter, page Page, fn func(Task) error) error
+	// EachTaskComment calls fn with the page of the task's comments, oldest
+	// first, and stops at the first error fn returns.
+	EachTaskComment(taskID int, page Page, fn func(TaskComment) error) error
+}
+
+// TaskCursor is the task's position in task lists.
+func TaskCursor(task Task) Cursor {
+	return Cursor{Time: task.UpdatedAt, ID: task.ID}
+}
+
+// TaskCommentCursor is the comment's position in comment lists.
+func TaskCommentCursor(comment TaskComment) Cursor {
+	return Cursor{Time: comment.CreatedAt, ID: comment.ID}
+}
+
+var (
+	_ TaskStore = TaskDto{}
+	_ TaskStore = (*FileTaskStore)(nil)
+)
@@ -0,0 +1,303 @@
+package model
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// ErrTaskCycle is returned when re-parenting a task would make it a descendant of itself.
+var ErrTaskCycle = errors.New("task hierarchy would contain a cycle")
+
+// taskHierarchyLock is the advisory lock taken while a task is checked for
+// cycles and given a new parent. Two tasks moved under each other at once
+// could each pass the check, so locking the rows being moved is not enough.
+const taskHierarchyLock = 0x746d735f706172 // "tms_par"
+
+// TaskNode is a task together with its subtasks, as returned by GetTaskTree.
+// Progress is 1 for a completed leaf task, 0 for an open one, and the mean
+// of the children's progress for a parent task.
+type TaskNode struct {
+	Task
+	Depth    int         `json:"depth"`
+	Progress float64     `json:"progress"`
+	Subtasks []*TaskNode `json:"subtasks"`
+}
+
+// nullableID maps the zero ID used by Task to SQL NULL.
+func nullableID(id int) any {
+	if id == 0 {
+		return nil
+	}
+	return id
+}
+
+// GetSubtasks returns the task's direct subtasks. Only subtasks in the
+// parent's project are returned.
+func (taskDto TaskDto) GetSubtasks(parentID int) ([]Task, error) {
+	return taskDto.queryTasks(`
+		SELECT `+taskColumns+`
+		FROM task t
+		JOIN task p ON p.id = t.parent_task_id
+		WHERE t.parent_task_id = $1 AND t.project_id = p.project_id AND t.deleted_at IS NULL
+		ORDER BY t.id
+	`, parentID)
+}
+
+// GetTaskTree loads the task with the given ID and all of its descendants
+// in its project with a recursive CTE, and returns the root of the resulting
+// tree. The CTE tracks the path it took, so it ends even if the table holds
+// a cycle.
+func (taskDto TaskDto) GetTaskTree(id int) (*TaskNode, error) {
+	query := `
+		WITH RECURSIVE tree AS (
+			SELECT id, project_id, 0 AS depth, ARRAY[id] AS path
+			FROM task
+			WHERE id = $1 AND deleted_at IS NULL
+			UNION ALL
+			SELECT t.id, t.project_id, tree.depth + 1, tree.path || t.id
+			FROM task t
+			JOIN tree ON t.parent_task_id = tree.id
+			WHERE t.project_id = tree.project_id AND t.deleted_at IS NULL AND NOT t.id = ANY (tree.path)
+		)
+		SELECT ` + taskColumns + `, tree.depth
+		FROM tree
+		JOIN task t ON t.id = tree.id
+		ORDER BY tree.depth, t.id
+	`
+
+	rows, err := taskDto.DB.Query(query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var nodes []*TaskNode
+	for rows.Next() {
+		node := &TaskNode{Subtasks: make([]*TaskNode, 0)}
+		err := scanTask(rows, &node.Task, &node.Depth)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(nodes) == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	return buildTaskTree(nodes), nil
+}
+
+// buildTaskTree links nodes, ordered by depth, to their parents and rolls
+// progress up from the leaves. The first node is the root.
+func buildTaskTree(nodes []*TaskNode) *TaskNode {
+	root := nodes[0]
+	nodeMap := make(map[int]*TaskNode, len(nodes))
+	for _, node := range nodes {
+		nodeMap[node.ID] = node
+		if node == root {
+			continue
+		}
+		if parent, ok := nodeMap[node.ParentTaskID]; ok {
+			parent.Subtasks = append(parent.Subtasks, node)
+		}
+	}
+
+	rollUpProgress(root)
+	return root
+}
+
+func rollUpProgress(node *TaskNode) float64 {
+	if len(node.Subtasks) == 0 {
+		node.Progress = 0
+		if node.Completed {
+			node.Progress = 1
+		}
+		return node.Progress
+	}
+
+	var total float64
+	for _, child := range node.Subtasks {
+		total += rollUpProgress(child)
+	}
+	node.Progress = total / float64(len(node.Subtasks))
+	return node.Progress
+}
+
+// SetParentTask moves a task under a new parent, or makes it a root task
+// when parentID is 0. It returns ErrTaskCycle if the new parent is the task
+// itself or one of its descendants, sql.ErrNoRows if the task is missing or
+// in the trash, and ErrParentNotFound if the parent is, or is in another
+// project.
+func (taskDto TaskDto) SetParentTask(id, parentID int) error {
+	if id == parentID {
+		return ErrTaskCycle
+	}
+
+	tx, err := taskDto.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = lockTaskHierarchy(tx)
+	if err != nil {
+		return err
+	}
+
+	var previousParentID, projectID int
+	err = tx.QueryRow(`SELECT COALESCE(parent_task_id, 0), project_id FROM task WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`, id).Scan(&previousParentID, &projectID)
+	if err != nil {
+		return err
+	}
+
+	if parentID != 0 {
+		// Locking the parent keeps it from moving to another project first.
+		var one int
+		err = tx.QueryRow(`SELECT 1 FROM task WHERE id = $1 AND project_id = $2 AND deleted_at IS NULL FOR SHARE`, parentID, projectID).Scan(&one)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrParentNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		cycle, err := wouldCycle(tx, id, parentID)
+		if err != nil {
+			return err
+		}
+		if cycle {
+			return ErrTaskCycle
+		}
+	}
+
+	result, err := tx.Exec(`
+		UPDATE task
+		SET parent_task_id = $1, updated_at = $2
+		WHERE id = $3
+	`, nullableID(parentID), time.Now(), id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	changes := fieldChanges{}
+	changes.diff("parent_task_id", previousParentID, parentID)
+
+	if len(changes) > 0 {
+		err = taskDto.recordTaskEvent(tx, id, TaskEventParentChanged, changes)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// lockTaskHierarchy takes taskHierarchyLock until tx ends. Take it before
+// wouldCycle.
+func lockTaskHierarchy(tx *sql.Tx) error {
+	_, err := tx.Exec(`SELECT pg_advisory_xact_lock($1)`, taskHierarchyLock)
+	return err
+}
+
+// wouldCycle reports whether parentID is the task or one of its
+// descendants. It walks up from the new parent; if it meets the task being
+// moved, the new parent sits inside its subtree.
+func wouldCycle(tx *sql.Tx, id, parentID int) (bool, error) {
+	var cycle bool
+	err := tx.QueryRow(`
+		WITH RECURSIVE ancestors AS (
+			SELECT id, parent_task_id
+			FROM task
+			WHERE id = $1
+			UNION
+			SELECT t.id, t.parent_task_id
+			FROM task t
+			JOIN ancestors a ON t.id = a.parent_task_id
+		)
+		SELECT EXISTS (SELECT 1 FROM ancestors WHERE id = $2)
+	`, parentID, id).Scan(&cycle)
+	return cycle, err
+}
+
+// DeleteTaskReparent moves a task to the trash and moves its direct subtasks
+// up to the deleted task's parent instead of trashing them too. Each move is
+// recorded in the subtask's history. Restoring the task later does not move
+// the subtasks back under it.
+func (taskDto TaskDto) DeleteTaskReparent(id int) error {
+	tx, err := taskDto.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = lockTaskHierarchy(tx)
+	if err != nil {
+		return err
+	}
+
+	var parentID int
+	err = tx.QueryRow(`SELECT COALESCE(parent_task_id, 0) FROM task WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`, id).Scan(&parentID)
+	if err != nil {
+		return err
+	}
+
+	// The subtasks change too, so lists that hold them are not served stale.
+	deletedAt := time.Now()
+	rows, err := tx.Query(`
+		UPDATE task
+		SET parent_task_id = $2, updated_at = $3
+		WHERE parent_task_id = $1
+		RETURNING id
+	`, id, nullableID(parentID), deletedAt)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var subtaskIDs []int
+	for rows.Next() {
+		var subtaskID int
+		err := rows.Scan(&subtaskID)
+		if err != nil {
+			return err
+		}
+		subtaskIDs = append(subtaskIDs, subtaskID)
+	}
+
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	for _, subtaskID := range subtaskIDs {
+		err = taskDto.recordTaskEvent(tx, subtaskID, TaskEventParentChanged, fieldChanges{"parent_task_id": {Before: id, After: parentID}})
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec(`UPDATE task SET deleted_at = $2 WHERE id = $1`, id, deletedAt)
+	if err != nil {
+		return err
+	}
+
+	err = taskDto.recordTaskEvent(tx, id, TaskEventDeleted, fieldChanges{"deleted_at": {After: deletedAt}})
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,239 @@
+package model
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetSubtasks_SuccessfulGet(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	taskDto := TaskDto{DB: db}
+
+	columns := []string{"id", "title", "description", "completed", "created_at", "updated_at", "assigned_user_id", "parent_task_id", "project_id", "items"}
+	mockRows := sqlmock.NewRows(columns).
+		AddRow(4, "Develop a 5G deployment strategy", "", false, time.Now(), time.Now(), 7, 2, 1, "{Draft,Review}").
+		AddRow(5, "Upgrade base stations", "", true, time.Now(), time.Now(), 8, 2, 1, "{}")
+
+	mock.ExpectQuery("^SELECT t.id, t.title.*FROM task t.*JOIN task p ON p.id = t.parent_task_id WHERE t.parent_task_id = \\$1 AND t.project_id = p.project_id AND t.deleted_at IS NULL ORDER BY t.id$").
+		WithArgs(2).
+		WillReturnRows(mockRows)
+
+	tasks, err := taskDto.GetSubtasks(2)
+	assert.NoError(t, err)
+	assert.Len(t, tasks, 2)
+	assert.Equal(t, 4, tasks[0].ID)
+	assert.Equal(t, []string{"Draft", "Review"}, tasks[0].Items)
+	assert.Equal(t, 5, tasks[1].ID)
+	assert.Empty(t, tasks[1].Items)
+
+	err = mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+func TestGetTaskTree_RollsUpProgress(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	taskDto := TaskDto{DB: db}
+
+	// 1 -> (2 -> (4 done, 5 open), 3 done)
+	columns := []string{"id", "title", "description", "completed", "created_at", "updated_at", "assigned_user_id", "parent_task_id", "project_id", "items", "depth"}
+	mockRows := sqlmock.NewRows(columns).
+		AddRow(1, "Root", "", false, time.Now(), time.Now(), 0, 0, 1, "{}", 0).
+		AddRow(2, "Child A", "", false, time.Now(), time.Now(), 0, 1, 1, "{}", 1).
+		AddRow(3, "Child B", "", true, time.Now(), time.Now(), 0, 1, 1, "{Item}", 1).
+		AddRow(4, "Grandchild A1", "", true, time.Now(), time.Now(), 0, 2, 1, "{}", 2).
+		AddRow(5, "Grandchild A2", "", false, time.Now(), time.Now(), 0, 2, 1, "{}", 2)
+
+	mock.ExpectQuery("^WITH RECURSIVE tree AS").
+		WithArgs(1).
+		WillReturnRows(mockRows)
+
+	tree, err := taskDto.GetTaskTree(1)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, tree.ID)
+	assert.Len(t, tree.Subtasks, 2)
+	assert.Len(t, tree.Subtasks[0].Subtasks, 2)
+	assert.Equal(t, []string{"Item"}, tree.Subtasks[1].Items)
+	assert.Equal(t, 0.5, tree.Subtasks[0].Progress)
+	assert.Equal(t, 1.0, tree.Subtasks[1].Progress)
+	assert.Equal(t, 0.75, tree.Progress)
+
+	err = mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+func TestGetTaskTree_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	taskDto := TaskDto{DB: db}
+
+	columns := []string{"id", "title", "description", "completed", "created_at", "updated_at", "assigned_user_id", "parent_task_id", "project_id", "items", "depth"}
+	mock.ExpectQuery("^WITH RECURSIVE tree AS").
+		WithArgs(99).
+		WillReturnRows(sqlmock.NewRows(columns))
+
+	_, err = taskDto.GetTaskTree(99)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestSetParentTask_RejectsCycle(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	taskDto := TaskDto{DB: db}
+
+	// Moving task 1 under task 4, where 4 is already a descendant of 1.
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT pg_advisory_xact_lock\\(\\$1\\)").
+		WithArgs(taskHierarchyLock).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT COALESCE\\(parent_task_id, 0\\), project_id FROM task WHERE id = \\$1 AND deleted_at IS NULL FOR UPDATE").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"parent_task_id", "project_id"}).AddRow(0, 3))
+	mock.ExpectQuery("SELECT 1 FROM task WHERE id = \\$1 AND project_id = \\$2 AND deleted_at IS NULL FOR SHARE").
+		WithArgs(4, 3).
+		WillReturnRows(sqlmock.NewRows([]string{"?column?"}).AddRow(1))
+	mock.ExpectQuery("WITH RECURSIVE ancestors AS").
+		WithArgs(4, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectRollback()
+
+	err = taskDto.SetParentTask(1, 4)
+	assert.ErrorIs(t, err, ErrTaskCycle)
+
+	err = mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+func TestSetParentTask_RejectsSelf(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	taskDto := TaskDto{DB: db}
+
+	err = taskDto.SetParentTask(3, 3)
+	assert.ErrorIs(t, err, ErrTaskCycle)
+
+	err = mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+func TestSetParentTask_RejectsTrashedTasks(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	taskDto := TaskDto{DB: db}
+
+	// A trashed task cannot be moved...
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT pg_advisory_xact_lock").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT COALESCE\\(parent_task_id, 0\\), project_id FROM task WHERE id = \\$1 AND deleted_at IS NULL").
+		WithArgs(4).
+		WillReturnRows(sqlmock.NewRows([]string{"parent_task_id", "project_id"}))
+	mock.ExpectRollback()
+
+	err = taskDto.SetParentTask(4, 2)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	// ...nor can a task be moved under one, or under a task in another project.
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT pg_advisory_xact_lock").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT COALESCE\\(parent_task_id, 0\\), project_id FROM task WHERE id = \\$1 AND deleted_at IS NULL").
+		WithArgs(4).
+		WillReturnRows(sqlmock.NewRows([]string{"parent_task_id", "project_id"}).AddRow(0, 3))
+	mock.ExpectQuery("SELECT 1 FROM task WHERE id = \\$1 AND project_id = \\$2 AND deleted_at IS NULL FOR SHARE").
+		WithArgs(2, 3).
+		WillReturnRows(sqlmock.NewRows([]string{"?column?"}))
+	mock.ExpectRollback()
+
+	err = taskDto.SetParentTask(4, 2)
+	assert.ErrorIs(t, err, ErrParentNotFound)
+
+	err = mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+func TestSetParentTask_SuccessfulMove(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	taskDto := TaskDto{DB: db}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT pg_advisory_xact_lock\\(\\$1\\)").
+		WithArgs(taskHierarchyLock).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT COALESCE\\(parent_task_id, 0\\), project_id FROM task WHERE id = \\$1 AND deleted_at IS NULL FOR UPDATE").
+		WithArgs(4).
+		WillReturnRows(sqlmock.NewRows([]string{"parent_task_id", "project_id"}).AddRow(1, 3))
+	mock.ExpectQuery("SELECT 1 FROM task WHERE id = \\$1 AND project_id = \\$2 AND deleted_at IS NULL FOR SHARE").
+		WithArgs(2, 3).
+		WillReturnRows(sqlmock.NewRows([]string{"?column?"}).AddRow(1))
+	mock.ExpectQuery("WITH RECURSIVE ancestors AS").
+		WithArgs(2, 4).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectExec("UPDATE task SET parent_task_id = \\$1, updated_at = \\$2 WHERE id = \\$3").
+		WithArgs(2, sqlmock.AnyArg(), 4).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO task_event").
+		WithArgs(4, 0, TaskEventParentChanged, []byte(`{"parent_task_id":{"before":1,"after":2}}`), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	err = taskDto.SetParentTask(4, 2)
+	assert.NoError(t, err)
+
+	err = mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+func TestDeleteTaskReparent_SuccessfulDelete(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	taskDto := TaskDto{DB: db}
+
+	// Task 2, under task 1, is deleted and its subtasks 5 and 6 move up to 1.
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT pg_advisory_xact_lock").WithArgs(taskHierarchyLock).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT COALESCE\\(parent_task_id, 0\\) FROM task WHERE id = \\$1 AND deleted_at IS NULL FOR UPDATE").
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"parent_task_id"}).AddRow(1))
+	mock.ExpectQuery("UPDATE task SET parent_task_id = \\$2, updated_at = \\$3 WHERE parent_task_id = \\$1 RETURNING id").
+		WithArgs(2, 1, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5).AddRow(6))
+	for _, id := range []int{5, 6} {
+		mock.ExpectExec("INSERT INTO task_event").
+			WithArgs(id, 0, TaskEventParentChanged, []byte(`{"parent_task_id":{"before":2,"after":1}}`), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+	}
+	mock.ExpectExec("UPDATE task SET deleted_at = \\$2 WHERE id = \\$1").
+		WithArgs(2, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO task_event").
+		WithArgs(2, 0, TaskEventDeleted, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	err = taskDto.DeleteTaskReparent(2)
+	assert.NoError(t, err)
+
+	err = mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
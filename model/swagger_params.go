@@ -1,7 +1,7 @@
 package model
 
 // GetTask input params.
-// swagger:parameters getTaskEndpoint deleteTaskEndpoint
+// swagger:parameters getTaskEndpoint deleteTaskEndpoint getSubtasksEndpoint getTaskTreeEndpoint
 type GetTaskParams struct {
 	// The ID of the task to retrieve.
 	// in: path
@@ -52,3 +52,362 @@ type GetTaskCommentsParams struct {
 	// required: true
 	TaskID int `json:"taskID"`
 }
+
+// DeleteTaskParams defines the optional query parameters for deleting a task.
+// swagger:parameters deleteTaskEndpoint
+type DeleteTaskParams struct {
+	// What happens to the task's subtasks: cascade (the default) deletes them, reparent moves them up to the task's parent.
+	// in: query
+	Subtasks string `json:"subtasks"`
+}
+
+// swagger:parameters setParentTaskEndpoint
+type SetParentTaskParams struct {
+	// The ID of the task to move.
+	// in: path
+	// required: true
+	TaskID int `json:"taskID"`
+
+	// The ID of the new parent task, or 0 to make the task a root task.
+	// in: path
+	// required: true
+	ParentID int `json:"parentID"`
+}
+
+// swagger:parameters createTaskDependencyEndpoint
+type CreateTaskDependencyParams struct {
+	// The ID of the task the link starts from.
+	// in: path
+	// required: true
+	ID int `json:"id"`
+	// The other task and the type of link: blocks, blocked_by or relates_to.
+	// in: body
+	// required: true
+	Body TaskDependency
+}
+
+// swagger:parameters getTaskDependenciesEndpoint
+type GetTaskDependenciesParams struct {
+	// The ID of the task whose links are to be retrieved.
+	// in: path
+	// required: true
+	ID int `json:"id"`
+}
+
+// swagger:parameters deleteTaskDependencyEndpoint
+type DeleteTaskDependencyParams struct {
+	// The ID of the task the link belongs to.
+	// in: path
+	// required: true
+	ID int `json:"id"`
+	// The ID of the link to remove.
+	// in: path
+	// required: true
+	DependencyID int `json:"dependencyID"`
+}
+
+// swagger:parameters createProjectEndpoint
+type CreateProjectParams struct {
+	// The project to create. The caller becomes its owner.
+	// in: body
+	// required: true
+	Body Project
+}
+
+// swagger:parameters getProjectEndpoint getProjectMembersEndpoint getProjectTasksEndpoint
+type GetProjectParams struct {
+	// The ID of the project.
+	// in: path
+	// required: true
+	ID int `json:"id"`
+}
+
+// swagger:parameters addProjectMemberEndpoint
+type AddProjectMemberParams struct {
+	// The ID of the project.
+	// in: path
+	// required: true
+	ID int `json:"id"`
+	// The user to add, given as user_id.
+	// in: body
+	// required: true
+	Body ProjectMember
+}
+
+// swagger:parameters removeProjectMemberEndpoint
+type RemoveProjectMemberParams struct {
+	// The ID of the project.
+	// in: path
+	// required: true
+	ID int `json:"id"`
+	// The ID of the user to remove.
+	// in: path
+	// required: true
+	UserID int `json:"userID"`
+}
+
+// swagger:parameters createProjectTaskEndpoint
+type CreateProjectTaskParams struct {
+	// The ID of the project the task is created in.
+	// in: path
+	// required: true
+	ID int `json:"id"`
+	// The task to create.
+	// in: body
+	// required: true
+	Body Task
+}
+
+// swagger:parameters moveTaskToProjectEndpoint
+type MoveTaskToProjectParams struct {
+	// The ID of the task to move.
+	// in: path
+	// required: true
+	TaskID int `json:"taskID"`
+	// The ID of the project to move the task to.
+	// in: path
+	// required: true
+	ProjectID int `json:"projectID"`
+}
+
+// swagger:parameters createBoardEndpoint
+type CreateBoardParams struct {
+	// The ID of the project the board belongs to.
+	// in: path
+	// required: true
+	ID int `json:"id"`
+	// The board name and its columns, in display order.
+	// in: body
+	// required: true
+	Body Board
+}
+
+// swagger:parameters getProjectBoardsEndpoint getBoardEndpoint
+type GetBoardParams struct {
+	// The ID of the project or board.
+	// in: path
+	// required: true
+	ID int `json:"id"`
+}
+
+// swagger:parameters moveCardEndpoint
+type MoveCardParams struct {
+	// The ID of the board.
+	// in: path
+	// required: true
+	ID int `json:"id"`
+	// The ID of the task whose card is moved.
+	// in: path
+	// required: true
+	TaskID int `json:"taskID"`
+	// The target column and the card to place this one after or before.
+	// in: body
+	// required: true
+	Body CardMove
+}
+
+// swagger:parameters removeCardEndpoint
+type RemoveCardParams struct {
+	// The ID of the board.
+	// in: path
+	// required: true
+	ID int `json:"id"`
+	// The ID of the task whose card is removed.
+	// in: path
+	// required: true
+	TaskID int `json:"taskID"`
+}
+
+// swagger:parameters setTaskRecurrenceEndpoint
+type SetTaskRecurrenceParams struct {
+	// The ID of the template task.
+	// in: path
+	// required: true
+	ID int `json:"id"`
+	// The rrule, such as FREQ=MONTHLY;BYDAY=1MO, and an optional dtstart.
+	// in: body
+	// required: true
+	Body TaskRecurrence
+}
+
+// swagger:parameters getTaskRecurrenceEndpoint deleteTaskRecurrenceEndpoint
+type GetTaskRecurrenceParams struct {
+	// The ID of the template task.
+	// in: path
+	// required: true
+	ID int `json:"id"`
+}
+
+// swagger:parameters getTaskHistoryEndpoint
+type GetTaskHistoryParams struct {
+	// The ID of the task.
+	// in: path
+	// required: true
+	ID int `json:"id"`
+}
+
+// swagger:parameters restoreTaskEndpoint
+type RestoreTaskParams struct {
+	// The ID of the task in the trash.
+	// in: path
+	// required: true
+	ID int `json:"id"`
+}
+
+// swagger:parameters uploadTaskAttachmentEndpoint
+type UploadTaskAttachmentParams struct {
+	// The ID of the task.
+	// in: path
+	// required: true
+	ID int `json:"id"`
+	// The file to attach.
+	// in: formData
+	// required: true
+	// swagger:file
+	File interface{} `json:"file"`
+}
+
+// swagger:parameters getTaskAttachmentsEndpoint
+type GetTaskAttachmentsParams struct {
+	// The ID of the task.
+	// in: path
+	// required: true
+	ID int `json:"id"`
+}
+
+// swagger:parameters downloadTaskAttachmentEndpoint deleteTaskAttachmentEndpoint
+type TaskAttachmentParams struct {
+	// The ID of the task.
+	// in: path
+	// required: true
+	ID int `json:"id"`
+	// The ID of the attachment.
+	// in: path
+	// required: true
+	AttachmentID int `json:"attachmentID"`
+	// A byte range of the content, such as bytes=0-1023.
+	// in: header
+	Range string `json:"Range"`
+}
+
+// swagger:parameters createWebhookEndpoint
+type CreateWebhookParams struct {
+	// The URL to send events to and the events to subscribe to, such as task.created.
+	// A secret is generated if none is given.
+	// in: body
+	// required: true
+	Body Webhook
+}
+
+// swagger:parameters getWebhookEndpoint deleteWebhookEndpoint
+type WebhookParams struct {
+	// The ID of the webhook.
+	// in: path
+	// required: true
+	ID int `json:"id"`
+}
+
+// swagger:parameters getWebhookDeliveriesEndpoint
+type GetWebhookDeliveriesParams struct {
+	// The ID of the webhook.
+	// in: path
+	// required: true
+	ID int `json:"id"`
+	// How many deliveries to return, from 1 to 1000. Defaults to 100.
+	// in: query
+	Limit int `json:"limit"`
+}
+
+// swagger:parameters redeliverWebhookEndpoint
+type RedeliverWebhookParams struct {
+	// The ID of the webhook.
+	// in: path
+	// required: true
+	ID int `json:"id"`
+	// The ID of the delivery to send again.
+	// in: path
+	// required: true
+	DeliveryID int `json:"deliveryID"`
+}
+
+// swagger:parameters streamEventsEndpoint
+type StreamEventsParams struct {
+	// Only send events about this task.
+	// in: query
+	TaskID int `json:"task_id"`
+	// Only send events about tasks assigned to this user.
+	// in: query
+	AssigneeID int `json:"assignee_id"`
+	// The ID of the last event received, to resume a stream.
+	// in: header
+	LastEventID string `json:"Last-Event-ID"`
+}
+
+// swagger:parameters setUserEmailEndpoint
+type SetUserEmailParams struct {
+	// The ID of the user, who must be the caller.
+	// in: path
+	// required: true
+	UserID int `json:"userID"`
+	// The address to send notifications to.
+	// in: body
+	// required: true
+	Body struct {
+		Email string `json:"email"`
+	}
+}
+
+// swagger:parameters deleteUserEmailEndpoint
+type DeleteUserEmailParams struct {
+	// The ID of the user, who must be the caller.
+	// in: path
+	// required: true
+	UserID int `json:"userID"`
+}
+
+// swagger:parameters bulkTasksEndpoint
+type BulkTasksParams struct {
+	// The mode, atomic or best_effort, and the operations to run in order.
+	// in: body
+	// required: true
+	Body BulkRequest
+}
+
+// swagger:parameters exportTasksEndpoint
+type ExportTasksParams struct {
+	// The format: json, ndjson or csv. Defaults to json.
+	// in: query
+	Format string `json:"format"`
+}
+
+// swagger:parameters importTasksEndpoint
+type ImportTasksParams struct {
+	// The format: json, ndjson or csv. Defaults to the request's content type.
+	// in: query
+	Format string `json:"format"`
+	// Check the import and report the result without saving anything.
+	// in: query
+	DryRun bool `json:"dry_run"`
+	// The tasks, as written by the export.
+	// in: body
+	// required: true
+	Body []Task
+}
+
+// swagger:parameters rpcEndpoint
+type RPCParams struct {
+	// A JSON-RPC 2.0 request, or a batch array of them.
+	// in: body
+	// required: true
+	Body RPCRequest
+}
+
+// swagger:parameters getAllTasksEndpoint getUserAssignedTasksEndpoint getTaskCommentsEndpoint getTaskHistoryEndpoint getProjectTasksEndpoint
+type PageParams struct {
+	// Return at most this many items, from 1 to 1000, and a Link header to the next page.
+	// in: query
+	Limit int `json:"limit"`
+	// Where the page starts, taken from the Link of the page before.
+	// in: query
+	Cursor string `json:"cursor"`
+}
@@ -78,3 +78,288 @@ type AllCommentsResponse struct {
 	// in: body
 	Body []TaskComment `json:"body"`
 }
+
+// Response for a task together with its subtasks.
+// swagger:response taskTreeResponse
+type TaskTreeResponse struct {
+	// in: body
+	Body TaskNode `json:"body"`
+}
+
+// The requested parent would make the task hierarchy cyclic.
+// swagger:response taskCycleError
+type TaskCycleError struct {
+	Error string `json:"error"`
+}
+
+// A subtask cannot be moved to another project without its parent.
+// swagger:response subtaskMoveError
+type SubtaskMoveError struct {
+	Error string `json:"error"`
+}
+
+// Response for a successfully created task dependency.
+// swagger:response taskDependencyCreatedResponse
+type TaskDependencyCreatedResponse struct {
+	// in: body
+	Body TaskDependency `json:"body"`
+}
+
+// Response for getting the links of a task.
+// swagger:response allTaskDependenciesResponse
+type AllTaskDependenciesResponse struct {
+	// in: body
+	Body []TaskDependency `json:"body"`
+}
+
+// The dependency already exists or would create a cycle.
+// swagger:response dependencyConflictError
+type DependencyConflictError struct {
+	Error string `json:"error"`
+}
+
+// The task cannot be completed while tasks blocking it are still open.
+// swagger:response unfinishedBlockersError
+type UnfinishedBlockersError struct {
+	Error string `json:"error"`
+}
+
+// Response for a successfully created project.
+// swagger:response projectCreatedResponse
+type ProjectCreatedResponse struct {
+	// in: body
+	Body Project `json:"body"`
+}
+
+// Response for successfully retrieved project by ID.
+// swagger:response projectResponse
+type ProjectResponse struct {
+	// in: body
+	Body Project `json:"body"`
+}
+
+// Response for getting the caller's projects.
+// swagger:response allProjectsResponse
+type AllProjectsResponse struct {
+	// in: body
+	Body []Project `json:"body"`
+}
+
+// Response for a successfully added project member.
+// swagger:response projectMemberCreatedResponse
+type ProjectMemberCreatedResponse struct {
+	// in: body
+	Body ProjectMember `json:"body"`
+}
+
+// Response for getting the members of a project.
+// swagger:response allProjectMembersResponse
+type AllProjectMembersResponse struct {
+	// in: body
+	Body []ProjectMember `json:"body"`
+}
+
+// The request did not identify the caller with the X-User-ID header.
+// swagger:response unauthorizedError
+type UnauthorizedError struct {
+	Error string `json:"error"`
+}
+
+// The caller is not allowed to perform the operation.
+// swagger:response forbiddenError
+type ForbiddenError struct {
+	Error string `json:"error"`
+}
+
+// Response for a board with its columns and cards.
+// swagger:response boardResponse
+type BoardResponse struct {
+	// in: body
+	Body Board `json:"body"`
+}
+
+// Response for getting the boards of a project.
+// swagger:response allBoardsResponse
+type AllBoardsResponse struct {
+	// in: body
+	Body []Board `json:"body"`
+}
+
+// Response for a card that was moved.
+// swagger:response boardCardResponse
+type BoardCardResponse struct {
+	// in: body
+	Body BoardCard `json:"body"`
+}
+
+// Response for the recurrence schedule of a template task.
+// swagger:response taskRecurrenceResponse
+type TaskRecurrenceResponse struct {
+	// in: body
+	Body TaskRecurrence `json:"body"`
+}
+
+// Response for the history of a task, newest first.
+// swagger:response taskHistoryResponse
+type TaskHistoryResponse struct {
+	// in: body
+	Body []TaskEvent `json:"body"`
+}
+
+// Response for the tasks in the trash.
+// swagger:response trashResponse
+type TrashResponse struct {
+	// in: body
+	Body []Task `json:"body"`
+}
+
+// Response for a successfully attached file.
+// swagger:response taskAttachmentResponse
+type TaskAttachmentResponse struct {
+	// in: body
+	Body TaskAttachment `json:"body"`
+}
+
+// Response for the attachments of a task.
+// swagger:response allTaskAttachmentsResponse
+type AllTaskAttachmentsResponse struct {
+	// in: body
+	Body []TaskAttachment `json:"body"`
+}
+
+// The content of an attachment, or the requested range of it.
+// swagger:response attachmentContentResponse
+type AttachmentContentResponse struct {
+	// in: body
+	// swagger:file
+	Body interface{} `json:"body"`
+}
+
+// The attachment is larger than the server accepts.
+// swagger:response payloadTooLargeError
+type PayloadTooLargeError struct {
+	Error string `json:"error"`
+}
+
+// The attachment's content was removed while it was uploaded; try again.
+// swagger:response uploadInterruptedError
+type UploadInterruptedError struct {
+	Error string `json:"error"`
+}
+
+// The attachment's content type is not allowed.
+// swagger:response unsupportedMediaTypeError
+type UnsupportedMediaTypeError struct {
+	Error string `json:"error"`
+}
+
+// The requested range lies outside the attachment.
+// swagger:response rangeNotSatisfiableError
+type RangeNotSatisfiableError struct {
+	Error string `json:"error"`
+}
+
+// Response for a created webhook, including its secret.
+// swagger:response webhookCreatedResponse
+type WebhookCreatedResponse struct {
+	// in: body
+	Body Webhook `json:"body"`
+}
+
+// Response for a webhook.
+// swagger:response webhookResponse
+type WebhookResponse struct {
+	// in: body
+	Body Webhook `json:"body"`
+}
+
+// Response for all webhooks.
+// swagger:response allWebhooksResponse
+type AllWebhooksResponse struct {
+	// in: body
+	Body []Webhook `json:"body"`
+}
+
+// Response for a queued webhook delivery.
+// swagger:response webhookDeliveryResponse
+type WebhookDeliveryResponse struct {
+	// in: body
+	Body WebhookDelivery `json:"body"`
+}
+
+// Response for the delivery log of a webhook.
+// swagger:response allWebhookDeliveriesResponse
+type AllWebhookDeliveriesResponse struct {
+	// in: body
+	Body []WebhookDelivery `json:"body"`
+}
+
+// A stream of Server-Sent Events. Each event has an id, an event type such as task.updated,
+// and a data line holding the task or comment as JSON.
+// swagger:response eventStreamResponse
+type EventStreamResponse struct {
+	// in: body
+	Body string `json:"body"`
+}
+
+// Response for a user's notification address.
+// swagger:response userEmailResponse
+type UserEmailResponse struct {
+	// in: body
+	Body UserEmail `json:"body"`
+}
+
+// Response for a bulk request, with the outcome of every operation.
+// swagger:response bulkTasksResponse
+type BulkTasksResponse struct {
+	// in: body
+	Body BulkResponse `json:"body"`
+}
+
+// All tasks not in the trash, as a JSON array, newline-delimited JSON or CSV.
+// swagger:response exportTasksResponse
+type ExportTasksResponse struct {
+	// in: body
+	Body []Task `json:"body"`
+}
+
+// Response for an import, with the error of every rejected row.
+// swagger:response importReportResponse
+type ImportReportResponse struct {
+	// in: body
+	Body ImportReport `json:"body"`
+}
+
+// The JSON-RPC response, or for a batch an array with one response per call that had an id.
+// swagger:response rpcResponse
+type RPCResponseBody struct {
+	// in: body
+	Body RPCResponse `json:"body"`
+}
+
+// The request held only notifications, so there is nothing to respond with.
+// swagger:response rpcNotificationsResponse
+type RPCNotificationsResponse struct{}
+
+// The OpenAPI 3 document describing this API.
+// swagger:response openAPIResponse
+type OpenAPIResponse struct {
+	// in: body
+	Body map[string]any `json:"body"`
+}
+
+// An HTML page rendering the OpenAPI document.
+// swagger:response docsResponse
+type DocsResponse struct {
+	// in: body
+	Body string `json:"body"`
+}
+
+// The client's copy, named by If-None-Match or If-Modified-Since, is current.
+// swagger:response notModifiedResponse
+type NotModifiedResponse struct {
+	// in: header
+	ETag string `json:"ETag"`
+	// in: header
+	LastModified string `json:"Last-Modified"`
+}
@@ -3,22 +3,30 @@ package model
 import (
 	"database/sql"
 	"time"
+
+	"github.com/lib/pq"
 )
 
 type Task struct {
-	ID             int       `json:"id"`
-	Title          string    `json:"title"`
-	Description    string    `json:"description"`
-	Completed      bool      `json:"completed"`
-	CreatedAt      time.Time `json:"created_at"`
-	UpdatedAt      time.Time `json:"updated_at"`
-	AssignedUserID int       `json:"assigned_user_id,omitempty"`
-	Items          []string  `json:"items"`
-	Comments       []string  `json:"comments,omitempty"`
+	ID             int        `json:"id"`
+	Title          string     `json:"title"`
+	Description    string     `json:"description"`
+	Completed      bool       `json:"completed"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	AssignedUserID int        `json:"assigned_user_id,omitempty"`
+	ParentTaskID   int        `json:"parent_task_id,omitempty"`
+	ProjectID      int        `json:"project_id"`
+	Items          []string   `json:"items"`
+	Comments       []string   `json:"comments,omitempty"`
+	DeletedAt      *time.Time `json:"deleted_at,omitempty"`
 }
 
+// TaskDto reads and writes tasks. ActorID is the user making the changes;
+// it is recorded against every mutation in the task's history.
 type TaskDto struct {
-	DB *sql.DB
+	DB      *sql.DB
+	ActorID int
 }
 
 type TaskComment struct {
@@ -28,70 +36,148 @@ type TaskComment struct {
 	CreatedAt time.Time `json:"created_at"`
 }
 
-func (taskDto TaskDto) GetAllTasks() ([]Task, error) {
+// taskColumns are the columns scanTask reads, selected from task t. Items
+// are aggregated into an array in SQL, so every task is a single row
+// however many items it has.
+const taskColumns = `t.id, t.title, t.description, t.completed, t.created_at, t.updated_at, t.assigned_user_id, COALESCE(t.parent_task_id, 0), t.project_id,
+			ARRAY(SELECT ti.item FROM task_item ti WHERE ti.task_id = t.id ORDER BY ti.id)`
 
-	query := `
-		SELECT t.id, t.title, t.description, t.completed, t.created_at, t.updated_at, t.assigned_user_id, ti.item
-		FROM task t
-		LEFT JOIN task_item ti ON t.id = ti.task_id
-		ORDER BY t.id, ti.id
-	`
+// taskCommentsColumn aggregates a task's comments the same way, for the
+// queries that want them after taskColumns.
+const taskCommentsColumn = `ARRAY(SELECT tc.comment FROM task_comment tc WHERE tc.task_id = t.id ORDER BY tc.created_at, tc.id)`
+
+// rowScanner is a *sql.Row or *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
 
-	rows, err := taskDto.DB.Query(query)
+// scanTask reads the taskColumns of a row into task, and any columns the
+// query selected after them into extra.
+func scanTask(row rowScanner, task *Task, extra ...any) error {
+	dest := append([]any{
+		&task.ID,
+		&task.Title,
+		&task.Description,
+		&task.Completed,
+		&task.CreatedAt,
+		&task.UpdatedAt,
+		&task.AssignedUserID,
+		&task.ParentTaskID,
+		&task.ProjectID,
+		pq.Array(&task.Items),
+	}, extra...)
+	return row.Scan(dest...)
+}
+
+// queryTasks runs a query selecting taskColumns, and returns the tasks in
+// the order it gives them.
+func (taskDto TaskDto) queryTasks(query string, args ...any) ([]Task, error) {
+	rows, err := taskDto.DB.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	taskMap := make(map[int]*Task)
-
-	taskItemsMap := make(map[int][]string)
-
+	tasks := make([]Task, 0)
 	for rows.Next() {
-		var taskItem sql.NullString
-		task := &Task{}
-		err := rows.Scan(
-			&task.ID,
-			&task.Title,
-			&task.Description,
-			&task.Completed,
-			&task.CreatedAt,
-			&task.UpdatedAt,
-			&task.AssignedUserID,
-			&taskItem,
-		)
+		var task Task
+		err := scanTask(rows, &task)
 		if err != nil {
 			return nil, err
 		}
+		tasks = append(tasks, task)
+	}
 
-		if taskItem.Valid {
-			taskItemsMap[task.ID] = append(taskItemsMap[task.ID], taskItem.String)
-		}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
 
-		if _, ok := taskMap[task.ID]; !ok {
-			taskMap[task.ID] = task
-		}
+	return tasks, nil
+}
+
+// eachRow runs a query and calls scan for every row, stopping at the first
+// error it returns.
+func eachRow(db *sql.DB, scan func(*sql.Rows) error, query string, args ...any) error {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return err
 	}
+	defer rows.Close()
 
-	for taskID, taskItems := range taskItemsMap {
-		if task, ok := taskMap[taskID]; ok {
-			task.Items = taskItems
+	for rows.Next() {
+		err = scan(rows)
+		if err != nil {
+			return err
 		}
 	}
 
-	tasks := make([]Task, 0, len(taskMap))
-	for _, task := range taskMap {
-		tasks = append(tasks, *task)
-	}
+	return rows.Err()
+}
 
-	return tasks, nil
+// EachTask reads the tasks a row at a time, so the list is never held in
+// memory. The page's cursor is matched on (updated_at, id), which the
+// task_updated_at_id_idx index serves.
+func (taskDto TaskDto) EachTask(filter TaskFilter, page Page, fn func(Task) error) error {
+	var where conditions
+	where.add("t.deleted_at IS NULL")
+	filter.addTo(&where)
+	page.addTo(&where, "t.updated_at, t.id", ">")
+	limit := page.limit(&where)
+
+	return eachRow(taskDto.DB, func(rows *sql.Rows) error {
+		var task Task
+		err := scanTask(rows, &task)
+		if err != nil {
+			return err
+		}
+		return fn(task)
+	}, `
+		SELECT `+taskColumns+`
+		FROM task t
+		WHERE `+where.String()+`
+		ORDER BY t.updated_at, t.id
+		`+limit, where.args...)
 }
 
+func (taskDto TaskDto) GetAllTasks() ([]Task, error) {
+	return taskDto.queryTasks(`
+		SELECT ` + taskColumns + `
+		FROM task t
+		WHERE t.deleted_at IS NULL
+		ORDER BY t.id
+	`)
+}
+
+// Insert adds the task, without its items, and sets its ID. It returns
+// ErrProjectNotFound or ErrParentNotFound if its project does not exist, or
+// its parent is not a live task in the same project.
 func (taskDto TaskDto) Insert(task *Task) error {
 
-	stmt, err := taskDto.DB.Prepare(`
-			INSERT INTO task (title, description, completed, created_at, updated_at, assigned_user_id)
-			VALUES ($1, $2, $3, $4, $5, $6)
+	tx, err := taskDto.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = checkProjectAndParent(tx, *task, 0)
+	if err != nil {
+		return err
+	}
+
+	err = taskDto.insertTask(tx, task)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+
+}
+
+// insertTask inserts the task within tx.
+func (taskDto TaskDto) insertTask(tx *sql.Tx, task *Task) error {
+	stmt, err := tx.Prepare(`
+			INSERT INTO task (title, description, completed, created_at, updated_at, assigned_user_id, parent_task_id, project_id)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 			RETURNING id
 `)
 	if err != nil {
@@ -100,15 +186,24 @@ func (taskDto TaskDto) Insert(task *Task) error {
 	defer stmt.Close()
 
 	var taskID int
-	err = stmt.QueryRow(task.Title, task.Description, task.Completed, task.CreatedAt, task.UpdatedAt, task.AssignedUserID).Scan(&taskID)
+	err = stmt.QueryRow(task.Title, task.Description, task.Completed, task.CreatedAt, task.UpdatedAt, task.AssignedUserID, nullableID(task.ParentTaskID), task.ProjectID).Scan(&taskID)
 	if err != nil {
 		return err
 	}
 
 	task.ID = taskID
 
-	return nil
+	changes := fieldChanges{}
+	changes.diff("title", nil, task.Title)
+	changes.diff("description", nil, task.Description)
+	changes.diff("completed", nil, task.Completed)
+	changes.diff("assigned_user_id", nil, task.AssignedUserID)
+	if task.ParentTaskID != 0 {
+		changes.diff("parent_task_id", nil, task.ParentTaskID)
+	}
+	changes.diff("project_id", nil, task.ProjectID)
 
+	return taskDto.recordTaskEvent(tx, task.ID, TaskEventCreated, changes)
 }
 
 func (taskDto TaskDto) Get(id int64) (*Task, error) {
@@ -117,81 +212,229 @@ func (taskDto TaskDto) Get(id int64) (*Task, error) {
 
 func (taskDto TaskDto) UpdateTask(id int, task *Task) error {
 
-	stmt, err := taskDto.DB.Prepare(`
+	tx, err := taskDto.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = taskDto.updateTask(tx, id, task)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// updateTask updates the task within tx and reports whether any field
+// changed. Completing the task fails with ErrTaskBlocked if it has
+// unfinished blockers.
+func (taskDto TaskDto) updateTask(tx *sql.Tx, id int, task *Task) (bool, error) {
+	before := &Task{Items: make([]string, 0)}
+	err := tx.QueryRow(`
+		SELECT title, description, completed, ARRAY(SELECT item FROM task_item WHERE task_id = $1 ORDER BY id)
+		FROM task
+		WHERE id = $1 AND deleted_at IS NULL
+		FOR UPDATE
+	`, id).Scan(&before.Title, &before.Description, &before.Completed, pq.Array(&before.Items))
+	if err != nil {
+		return false, err
+	}
+
+	if task.Completed && !before.Completed {
+		err = checkBlockers(tx, id)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	stmt, err := tx.Prepare(`
 		UPDATE task
 		SET title = $1, description = $2, completed = $3, updated_at = $4
 		WHERE id = $5
 	`)
 	if err != nil {
-		return err
+		return false, err
 	}
 	defer stmt.Close()
 
 	_, err = stmt.Exec(task.Title, task.Description, task.Completed, time.Now(), id)
 	if err != nil {
-		return err
+		return false, err
 	}
 
-	_, err = taskDto.DB.Exec("DELETE FROM task_item WHERE task_id = $1", task.ID)
+	_, err = tx.Exec("DELETE FROM task_item WHERE task_id = $1", task.ID)
 	if err != nil {
-		return err
+		return false, err
 	}
 
-	stmt, err = taskDto.DB.Prepare("INSERT INTO task_item (task_id, item) VALUES ($1, $2)")
+	stmt, err = tx.Prepare("INSERT INTO task_item (task_id, item) VALUES ($1, $2)")
 	if err != nil {
-		return err
+		return false, err
 	}
 	defer stmt.Close()
 
 	for _, item := range task.Items {
 		_, err := stmt.Exec(task.ID, item)
 		if err != nil {
-			return err
+			return false, err
 		}
 	}
-	return nil
+
+	items := task.Items
+	if items == nil {
+		items = make([]string, 0)
+	}
+
+	changes := fieldChanges{}
+	changes.diff("title", before.Title, task.Title)
+	changes.diff("description", before.Description, task.Description)
+	changes.diff("completed", before.Completed, task.Completed)
+	changes.diff("items", before.Items, items)
+
+	if len(changes) == 0 {
+		return false, nil
+	}
+
+	return true, taskDto.recordTaskEvent(tx, id, TaskEventUpdated, changes)
 }
 
 func (taskDto TaskDto) AssignUserToTask(id, userID int, updatedAt time.Time) error {
-	stmt, err := taskDto.DB.Prepare(`
+	tx, err := taskDto.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = taskDto.assignUserToTask(tx, id, userID, updatedAt)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// assignUserToTask assigns the task within tx and reports whether its
+// assignee changed. A task already assigned to the user is left untouched.
+func (taskDto TaskDto) assignUserToTask(tx *sql.Tx, id, userID int, updatedAt time.Time) (bool, error) {
+	var previousUserID int
+	err := tx.QueryRow(`SELECT assigned_user_id FROM task WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`, id).Scan(&previousUserID)
+	if err != nil {
+		return false, err
+	}
+	if previousUserID == userID {
+		return false, nil
+	}
+
+	stmt, err := tx.Prepare(`
 		UPDATE task
 		SET assigned_user_id = $1, updated_at = $2
 		WHERE id = $3
 	`)
 	if err != nil {
-		return err
+		return false, err
 	}
 	defer stmt.Close()
 
 	_, err = stmt.Exec(userID, updatedAt, id)
 	if err != nil {
-		return err
+		return false, err
 	}
 
-	return nil
+	changes := fieldChanges{}
+	changes.diff("assigned_user_id", previousUserID, userID)
+
+	return true, taskDto.recordTaskEvent(tx, id, TaskEventAssigned, changes)
 }
 
+// DeleteTask moves the task and all of its subtasks to the trash. They stay
+// in the database, hidden from every read, until they are restored or purged.
 func (taskDto TaskDto) DeleteTask(id int) error {
 
-	stmt, err := taskDto.DB.Prepare(`
-		DELETE FROM task WHERE id = $1
+	tx, err := taskDto.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = taskDto.deleteTask(tx, id)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// deleteTask moves the task and its subtasks to the trash within tx.
+func (taskDto TaskDto) deleteTask(tx *sql.Tx, id int) error {
+	stmt, err := tx.Prepare(`
+		WITH RECURSIVE subtree AS (
+			SELECT id FROM task WHERE id = $1 AND deleted_at IS NULL
+			UNION
+			SELECT t.id FROM task t JOIN subtree s ON t.parent_task_id = s.id WHERE t.deleted_at IS NULL
+		)
+		UPDATE task SET deleted_at = $2 WHERE id IN (SELECT id FROM subtree)
+		RETURNING id
 	`)
 	if err != nil {
 		return err
 	}
 	defer stmt.Close()
 
-	_, err = stmt.Exec(id)
+	deletedAt := time.Now()
+	rows, err := stmt.Query(id, deletedAt)
 	if err != nil {
 		return err
 	}
 
+	var ids []int
+	for rows.Next() {
+		var taskID int
+		err := rows.Scan(&taskID)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		ids = append(ids, taskID)
+	}
+	rows.Close()
+
+	if err = rows.Err(); err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return sql.ErrNoRows
+	}
+
+	for _, taskID := range ids {
+		err = taskDto.recordTaskEvent(tx, taskID, TaskEventDeleted, fieldChanges{"deleted_at": {After: deletedAt}})
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
 func (taskDto TaskDto) InsertTaskItem(taskID int, item string) error {
 
-	stmt, err := taskDto.DB.Prepare(`
+	tx, err := taskDto.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = taskDto.insertTaskItem(tx, taskID, item)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// insertTaskItem adds a checklist item within tx.
+func (taskDto TaskDto) insertTaskItem(tx *sql.Tx, taskID int, item string) error {
+	stmt, err := tx.Prepare(`
 		INSERT INTO task_item (task_id, item)
 		VALUES ($1, $2)
 	`)
@@ -205,12 +448,27 @@ func (taskDto TaskDto) InsertTaskItem(taskID int, item string) error {
 		return err
 	}
 
-	return nil
+	return taskDto.recordTaskEvent(tx, taskID, TaskEventItemAdded, fieldChanges{"item": {After: item}})
 }
 
+// InsertTaskComment adds the comment, returning sql.ErrNoRows if the task
+// does not exist or is in the trash.
 func (taskDto TaskDto) InsertTaskComment(taskComment *TaskComment) error {
 
-	stmt, err := taskDto.DB.Prepare(`
+	tx, err := taskDto.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Locked so the task cannot be moved to the trash before the comment is added.
+	var taskID int
+	err = tx.QueryRow(`SELECT id FROM task WHERE id = $1 AND deleted_at IS NULL FOR SHARE`, taskComment.TaskID).Scan(&taskID)
+	if err != nil {
+		return err
+	}
+
+	stmt, err := tx.Prepare(`
 		INSERT INTO task_comment (task_id, comment, created_at)
 		VALUES ($1, $2, $3)
 	`)
@@ -224,123 +482,86 @@ func (taskDto TaskDto) InsertTaskComment(taskComment *TaskComment) error {
 		return err
 	}
 
-	return nil
+	err = taskDto.recordTaskEvent(tx, taskComment.TaskID, TaskEventCommented, fieldChanges{"comment": {After: taskComment.Comment}})
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
 func (taskDto TaskDto) GetTask(id int) (*Task, error) {
-	query := `
-		SELECT t.id, t.title, t.description, t.completed, t.created_at, t.updated_at, t.assigned_user_id, ti.item
+	row := taskDto.DB.QueryRow(`
+		SELECT `+taskColumns+`
 		FROM task t
-		LEFT JOIN task_item ti ON t.id = ti.task_id
-		WHERE t.id = $1
-	`
-
-	rows, err := taskDto.DB.Query(query, id)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
+		WHERE t.id = $1 AND t.deleted_at IS NULL
+	`, id)
 
 	task := &Task{}
-	task.Items = make([]string, 0)
-
-	hasRows := false
-	for rows.Next() {
-		hasRows = true
-		var taskItem sql.NullString
-		err := rows.Scan(
-			&task.ID,
-			&task.Title,
-			&task.Description,
-			&task.Completed,
-			&task.CreatedAt,
-			&task.UpdatedAt,
-			&task.AssignedUserID,
-			&taskItem,
-		)
-		if err != nil {
-			return nil, err
-		}
-
-		if taskItem.Valid {
-			task.Items = append(task.Items, taskItem.String)
-		}
-	}
-
-	if err = rows.Err(); err != nil {
+	err := scanTask(row, task)
+	if err != nil {
 		return nil, err
 	}
 
-	if !hasRows {
-		return nil, sql.ErrNoRows
-	}
-
 	return task, nil
 }
 
 func (taskDto TaskDto) GetAllTaskByAssignedUserID(userID int) ([]Task, error) {
-	query := `
-		SELECT t.id, t.title, t.description, t.completed, t.created_at, t.updated_at, t.assigned_user_id, ti.item
+	return taskDto.queryTasks(`
+		SELECT `+taskColumns+`
 		FROM task t
-		LEFT JOIN task_item ti ON t.id = ti.task_id
-		WHERE t.assigned_user_id = $1
-	`
+		WHERE t.assigned_user_id = $1 AND t.deleted_at IS NULL
+		ORDER BY t.id
+	`, userID)
+}
 
-	rows, err := taskDto.DB.Query(query, userID)
+// EachTaskComment calls fn with the page of the task's comments, oldest
+// first. It returns sql.ErrNoRows, before any call, if the task does not
+// exist or is in the trash.
+func (taskDto TaskDto) EachTaskComment(taskID int, page Page, fn func(TaskComment) error) error {
+	err := taskDto.checkLiveTask(taskID)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	defer rows.Close()
-
-	taskMap := make(map[int]*Task)
 
-	taskItemsMap := make(map[int][]string)
+	var where conditions
+	where.add("tc.task_id = $%d", taskID)
+	page.addTo(&where, "tc.created_at, tc.id", ">")
+	limit := page.limit(&where)
 
-	for rows.Next() {
-		var taskItem sql.NullString
-		task := &Task{}
+	return eachRow(taskDto.DB, func(rows *sql.Rows) error {
+		var taskComment TaskComment
 		err := rows.Scan(
-			&task.ID,
-			&task.Title,
-			&task.Description,
-			&task.Completed,
-			&task.CreatedAt,
-			&task.UpdatedAt,
-			&task.AssignedUserID,
-			&taskItem,
+			&taskComment.ID,
+			&taskComment.TaskID,
+			&taskComment.Comment,
+			&taskComment.CreatedAt,
 		)
 		if err != nil {
-			return nil, err
-		}
-
-		if taskItem.Valid {
-			taskItemsMap[task.ID] = append(taskItemsMap[task.ID], taskItem.String)
-		}
-
-		if _, ok := taskMap[task.ID]; !ok {
-			taskMap[task.ID] = task
-		}
-	}
-
-	for taskID, taskItems := range taskItemsMap {
-		if task, ok := taskMap[taskID]; ok {
-			task.Items = taskItems
+			return err
 		}
-	}
-
-	tasks := make([]Task, 0, len(taskMap))
-	for _, task := range taskMap {
-		tasks = append(tasks, *task)
-	}
-
-	return tasks, nil
+		return fn(taskComment)
+	}, `
+		SELECT tc.id, tc.task_id, tc.comment, tc.created_at
+		FROM task_comment tc
+		WHERE `+where.String()+`
+		ORDER BY tc.created_at, tc.id
+		`+limit, where.args...)
 }
 
+// GetAllTaskCommentsByTaskID returns the task's comments, oldest first, or
+// sql.ErrNoRows if the task does not exist or is in the trash.
 func (taskDto TaskDto) GetAllTaskCommentsByTaskID(taskID int) ([]TaskComment, error) {
+	err := taskDto.checkLiveTask(taskID)
+	if err != nil {
+		return nil, err
+	}
+
 	query := `
 		SELECT tc.id, tc.task_id, tc.comment, tc.created_at
 		FROM task_comment tc
 		WHERE tc.task_id = $1
+		ORDER BY tc.created_at, tc.id
 	`
 
 	rows, err := taskDto.DB.Query(query, taskID)
@@ -349,10 +570,9 @@ func (taskDto TaskDto) GetAllTaskCommentsByTaskID(taskID int) ([]TaskComment, er
 	}
 	defer rows.Close()
 
-	taskCommentsMap := make(map[int]*TaskComment)
-
+	taskComments := make([]TaskComment, 0)
 	for rows.Next() {
-		taskComment := &TaskComment{}
+		var taskComment TaskComment
 		err := rows.Scan(
 			&taskComment.ID,
 			&taskComment.TaskID,
@@ -362,16 +582,26 @@ func (taskDto TaskDto) GetAllTaskCommentsByTaskID(taskID int) ([]TaskComment, er
 		if err != nil {
 			return nil, err
 		}
-
-		if _, ok := taskCommentsMap[taskComment.ID]; !ok {
-			taskCommentsMap[taskComment.ID] = taskComment
-		}
+		taskComments = append(taskComments, taskComment)
 	}
 
-	taskComments := make([]TaskComment, 0, len(taskCommentsMap))
-	for _, taskComment := range taskCommentsMap {
-		taskComments = append(taskComments, *taskComment)
+	if err = rows.Err(); err != nil {
+		return nil, err
 	}
 
 	return taskComments, nil
 }
+
+// checkLiveTask returns sql.ErrNoRows unless the task exists and is not in
+// the trash.
+func (taskDto TaskDto) checkLiveTask(id int) error {
+	var exists bool
+	err := taskDto.DB.QueryRow(`SELECT EXISTS (SELECT 1 FROM task WHERE id = $1 AND deleted_at IS NULL)`, id).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return sql.ErrNoRows
+	}
+	return nil
+}
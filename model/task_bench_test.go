@@ -0,0 +1,216 @@
+package model
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// The benchmarks read a list of tasks with a typical checklist each. The
+// sqlmock ones only measure scanning the rows; the live ones, which need a
+// PostgreSQL database in TMS_TEST_DSN, also measure the queries.
+const (
+	benchmarkTasks = 500
+	benchmarkItems = 8
+)
+
+// getAllTasksRowPerItem is how GetAllTasks read tasks before their items
+// were aggregated in SQL: one row per item, repeating every task column,
+// merged back into tasks through maps. It is kept as the benchmark baseline.
+func getAllTasksRowPerItem(db *sql.DB) ([]Task, error) {
+	rows, err := db.Query(`
+		SELECT t.id, t.title, t.description, t.completed, t.created_at, t.updated_at, t.assigned_user_id, COALESCE(t.parent_task_id, 0), t.project_id, ti.item
+		FROM task t
+		LEFT JOIN task_item ti ON t.id = ti.task_id
+		WHERE t.deleted_at IS NULL
+		ORDER BY t.id, ti.id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	taskMap := make(map[int]*Task)
+	taskItemsMap := make(map[int][]string)
+	for rows.Next() {
+		var taskItem sql.NullString
+		task := &Task{}
+		err := rows.Scan(&task.ID, &task.Title, &task.Description, &task.Completed, &task.CreatedAt, &task.UpdatedAt,
+			&task.AssignedUserID, &task.ParentTaskID, &task.ProjectID, &taskItem)
+		if err != nil {
+			return nil, err
+		}
+		if taskItem.Valid {
+			taskItemsMap[task.ID] = append(taskItemsMap[task.ID], taskItem.String)
+		}
+		if _, ok := taskMap[task.ID]; !ok {
+			taskMap[task.ID] = task
+		}
+	}
+	for taskID, taskItems := range taskItemsMap {
+		taskMap[taskID].Items = taskItems
+	}
+
+	tasks := make([]Task, 0, len(taskMap))
+	for _, task := range taskMap {
+		tasks = append(tasks, *task)
+	}
+	return tasks, rows.Err()
+}
+
+// benchmarkRows returns the rows the database sends for benchmarkTasks
+// tasks: one per item, or one per task with the items as an array.
+func benchmarkRows(aggregated bool) *sqlmock.Rows {
+	columns := []string{"id", "title", "description", "completed", "created_at", "updated_at", "assigned_user_id", "parent_task_id", "project_id", "items"}
+	rows := sqlmock.NewRows(columns)
+	now := time.Now()
+	description := strings.Repeat("Roll the change out region by region. ", 8)
+
+	for id := 1; id <= benchmarkTasks; id++ {
+		title := fmt.Sprintf("Task %d", id)
+		items := make([]string, benchmarkItems)
+		for i := range items {
+			items[i] = fmt.Sprintf("Step %d", i+1)
+		}
+
+		if aggregated {
+			rows.AddRow(id, title, description, false, now, now, 7, 0, 1, `{"`+strings.Join(items, `","`)+`"}`)
+			continue
+		}
+		for _, item := range items {
+			rows.AddRow(id, title, description, false, now, now, 7, 0, 1, item)
+		}
+	}
+	return rows
+}
+
+func benchmarkGetAllTasks(b *testing.B, aggregated bool, getAllTasks func(*sql.DB) ([]Task, error)) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer db.Close()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		mock.ExpectQuery("SELECT .* FROM task t").WillReturnRows(benchmarkRows(aggregated))
+		b.StartTimer()
+
+		tasks, err := getAllTasks(db)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if len(tasks) != benchmarkTasks || len(tasks[0].Items) != benchmarkItems {
+			b.Fatalf("got %d tasks with %d items", len(tasks), len(tasks[0].Items))
+		}
+	}
+
+	rowsPerTask := 1
+	if !aggregated {
+		rowsPerTask = benchmarkItems
+	}
+	b.ReportMetric(float64(benchmarkTasks*rowsPerTask), "rows/op")
+}
+
+func BenchmarkGetAllTasks_RowPerItem(b *testing.B) {
+	benchmarkGetAllTasks(b, false, getAllTasksRowPerItem)
+}
+
+func BenchmarkGetAllTasks_Aggregated(b *testing.B) {
+	benchmarkGetAllTasks(b, true, func(db *sql.DB) ([]Task, error) {
+		return TaskDto{DB: db}.GetAllTasks()
+	})
+}
+
+// openBenchmarkDB seeds a schema of its own in the TMS_TEST_DSN database
+// with the benchmark tasks, and drops it when the benchmark ends. The pool
+// is held to one connection so every query sees the schema.
+func openBenchmarkDB(b *testing.B) *sql.DB {
+	dsn := os.Getenv("TMS_TEST_DSN")
+	if dsn == "" {
+		b.Skip("TMS_TEST_DSN is not set")
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		b.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	b.Cleanup(func() { db.Close() })
+
+	schema, err := os.ReadFile("../create_table.sql")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	name := fmt.Sprintf("tms_benchmark_%d", os.Getpid())
+	_, err = db.Exec(`CREATE SCHEMA ` + name + `; SET search_path TO ` + name)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() {
+		if _, err := db.Exec(`DROP SCHEMA ` + name + ` CASCADE`); err != nil {
+			b.Error(err)
+		}
+	})
+
+	_, err = db.Exec(string(schema))
+	if err != nil {
+		b.Fatal(err)
+	}
+	_, err = db.Exec(`
+		INSERT INTO task (title, description, completed, created_at, updated_at, assigned_user_id)
+		SELECT 'Task ' || n, repeat('Roll the change out region by region. ', 8), false, NOW(), NOW(), 7
+		FROM generate_series(1, $1) n
+	`, benchmarkTasks)
+	if err != nil {
+		b.Fatal(err)
+	}
+	_, err = db.Exec(`
+		INSERT INTO task_item (task_id, item)
+		SELECT t.id, 'Step ' || i
+		FROM task t, generate_series(1, $1) i
+		ORDER BY t.id, i
+	`, benchmarkItems)
+	if err != nil {
+		b.Fatal(err)
+	}
+	_, err = db.Exec(`ANALYZE task; ANALYZE task_item`)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	return db
+}
+
+func benchmarkGetAllTasksLive(b *testing.B, getAllTasks func(*sql.DB) ([]Task, error)) {
+	db := openBenchmarkDB(b)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tasks, err := getAllTasks(db)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if len(tasks) != benchmarkTasks || len(tasks[0].Items) != benchmarkItems {
+			b.Fatalf("got %d tasks with %d items", len(tasks), len(tasks[0].Items))
+		}
+	}
+}
+
+func BenchmarkGetAllTasksLive_RowPerItem(b *testing.B) {
+	benchmarkGetAllTasksLive(b, getAllTasksRowPerItem)
+}
+
+func BenchmarkGetAllTasksLive_Aggregated(b *testing.B) {
+	benchmarkGetAllTasksLive(b, func(db *sql.DB) ([]Task, error) {
+		return TaskDto{DB: db}.GetAllTasks()
+	})
+}
@@ -31,14 +31,16 @@ func TestGetAllTasks_SuccessfulRetrieval(t *testing.T) {
 	taskDto := TaskDto{DB: db}
 
 	// Mock the expected rows
-	rows := sqlmock.NewRows([]string{"id", "title", "description", "completed", "created_at", "updated_at", "assigned_user_id", "item"}).
-		AddRow(1, "TestTitle1", "TestDescription1", false, time.Now(), time.Now(), 0, "Item1").
-		AddRow(2, "TestTitle2", "TestDescription2", true, time.Now(), time.Now(), 1, "Item2")
-	mock.ExpectQuery(`SELECT (.+) FROM task`).WillReturnRows(rows)
+	rows := sqlmock.NewRows([]string{"id", "title", "description", "completed", "created_at", "updated_at", "assigned_user_id", "parent_task_id", "project_id", "items"}).
+		AddRow(1, "TestTitle1", "TestDescription1", false, time.Now(), time.Now(), 0, 0, 1, "{Item1}").
+		AddRow(2, "TestTitle2", "TestDescription2", true, time.Now(), time.Now(), 1, 0, 1, "{}")
+	mock.ExpectQuery(`SELECT (.+) FROM task t WHERE t.deleted_at IS NULL ORDER BY t.id$`).WillReturnRows(rows)
 
 	tasks, err := taskDto.GetAllTasks()
 	assert.NoError(t, err)
 	assert.Len(t, tasks, 2)
+	assert.Equal(t, []string{"Item1"}, tasks[0].Items)
+	assert.Equal(t, []string{}, tasks[1].Items)
 }
 
 func TestInsert(t *testing.T) {
@@ -47,9 +49,14 @@ func TestInsert(t *testing.T) {
 
 	taskDto := TaskDto{DB: db}
 
+	mock.ExpectBegin()
 	mock.ExpectPrepare(`INSERT INTO task (.+) RETURNING id`).ExpectQuery().
 		WithArgs("TestTitle", "TestDescription", false, time.Now(), time.Now()).
 		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectExec("INSERT INTO task_event").
+		WithArgs(1, 0, TaskEventCreated, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
 
 	task := &Task{
 		Title:       "TestTitle",
@@ -82,6 +89,14 @@ func TestUpdateTask_SuccessfulUpdate(t *testing.T) {
 		Items:       []string{"item1", "item2"},
 	}
 
+	mock.ExpectBegin()
+
+	// Mock for reading the task as it was before the update.
+	mock.ExpectQuery("^SELECT title, description, completed, ARRAY.*FROM task WHERE id = \\$1 AND deleted_at IS NULL FOR UPDATE$").
+		WithArgs(task.ID).
+		WillReturnRows(sqlmock.NewRows([]string{"title", "description", "completed", "items"}).
+			AddRow("Old Task", "Test Description", false, "{item1}"))
+
 	// Mock for the initial UPDATE.
 	mock.ExpectPrepare("^UPDATE task SET title.*WHERE id = \\$5").
 		ExpectExec().
@@ -101,6 +116,12 @@ func TestUpdateTask_SuccessfulUpdate(t *testing.T) {
 			WillReturnResult(sqlmock.NewResult(1, 1))
 	}
 
+	// Mock for recording the title and items changes.
+	mock.ExpectExec("INSERT INTO task_event").
+		WithArgs(task.ID, 0, TaskEventUpdated, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
 	// Call method
 	err = taskDto.UpdateTask(task.ID, task)
 	assert.NoError(t, err)
@@ -110,6 +131,33 @@ func TestUpdateTask_SuccessfulUpdate(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestUpdateTask_RejectsBlockedCompletion(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	taskDto := TaskDto{DB: db}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("^SELECT title, description, completed, ARRAY.*FROM task WHERE id = \\$1 AND deleted_at IS NULL FOR UPDATE$").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"title", "description", "completed", "items"}).
+			AddRow("Test Task", "", false, "{}"))
+	mock.ExpectExec("SELECT pg_advisory_xact_lock\\(\\$1\\)").
+		WithArgs(dependencyGraphLock).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT EXISTS").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectRollback()
+
+	err = taskDto.UpdateTask(1, &Task{ID: 1, Title: "Test Task", Completed: true})
+	assert.ErrorIs(t, err, ErrTaskBlocked)
+
+	err = mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
 func TestAssignUserToTask_SuccessfulAssign(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	assert.NoError(t, err)
@@ -120,11 +168,20 @@ func TestAssignUserToTask_SuccessfulAssign(t *testing.T) {
 	id, userID := 1, 42
 	updatedAt := time.Now()
 
+	mock.ExpectBegin()
+	mock.ExpectQuery("^SELECT assigned_user_id FROM task WHERE id = \\$1 AND deleted_at IS NULL FOR UPDATE$").
+		WithArgs(id).
+		WillReturnRows(sqlmock.NewRows([]string{"assigned_user_id"}).AddRow(0))
+
 	// Mock the preparation and execution of the UPDATE query.
 	mock.ExpectPrepare("^UPDATE task SET assigned_to.*WHERE id = \\$3").
 		ExpectExec().
 		WithArgs(userID, updatedAt, id).
 		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO task_event").
+		WithArgs(id, 0, TaskEventAssigned, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
 
 	// Call the method.
 	err = taskDto.AssignUserToTask(id, userID, updatedAt)
@@ -144,11 +201,19 @@ func TestDeleteTask_SuccessfulDelete(t *testing.T) {
 
 	id := 1
 
-	// Mock the preparation and execution of the DELETE query.
-	mock.ExpectPrepare("^DELETE FROM task WHERE id = \\$1$").
-		ExpectExec().
-		WithArgs(id).
+	// Mock moving the task and its subtask to the trash.
+	mock.ExpectBegin()
+	mock.ExpectPrepare("^WITH RECURSIVE subtree AS .* UPDATE task SET deleted_at = \\$2 WHERE id IN \\(SELECT id FROM subtree\\) RETURNING id$").
+		ExpectQuery().
+		WithArgs(id, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(id).AddRow(2))
+	mock.ExpectExec("INSERT INTO task_event").
+		WithArgs(id, 0, TaskEventDeleted, sqlmock.AnyArg(), sqlmock.AnyArg()).
 		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO task_event").
+		WithArgs(2, 0, TaskEventDeleted, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectCommit()
 
 	// Call the method.
 	err = taskDto.DeleteTask(id)
@@ -170,10 +235,15 @@ func TestInsertTaskItem_SuccessfulInsert(t *testing.T) {
 	item := "Test item"
 
 	// Mock the preparation and execution of the INSERT query.
+	mock.ExpectBegin()
 	mock.ExpectPrepare("^INSERT INTO task_item \\(task_id, item\\) VALUES \\(\\$1, \\$2\\)$").
 		ExpectExec().
 		WithArgs(taskID, item).
 		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO task_event").
+		WithArgs(taskID, 0, TaskEventItemAdded, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
 
 	// Call the method.
 	err = taskDto.InsertTaskItem(taskID, item)
@@ -198,10 +268,18 @@ func TestInsertTaskComment_SuccessfulInsert(t *testing.T) {
 	}
 
 	// Mock the preparation and execution of the INSERT query.
+	mock.ExpectBegin()
+	mock.ExpectQuery("^SELECT id FROM task WHERE id = \\$1 AND deleted_at IS NULL FOR SHARE$").
+		WithArgs(taskComment.TaskID).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(taskComment.TaskID))
 	mock.ExpectPrepare("^INSERT INTO task_comment \\(task_id, comment, created_at\\) VALUES \\(\\$1, \\$2, \\$3\\)$").
 		ExpectExec().
 		WithArgs(taskComment.TaskID, taskComment.Comment, taskComment.CreatedAt).
 		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO task_event").
+		WithArgs(taskComment.TaskID, 0, TaskEventCommented, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
 
 	// Call the method.
 	err = taskDto.InsertTaskComment(taskComment)
@@ -221,12 +299,11 @@ func TestGetTask_SuccessfulGet(t *testing.T) {
 
 	id := 1
 	// Mocking the rows you'll be retrieving.
-	columns := []string{"id", "title", "description", "completed", "created_at", "updated_at", "assigned_user_id", "item"}
+	columns := []string{"id", "title", "description", "completed", "created_at", "updated_at", "assigned_user_id", "parent_task_id", "project_id", "items"}
 	mockRows := sqlmock.NewRows(columns).
-		AddRow(1, "Test Title", "Test Description", false, time.Now(), time.Now(), 42, "Item 1").
-		AddRow(1, "Test Title", "Test Description", false, time.Now(), time.Now(), 42, "Item 2")
+		AddRow(1, "Test Title", "Test Description", false, time.Now(), time.Now(), 42, 0, 1, `{"Item 1","Item 2"}`)
 
-	mock.ExpectQuery("^SELECT t.id, t.title, t.description.*FROM task t.*WHERE t.id = \\$1$").
+	mock.ExpectQuery("^SELECT t.id, t.title, t.description.*FROM task t.*WHERE t.id = \\$1 AND t.deleted_at IS NULL$").
 		WithArgs(id).
 		WillReturnRows(mockRows)
 
@@ -234,7 +311,7 @@ func TestGetTask_SuccessfulGet(t *testing.T) {
 	task, err := taskDto.GetTask(id)
 	assert.NoError(t, err)
 	assert.NotNil(t, task)
-	assert.Equal(t, 2, len(task.Items)) // since we added two rows with two items.
+	assert.Equal(t, []string{"Item 1", "Item 2"}, task.Items)
 
 	// Ensure all mock expectations were met.
 	err = mock.ExpectationsWereMet()
@@ -250,14 +327,12 @@ func TestGetAllTaskByAssignedUserID_SuccessfulGet(t *testing.T) {
 
 	userID := 42
 	// Mocking the rows you'll be retrieving.
-	columns := []string{"id", "title", "description", "completed", "created_at", "updated_at", "assigned_user_id", "item"}
+	columns := []string{"id", "title", "description", "completed", "created_at", "updated_at", "assigned_user_id", "parent_task_id", "project_id", "items"}
 	mockRows := sqlmock.NewRows(columns).
-		AddRow(1, "Test Title 1", "Description 1", false, time.Now(), time.Now(), userID, "Item 1").
-		AddRow(1, "Test Title 1", "Description 1", false, time.Now(), time.Now(), userID, "Item 2").
-		AddRow(2, "Test Title 2", "Description 2", false, time.Now(), time.Now(), userID, "Item A").
-		AddRow(2, "Test Title 2", "Description 2", false, time.Now(), time.Now(), userID, "Item B")
+		AddRow(1, "Test Title 1", "Description 1", false, time.Now(), time.Now(), userID, 0, 1, `{"Item 1","Item 2"}`).
+		AddRow(2, "Test Title 2", "Description 2", false, time.Now(), time.Now(), userID, 0, 1, `{"Item A","Item B"}`)
 
-	mock.ExpectQuery("^SELECT t.id, t.title, t.description.*FROM task t.*WHERE t.assigned_user_id = \\$1$").
+	mock.ExpectQuery("^SELECT t.id, t.title, t.description.*FROM task t.*WHERE t.assigned_user_id = \\$1 AND t.deleted_at IS NULL ORDER BY t.id$").
 		WithArgs(userID).
 		WillReturnRows(mockRows)
 
@@ -289,7 +364,10 @@ func TestGetAllTaskCommentsByTaskID_SuccessfulGet(t *testing.T) {
 		AddRow(2, taskID, "Comment 2", time.Now()).
 		AddRow(3, taskID, "Comment 3", time.Now())
 
-	mock.ExpectQuery("^SELECT tc.id, tc.task_id, tc.comment.*FROM task_comment tc.*WHERE tc.task_id = \\$1$").
+	mock.ExpectQuery("^SELECT EXISTS \\(SELECT 1 FROM task WHERE id = \\$1 AND deleted_at IS NULL\\)$").
+		WithArgs(taskID).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectQuery("^SELECT tc.id, tc.task_id, tc.comment.*FROM task_comment tc.*WHERE tc.task_id = \\$1 ORDER BY tc.created_at, tc.id$").
 		WithArgs(taskID).
 		WillReturnRows(mockRows)
 
@@ -298,6 +376,7 @@ func TestGetAllTaskCommentsByTaskID_SuccessfulGet(t *testing.T) {
 	assert.NoError(t, err)
 	assert.NotNil(t, taskComments)
 	assert.Equal(t, 3, len(taskComments)) // 3 comments for the task.
+	assert.Equal(t, "Comment 1", taskComments[0].Comment)
 
 	// Ensure all mock expectations were met.
 	err = mock.ExpectationsWereMet()
@@ -0,0 +1,137 @@
+package model
+
+import (
+	"time"
+)
+
+// GetDeletedTasks returns the tasks in the trash that filter matches, most
+// recently deleted first.
+func (taskDto TaskDto) GetDeletedTasks(filter TaskFilter) ([]Task, error) {
+	var where conditions
+	where.add("t.deleted_at IS NOT NULL")
+	filter.addTo(&where)
+
+	rows, err := taskDto.DB.Query(`
+		SELECT `+taskColumns+`, t.deleted_at
+		FROM task t
+		WHERE `+where.String()+`
+		ORDER BY t.deleted_at DESC, t.id
+	`, where.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tasks := make([]Task, 0)
+	for rows.Next() {
+		var task Task
+		err := scanTask(rows, &task, &task.DeletedAt)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tasks, nil
+}
+
+// RestoreTask takes the task out of the trash together with the subtasks
+// that were deleted along with it. If the task's parent is still in the
+// trash, the task is restored as a root task. It returns sql.ErrNoRows if
+// the task is not in the trash.
+func (taskDto TaskDto) RestoreTask(id int) error {
+	tx, err := taskDto.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var deletedAt time.Time
+	err = tx.QueryRow(`
+		SELECT deleted_at
+		FROM task
+		WHERE id = $1 AND deleted_at IS NOT NULL
+		FOR UPDATE
+	`, id).Scan(&deletedAt)
+	if err != nil {
+		return err
+	}
+
+	// Subtasks trashed at the same instant were deleted with this task;
+	// ones trashed earlier were deleted on their own and stay in the trash.
+	rows, err := tx.Query(`
+		WITH RECURSIVE subtree AS (
+			SELECT id FROM task WHERE id = $1
+			UNION
+			SELECT t.id FROM task t JOIN subtree s ON t.parent_task_id = s.id WHERE t.deleted_at = $2
+		)
+		UPDATE task SET deleted_at = NULL, updated_at = $3 WHERE id IN (SELECT id FROM subtree)
+		RETURNING id
+	`, id, deletedAt, time.Now())
+	if err != nil {
+		return err
+	}
+
+	var ids []int
+	for rows.Next() {
+		var taskID int
+		err := rows.Scan(&taskID)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		ids = append(ids, taskID)
+	}
+	rows.Close()
+
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		UPDATE task
+		SET parent_task_id = NULL
+		WHERE id = $1 AND parent_task_id IN (SELECT id FROM task WHERE deleted_at IS NOT NULL)
+	`, id)
+	if err != nil {
+		return err
+	}
+
+	for _, taskID := range ids {
+		err = taskDto.recordTaskEvent(tx, taskID, TaskEventRestored, fieldChanges{"deleted_at": {Before: deletedAt}})
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// PurgeDeletedTasks permanently deletes the tasks that were moved to the
+// trash before the given time, along with their items and comments. Their
+// history is kept and ends with a purged event.
+func (taskDto TaskDto) PurgeDeletedTasks(before time.Time) (int, error) {
+	result, err := taskDto.DB.Exec(`
+		WITH purged AS (
+			DELETE FROM task
+			WHERE deleted_at IS NOT NULL AND deleted_at < $1
+			RETURNING id
+		)
+		INSERT INTO task_event (task_id, actor_id, action, changes, created_at)
+		SELECT id, $2, $3, '{}', $4 FROM purged
+	`, before, taskDto.ActorID, TaskEventPurged, time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(rowsAffected), nil
+}
@@ -0,0 +1,167 @@
+package model
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeleteTask_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	taskDto := TaskDto{DB: db}
+
+	mock.ExpectBegin()
+	mock.ExpectPrepare("UPDATE task SET deleted_at").ExpectQuery().
+		WithArgs(99, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectRollback()
+
+	err = taskDto.DeleteTask(99)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	err = mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+func TestGetDeletedTasks_SuccessfulGet(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	taskDto := TaskDto{DB: db}
+
+	deletedAt := time.Now()
+	rows := sqlmock.NewRows([]string{"id", "title", "description", "completed", "created_at", "updated_at", "assigned_user_id", "parent_task_id", "project_id", "items", "deleted_at"}).
+		AddRow(3, "Trashed", "", false, time.Now(), time.Now(), 0, 0, 1, "{a,b}", deletedAt)
+	mock.ExpectQuery("SELECT t.id, .* FROM task t WHERE t.deleted_at IS NOT NULL ORDER BY t.deleted_at DESC").
+		WillReturnRows(rows)
+
+	tasks, err := taskDto.GetDeletedTasks(TaskFilter{})
+	assert.NoError(t, err)
+	assert.Len(t, tasks, 1)
+	assert.Equal(t, []string{"a", "b"}, tasks[0].Items)
+	assert.Equal(t, deletedAt, *tasks[0].DeletedAt)
+
+	err = mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+func TestRestoreTask_RestoresSubtasksDeletedWithIt(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	taskDto := TaskDto{DB: db, ActorID: 7}
+
+	deletedAt := time.Now().Add(-time.Hour)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT deleted_at FROM task WHERE id = \\$1 AND deleted_at IS NOT NULL FOR UPDATE").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"deleted_at"}).AddRow(deletedAt))
+	mock.ExpectQuery("WITH RECURSIVE subtree AS .* WHERE t.deleted_at = \\$2 .* UPDATE task SET deleted_at = NULL").
+		WithArgs(1, deletedAt, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+	mock.ExpectExec("UPDATE task SET parent_task_id = NULL").
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO task_event").
+		WithArgs(1, 7, TaskEventRestored, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO task_event").
+		WithArgs(2, 7, TaskEventRestored, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectCommit()
+
+	err = taskDto.RestoreTask(1)
+	assert.NoError(t, err)
+
+	err = mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+func TestRestoreTask_NotInTrash(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	taskDto := TaskDto{DB: db}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT deleted_at FROM task").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"deleted_at"}))
+	mock.ExpectRollback()
+
+	err = taskDto.RestoreTask(1)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	err = mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+func TestPurgeDeletedTasks(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	taskDto := TaskDto{DB: db}
+
+	before := time.Now().Add(-30 * 24 * time.Hour)
+	mock.ExpectExec("WITH purged AS \\( DELETE FROM task WHERE deleted_at IS NOT NULL AND deleted_at < \\$1 RETURNING id \\) INSERT INTO task_event").
+		WithArgs(before, 0, TaskEventPurged, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+
+	purged, err := taskDto.PurgeDeletedTasks(before)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, purged)
+
+	err = mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+func TestTrashedTask_NoCommentsOrAssignment(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	taskDto := TaskDto{DB: db}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id FROM task WHERE id = \\$1 AND deleted_at IS NULL FOR SHARE").
+		WithArgs(4).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectRollback()
+	err = taskDto.InsertTaskComment(&TaskComment{TaskID: 4, Comment: "Too late", CreatedAt: time.Now()})
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT assigned_user_id FROM task WHERE id = \\$1 AND deleted_at IS NULL FOR UPDATE").
+		WithArgs(4).
+		WillReturnRows(sqlmock.NewRows([]string{"assigned_user_id"}))
+	mock.ExpectRollback()
+	err = taskDto.AssignUserToTask(4, 7, time.Now())
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	for i := 0; i < 2; i++ {
+		mock.ExpectQuery("SELECT EXISTS \\(SELECT 1 FROM task WHERE id = \\$1 AND deleted_at IS NULL\\)").
+			WithArgs(4).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	}
+	_, err = taskDto.GetAllTaskCommentsByTaskID(4)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+	err = taskDto.EachTaskComment(4, Page{}, func(TaskComment) error {
+		t.Error("no comments are listed for a task in the trash")
+		return nil
+	})
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	err = mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
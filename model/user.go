@@ -0,0 +1,50 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+)
+
+// UserEmail is the address a user is sent notifications at. Users are only
+// known by ID, so a user without one is simply not emailed.
+type UserEmail struct {
+	UserID    int       `json:"user_id"`
+	Email     string    `json:"email"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type UserDto struct {
+	DB *sql.DB
+}
+
+// SetUserEmail sets the user's address, replacing any address they had.
+func (userDto UserDto) SetUserEmail(userEmail *UserEmail) error {
+	_, err := userDto.DB.Exec(`
+		INSERT INTO user_email (user_id, email, updated_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE
+		SET email = EXCLUDED.email, updated_at = EXCLUDED.updated_at
+	`, userEmail.UserID, userEmail.Email, userEmail.UpdatedAt)
+	return err
+}
+
+// GetUserEmail returns the user's address, or sql.ErrNoRows if they have none.
+func (userDto UserDto) GetUserEmail(userID int) (string, error) {
+	var email string
+	err := userDto.DB.QueryRow(`SELECT email FROM user_email WHERE user_id = $1`, userID).Scan(&email)
+	return email, err
+}
+
+func (userDto UserDto) DeleteUserEmail(userID int) (bool, error) {
+	result, err := userDto.DB.Exec(`DELETE FROM user_email WHERE user_id = $1`, userID)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAffected > 0, nil
+}
@@ -0,0 +1,43 @@
+package model
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetUserEmail_Upserts(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	userDto := UserDto{DB: db}
+	userEmail := &UserEmail{UserID: 5, Email: "dev@example.com", UpdatedAt: time.Now()}
+
+	mock.ExpectExec("INSERT INTO user_email(.|\n)*ON CONFLICT \\(user_id\\) DO UPDATE").
+		WithArgs(5, "dev@example.com", userEmail.UpdatedAt).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err = userDto.SetUserEmail(userEmail)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetUserEmail_NoAddress(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	userDto := UserDto{DB: db}
+
+	mock.ExpectQuery("SELECT email FROM user_email").
+		WithArgs(9).
+		WillReturnRows(sqlmock.NewRows([]string{"email"}))
+
+	_, err = userDto.GetUserEmail(9)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,357 @@
+package model
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Delivery states. A delivery is retried while it is pending and ends up
+// succeeded, or failed once it runs out of attempts.
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliverySucceeded = "succeeded"
+	WebhookDeliveryFailed    = "failed"
+)
+
+// WebhookEventPrefix is prepended to a task event's action to give the
+// webhook event name, such as "task.created".
+const WebhookEventPrefix = "task."
+
+// WebhookEvents lists the events a webhook can subscribe to.
+var WebhookEvents = []string{
+	WebhookEventPrefix + TaskEventCreated,
+	WebhookEventPrefix + TaskEventUpdated,
+	WebhookEventPrefix + TaskEventAssigned,
+	WebhookEventPrefix + TaskEventCommented,
+	WebhookEventPrefix + TaskEventDeleted,
+	WebhookEventPrefix + TaskEventRestored,
+	WebhookEventPrefix + TaskEventItemAdded,
+	WebhookEventPrefix + TaskEventParentChanged,
+	WebhookEventPrefix + TaskEventProjectChanged,
+	WebhookEventPrefix + TaskEventDependencyAdded,
+	WebhookEventPrefix + TaskEventDependencyRemoved,
+	WebhookEventPrefix + TaskEventRecurrenceSet,
+	WebhookEventPrefix + TaskEventRecurrenceRemoved,
+	WebhookEventPrefix + TaskEventAttachmentAdded,
+	WebhookEventPrefix + TaskEventAttachmentRemoved,
+}
+
+// Webhook is a subscription to the events of the tasks in one project, which
+// its creator must be a member of. Secret is only returned when the webhook
+// is created.
+type Webhook struct {
+	ID          int       `json:"id"`
+	ProjectID   int       `json:"project_id"`
+	URL         string    `json:"url"`
+	Secret      string    `json:"secret,omitempty"`
+	Events      []string  `json:"events"`
+	Active      bool      `json:"active"`
+	CreatedByID int       `json:"created_by"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// WebhookDelivery is one attempt, or series of retried attempts, to send a
+// task event to a webhook. Payload is fixed on the first attempt so retries
+// and redeliveries send the same body.
+type WebhookDelivery struct {
+	ID             int             `json:"id"`
+	WebhookID      int             `json:"webhook_id"`
+	EventID        int             `json:"event_id"`
+	Event          string          `json:"event"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	ResponseStatus int             `json:"response_status,omitempty"`
+	LastError      string          `json:"last_error,omitempty"`
+	Payload        json.RawMessage `json:"payload,omitempty"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
+}
+
+// DueDelivery is a claimed delivery with what is needed to send it.
+type DueDelivery struct {
+	WebhookDelivery
+	URL       string
+	Secret    string
+	TaskEvent TaskEvent
+}
+
+type WebhookDto struct {
+	DB *sql.DB
+}
+
+func (webhookDto WebhookDto) Insert(hook *Webhook) error {
+	return webhookDto.DB.QueryRow(`
+		INSERT INTO webhook (project_id, url, secret, events, active, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id
+	`, hook.ProjectID, hook.URL, hook.Secret, pq.Array(hook.Events), hook.Active, hook.CreatedByID, hook.CreatedAt).Scan(&hook.ID)
+}
+
+// GetWebhooks returns the webhooks the user created, without their secrets.
+func (webhookDto WebhookDto) GetWebhooks(createdByID int) ([]Webhook, error) {
+	rows, err := webhookDto.DB.Query(`
+		SELECT id, project_id, url, events, active, created_by, created_at
+		FROM webhook
+		WHERE created_by = $1
+		ORDER BY id
+	`, createdByID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hooks := make([]Webhook, 0)
+	for rows.Next() {
+		var hook Webhook
+		err := rows.Scan(&hook.ID, &hook.ProjectID, &hook.URL, pq.Array(&hook.Events), &hook.Active, &hook.CreatedByID, &hook.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		hooks = append(hooks, hook)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return hooks, nil
+}
+
+// GetWebhook returns the webhook without its secret. Webhooks created by
+// other users are reported as sql.ErrNoRows.
+func (webhookDto WebhookDto) GetWebhook(id, createdByID int) (*Webhook, error) {
+	hook := &Webhook{}
+	err := webhookDto.DB.QueryRow(`
+		SELECT id, project_id, url, events, active, created_by, created_at
+		FROM webhook
+		WHERE id = $1 AND created_by = $2
+	`, id, createdByID).Scan(&hook.ID, &hook.ProjectID, &hook.URL, pq.Array(&hook.Events), &hook.Active, &hook.CreatedByID, &hook.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return hook, nil
+}
+
+// DeleteWebhook removes the webhook together with its delivery log, if the
+// user created it.
+func (webhookDto WebhookDto) DeleteWebhook(id, createdByID int) (bool, error) {
+	result, err := webhookDto.DB.Exec(`DELETE FROM webhook WHERE id = $1 AND created_by = $2`, id, createdByID)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// GetDeliveries returns the most recent deliveries of the webhook, newest first.
+func (webhookDto WebhookDto) GetDeliveries(webhookID, limit int) ([]WebhookDelivery, error) {
+	rows, err := webhookDto.DB.Query(`
+		SELECT id, webhook_id, event_id, event, status, attempts, COALESCE(response_status, 0), COALESCE(last_error, ''), payload, next_attempt_at, created_at, delivered_at
+		FROM webhook_delivery
+		WHERE webhook_id = $1
+		ORDER BY id DESC
+		LIMIT $2
+	`, webhookID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := make([]WebhookDelivery, 0)
+	for rows.Next() {
+		delivery, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, *delivery)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+func scanWebhookDelivery(row interface{ Scan(...any) error }, extra ...any) (*WebhookDelivery, error) {
+	delivery := &WebhookDelivery{}
+	var payload []byte
+	var nextAttemptAt, deliveredAt sql.NullTime
+
+	dest := []any{
+		&delivery.ID,
+		&delivery.WebhookID,
+		&delivery.EventID,
+		&delivery.Event,
+		&delivery.Status,
+		&delivery.Attempts,
+		&delivery.ResponseStatus,
+		&delivery.LastError,
+		&payload,
+		&nextAttemptAt,
+		&delivery.CreatedAt,
+		&deliveredAt,
+	}
+	err := row.Scan(append(dest, extra...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	if payload != nil {
+		delivery.Payload = json.RawMessage(payload)
+	}
+	if nextAttemptAt.Valid {
+		delivery.NextAttemptAt = &nextAttemptAt.Time
+	}
+	if deliveredAt.Valid {
+		delivery.DeliveredAt = &deliveredAt.Time
+	}
+
+	return delivery, nil
+}
+
+// Redeliver queues a fresh delivery of an earlier delivery's event, with the
+// same payload. It returns sql.ErrNoRows if the webhook has no such delivery
+// or the user did not create the webhook.
+func (webhookDto WebhookDto) Redeliver(webhookID, deliveryID, createdByID int, now time.Time) (*WebhookDelivery, error) {
+	row := webhookDto.DB.QueryRow(`
+		INSERT INTO webhook_delivery (webhook_id, event_id, event, status, attempts, payload, next_attempt_at, created_at)
+		SELECT d.webhook_id, d.event_id, d.event, $4, 0, d.payload, $5, $5
+		FROM webhook_delivery d
+		JOIN webhook w ON w.id = d.webhook_id
+		WHERE d.id = $1 AND d.webhook_id = $2 AND w.created_by = $3
+		RETURNING id, webhook_id, event_id, event, status, attempts, 0, '', payload, next_attempt_at, created_at, delivered_at
+	`, deliveryID, webhookID, createdByID, WebhookDeliveryPending, now)
+
+	return scanWebhookDelivery(row)
+}
+
+// ClaimDueDeliveries picks up to limit pending deliveries that are due and
+// pushes their next attempt back by lease, so another dispatcher does not
+// send them at the same time. A delivery whose sender dies is retried once
+// the lease runs out.
+func (webhookDto WebhookDto) ClaimDueDeliveries(now time.Time, lease time.Duration, limit int) ([]DueDelivery, error) {
+	tx, err := webhookDto.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`
+		SELECT d.id, d.webhook_id, d.event_id, d.event, d.status, d.attempts, COALESCE(d.response_status, 0), COALESCE(d.last_error, ''), d.payload, d.next_attempt_at, d.created_at, d.delivered_at,
+			w.url, w.secret, e.task_id, e.actor_id, e.action, e.changes, e.created_at
+		FROM webhook_delivery d
+		JOIN webhook w ON w.id = d.webhook_id
+		JOIN task_event e ON e.id = d.event_id
+		WHERE d.status = $1 AND d.next_attempt_at <= $2
+		ORDER BY d.next_attempt_at, d.id
+		LIMIT $3
+		FOR UPDATE OF d SKIP LOCKED
+	`, WebhookDeliveryPending, now, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	var due []DueDelivery
+	for rows.Next() {
+		var d DueDelivery
+		var changes []byte
+		delivery, err := scanWebhookDelivery(rows, &d.URL, &d.Secret, &d.TaskEvent.TaskID, &d.TaskEvent.ActorID, &d.TaskEvent.Action, &changes, &d.TaskEvent.CreatedAt)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		d.WebhookDelivery = *delivery
+		d.TaskEvent.ID = delivery.EventID
+
+		err = json.Unmarshal(changes, &d.TaskEvent.Changes)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		due = append(due, d)
+	}
+	rows.Close()
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, d := range due {
+		_, err = tx.Exec(`UPDATE webhook_delivery SET next_attempt_at = $1 WHERE id = $2`, now.Add(lease), d.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return due, nil
+}
+
+// RecordDeliveryAttempt stores the outcome of sending a delivery. A failed
+// attempt is retried at retryAt, or the delivery is marked failed when
+// retryAt is nil.
+func (webhookDto WebhookDto) RecordDeliveryAttempt(id int, payload []byte, responseStatus int, sendErr error, retryAt *time.Time, now time.Time) error {
+	status, lastError := WebhookDeliverySucceeded, ""
+	var deliveredAt *time.Time
+	switch {
+	case sendErr == nil:
+		deliveredAt = &now
+	case retryAt != nil:
+		status, lastError = WebhookDeliveryPending, sendErr.Error()
+	default:
+		status, lastError = WebhookDeliveryFailed, sendErr.Error()
+	}
+
+	result, err := webhookDto.DB.Exec(`
+		UPDATE webhook_delivery
+		SET status = $1, attempts = attempts + 1, response_status = $2, last_error = $3,
+			payload = COALESCE(payload, $4), next_attempt_at = $5, delivered_at = $6
+		WHERE id = $7
+	`, status, nullableID(responseStatus), nullableString(lastError), payload, retryAt, deliveredAt, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// ValidWebhookEvent reports whether a webhook can subscribe to the event.
+func ValidWebhookEvent(event string) bool {
+	for _, known := range WebhookEvents {
+		if event == known {
+			return true
+		}
+	}
+	return false
+}
+
+// nullableString stores an empty string as NULL.
+func nullableString(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
@@ -0,0 +1,136 @@
+package model
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+var webhookDeliveryColumns = []string{"id", "webhook_id", "event_id", "event", "status", "attempts", "response_status", "last_error", "payload", "next_attempt_at", "created_at", "delivered_at"}
+
+func TestClaimDueDeliveries_LeasesClaimedRows(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	webhookDto := WebhookDto{DB: db}
+	now := time.Now()
+	lease := 20 * time.Second
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("FROM webhook_delivery d(.|\n)*FOR UPDATE OF d SKIP LOCKED").
+		WithArgs(WebhookDeliveryPending, now, 50).
+		WillReturnRows(sqlmock.NewRows(append(webhookDeliveryColumns, "url", "secret", "task_id", "actor_id", "action", "changes", "event_created_at")).
+			AddRow(3, 1, 9, "task.updated", WebhookDeliveryPending, 0, 0, "", nil, now, now, nil,
+				"https://example.com/hook", "s3cret", 5, 7, TaskEventUpdated, []byte(`{"title":{"before":"a","after":"b"}}`), now))
+	mock.ExpectExec("UPDATE webhook_delivery SET next_attempt_at").
+		WithArgs(now.Add(lease), 3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	due, err := webhookDto.ClaimDueDeliveries(now, lease, 50)
+
+	assert.NoError(t, err)
+	assert.Len(t, due, 1)
+	assert.Equal(t, "https://example.com/hook", due[0].URL)
+	assert.Equal(t, "s3cret", due[0].Secret)
+	assert.Equal(t, 9, due[0].TaskEvent.ID)
+	assert.Equal(t, 5, due[0].TaskEvent.TaskID)
+	assert.Equal(t, FieldChange{Before: "a", After: "b"}, due[0].TaskEvent.Changes["title"])
+	assert.Nil(t, due[0].Payload)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRecordDeliveryAttempt(t *testing.T) {
+	now := time.Now()
+	retryAt := now.Add(time.Minute)
+	payload := []byte(`{"event":"task.updated"}`)
+
+	tests := []struct {
+		name       string
+		sendErr    error
+		retryAt    *time.Time
+		status     string
+		lastError  any
+		delivered  any
+		respStatus any
+	}{
+		{name: "succeeded", status: WebhookDeliverySucceeded, lastError: nil, delivered: &now, respStatus: 200},
+		{name: "retried", sendErr: errors.New("receiver returned 503"), retryAt: &retryAt, status: WebhookDeliveryPending, lastError: "receiver returned 503", respStatus: 503},
+		{name: "failed", sendErr: errors.New("receiver returned 503"), status: WebhookDeliveryFailed, lastError: "receiver returned 503", respStatus: 503},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer db.Close()
+
+			webhookDto := WebhookDto{DB: db}
+
+			mock.ExpectExec("UPDATE webhook_delivery(.|\n)*attempts = attempts \\+ 1").
+				WithArgs(tt.status, tt.respStatus, tt.lastError, payload, tt.retryAt, tt.delivered, 3).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+
+			err = webhookDto.RecordDeliveryAttempt(3, payload, tt.respStatus.(int), tt.sendErr, tt.retryAt, now)
+
+			assert.NoError(t, err)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestGetWebhooks_OnlyTheCreators(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	webhookDto := WebhookDto{DB: db}
+	now := time.Now()
+
+	mock.ExpectQuery("SELECT id, project_id, url, events, active, created_by, created_at FROM webhook WHERE created_by = \\$1").
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "project_id", "url", "events", "active", "created_by", "created_at"}).
+			AddRow(2, 3, "https://example.com/hook", "{task.created}", true, 7, now))
+
+	hooks, err := webhookDto.GetWebhooks(7)
+	assert.NoError(t, err)
+	assert.Equal(t, []Webhook{{ID: 2, ProjectID: 3, URL: "https://example.com/hook", Events: []string{"task.created"}, Active: true, CreatedByID: 7, CreatedAt: now}}, hooks)
+
+	mock.ExpectQuery("FROM webhook WHERE id = \\$1 AND created_by = \\$2").
+		WithArgs(2, 8).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "project_id", "url", "events", "active", "created_by", "created_at"}))
+
+	_, err = webhookDto.GetWebhook(2, 8)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRedeliver_UnknownOrOthersDelivery(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	webhookDto := WebhookDto{DB: db}
+	now := time.Now()
+
+	mock.ExpectQuery("INSERT INTO webhook_delivery(.|\n)*SELECT(.|\n)*FROM webhook_delivery d(.|\n)*w.created_by = \\$3").
+		WithArgs(8, 1, 7, WebhookDeliveryPending, now).
+		WillReturnError(sql.ErrNoRows)
+
+	delivery, err := webhookDto.Redeliver(1, 8, 7, now)
+
+	assert.Nil(t, delivery)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestValidWebhookEvent(t *testing.T) {
+	assert.True(t, ValidWebhookEvent("task.created"))
+	assert.False(t, ValidWebhookEvent("created"))
+	assert.False(t, ValidWebhookEvent("task.purged"))
+}
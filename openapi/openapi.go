@@ -0,0 +1,222 @@
+// Package openapi builds an OpenAPI 3 description of an HTTP API in code,
+// deriving schemas from Go types so the document cannot drift from the
+// structs the handlers decode and encode, and validates requests against it.
+package openapi
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Version is the OpenAPI version documents are written in.
+const Version = "3.0.3"
+
+// Document is an OpenAPI document. Only the parts the API uses are modelled.
+type Document struct {
+	OpenAPI    string               `json:"openapi"`
+	Info       Info                 `json:"info"`
+	Servers    []Server             `json:"servers,omitempty"`
+	Tags       []Tag                `json:"tags,omitempty"`
+	Paths      map[string]*PathItem `json:"paths"`
+	Components Components           `json:"components"`
+
+	// schemaTypes maps component names to the type they were made from, to
+	// catch two types claiming the same name.
+	schemaTypes map[string]reflect.Type
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+type Server struct {
+	URL         string `json:"url"`
+	Description string `json:"description,omitempty"`
+}
+
+type Tag struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas"`
+}
+
+// PathItem holds the operations on one path, by lower-case method.
+type PathItem map[string]*Operation
+
+type Operation struct {
+	OperationID string               `json:"operationId"`
+	Summary     string               `json:"summary,omitempty"`
+	Description string               `json:"description,omitempty"`
+	Tags        []string             `json:"tags,omitempty"`
+	Parameters  []*Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses"`
+
+	// RawBody leaves the body's content unchecked by Validator, for
+	// handlers that report problems with it in their own format.
+	RawBody bool `json:"-"`
+}
+
+// Parameter is a path, query or header parameter.
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Description string               `json:"description,omitempty"`
+	Required    bool                 `json:"required,omitempty"`
+	Content     map[string]MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema,omitempty"`
+}
+
+// New returns an empty document.
+func New(info Info) *Document {
+	return &Document{
+		OpenAPI:     Version,
+		Info:        info,
+		Paths:       map[string]*PathItem{},
+		Components:  Components{Schemas: map[string]*Schema{}},
+		schemaTypes: map[string]reflect.Type{},
+	}
+}
+
+// Add documents an operation. Path uses OpenAPI templates, as in
+// /tasks/{id}; every template segment must have a path parameter, which
+// Add declares as a required integer if op does not declare it itself.
+// Add panics if the operation is already documented.
+func (doc *Document) Add(method, path string, op *Operation) {
+	item := doc.Paths[path]
+	if item == nil {
+		item = &PathItem{}
+		doc.Paths[path] = item
+	}
+	key := strings.ToLower(method)
+	if (*item)[key] != nil {
+		panic(fmt.Sprintf("openapi: %s %s documented twice", method, path))
+	}
+
+	for _, name := range pathParams(path) {
+		if op.param(name, "path") == nil {
+			op.Parameters = append(op.Parameters, &Parameter{Name: name, In: "path", Required: true, Schema: Integer()})
+		}
+	}
+	if op.Responses == nil {
+		op.Responses = map[string]*Response{}
+	}
+	(*item)[key] = op
+}
+
+// Operation returns the operation documented for method and path, or nil.
+func (doc *Document) Operation(method, path string) *Operation {
+	item := doc.Paths[path]
+	if item == nil {
+		return nil
+	}
+	return (*item)[strings.ToLower(method)]
+}
+
+// Routes lists the documented operations as "METHOD /path", sorted.
+func (doc *Document) Routes() []string {
+	var routes []string
+	for path, item := range doc.Paths {
+		for method := range *item {
+			routes = append(routes, strings.ToUpper(method)+" "+path)
+		}
+	}
+	sort.Strings(routes)
+	return routes
+}
+
+func (op *Operation) param(name, in string) *Parameter {
+	for _, param := range op.Parameters {
+		if param.Name == name && param.In == in {
+			return param
+		}
+	}
+	return nil
+}
+
+// Respond documents a response. An empty content type documents one
+// without a body; documenting the same status again adds a content type.
+func (op *Operation) Respond(status int, description, contentType string, schema *Schema) *Operation {
+	if op.Responses == nil {
+		op.Responses = map[string]*Response{}
+	}
+	response := op.Responses[strconv.Itoa(status)]
+	if response == nil {
+		response = &Response{Description: description}
+		op.Responses[strconv.Itoa(status)] = response
+	}
+	if contentType != "" {
+		if response.Content == nil {
+			response.Content = map[string]MediaType{}
+		}
+		response.Content[contentType] = MediaType{Schema: schema}
+	}
+	return op
+}
+
+// JSON documents a JSON response.
+func (op *Operation) JSON(status int, description string, schema *Schema) *Operation {
+	return op.Respond(status, description, "application/json", schema)
+}
+
+// Error documents a plain-text error response.
+func (op *Operation) Error(status int, description string) *Operation {
+	return op.Respond(status, description, "text/plain", String())
+}
+
+// Query declares a query parameter.
+func (op *Operation) Query(name, description string, schema *Schema) *Operation {
+	op.Parameters = append(op.Parameters, &Parameter{Name: name, In: "query", Description: description, Schema: schema})
+	return op
+}
+
+// Header declares a request header.
+func (op *Operation) Header(name, description string, schema *Schema) *Operation {
+	op.Parameters = append(op.Parameters, &Parameter{Name: name, In: "header", Description: description, Schema: schema})
+	return op
+}
+
+// Body declares a required request body in the given content type.
+func (op *Operation) Body(contentType, description string, schema *Schema) *Operation {
+	if op.RequestBody == nil {
+		op.RequestBody = &RequestBody{Required: true, Content: map[string]MediaType{}}
+	}
+	if description != "" {
+		op.RequestBody.Description = description
+	}
+	op.RequestBody.Content[contentType] = MediaType{Schema: schema}
+	return op
+}
+
+// pathParams returns the names of the template segments in path.
+func pathParams(path string) []string {
+	var names []string
+	for _, segment := range strings.Split(path, "/") {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			names = append(names, segment[1:len(segment)-1])
+		}
+	}
+	return names
+}
@@ -0,0 +1,138 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type note struct {
+	ID       int        `json:"id"`
+	Text     string     `json:"text"`
+	Tags     []string   `json:"tags,omitempty"`
+	Parent   *note      `json:"parent,omitempty"`
+	Due      *time.Time `json:"due,omitempty"`
+	internal string
+	Secret   string `json:"-"`
+}
+
+type pinnedNote struct {
+	note
+	Pinned bool `json:"pinned"`
+}
+
+func TestSchemaOf(t *testing.T) {
+	doc := New(Info{Title: "Notes", Version: "1"})
+
+	ref := doc.SchemaOf(pinnedNote{})
+
+	assert.Equal(t, "#/components/schemas/pinnedNote", ref.Ref)
+	pinned := doc.Components.Schemas["pinnedNote"]
+	assert.ElementsMatch(t, []string{"id", "text", "tags", "parent", "due", "pinned"}, keys(pinned.Properties))
+	assert.Equal(t, "integer", pinned.Properties["id"].Type)
+	assert.Equal(t, &Schema{Type: "array", Items: String(), Nullable: true}, pinned.Properties["tags"])
+	assert.Equal(t, &Schema{Type: "string", Format: "date-time", Nullable: true}, pinned.Properties["due"])
+
+	// The self-reference resolves to the component rather than recursing.
+	parent := doc.Components.Schemas["note"].Properties["parent"]
+	assert.True(t, parent.Nullable)
+	assert.Equal(t, "#/components/schemas/note", parent.AllOf[0].Ref)
+}
+
+func newNotesValidator() *Validator {
+	doc := New(Info{Title: "Notes", Version: "1"})
+	doc.Add(http.MethodPost, "/notes", (&Operation{OperationID: "createNote"}).
+		Body("application/json", "", Require(doc.SchemaOf(note{}), "text")).
+		Query("dry_run", "", Boolean()))
+	doc.Add(http.MethodGet, "/notes/{id}", &Operation{OperationID: "getNote"})
+	doc.Add(http.MethodGet, "/notes/latest", (&Operation{OperationID: "getLatestNote"}).
+		Query("order", "", Enum("asc", "desc")))
+	doc.Add(http.MethodPost, "/notes/{id}/files", (&Operation{OperationID: "uploadFile"}).
+		Body("multipart/form-data", "", Binary()))
+	return NewValidator(doc, 1<<20)
+}
+
+func validate(t *testing.T, v *Validator, method, target, contentType, body string) []ValidationError {
+	t.Helper()
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	if contentType != "" {
+		r.Header.Set("Content-Type", contentType)
+	}
+	problems, err := v.Validate(r)
+	assert.NoError(t, err)
+	return problems
+}
+
+func TestValidate_Body(t *testing.T) {
+	v := newNotesValidator()
+
+	r := httptest.NewRequest(http.MethodPost, "/notes", strings.NewReader(`{"text":"hi","tags":["a"],"parent":null}`))
+	problems, err := v.Validate(r)
+	assert.NoError(t, err)
+	assert.Empty(t, problems)
+
+	// The body is still there for the handler.
+	var decoded note
+	assert.NoError(t, json.NewDecoder(r.Body).Decode(&decoded))
+	assert.Equal(t, "hi", decoded.Text)
+
+	problems = validate(t, v, http.MethodPost, "/notes", "", `{"id":1.5,"tags":["a",2],"parent":{"text":7},"due":"tomorrow"}`)
+	assert.Equal(t, []ValidationError{
+		{In: "body", Field: "due", Message: "must be an RFC 3339 date-time"},
+		{In: "body", Field: "id", Message: "must be an integer"},
+		{In: "body", Field: "parent.text", Message: "must be a string, not a number"},
+		{In: "body", Field: "tags[1]", Message: "must be a string, not a number"},
+		{In: "body", Field: "text", Message: "is required"},
+	}, problems)
+
+	problems = validate(t, v, http.MethodPost, "/notes", "", `{"text":`)
+	assert.Len(t, problems, 1)
+	assert.Contains(t, problems[0].Message, "invalid JSON")
+
+	problems = validate(t, v, http.MethodPost, "/notes", "", ``)
+	assert.Equal(t, []ValidationError{{In: "body", Message: "is required"}}, problems)
+}
+
+func TestValidate_Parameters(t *testing.T) {
+	v := newNotesValidator()
+
+	assert.Empty(t, validate(t, v, http.MethodGet, "/notes/3", "", ""))
+	assert.Equal(t, []ValidationError{{In: "path", Field: "id", Message: "must be an integer"}},
+		validate(t, v, http.MethodGet, "/notes/three", "", ""))
+
+	// A literal segment wins over a template.
+	assert.Empty(t, validate(t, v, http.MethodGet, "/notes/latest?order=desc", "", ""))
+	assert.Equal(t, []ValidationError{{In: "query", Field: "order", Message: "must be one of asc, desc"}},
+		validate(t, v, http.MethodGet, "/notes/latest?order=newest", "", ""))
+
+	assert.Equal(t, []ValidationError{{In: "query", Field: "dry_run", Message: "must be true or false"}},
+		validate(t, v, http.MethodPost, "/notes?dry_run=maybe", "application/json", `{"text":"hi"}`))
+
+	// Requests the document does not describe are left to the router.
+	assert.Empty(t, validate(t, v, http.MethodDelete, "/notes/3", "", ""))
+}
+
+func TestValidate_ContentType(t *testing.T) {
+	v := newNotesValidator()
+
+	assert.Empty(t, validate(t, v, http.MethodPost, "/notes/3/files", "multipart/form-data; boundary=x", "--x--"))
+
+	problems := validate(t, v, http.MethodPost, "/notes/3/files", "application/json", `{}`)
+	if assert.Len(t, problems, 1) {
+		assert.True(t, problems[0].UnsupportedMediaType())
+		assert.Equal(t, "application/json is not accepted, expected multipart/form-data", problems[0].Message)
+	}
+}
+
+func keys(m map[string]*Schema) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	return names
+}
@@ -0,0 +1,215 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Schema is a JSON Schema, in the dialect OpenAPI 3.0 uses.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	AllOf                []*Schema          `json:"allOf,omitempty"`
+	OneOf                []*Schema          `json:"oneOf,omitempty"`
+}
+
+// Integer returns an integer schema.
+func Integer() *Schema { return &Schema{Type: "integer"} }
+
+// String returns a string schema.
+func String() *Schema { return &Schema{Type: "string"} }
+
+// Boolean returns a boolean schema.
+func Boolean() *Schema { return &Schema{Type: "boolean"} }
+
+// Binary returns the schema of a raw file body.
+func Binary() *Schema { return &Schema{Type: "string", Format: "binary"} }
+
+// ArrayOf returns an array schema.
+func ArrayOf(items *Schema) *Schema { return &Schema{Type: "array", Items: items} }
+
+// Enum returns a string schema limited to values.
+func Enum(values ...string) *Schema {
+	schema := String()
+	for _, value := range values {
+		schema.Enum = append(schema.Enum, value)
+	}
+	return schema
+}
+
+// Between limits a number, or the length of an array, to [min, max].
+func (s *Schema) Between(min, max int) *Schema {
+	if s.Type == "array" {
+		s.MinItems, s.MaxItems = &min, &max
+		return s
+	}
+	low, high := float64(min), float64(max)
+	s.Minimum, s.Maximum = &low, &high
+	return s
+}
+
+// Min sets the lowest number allowed.
+func (s *Schema) Min(min int) *Schema {
+	low := float64(min)
+	s.Minimum = &low
+	return s
+}
+
+// Describe sets the schema's description.
+func (s *Schema) Describe(description string) *Schema {
+	s.Description = description
+	return s
+}
+
+// Require returns a schema matching s that also requires the given
+// properties to be present.
+func Require(s *Schema, names ...string) *Schema {
+	return &Schema{AllOf: []*Schema{s, {Required: names}}}
+}
+
+// resolve follows a $ref to the component schema it names.
+func (doc *Document) resolve(s *Schema) *Schema {
+	for s != nil && s.Ref != "" {
+		s = doc.Components.Schemas[strings.TrimPrefix(s.Ref, "#/components/schemas/")]
+	}
+	if s == nil {
+		return &Schema{}
+	}
+	return s
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+// SchemaOf returns the schema of the JSON encoding of v's type. Named
+// struct types are added to the document's components and referenced.
+// Fields follow encoding/json: json tags name them, "-" drops them and
+// embedded structs are flattened. No property is required; use Require.
+func (doc *Document) SchemaOf(v any) *Schema {
+	return doc.schemaOf(reflect.TypeOf(v))
+}
+
+func (doc *Document) schemaOf(t reflect.Type) *Schema {
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case rawMessageType:
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return Boolean()
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return String()
+	case reflect.Interface:
+		return &Schema{}
+	case reflect.Pointer:
+		elem := doc.schemaOf(t.Elem())
+		if elem.Ref != "" {
+			// Siblings of $ref are ignored, so wrap it to allow null.
+			return &Schema{Nullable: true, AllOf: []*Schema{elem}}
+		}
+		elem.Nullable = true
+		return elem
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		// A nil slice or map encodes as null.
+		return &Schema{Type: "array", Items: doc.schemaOf(t.Elem()), Nullable: true}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: doc.schemaOf(t.Elem()), Nullable: true}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return doc.structSchema(t)
+		}
+		return doc.component(t)
+	}
+	panic(fmt.Sprintf("openapi: no schema for %s", t))
+}
+
+// component adds a named struct type to the components, once, and returns
+// a reference to it.
+func (doc *Document) component(t reflect.Type) *Schema {
+	name := t.Name()
+	ref := &Schema{Ref: "#/components/schemas/" + name}
+
+	if existing, ok := doc.schemaTypes[name]; ok {
+		if existing != t {
+			panic(fmt.Sprintf("openapi: %s and %s both want the schema name %s", existing, t, name))
+		}
+		return ref
+	}
+
+	// Register before building, so self-referencing types terminate.
+	doc.schemaTypes[name] = t
+	schema := &Schema{}
+	doc.Components.Schemas[name] = schema
+	*schema = *doc.structSchema(t)
+	return ref
+}
+
+func (doc *Document) structSchema(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	doc.addFields(schema, t)
+	return schema
+}
+
+func (doc *Document) addFields(schema *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				doc.addFields(schema, embedded)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+		property := doc.schemaOf(field.Type)
+		if strings.Contains(opts, "string") && property.Ref == "" {
+			property = String()
+		}
+		schema.Properties[name] = property
+	}
+}
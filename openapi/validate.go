@@ -0,0 +1,425 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ValidationError is one way a request does not match the document. In is
+// where the problem is: path, query, header or body. Field names the
+// parameter, or the JSON path within the body.
+type ValidationError struct {
+	In      string `json:"in"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+func (e ValidationError) Error() string {
+	if e.Field == "" {
+		return e.In + ": " + e.Message
+	}
+	return e.In + " " + e.Field + ": " + e.Message
+}
+
+// UnsupportedMediaType reports whether the error is that the body is not
+// in any content type the operation accepts.
+func (e ValidationError) UnsupportedMediaType() bool {
+	return e.In == "header" && e.Field == "Content-Type"
+}
+
+// Validator checks requests against a document.
+type Validator struct {
+	doc    *Document
+	routes []route
+	// maxBody bounds how much of a JSON body is read to validate it.
+	maxBody int64
+}
+
+type route struct {
+	method   string
+	segments []string
+	op       *Operation
+	// static is the number of literal segments, so /tasks/order is
+	// preferred over /tasks/{id}.
+	static int
+}
+
+// NewValidator returns a validator for the document's operations. Bodies
+// larger than maxBody are left for the handler to reject.
+func NewValidator(doc *Document, maxBody int64) *Validator {
+	v := &Validator{doc: doc, maxBody: maxBody}
+	for path, item := range doc.Paths {
+		segments := strings.Split(strings.Trim(path, "/"), "/")
+		static := 0
+		for _, segment := range segments {
+			if !strings.HasPrefix(segment, "{") {
+				static++
+			}
+		}
+		for method, op := range *item {
+			v.routes = append(v.routes, route{method: strings.ToUpper(method), segments: segments, op: op, static: static})
+		}
+	}
+	sort.Slice(v.routes, func(i, j int) bool { return v.routes[i].static > v.routes[j].static })
+	return v
+}
+
+// Validate checks the request against its operation and returns every
+// problem found. Requests the document does not describe pass, for the
+// router to answer. A JSON body is read and replaced, so the handler can
+// still read it.
+func (v *Validator) Validate(r *http.Request) ([]ValidationError, error) {
+	op, pathValues := v.match(r.Method, r.URL.Path)
+	if op == nil {
+		return nil, nil
+	}
+
+	var problems []ValidationError
+	query := r.URL.Query()
+	for _, param := range op.Parameters {
+		var values []string
+		switch param.In {
+		case "path":
+			values = []string{pathValues[param.Name]}
+		case "query":
+			values = query[param.Name]
+		case "header":
+			values = r.Header.Values(param.Name)
+		default:
+			continue
+		}
+
+		if len(values) == 0 || values[0] == "" {
+			if param.Required {
+				problems = append(problems, ValidationError{In: param.In, Field: param.Name, Message: "is required"})
+			}
+			continue
+		}
+		for _, value := range values {
+			err := v.checkParam(param.Schema, value)
+			if err != nil {
+				problems = append(problems, ValidationError{In: param.In, Field: param.Name, Message: err.Error()})
+			}
+		}
+	}
+
+	if op.RequestBody != nil {
+		bodyProblems, err := v.checkBody(r, op)
+		if err != nil {
+			return nil, err
+		}
+		problems = append(problems, bodyProblems...)
+	}
+	return problems, nil
+}
+
+// match finds the operation for a request, and the values of its path
+// parameters.
+func (v *Validator) match(method, path string) (*Operation, map[string]string) {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for _, route := range v.routes {
+		if route.method != method || len(route.segments) != len(segments) {
+			continue
+		}
+		values := map[string]string{}
+		matched := true
+		for i, segment := range route.segments {
+			if strings.HasPrefix(segment, "{") {
+				values[segment[1:len(segment)-1]] = segments[i]
+				continue
+			}
+			if segment != segments[i] {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return route.op, values
+		}
+	}
+	return nil, nil
+}
+
+// checkParam checks a parameter's string value against its schema.
+func (v *Validator) checkParam(schema *Schema, value string) error {
+	schema = v.doc.resolve(schema)
+	var decoded any = value
+	switch schema.Type {
+	case "integer":
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return errors.New("must be an integer")
+		}
+		decoded = json.Number(strconv.FormatInt(n, 10))
+	case "number":
+		_, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return errors.New("must be a number")
+		}
+		decoded = json.Number(value)
+	case "boolean":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return errors.New("must be true or false")
+		}
+		decoded = b
+	}
+
+	problems := v.checkValue(schema, decoded, "")
+	if len(problems) > 0 {
+		return errors.New(problems[0].Message)
+	}
+	return nil
+}
+
+func (v *Validator) checkBody(r *http.Request, op *Operation) ([]ValidationError, error) {
+	body := op.RequestBody
+	if r.ContentLength == 0 {
+		if body.Required {
+			return []ValidationError{{In: "body", Message: "is required"}}, nil
+		}
+		return nil, nil
+	}
+
+	contentType := r.Header.Get("Content-Type")
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if mediaType == "" {
+		// The JSON handlers do not look at the header, so neither does this.
+		mediaType = "application/json"
+	}
+
+	media, ok := body.Content[mediaType]
+	if !ok {
+		accepted := make([]string, 0, len(body.Content))
+		for name := range body.Content {
+			accepted = append(accepted, name)
+		}
+		sort.Strings(accepted)
+		return []ValidationError{{
+			In:      "header",
+			Field:   "Content-Type",
+			Message: fmt.Sprintf("%s is not accepted, expected %s", mediaType, strings.Join(accepted, " or ")),
+		}}, nil
+	}
+	if mediaType != "application/json" || op.RawBody {
+		// Only JSON bodies are checked against their schema.
+		return nil, nil
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r.Body, v.maxBody+1))
+	if err != nil {
+		return nil, err
+	}
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(data), r.Body), r.Body}
+	if int64(len(data)) > v.maxBody {
+		return nil, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value any
+	err = decoder.Decode(&value)
+	if err != nil {
+		return []ValidationError{{In: "body", Message: "invalid JSON: " + err.Error()}}, nil
+	}
+	if decoder.More() {
+		return []ValidationError{{In: "body", Message: "invalid JSON: more than one value"}}, nil
+	}
+	return v.checkValue(media.Schema, value, ""), nil
+}
+
+// checkValue checks a decoded JSON value against a schema. Numbers are
+// json.Number.
+func (v *Validator) checkValue(schema *Schema, value any, path string) []ValidationError {
+	if schema == nil {
+		return nil
+	}
+	if schema.Ref != "" {
+		return v.checkValue(v.doc.resolve(schema), value, path)
+	}
+	if value == nil {
+		if schema.Nullable || (schema.Type == "" && len(schema.AllOf) == 0 && len(schema.OneOf) == 0) {
+			return nil
+		}
+	}
+
+	var problems []ValidationError
+	fail := func(format string, args ...any) {
+		problems = append(problems, ValidationError{In: "body", Field: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	for _, sub := range schema.AllOf {
+		problems = append(problems, v.checkValue(sub, value, path)...)
+	}
+	if len(schema.OneOf) > 0 {
+		matches := 0
+		for _, sub := range schema.OneOf {
+			if len(v.checkValue(sub, value, path)) == 0 {
+				matches++
+			}
+		}
+		if matches != 1 {
+			fail("must match exactly one of %d alternatives, matches %d", len(schema.OneOf), matches)
+		}
+	}
+
+	switch schema.Type {
+	case "":
+		if len(schema.Required) > 0 {
+			if object, ok := value.(map[string]any); ok {
+				problems = append(problems, v.checkRequired(schema, object, path)...)
+			}
+		}
+	case "object":
+		object, ok := value.(map[string]any)
+		if !ok {
+			fail("must be an object, not %s", jsonType(value))
+			break
+		}
+		problems = append(problems, v.checkRequired(schema, object, path)...)
+		names := make([]string, 0, len(object))
+		for name := range object {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			property, known := schema.Properties[name]
+			if !known {
+				property = schema.AdditionalProperties
+			}
+			problems = append(problems, v.checkValue(property, object[name], joinPath(path, name))...)
+		}
+	case "array":
+		items, ok := value.([]any)
+		if !ok {
+			fail("must be an array, not %s", jsonType(value))
+			break
+		}
+		if schema.MinItems != nil && len(items) < *schema.MinItems {
+			fail("must have at least %d items", *schema.MinItems)
+		}
+		if schema.MaxItems != nil && len(items) > *schema.MaxItems {
+			fail("must have at most %d items", *schema.MaxItems)
+		}
+		for i, item := range items {
+			problems = append(problems, v.checkValue(schema.Items, item, fmt.Sprintf("%s[%d]", path, i))...)
+		}
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			fail("must be a string, not %s", jsonType(value))
+			break
+		}
+		if schema.MinLength != nil && len([]rune(s)) < *schema.MinLength {
+			fail("must be at least %d characters", *schema.MinLength)
+		}
+		if schema.Format == "date-time" {
+			if _, err := time.Parse(time.RFC3339, s); err != nil {
+				fail("must be an RFC 3339 date-time")
+			}
+		}
+		if len(schema.Enum) > 0 && !inEnum(schema.Enum, s) {
+			fail("must be one of %s", enumList(schema.Enum))
+		}
+	case "integer", "number":
+		n, ok := value.(json.Number)
+		if !ok {
+			fail("must be a%s %s, not %s", article(schema.Type), schema.Type, jsonType(value))
+			break
+		}
+		f, err := n.Float64()
+		if err != nil {
+			fail("must be a number")
+			break
+		}
+		if schema.Type == "integer" {
+			if _, err := n.Int64(); err != nil || f != math.Trunc(f) {
+				fail("must be an integer")
+				break
+			}
+		}
+		if schema.Minimum != nil && f < *schema.Minimum {
+			fail("must be at least %v", *schema.Minimum)
+		}
+		if schema.Maximum != nil && f > *schema.Maximum {
+			fail("must be at most %v", *schema.Maximum)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			fail("must be a boolean, not %s", jsonType(value))
+		}
+	}
+	return problems
+}
+
+func (v *Validator) checkRequired(schema *Schema, object map[string]any, path string) []ValidationError {
+	var problems []ValidationError
+	for _, name := range schema.Required {
+		if _, ok := object[name]; !ok {
+			problems = append(problems, ValidationError{In: "body", Field: joinPath(path, name), Message: "is required"})
+		}
+	}
+	return problems
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func jsonType(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "a boolean"
+	case json.Number:
+		return "a number"
+	case string:
+		return "a string"
+	case []any:
+		return "an array"
+	case map[string]any:
+		return "an object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func article(word string) string {
+	if strings.HasPrefix(word, "i") {
+		return "n"
+	}
+	return ""
+}
+
+func inEnum(enum []any, s string) bool {
+	for _, value := range enum {
+		if value == s {
+			return true
+		}
+	}
+	return false
+}
+
+func enumList(enum []any) string {
+	values := make([]string, len(enum))
+	for i, value := range enum {
+		values[i] = fmt.Sprint(value)
+	}
+	return strings.Join(values, ", ")
+}
@@ -0,0 +1,133 @@
+// Package storage holds the blob stores used for task attachments.
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// ErrBlobNotFound is returned when no blob is stored under a key.
+var ErrBlobNotFound = errors.New("blob not found")
+
+// Blob is an open blob. It can seek, so it can serve range requests.
+type Blob interface {
+	io.ReadSeekCloser
+}
+
+// BlobStore stores opaque content under a key chosen by the store.
+type BlobStore interface {
+	// Put stores the content read from r and returns its key and size.
+	Put(r io.Reader) (key string, size int64, err error)
+	// Open returns the content stored under key.
+	Open(key string) (Blob, error)
+	// Delete removes the content stored under key. Deleting a missing key is not an error.
+	Delete(key string) error
+}
+
+var sha256Key = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// LocalBlobStore keeps blobs on the local filesystem, content-addressed by
+// their SHA-256, so identical content is only stored once. A blob with key
+// "ab12..." lives at <Root>/ab/ab12....
+type LocalBlobStore struct {
+	Root string
+}
+
+// NewLocalBlobStore returns a store rooted at dir, creating it if needed.
+func NewLocalBlobStore(dir string) (*LocalBlobStore, error) {
+	err := os.MkdirAll(dir, 0o750)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LocalBlobStore{Root: dir}, nil
+}
+
+func (store *LocalBlobStore) path(key string) (string, error) {
+	if !sha256Key.MatchString(key) {
+		return "", fmt.Errorf("%w: invalid key %q", ErrBlobNotFound, key)
+	}
+	return filepath.Join(store.Root, key[:2], key), nil
+}
+
+// Put streams r to a temporary file while hashing it, then moves the file
+// to its content address. If that content is already stored, the copy is
+// discarded.
+func (store *LocalBlobStore) Put(r io.Reader) (string, int64, error) {
+	tmp, err := os.CreateTemp(store.Root, ".upload-*")
+	if err != nil {
+		return "", 0, err
+	}
+	defer os.Remove(tmp.Name())
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hash), r)
+	if err != nil {
+		tmp.Close()
+		return "", 0, err
+	}
+
+	err = tmp.Close()
+	if err != nil {
+		return "", 0, err
+	}
+
+	key := hex.EncodeToString(hash.Sum(nil))
+	path, err := store.path(key)
+	if err != nil {
+		return "", 0, err
+	}
+
+	if _, err := os.Stat(path); err == nil {
+		return key, size, nil
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0o750)
+	if err != nil {
+		return "", 0, err
+	}
+
+	err = os.Rename(tmp.Name(), path)
+	if err != nil {
+		return "", 0, err
+	}
+
+	return key, size, nil
+}
+
+func (store *LocalBlobStore) Open(key string) (Blob, error) {
+	path, err := store.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrBlobNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return file, nil
+}
+
+func (store *LocalBlobStore) Delete(key string) error {
+	path, err := store.path(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
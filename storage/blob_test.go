@@ -0,0 +1,64 @@
+package storage
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalBlobStore_PutStoresDuplicatesOnce(t *testing.T) {
+	store, err := NewLocalBlobStore(t.TempDir())
+	assert.NoError(t, err)
+
+	key, size, err := store.Put(strings.NewReader("hello"))
+	assert.NoError(t, err)
+	assert.Equal(t, "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824", key)
+	assert.Equal(t, int64(5), size)
+
+	again, _, err := store.Put(strings.NewReader("hello"))
+	assert.NoError(t, err)
+	assert.Equal(t, key, again)
+
+	// One blob directory and no leftover temporary files.
+	entries, err := os.ReadDir(store.Root)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+	blobs, err := os.ReadDir(filepath.Join(store.Root, "2c"))
+	assert.NoError(t, err)
+	assert.Len(t, blobs, 1)
+}
+
+func TestLocalBlobStore_OpenSeeksAndDeletes(t *testing.T) {
+	store, err := NewLocalBlobStore(t.TempDir())
+	assert.NoError(t, err)
+
+	key, _, err := store.Put(strings.NewReader("0123456789"))
+	assert.NoError(t, err)
+
+	blob, err := store.Open(key)
+	assert.NoError(t, err)
+	_, err = blob.Seek(4, io.SeekStart)
+	assert.NoError(t, err)
+	rest, err := io.ReadAll(blob)
+	assert.NoError(t, err)
+	assert.Equal(t, "456789", string(rest))
+	assert.NoError(t, blob.Close())
+
+	assert.NoError(t, store.Delete(key))
+	assert.NoError(t, store.Delete(key))
+
+	_, err = store.Open(key)
+	assert.ErrorIs(t, err, ErrBlobNotFound)
+}
+
+func TestLocalBlobStore_RejectsInvalidKeys(t *testing.T) {
+	store, err := NewLocalBlobStore(t.TempDir())
+	assert.NoError(t, err)
+
+	_, err = store.Open("../../etc/passwd")
+	assert.ErrorIs(t, err, ErrBlobNotFound)
+}
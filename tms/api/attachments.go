@@ -0,0 +1,366 @@
+package main
+
+import (
+	"bufio"
+	"database/sql"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+
+	"tms.zinkworks.com/model"
+	"tms.zinkworks.com/storage"
+)
+
+// swagger:route POST /tasks/{id}/attachments attachments uploadTaskAttachmentEndpoint
+// Attach a file to a task.
+// The file is sent as the "file" part of a multipart/form-data body. Its type is detected
+// from its content and must be one of the server's allowed types.
+// Consumes:
+// - multipart/form-data
+// Produces:
+// - application/json
+// Schemes: http, https
+// responses:
+//
+//	201: taskAttachmentResponse
+//	400: badRequestError
+//	401: unauthorizedError
+//	404: notFoundError
+//	413: payloadTooLargeError
+//	415: unsupportedMediaTypeError
+//	500: internalServerError
+//	503: uploadInterruptedError
+func (app *application) uploadTaskAttachmentHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	// Parse the task ID from the URL parameters.
+	taskID, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	taskDto := model.TaskDto{DB: app.db, ActorID: app.contextGetUser(r)}
+
+	_, err = taskDto.GetTask(taskID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.NotFound(w, r)
+		} else {
+			http.Error(w, "Error fetching task", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	maxSize := app.config.attachments.maxSize
+
+	// Leave room for the multipart headers around the file itself.
+	r.Body = http.MaxBytesReader(w, r.Body, maxSize+1<<20)
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		http.Error(w, "Expected a multipart/form-data body", http.StatusBadRequest)
+		return
+	}
+
+	var part io.Reader
+	var filename string
+	for {
+		p, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			app.attachmentReadError(w, err)
+			return
+		}
+		if p.FormName() == "file" {
+			part, filename = p, filepath.Base(p.FileName())
+			break
+		}
+	}
+	if part == nil {
+		http.Error(w, "Missing file part", http.StatusBadRequest)
+		return
+	}
+	if filename == "" || filename == "." || filename == string(filepath.Separator) {
+		filename = "attachment"
+	}
+
+	// Sniff the type from the content instead of trusting the client.
+	buffered := bufio.NewReaderSize(part, 512)
+	head, err := buffered.Peek(512)
+	if err != nil && err != io.EOF {
+		app.attachmentReadError(w, err)
+		return
+	}
+	contentType := http.DetectContentType(head)
+	if !app.attachmentTypeAllowed(contentType) {
+		http.Error(w, "Attachments of type "+contentType+" are not allowed", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	// The upload can be slow, so the blob is stored before taking the lock,
+	// which would otherwise hold up every release for as long.
+	key, size, err := app.blobs.Put(io.LimitReader(buffered, maxSize+1))
+	if err != nil {
+		app.attachmentReadError(w, err)
+		return
+	}
+
+	if size > maxSize {
+		app.releaseBlob(key)
+		http.Error(w, "Attachment is too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	attachment := model.TaskAttachment{
+		TaskID:      taskID,
+		Filename:    filename,
+		ContentType: contentType,
+		Size:        size,
+		SHA256:      key,
+		UploadedBy:  app.contextGetUser(r),
+		CreatedAt:   time.Now(),
+	}
+
+	// Hold the read lock while the metadata is stored, so the blob cannot be
+	// released as unreferenced in between. A release of the same content
+	// that ran after the Put may have deleted it already, so check first.
+	app.blobMu.RLock()
+	blob, err := app.blobs.Open(key)
+	if err == nil {
+		blob.Close()
+		err = taskDto.InsertTaskAttachment(&attachment)
+	}
+	app.blobMu.RUnlock()
+	if errors.Is(err, storage.ErrBlobNotFound) {
+		w.Header().Set("Retry-After", "1")
+		http.Error(w, "The attachment was removed while it was uploaded, please try again", http.StatusServiceUnavailable)
+		return
+	}
+	if err != nil {
+		app.releaseBlob(key)
+		app.logger.Printf("Failed to attach file to task %d: %v", taskID, err)
+		http.Error(w, "Error saving attachment", http.StatusInternalServerError)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, attachment, nil)
+	if err != nil {
+		app.logger.Print(err)
+		http.Error(w, "The server encountered a problem and could not process your request", http.StatusInternalServerError)
+	}
+}
+
+// swagger:route GET /tasks/{id}/attachments attachments getTaskAttachmentsEndpoint
+// Get the attachments of a task.
+// Produces:
+// - application/json
+// Schemes: http, https
+// responses:
+//
+//	200: allTaskAttachmentsResponse
+//	400: invalidTaskIdError
+//	401: unauthorizedError
+//	404: notFoundError
+//	500: internalServerError
+func (app *application) getTaskAttachmentsHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	// Parse the task ID from the URL parameters.
+	taskID, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	taskDto := model.TaskDto{DB: app.db}
+
+	_, err = taskDto.GetTask(taskID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.NotFound(w, r)
+		} else {
+			http.Error(w, "Error fetching task", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	attachments, err := taskDto.GetTaskAttachments(taskID)
+	if err != nil {
+		http.Error(w, "Error fetching attachments", http.StatusInternalServerError)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, attachments, nil)
+	if err != nil {
+		app.logger.Print(err)
+		http.Error(w, "The server encountered a problem and could not process your request", http.StatusInternalServerError)
+	}
+}
+
+// swagger:route GET /tasks/{id}/attachments/{attachmentID} attachments downloadTaskAttachmentEndpoint
+// Download an attachment.
+// Supports Range requests, so large files can be fetched in parts or resumed.
+// Produces:
+// - application/octet-stream
+// Schemes: http, https
+// responses:
+//
+//	200: attachmentContentResponse
+//	206: attachmentContentResponse
+//	400: invalidIdError
+//	401: unauthorizedError
+//	404: notFoundError
+//	416: rangeNotSatisfiableError
+//	500: internalServerError
+func (app *application) downloadTaskAttachmentHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	taskID, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	attachmentID, err := strconv.Atoi(ps.ByName("attachmentID"))
+	if err != nil {
+		http.Error(w, "Invalid attachment ID", http.StatusBadRequest)
+		return
+	}
+
+	taskDto := model.TaskDto{DB: app.db}
+
+	_, err = taskDto.GetTask(taskID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.NotFound(w, r)
+		} else {
+			http.Error(w, "Error fetching task", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	attachment, err := taskDto.GetTaskAttachment(taskID, attachmentID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.NotFound(w, r)
+		} else {
+			http.Error(w, "Error fetching attachment", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	blob, err := app.blobs.Open(attachment.SHA256)
+	if err != nil {
+		app.logger.Printf("Failed to open blob of attachment %d: %v", attachment.ID, err)
+		http.Error(w, "Error reading attachment", http.StatusInternalServerError)
+		return
+	}
+	defer blob.Close()
+
+	w.Header().Set("Content-Type", attachment.ContentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("ETag", `"`+attachment.SHA256+`"`)
+
+	// ServeContent handles Range, If-Range and the conditional headers.
+	http.ServeContent(w, r, "", attachment.CreatedAt, blob)
+}
+
+// swagger:route DELETE /tasks/{id}/attachments/{attachmentID} attachments deleteTaskAttachmentEndpoint
+// Remove an attachment from a task.
+// The stored content is deleted once no other attachment uses it.
+// Produces:
+// - application/json
+// Schemes: http, https
+// Responses:
+//
+//	200: successfullyDeletedResponse
+//	400: invalidIdError
+//	401: unauthorizedError
+//	404: notFoundError
+//	500: internalServerError
+func (app *application) deleteTaskAttachmentHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	taskID, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	attachmentID, err := strconv.Atoi(ps.ByName("attachmentID"))
+	if err != nil {
+		http.Error(w, "Invalid attachment ID", http.StatusBadRequest)
+		return
+	}
+
+	taskDto := model.TaskDto{DB: app.db, ActorID: app.contextGetUser(r)}
+
+	attachment, err := taskDto.DeleteTaskAttachment(taskID, attachmentID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.NotFound(w, r)
+		} else {
+			app.logger.Printf("Failed to delete attachment %d of task %d: %v", attachmentID, taskID, err)
+			http.Error(w, "Error deleting attachment", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	app.releaseBlob(attachment.SHA256)
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// attachmentTypeAllowed reports whether the detected content type is in the
+// configured allow list. Parameters such as charset are ignored.
+func (app *application) attachmentTypeAllowed(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	for _, allowed := range app.config.attachments.allowedTypes {
+		if strings.EqualFold(mediaType, allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// attachmentReadError reports a failure to read an upload, telling a body
+// that went over the size limit apart from other errors.
+func (app *application) attachmentReadError(w http.ResponseWriter, err error) {
+	var maxBytesError *http.MaxBytesError
+	if errors.As(err, &maxBytesError) {
+		http.Error(w, "Attachment is too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	app.logger.Printf("Failed to read attachment: %v", err)
+	http.Error(w, "Error reading attachment", http.StatusBadRequest)
+}
+
+// releaseBlob deletes a blob from the store if no attachment uses it any more.
+func (app *application) releaseBlob(key string) {
+	app.blobMu.Lock()
+	defer app.blobMu.Unlock()
+
+	taskDto := model.TaskDto{DB: app.db}
+
+	referenced, err := taskDto.IsBlobReferenced(key)
+	if err != nil {
+		app.logger.Printf("Failed to check references to blob %s: %v", key, err)
+		return
+	}
+	if referenced {
+		return
+	}
+
+	err = app.blobs.Delete(key)
+	if err != nil && !errors.Is(err, storage.ErrBlobNotFound) {
+		app.logger.Printf("Failed to delete blob %s: %v", key, err)
+	}
+}
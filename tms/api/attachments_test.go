@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/assert"
+
+	"tms.zinkworks.com/storage"
+)
+
+func TestUploadAttachment_DoesNotHoldUpReleases(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	app := &application{logger: log.New(io.Discard, "", 0), db: db, blobs: &storage.LocalBlobStore{Root: t.TempDir()}}
+	app.config.attachments.maxSize = 1 << 20
+	app.config.attachments.allowedTypes = []string{"text/plain"}
+
+	now := time.Now()
+	mock.ExpectQuery("SELECT t.id").
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "description", "completed", "created_at", "updated_at", "assigned_user_id", "parent_task_id", "project_id", "items"}).
+			AddRow(3, "Task 3", "", false, now, now, 0, 0, 1, `{}`))
+
+	// The body arrives in two halves, with a release of other content in between.
+	body, bodyWriter := io.Pipe()
+	form := multipart.NewWriter(bodyWriter)
+	req := httptest.NewRequest(http.MethodPost, "/tasks/3/attachments", body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	res := httptest.NewRecorder()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		app.uploadTaskAttachmentHandler(res, req, httprouter.Params{{Key: "id", Value: "3"}})
+	}()
+
+	part, err := form.CreateFormFile("file", "notes.txt")
+	assert.NoError(t, err)
+	_, err = part.Write(bytes.Repeat([]byte("first half "), 100))
+	assert.NoError(t, err)
+
+	other := strings.Repeat("0", 64)
+	mock.ExpectQuery("SELECT EXISTS \\(SELECT 1 FROM task_attachment WHERE sha256 = \\$1\\)").
+		WithArgs(other).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	released := make(chan struct{})
+	go func() {
+		app.releaseBlob(other)
+		close(released)
+	}()
+	select {
+	case <-released:
+	case <-time.After(5 * time.Second):
+		t.Fatal("a release waited for an upload that was still streaming")
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO task_attachment").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectExec("INSERT INTO task_event").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	_, err = part.Write([]byte("second half"))
+	assert.NoError(t, err)
+	assert.NoError(t, form.Close())
+	assert.NoError(t, bodyWriter.Close())
+	<-done
+
+	assert.Equal(t, http.StatusCreated, res.Code, res.Body.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
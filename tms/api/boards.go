@@ -0,0 +1,313 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+
+	"tms.zinkworks.com/events"
+	"tms.zinkworks.com/mail"
+	"tms.zinkworks.com/model"
+)
+
+// swagger:route POST /projects/{id}/boards boards createBoardEndpoint
+// Create a board in a project.
+// Columns are positioned in the order given, and each maps to the open or completed task state.
+// Consumes:
+// - application/json
+// Produces:
+// - application/json
+// Schemes: http, https
+// responses:
+//
+//	201: boardResponse
+//	400: badRequestError
+//	401: unauthorizedError
+//	404: notFoundError
+//	500: internalServerError
+func (app *application) createBoardHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	projectID, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid project ID", http.StatusBadRequest)
+		return
+	}
+
+	if _, ok := app.requireProjectMember(w, r, projectID); !ok {
+		return
+	}
+
+	var createBoard model.Board
+	err = json.NewDecoder(r.Body).Decode(&createBoard)
+	if err != nil || createBoard.Name == "" || len(createBoard.Columns) == 0 {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	for _, column := range createBoard.Columns {
+		if column.Name == "" || (column.State != model.ColumnStateOpen && column.State != model.ColumnStateCompleted) {
+			http.Error(w, "Every column needs a name and a state of open or completed", http.StatusBadRequest)
+			return
+		}
+	}
+
+	createBoard.ProjectID = projectID
+	createBoard.CreatedAt = time.Now()
+
+	boardDto := model.BoardDto{DB: app.db}
+
+	err = boardDto.Insert(&createBoard)
+	if err != nil {
+		http.Error(w, "Error inserting board", http.StatusInternalServerError)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, createBoard, nil)
+	if err != nil {
+		app.logger.Print(err)
+		http.Error(w, "The server encountered a problem and could not process your request", http.StatusInternalServerError)
+	}
+}
+
+// swagger:route GET /projects/{id}/boards boards getProjectBoardsEndpoint
+// Get the boards of a project.
+// Columns and cards are not included; fetch a single board for those.
+// Produces:
+// - application/json
+// Schemes: http, https
+// responses:
+//
+//	200: allBoardsResponse
+//	400: invalidIdError
+//	401: unauthorizedError
+//	404: notFoundError
+//	500: internalServerError
+func (app *application) getProjectBoardsHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	projectID, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid project ID", http.StatusBadRequest)
+		return
+	}
+
+	if _, ok := app.requireProjectMember(w, r, projectID); !ok {
+		return
+	}
+
+	boardDto := model.BoardDto{DB: app.db}
+
+	boards, err := boardDto.GetBoardsByProjectID(projectID)
+	if err != nil {
+		http.Error(w, "Error fetching boards", http.StatusInternalServerError)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, boards, nil)
+	if err != nil {
+		app.logger.Print(err)
+		http.Error(w, "The server encountered a problem and could not process your request", http.StatusInternalServerError)
+	}
+}
+
+// swagger:route GET /boards/{id} boards getBoardEndpoint
+// Get a board with its columns and their ordered cards.
+// Produces:
+// - application/json
+// Schemes: http, https
+// responses:
+//
+//	200: boardResponse
+//	400: invalidIdError
+//	401: unauthorizedError
+//	404: notFoundError
+//	500: internalServerError
+func (app *application) getBoardHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	boardID, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid board ID", http.StatusBadRequest)
+		return
+	}
+
+	boardDto := model.BoardDto{DB: app.db}
+
+	board, err := boardDto.GetBoard(boardID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.NotFound(w, r)
+		} else {
+			http.Error(w, "Error fetching board", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if _, ok := app.requireProjectMember(w, r, board.ProjectID); !ok {
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, board, nil)
+	if err != nil {
+		app.logger.Print(err)
+		http.Error(w, "The server encountered a problem and could not process your request", http.StatusInternalServerError)
+	}
+}
+
+// swagger:route PATCH /boards/{id}/cards/{taskID} boards moveCardEndpoint
+// Move a task's card to a column and position.
+// Changes the card's column and rank in one step, adding the card to the board if needed.
+// The task's completed flag follows the state of the target column.
+// Consumes:
+// - application/json
+// Produces:
+// - application/json
+// Schemes: http, https
+// responses:
+//
+//	200: boardCardResponse
+//	400: badRequestError
+//	401: unauthorizedError
+//	404: notFoundError
+//	409: unfinishedBlockersError
+//	500: internalServerError
+func (app *application) moveCardHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	boardID, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid board ID", http.StatusBadRequest)
+		return
+	}
+
+	taskID, err := strconv.Atoi(ps.ByName("taskID"))
+	if err != nil {
+		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	var move model.CardMove
+	err = json.NewDecoder(r.Body).Decode(&move)
+	if err != nil || move.ColumnID == 0 || move.AfterTaskID == taskID || move.BeforeTaskID == taskID {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	boardDto := model.BoardDto{DB: app.db, ActorID: app.contextGetUser(r)}
+	taskDto := model.TaskDto{DB: app.db}
+
+	projectID, err := boardDto.GetBoardProjectID(boardID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.NotFound(w, r)
+		} else {
+			http.Error(w, "Error fetching board", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if _, ok := app.requireProjectMember(w, r, projectID); !ok {
+		return
+	}
+
+	task, err := taskDto.GetTask(taskID)
+	if err != nil || task.ProjectID != projectID {
+		http.Error(w, "Task not found in the board's project", http.StatusNotFound)
+		return
+	}
+
+	column, err := boardDto.GetColumn(boardID, move.ColumnID)
+	if err != nil {
+		http.Error(w, "Column not found on this board", http.StatusNotFound)
+		return
+	}
+
+	now := time.Now()
+	rank, changed, err := boardDto.MoveCard(boardID, taskID, move, now)
+	if err != nil {
+		switch {
+		case errors.Is(err, model.ErrTaskBlocked):
+			http.Error(w, "Task has unfinished blockers", http.StatusConflict)
+		case errors.Is(err, model.ErrCardNotOnBoard):
+			http.Error(w, "The neighbouring task is not in the target column", http.StatusBadRequest)
+		case errors.Is(err, sql.ErrNoRows):
+			http.Error(w, "Column not found on this board", http.StatusNotFound)
+		default:
+			app.logger.Printf("Failed to move task %d on board %d: %v", taskID, boardID, err)
+			http.Error(w, "Error moving card", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if changed {
+		task.Completed = column.State == model.ColumnStateCompleted
+		task.UpdatedAt = now
+
+		app.publish(events.TaskUpdated, task.ID, task.AssignedUserID, task)
+		if task.Completed {
+			app.notify(mail.TemplateTaskCompleted, task.AssignedUserID, app.contextGetUser(r), notificationData{Task: task})
+		}
+	}
+
+	card := model.BoardCard{Task: *task, ColumnID: column.ID, Rank: rank}
+
+	err = app.writeJSON(w, http.StatusOK, card, nil)
+	if err != nil {
+		app.logger.Print(err)
+		http.Error(w, "The server encountered a problem and could not process your request", http.StatusInternalServerError)
+	}
+}
+
+// swagger:route DELETE /boards/{id}/cards/{taskID} boards removeCardEndpoint
+// Take a task's card off a board.
+// The task itself is not deleted.
+// Produces:
+// - application/json
+// Schemes: http, https
+// Responses:
+//
+//	200: successfullyDeletedResponse
+//	400: invalidIdError
+//	401: unauthorizedError
+//	404: notFoundError
+//	500: internalServerError
+func (app *application) removeCardHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	boardID, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid board ID", http.StatusBadRequest)
+		return
+	}
+
+	taskID, err := strconv.Atoi(ps.ByName("taskID"))
+	if err != nil {
+		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	boardDto := model.BoardDto{DB: app.db}
+
+	projectID, err := boardDto.GetBoardProjectID(boardID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.NotFound(w, r)
+		} else {
+			http.Error(w, "Error fetching board", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if _, ok := app.requireProjectMember(w, r, projectID); !ok {
+		return
+	}
+
+	removed, err := boardDto.RemoveCard(boardID, taskID)
+	if err != nil {
+		app.logger.Printf("Failed to remove task %d from board %d: %v", taskID, boardID, err)
+		http.Error(w, "Error removing card", http.StatusInternalServerError)
+		return
+	}
+	if !removed {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"tms.zinkworks.com/events"
+	"tms.zinkworks.com/mail"
+	"tms.zinkworks.com/model"
+)
+
+// maxBulkOperations bounds the size of one bulk request.
+const maxBulkOperations = 1000
+
+// swagger:route POST /tasks/bulk tasks bulkTasksEndpoint
+// Run many task operations in one request.
+// Each operation is create, update, delete, assign or complete. Update targets a task by id;
+// delete, assign and complete take an id or a filter such as {"assigned_user_id": 5, "completed": false}.
+// In atomic mode, the default, the batch is committed only if every operation succeeds. In
+// best_effort mode each failed operation is undone on its own and the rest are committed.
+// Operations only reach tasks in the caller's projects.
+// Consumes:
+// - application/json
+// Produces:
+// - application/json
+// Schemes: http, https
+// responses:
+//
+//	200: bulkTasksResponse
+//	400: badRequestError
+//	401: unauthorizedError
+//	500: internalServerError
+func (app *application) bulkTasksHandler(w http.ResponseWriter, r *http.Request) {
+	if _, ok := app.requireUser(w, r); !ok {
+		return
+	}
+
+	var request model.BulkRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if request.Mode == "" {
+		request.Mode = model.BulkModeAtomic
+	}
+	if request.Mode != model.BulkModeAtomic && request.Mode != model.BulkModeBestEffort {
+		http.Error(w, "Invalid mode, expected atomic or best_effort", http.StatusBadRequest)
+		return
+	}
+
+	if len(request.Operations) == 0 || len(request.Operations) > maxBulkOperations {
+		http.Error(w, fmt.Sprintf("Send between 1 and %d operations", maxBulkOperations), http.StatusBadRequest)
+		return
+	}
+	for i, op := range request.Operations {
+		err = op.Validate()
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Operation %d: %v", i, err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	taskDto := model.TaskDto{DB: app.db, ActorID: app.contextGetUser(r)}
+
+	results, committed, err := taskDto.RunBulk(request.Operations, request.Mode == model.BulkModeAtomic, time.Now())
+	if err != nil {
+		app.logger.Printf("Failed to run bulk operations: %v", err)
+		http.Error(w, "Error running bulk operations", http.StatusInternalServerError)
+		return
+	}
+
+	for _, result := range results {
+		if result.Err != nil {
+			app.logger.Printf("Bulk operation %d (%s) failed: %v", result.Index, result.Op, result.Err)
+		}
+	}
+	if committed {
+		app.announceBulkResults(request.Operations, results, taskDto.ActorID)
+	}
+
+	response := model.BulkResponse{Mode: request.Mode, Committed: committed, Results: results}
+
+	err = app.writeJSON(w, http.StatusOK, response, nil)
+	if err != nil {
+		app.logger.Print(err)
+		http.Error(w, "The server encountered a problem and could not process your request", http.StatusInternalServerError)
+	}
+}
+
+// announceBulkResults publishes events and sends notifications for the
+// committed operations, as the single-task handlers do. Tasks an operation
+// left as they were are not announced.
+func (app *application) announceBulkResults(ops []model.BulkOperation, results []model.BulkResult, actorID int) {
+	taskDto := model.TaskDto{DB: app.db}
+
+	for i, result := range results {
+		if result.Status != model.BulkStatusOK {
+			continue
+		}
+
+		op := ops[i]
+		if op.Op == model.BulkCreate {
+			app.publish(events.TaskCreated, result.Task.ID, result.Task.AssignedUserID, result.Task)
+			continue
+		}
+
+		for _, change := range result.Changes {
+			if !change.Changed {
+				continue
+			}
+
+			if op.Op == model.BulkDelete {
+				app.publish(events.TaskDeleted, change.TaskID, change.AssignedUserID, map[string]int{"id": change.TaskID})
+				continue
+			}
+
+			task, err := taskDto.GetTask(change.TaskID)
+			if err != nil {
+				continue
+			}
+
+			switch op.Op {
+			case model.BulkUpdate:
+				app.publish(events.TaskUpdated, task.ID, task.AssignedUserID, task)
+			case model.BulkAssign:
+				app.publish(events.TaskAssigned, task.ID, task.AssignedUserID, task)
+				app.notify(mail.TemplateTaskAssigned, task.AssignedUserID, actorID, notificationData{Task: task})
+			case model.BulkComplete:
+				app.publish(events.TaskUpdated, task.ID, task.AssignedUserID, task)
+				app.notify(mail.TemplateTaskCompleted, task.AssignedUserID, actorID, notificationData{Task: task})
+			}
+		}
+	}
+}
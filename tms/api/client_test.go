@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"tms.zinkworks.com/events"
+	"tms.zinkworks.com/model"
+	"tms.zinkworks.com/tmsclient"
+)
+
+// newTestServer serves the real routes over a file store in a temp directory.
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	store, err := model.OpenFileTaskStore(filepath.Join(t.TempDir(), "tasks.json"))
+	assert.NoError(t, err)
+
+	app := &application{
+		logger:   log.New(io.Discard, "", 0),
+		taskFile: store,
+		hub:      events.NewHub(100),
+	}
+	app.config.auth.insecureUserHeader = true
+	server := httptest.NewServer(app.routes())
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestClient_TaskLifecycle(t *testing.T) {
+	server := newTestServer(t)
+	client := tmsclient.New(server.URL, tmsclient.WithUserID(7))
+	ctx := context.Background()
+
+	created, err := client.CreateTask(ctx, model.Task{Title: "Rotate certificates", Items: []string{"staging", "production"}})
+	assert.NoError(t, err)
+	assert.NotZero(t, created.ID)
+
+	got, err := client.GetTask(ctx, created.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"staging", "production"}, got.Items)
+
+	got.Completed = true
+	updated, err := client.UpdateTask(ctx, created.ID, *got)
+	assert.NoError(t, err)
+	assert.True(t, updated.Completed)
+
+	assigned, err := client.AssignTask(ctx, created.ID, 5)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, assigned.AssignedUserID)
+
+	tasks, err := client.ListAssignedTasks(ctx, 5, tmsclient.ListOptions{}).All()
+	assert.NoError(t, err)
+	assert.Len(t, tasks, 1)
+
+	_, err = client.AddComment(ctx, created.ID, "Staging done")
+	assert.NoError(t, err)
+	comments, err := client.ListComments(ctx, created.ID, tmsclient.ListOptions{}).All()
+	assert.NoError(t, err)
+	if assert.Len(t, comments, 1) {
+		assert.Equal(t, "Staging done", comments[0].Comment)
+	}
+
+	err = client.DeleteTask(ctx, created.ID, tmsclient.DeleteCascade)
+	assert.NoError(t, err)
+
+	_, err = client.GetTask(ctx, created.ID)
+	assert.ErrorIs(t, err, tmsclient.ErrNotFound)
+	_, err = client.AddComment(ctx, created.ID, "Production done")
+	assert.ErrorIs(t, err, tmsclient.ErrNotFound)
+	_, err = client.ListComments(ctx, created.ID, tmsclient.ListOptions{}).All()
+	assert.ErrorIs(t, err, tmsclient.ErrNotFound)
+}
+
+func TestClient_StreamEvents(t *testing.T) {
+	server := newTestServer(t)
+	client := tmsclient.New(server.URL, tmsclient.WithUserID(7))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream, err := client.StreamEvents(ctx, tmsclient.EventFilter{}, 0)
+	assert.NoError(t, err)
+	defer stream.Close()
+
+	created, err := client.CreateTask(ctx, model.Task{Title: "Rotate certificates"})
+	assert.NoError(t, err)
+
+	event, err := stream.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, "task.created", event.Type)
+	var task model.Task
+	assert.NoError(t, json.Unmarshal(event.Data, &task))
+	assert.Equal(t, created.ID, task.ID)
+	assert.Equal(t, event.ID, stream.LastEventID())
+}
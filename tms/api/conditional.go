@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"hash/fnv"
+	"net/http"
+	"strings"
+	"time"
+
+	"tms.zinkworks.com/model"
+)
+
+// resourceVersion identifies one state of a resource in a response: which
+// resource it is, and when it last changed.
+type resourceVersion struct {
+	id       int
+	modified time.Time
+}
+
+func taskVersions(tasks ...model.Task) []resourceVersion {
+	versions := make([]resourceVersion, len(tasks))
+	for i, task := range tasks {
+		versions[i] = resourceVersion{task.ID, task.UpdatedAt}
+	}
+	return versions
+}
+
+// checkNotModified sets the ETag and Last-Modified headers of a response
+// made up of resources at versions, and answers 304 Not Modified if the
+// request's If-None-Match or If-Modified-Since shows the client has it
+// already. It reports whether it did, in which case the handler is done.
+func checkNotModified(w http.ResponseWriter, r *http.Request, versions []resourceVersion) bool {
+	v := newValidators()
+	for _, version := range versions {
+		v.add(version.id, version.modified)
+	}
+	return v.notModified(w, r)
+}
+
+// validators builds the ETag and Last-Modified time of a response a
+// resource at a time, so a list need not be held to work them out.
+//
+// The ETag covers every resource, so a list's changes when one is added or
+// removed. Last-Modified is the latest change, which a removal does not
+// move; If-None-Match takes precedence when a client sends both.
+type validators struct {
+	hash         hash.Hash64
+	lastModified time.Time
+	buf          [16]byte
+}
+
+func newValidators() *validators {
+	return &validators{hash: fnv.New64a()}
+}
+
+// add adds the resource with the ID given, last changed at modified.
+func (v *validators) add(id int, modified time.Time) {
+	binary.BigEndian.PutUint64(v.buf[:], uint64(id))
+	binary.BigEndian.PutUint64(v.buf[8:], uint64(modified.UnixNano()))
+	v.hash.Write(v.buf[:])
+	if modified.After(v.lastModified) {
+		v.lastModified = modified
+	}
+}
+
+// etag is weak, as compressed and uncompressed responses are the same
+// resource but not the same bytes.
+func (v *validators) etag() string {
+	return fmt.Sprintf(`W/"%x"`, v.hash.Sum64())
+}
+
+// notModified sets the ETag and Last-Modified headers, and answers 304 Not
+// Modified if the request shows the client has the response already. It
+// reports whether it did.
+func (v *validators) notModified(w http.ResponseWriter, r *http.Request) bool {
+	etag := v.etag()
+
+	header := w.Header()
+	header.Set("ETag", etag)
+	if !v.lastModified.IsZero() {
+		header.Set("Last-Modified", v.lastModified.UTC().Format(http.TimeFormat))
+	}
+	// Clients may keep the response, but must check it is current before use.
+	header.Set("Cache-Control", "private, no-cache")
+
+	if match := r.Header.Get("If-None-Match"); match != "" {
+		if !etagMatches(match, etag) {
+			return false
+		}
+	} else {
+		since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+		// Last-Modified only has whole seconds.
+		if err != nil || v.lastModified.IsZero() || v.lastModified.Truncate(time.Second).After(since) {
+			return false
+		}
+	}
+
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// etagMatches reports whether an If-None-Match header lists etag, using the
+// weak comparison RFC 9110 asks for.
+func etagMatches(header, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
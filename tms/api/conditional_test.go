@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConditionalGet(t *testing.T) {
+	server := newTestServer(t)
+
+	get := func(path, header, value string) *http.Response {
+		req, err := http.NewRequest(http.MethodGet, server.URL+path, nil)
+		assert.NoError(t, err)
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+		res, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		res.Body.Close()
+		return res
+	}
+	send := func(method, path, body string) {
+		req, err := http.NewRequest(method, server.URL+path, bytes.NewBufferString(body))
+		assert.NoError(t, err)
+		res, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		res.Body.Close()
+	}
+
+	send(http.MethodPost, "/v1/tasks", `{"title":"Renew domain"}`)
+
+	res := get("/v1/tasks/1", "", "")
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	etag, lastModified := res.Header.Get("ETag"), res.Header.Get("Last-Modified")
+	assert.Regexp(t, `^W/"[0-9a-f]+"$`, etag)
+	assert.NotEmpty(t, lastModified)
+
+	res = get("/v1/tasks/1", "If-None-Match", `"abc", `+etag)
+	assert.Equal(t, http.StatusNotModified, res.StatusCode)
+	assert.Equal(t, etag, res.Header.Get("ETag"))
+	res = get("/v1/tasks/1", "If-Modified-Since", lastModified)
+	assert.Equal(t, http.StatusNotModified, res.StatusCode)
+	res = get("/v1/tasks/1", "If-None-Match", `W/"abc"`)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	listETag := get("/v1/tasks", "", "").Header.Get("ETag")
+	assert.Equal(t, http.StatusNotModified, get("/v1/tasks", "If-None-Match", listETag).StatusCode)
+
+	// Changing a task changes its ETag, and that of the lists it is in.
+	send(http.MethodPut, "/v1/tasks/1", `{"title":"Renew domain","completed":true}`)
+	res = get("/v1/tasks/1", "If-None-Match", etag)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.NotEqual(t, etag, res.Header.Get("ETag"))
+	assert.Equal(t, http.StatusOK, get("/v1/tasks", "If-None-Match", listETag).StatusCode)
+
+	// So does a task joining a list.
+	listETag = get("/v1/tasks", "", "").Header.Get("ETag")
+	send(http.MethodPost, "/v1/tasks", `{"title":"Rotate certificates"}`)
+	assert.Equal(t, http.StatusOK, get("/v1/tasks", "If-None-Match", listETag).StatusCode)
+}
@@ -0,0 +1,481 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	netmail "net/mail"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// The config struct holds configuration values like the server's port number and environment.
+//
+// Every setting is a flag. loadConfig fills it from, in rising order of
+// precedence, the flags' defaults, a YAML or JSON config file, TMS_*
+// environment variables and the command line.
+type config struct {
+	port   int
+	env    string
+	server struct {
+		readTimeout  time.Duration
+		writeTimeout time.Duration
+		idleTimeout  time.Duration
+	}
+	shutdown struct {
+		drainDelay time.Duration
+		timeout    time.Duration
+	}
+	tls struct {
+		cert         string
+		key          string
+		clientCA     string
+		clientAuth   string
+		selfSigned   bool
+		redirectPort int
+	}
+	auth struct {
+		trustedProxies     []string
+		insecureUserHeader bool
+	}
+	store struct {
+		kind string
+		file string
+	}
+	db struct {
+		dsn            string
+		maxOpenConns   int
+		maxIdleConns   int
+		maxIdleTime    time.Duration
+		connectTimeout time.Duration
+	}
+	limiter struct {
+		enabled bool
+		rps     float64
+		burst   int
+	}
+	recurrence struct {
+		interval time.Duration
+	}
+	trash struct {
+		retention     time.Duration
+		purgeInterval time.Duration
+	}
+	attachments struct {
+		dir          string
+		maxSize      int64
+		allowedTypes []string
+	}
+	webhooks struct {
+		interval    time.Duration
+		timeout     time.Duration
+		maxAttempts int
+	}
+	validation struct {
+		enabled bool
+	}
+	events struct {
+		replaySize int
+	}
+	mail struct {
+		transport string
+		from      string
+		dir       string
+		attempts  int
+		smtp      struct {
+			addr     string
+			username string
+			password string
+		}
+	}
+}
+
+// envPrefix starts the name of the environment variable for each setting,
+// as in TMS_DB_DSN for -db-dsn.
+const envPrefix = "TMS_"
+
+// redacted replaces secrets in the printed config.
+const redacted = "xxxxx"
+
+// secrets maps the settings that hold secrets to how to redact them.
+var secrets = map[string]func(string) string{
+	"db-dsn":        redactDSN,
+	"smtp-password": func(string) string { return redacted },
+}
+
+// flagSet registers every setting as a flag on cfg, with its default.
+func (cfg *config) flagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+
+	fs.IntVar(&cfg.port, "port", 4000, "API server port")
+	fs.StringVar(&cfg.env, "env", "development", "Environment (development|staging|production)")
+	fs.DurationVar(&cfg.server.readTimeout, "server-read-timeout", 10*time.Second, "How long to wait for a request, including its body")
+	fs.DurationVar(&cfg.server.writeTimeout, "server-write-timeout", 30*time.Second, "How long a response may take to write")
+	fs.DurationVar(&cfg.server.idleTimeout, "server-idle-timeout", time.Minute, "How long to keep an idle keep-alive connection open")
+
+	fs.DurationVar(&cfg.shutdown.drainDelay, "shutdown-drain-delay", 5*time.Second,
+		"How long /readyz reports 503 before the server stops accepting connections, for load balancers to drain it")
+	fs.DurationVar(&cfg.shutdown.timeout, "shutdown-timeout", 30*time.Second, "How long to wait for requests in flight to finish when shutting down")
+
+	fs.StringVar(&cfg.tls.cert, "tls-cert", "", "PEM certificate file to serve HTTPS with, with -tls-key")
+	fs.StringVar(&cfg.tls.key, "tls-key", "", "PEM private key file of -tls-cert")
+	fs.StringVar(&cfg.tls.clientCA, "tls-client-ca", "", "PEM file of the CAs client certificates must be signed by, for mutual TLS")
+	fs.StringVar(&cfg.tls.clientAuth, "tls-client-auth", "require", "Whether clients must present a certificate with -tls-client-ca (require|optional)")
+	fs.BoolVar(&cfg.tls.selfSigned, "tls-self-signed", false, "Serve HTTPS with a certificate generated at startup, for development")
+	fs.IntVar(&cfg.tls.redirectPort, "tls-redirect-port", 0, "Port to redirect plain HTTP to HTTPS from, or 0 for none")
+
+	fs.Var((*stringList)(&cfg.auth.trustedProxies), "trusted-proxies",
+		"Comma-separated IP addresses or CIDR ranges of the authenticating proxies whose X-User-ID header is trusted")
+	fs.BoolVar(&cfg.auth.insecureUserHeader, "insecure-user-header", false,
+		"Trust the X-User-ID header from every client, so anyone can act as any user. For development only")
+
+	fs.StringVar(&cfg.store.kind, "store", "postgres", "Where tasks are kept (postgres|file). The file store only serves the core task routes; see README.md")
+	fs.StringVar(&cfg.store.file, "store-file", "tms-data.json", "JSON file the file store keeps tasks in")
+
+	fs.StringVar(&cfg.db.dsn, "db-dsn", "postgres://localhost/postgres?sslmode=disable",
+		"PostgreSQL Connection. Credentials missing from it are read from PGUSER and PGPASSWORD")
+	fs.IntVar(&cfg.db.maxOpenConns, "db-max-open-conns", 25, "Most open database connections, or 0 for no limit")
+	fs.IntVar(&cfg.db.maxIdleConns, "db-max-idle-conns", 25, "Most idle database connections kept in the pool")
+	fs.DurationVar(&cfg.db.maxIdleTime, "db-max-idle-time", 15*time.Minute, "How long a database connection may sit idle before it is closed")
+	fs.DurationVar(&cfg.db.connectTimeout, "db-connect-timeout", 5*time.Second, "How long to wait for the database at startup")
+
+	fs.BoolVar(&cfg.limiter.enabled, "limiter-enabled", true, "Limit how many requests each client IP address may make")
+	fs.Float64Var(&cfg.limiter.rps, "limiter-rps", 10, "Requests per second allowed from each client IP address")
+	fs.IntVar(&cfg.limiter.burst, "limiter-burst", 20, "Requests a client IP address may make at once above the rate")
+
+	fs.DurationVar(&cfg.recurrence.interval, "recurrence-interval", time.Minute, "How often to check for due recurring tasks")
+
+	fs.DurationVar(&cfg.trash.retention, "trash-retention", 30*24*time.Hour, "How long deleted tasks stay in the trash before they are purged")
+	fs.DurationVar(&cfg.trash.purgeInterval, "trash-purge-interval", time.Hour, "How often to purge expired tasks from the trash")
+
+	fs.StringVar(&cfg.attachments.dir, "attachments-dir", "attachments", "Directory where attachment content is stored")
+	fs.Int64Var(&cfg.attachments.maxSize, "attachments-max-size", 10<<20, "Largest attachment accepted, in bytes")
+	cfg.attachments.allowedTypes = []string{"text/plain", "application/json", "application/pdf", "application/zip",
+		"application/x-gzip", "image/jpeg", "image/png", "image/gif", "image/webp"}
+	fs.Var((*stringList)(&cfg.attachments.allowedTypes), "attachments-allowed-types", "Comma-separated list of attachment content types to accept")
+
+	fs.DurationVar(&cfg.webhooks.interval, "webhook-interval", 2*time.Second, "How often to send queued webhook deliveries")
+	fs.DurationVar(&cfg.webhooks.timeout, "webhook-timeout", 10*time.Second, "How long to wait for a webhook receiver to respond")
+	fs.IntVar(&cfg.webhooks.maxAttempts, "webhook-max-attempts", 8, "How many times to try a webhook delivery before giving up")
+
+	fs.BoolVar(&cfg.validation.enabled, "validate-requests", false, "Reject requests that do not match the OpenAPI document served at /openapi.json")
+
+	fs.IntVar(&cfg.events.replaySize, "events-replay-size", 1000, "How many recent events to keep for clients resuming an event stream")
+
+	fs.StringVar(&cfg.mail.transport, "mail-transport", "none", "How to send notification emails (smtp|file|none)")
+	fs.StringVar(&cfg.mail.from, "mail-from", "Task Management System <tms@localhost>", "From address of notification emails")
+	fs.StringVar(&cfg.mail.dir, "mail-dir", "mail", "Directory the file transport writes emails to")
+	fs.IntVar(&cfg.mail.attempts, "mail-attempts", 5, "How many times to try sending an email before giving up")
+	fs.StringVar(&cfg.mail.smtp.addr, "smtp-addr", "localhost:25", "SMTP server host and port")
+	fs.StringVar(&cfg.mail.smtp.username, "smtp-username", "", "SMTP username, if the server needs authentication")
+	fs.StringVar(&cfg.mail.smtp.password, "smtp-password", "", "SMTP password")
+
+	return fs
+}
+
+// loadConfig reads the configuration from the command line args, the config
+// file they or TMS_CONFIG name, and the environment, and validates it.
+// printConfig reports whether -print-config was given.
+func loadConfig(args []string, getenv func(string) string) (cfg config, printConfig bool, err error) {
+	fs := cfg.flagSet("tms")
+	configFile := fs.String("config", getenv(envPrefix+"CONFIG"), "YAML or JSON file to read settings from, before the environment and flags")
+	fs.BoolVar(&printConfig, "print-config", false, "Print the effective configuration, with secrets redacted, and exit")
+
+	err = fs.Parse(args)
+	if err != nil {
+		return cfg, false, err
+	}
+	explicit := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+
+	if *configFile != "" {
+		settings, err := readConfigFile(*configFile)
+		if err != nil {
+			return cfg, false, err
+		}
+		for _, name := range sortedKeys(settings) {
+			if fs.Lookup(name) == nil || name == "config" || name == "print-config" {
+				return cfg, false, fmt.Errorf("%s: unknown setting %q", *configFile, name)
+			}
+			if explicit[name] {
+				continue
+			}
+			err = fs.Set(name, settings[name])
+			if err != nil {
+				return cfg, false, fmt.Errorf("%s: %s: %w", *configFile, name, err)
+			}
+		}
+	}
+
+	fs.VisitAll(func(f *flag.Flag) {
+		key := envKey(f.Name)
+		value := getenv(key)
+		if err != nil || value == "" || explicit[f.Name] || f.Name == "config" {
+			return
+		}
+		if setErr := fs.Set(f.Name, value); setErr != nil {
+			err = fmt.Errorf("%s: %w", key, setErr)
+		}
+	})
+	if err != nil {
+		return cfg, false, err
+	}
+
+	return cfg, printConfig, cfg.validate()
+}
+
+// envKey returns the environment variable for a setting.
+func envKey(name string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// readConfigFile reads the settings in a YAML or JSON file. Keys may nest,
+// so db: {max_open_conns: 10} sets -db-max-open-conns, and lists become
+// comma-separated values.
+func readConfigFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// JSON is YAML, so one decoder reads both.
+	var tree map[string]any
+	err = yaml.Unmarshal(data, &tree)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	settings := map[string]string{}
+	err = flattenSettings(settings, "", tree)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return settings, nil
+}
+
+func flattenSettings(settings map[string]string, prefix string, tree map[string]any) error {
+	for key, value := range tree {
+		name := strings.ToLower(strings.ReplaceAll(key, "_", "-"))
+		if prefix != "" {
+			name = prefix + "-" + name
+		}
+
+		switch value := value.(type) {
+		case map[string]any:
+			err := flattenSettings(settings, name, value)
+			if err != nil {
+				return err
+			}
+		case []any:
+			items := make([]string, len(value))
+			for i, item := range value {
+				items[i] = fmt.Sprint(item)
+			}
+			settings[name] = strings.Join(items, ",")
+		case nil:
+			settings[name] = ""
+		default:
+			settings[name] = fmt.Sprint(value)
+		}
+	}
+	return nil
+}
+
+// validate reports every setting that the server cannot start with.
+func (cfg config) validate() error {
+	var problems []string
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			problems = append(problems, fmt.Sprintf(format, args...))
+		}
+	}
+
+	check(cfg.port > 0 && cfg.port <= 65535, "port must be between 1 and 65535")
+	check(oneOf(cfg.env, "development", "staging", "production"), "env must be development, staging or production")
+	check(cfg.server.readTimeout > 0, "server-read-timeout must be positive")
+	check(cfg.server.writeTimeout > 0, "server-write-timeout must be positive")
+	check(cfg.server.idleTimeout > 0, "server-idle-timeout must be positive")
+	check(cfg.shutdown.drainDelay >= 0, "shutdown-drain-delay must not be negative")
+	check(cfg.shutdown.timeout > 0, "shutdown-timeout must be positive")
+
+	check((cfg.tls.cert == "") == (cfg.tls.key == ""), "tls-cert and tls-key must be set together")
+	check(!cfg.tls.selfSigned || cfg.tls.cert == "", "tls-self-signed cannot be used with tls-cert")
+	check(!cfg.tls.selfSigned || cfg.env != "production", "tls-self-signed is for development, not production")
+	check(cfg.tlsEnabled() || cfg.tls.clientCA == "", "tls-client-ca needs tls-cert or tls-self-signed")
+	check(oneOf(cfg.tls.clientAuth, "require", "optional"), "tls-client-auth must be require or optional")
+	check(cfg.tls.redirectPort >= 0 && cfg.tls.redirectPort <= 65535, "tls-redirect-port must be between 0 and 65535")
+	check(cfg.tls.redirectPort == 0 || cfg.tlsEnabled(), "tls-redirect-port needs tls-cert or tls-self-signed")
+	check(cfg.tls.redirectPort == 0 || cfg.tls.redirectPort != cfg.port, "tls-redirect-port must differ from port")
+
+	_, err := parseTrustedProxies(cfg.auth.trustedProxies)
+	check(err == nil, "trusted-proxies: %v", err)
+	check(!cfg.auth.insecureUserHeader || cfg.env != "production", "insecure-user-header is for development, not production")
+
+	switch cfg.store.kind {
+	case "file":
+		check(cfg.store.file != "", "store-file must be set for the file store")
+	case "postgres":
+		check(cfg.db.dsn != "", "db-dsn must be set for the postgres store")
+		check(cfg.db.maxOpenConns >= 0, "db-max-open-conns must not be negative")
+		check(cfg.db.maxIdleConns >= 0, "db-max-idle-conns must not be negative")
+		check(cfg.db.maxOpenConns == 0 || cfg.db.maxIdleConns <= cfg.db.maxOpenConns,
+			"db-max-idle-conns must not be more than db-max-open-conns")
+		check(cfg.db.maxIdleTime >= 0, "db-max-idle-time must not be negative")
+		check(cfg.db.connectTimeout > 0, "db-connect-timeout must be positive")
+		check(cfg.attachments.dir != "", "attachments-dir must be set")
+		check(cfg.attachments.maxSize > 0, "attachments-max-size must be positive")
+		check(cfg.recurrence.interval > 0, "recurrence-interval must be positive")
+		check(cfg.trash.retention > 0, "trash-retention must be positive")
+		check(cfg.trash.purgeInterval > 0, "trash-purge-interval must be positive")
+		check(cfg.webhooks.interval > 0, "webhook-interval must be positive")
+		check(cfg.webhooks.timeout > 0, "webhook-timeout must be positive")
+		check(cfg.webhooks.maxAttempts > 0, "webhook-max-attempts must be positive")
+	default:
+		check(false, "store must be postgres or file")
+	}
+
+	if cfg.limiter.enabled {
+		check(cfg.limiter.rps > 0, "limiter-rps must be positive")
+		check(cfg.limiter.burst > 0, "limiter-burst must be positive")
+	}
+	check(cfg.events.replaySize >= 0, "events-replay-size must not be negative")
+
+	switch cfg.mail.transport {
+	case "smtp":
+		_, _, err := net.SplitHostPort(cfg.mail.smtp.addr)
+		check(err == nil, "smtp-addr must be a host and port")
+		check(cfg.mail.smtp.password == "" || cfg.mail.smtp.username != "", "smtp-password is set without smtp-username")
+	case "file":
+		check(cfg.mail.dir != "", "mail-dir must be set for the file transport")
+	case "none", "":
+	default:
+		check(false, "mail-transport must be smtp, file or none")
+	}
+	if cfg.mail.transport == "smtp" || cfg.mail.transport == "file" {
+		_, err := netmail.ParseAddress(cfg.mail.from)
+		check(err == nil, "mail-from must be an email address")
+		check(cfg.mail.attempts > 0, "mail-attempts must be positive")
+	}
+
+	if len(problems) > 0 {
+		return errors.New("invalid configuration: " + strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// writeConfig writes the settings in cfg as a config file could hold them,
+// with secrets redacted.
+func writeConfig(w io.Writer, cfg config) error {
+	// Bind the flags to a copy, then overwrite their defaults with cfg.
+	var bound config
+	fs := bound.flagSet("tms")
+	bound = cfg
+
+	settings := map[string]string{}
+	fs.VisitAll(func(f *flag.Flag) {
+		value := f.Value.String()
+		if redact, ok := secrets[f.Name]; ok && value != "" {
+			value = redact(value)
+		}
+		settings[f.Name] = value
+	})
+
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	for _, name := range sortedKeys(settings) {
+		node.Content = append(node.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: name},
+			&yaml.Node{Kind: yaml.ScalarNode, Value: settings[name]})
+	}
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	err := encoder.Encode(node)
+	if err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+// dsnPassword matches the password in a key=value DSN.
+var dsnPassword = regexp.MustCompile(`(password\s*=\s*)('[^']*'|\S+)`)
+
+// redactDSN hides the password in a URL or key=value DSN.
+func redactDSN(dsn string) string {
+	u, err := url.Parse(dsn)
+	if err == nil && u.Scheme != "" {
+		return u.Redacted()
+	}
+	return dsnPassword.ReplaceAllString(dsn, "${1}"+redacted)
+}
+
+// parseTrustedProxies parses the addresses and CIDR ranges of -trusted-proxies.
+func parseTrustedProxies(proxies []string) ([]*net.IPNet, error) {
+	var networks []*net.IPNet
+	for _, proxy := range proxies {
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid IP address %q", proxy)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR range %q", proxy)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// stringList is a comma-separated flag value.
+type stringList []string
+
+func (l *stringList) String() string {
+	if l == nil {
+		return ""
+	}
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	*l = items
+	return nil
+}
+
+func oneOf(value string, allowed ...string) bool {
+	for _, a := range allowed {
+		if value == a {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
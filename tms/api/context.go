@@ -0,0 +1,25 @@
+package main
+
+import (
+	"context"
+	"net/http"
+)
+
+type contextKey string
+
+const userContextKey = contextKey("user")
+
+// contextSetUser returns a copy of the request with the caller's user ID added to its context.
+func (app *application) contextSetUser(r *http.Request, userID int) *http.Request {
+	ctx := context.WithValue(r.Context(), userContextKey, userID)
+	return r.WithContext(ctx)
+}
+
+// contextGetUser returns the caller's user ID, or 0 for an anonymous request.
+func (app *application) contextGetUser(r *http.Request) int {
+	userID, ok := r.Context().Value(userContextKey).(int)
+	if !ok {
+		return 0
+	}
+	return userID
+}
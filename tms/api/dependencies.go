@@ -0,0 +1,204 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+
+	"tms.zinkworks.com/model"
+)
+
+// swagger:route POST /tasks/{id}/dependencies dependencies createTaskDependencyEndpoint
+// Link a task to another task.
+// The type is one of blocks, blocked_by or relates_to. Blocking links that would create a cycle are rejected.
+// Consumes:
+// - application/json
+// Produces:
+// - application/json
+// Schemes: http, https
+// responses:
+//
+//	201: taskDependencyCreatedResponse
+//	400: badRequestError
+//	401: unauthorizedError
+//	404: notFoundError
+//	409: dependencyConflictError
+//	500: internalServerError
+func (app *application) createTaskDependencyHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	// Parse the task ID from the URL parameters.
+	taskID, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	var createDependency model.TaskDependency
+	err = json.NewDecoder(r.Body).Decode(&createDependency)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	createDependency.TaskID = taskID
+	createDependency.CreatedAt = time.Now()
+
+	taskDto := model.TaskDto{DB: app.db, ActorID: app.contextGetUser(r)}
+
+	// Both ends of the link must exist, and the caller must be able to see them.
+	for _, id := range []int{createDependency.TaskID, createDependency.RelatedTaskID} {
+		_, err = taskDto.GetTask(id)
+		if err == nil {
+			var ok bool
+			ok, err = app.canAccessTask(r, id)
+			if err == nil && !ok {
+				err = sql.ErrNoRows
+			}
+		}
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				http.Error(w, fmt.Sprintf("Task %d not found", id), http.StatusNotFound)
+			} else {
+				http.Error(w, "Error fetching task", http.StatusInternalServerError)
+			}
+			return
+		}
+	}
+
+	err = taskDto.InsertTaskDependency(&createDependency)
+	if err != nil {
+		switch {
+		case errors.Is(err, model.ErrInvalidDependency):
+			http.Error(w, "Invalid dependency, expected a type of blocks, blocked_by or relates_to between two different tasks", http.StatusBadRequest)
+		case errors.Is(err, model.ErrDependencyCycle):
+			http.Error(w, "Dependency would create a cycle", http.StatusConflict)
+		case errors.Is(err, model.ErrDuplicateDependency):
+			http.Error(w, "Dependency already exists", http.StatusConflict)
+		default:
+			http.Error(w, "Error inserting dependency", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, createDependency, nil)
+	if err != nil {
+		app.logger.Print(err)
+		http.Error(w, "The server encountered a problem and could not process your request", http.StatusInternalServerError)
+	}
+}
+
+// swagger:route GET /tasks/{id}/dependencies dependencies getTaskDependenciesEndpoint
+// Get the links of a task.
+// Returns every link involving the task, with task_id set to the given task.
+// Produces:
+// - application/json
+// Schemes: http, https
+// Responses:
+//
+//	200: allTaskDependenciesResponse
+//	400: invalidTaskIdError
+//	401: unauthorizedError
+//	404: notFoundError
+//	500: internalServerError
+func (app *application) getTaskDependenciesHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	// Parse the task ID from the URL parameters.
+	taskID, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	taskDto := model.TaskDto{DB: app.db}
+
+	deps, err := taskDto.GetTaskDependencies(taskID)
+	if err != nil {
+		http.Error(w, "Error fetching dependencies", http.StatusInternalServerError)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, deps, nil)
+	if err != nil {
+		app.logger.Print(err)
+		http.Error(w, "The server encountered a problem and could not process your request", http.StatusInternalServerError)
+	}
+}
+
+// swagger:route DELETE /tasks/{id}/dependencies/{dependencyID} dependencies deleteTaskDependencyEndpoint
+// Remove a link between two tasks.
+// Produces:
+// - application/json
+// Schemes: http, https
+// Responses:
+//
+//	200: successfullyDeletedResponse
+//	400: invalidIdError
+//	401: unauthorizedError
+//	404: notFoundError
+//	500: internalServerError
+func (app *application) deleteTaskDependencyHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	// Parse the task and dependency IDs from the URL parameters.
+	taskID, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	dependencyID, err := strconv.Atoi(ps.ByName("dependencyID"))
+	if err != nil {
+		http.Error(w, "Invalid dependency ID", http.StatusBadRequest)
+		return
+	}
+
+	taskDto := model.TaskDto{DB: app.db, ActorID: app.contextGetUser(r)}
+
+	deleted, err := taskDto.DeleteTaskDependency(taskID, dependencyID)
+	if err != nil {
+		app.logger.Printf("Failed to delete dependency %d of task %d: %v", dependencyID, taskID, err)
+		http.Error(w, "Error deleting dependency", http.StatusInternalServerError)
+		return
+	}
+	if !deleted {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// swagger:route GET /tasks/order dependencies getTaskOrderEndpoint
+// Get open tasks in dependency order.
+// Returns the open tasks topologically sorted so every task comes after the tasks blocking it.
+// Produces:
+// - application/json
+// Schemes: http, https
+// Responses:
+//
+//	200: allTasksResponse
+//	401: unauthorizedError
+//	500: internalServerError
+func (app *application) getTaskOrderHandler(w http.ResponseWriter, r *http.Request) {
+	filter, ok := app.visibleTasks(w, r)
+	if !ok {
+		return
+	}
+
+	taskDto := model.TaskDto{DB: app.db}
+
+	tasks, err := taskDto.GetOpenTaskOrder(filter)
+	if err != nil {
+		app.logger.Print(err)
+		http.Error(w, "Error ordering tasks", http.StatusInternalServerError)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, tasks, nil)
+	if err != nil {
+		app.logger.Print(err)
+		http.Error(w, "The server encountered a problem and could not process your request", http.StatusInternalServerError)
+	}
+}
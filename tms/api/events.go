@@ -0,0 +1,176 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"tms.zinkworks.com/events"
+)
+
+// eventsHeartbeat is how often an idle stream sends a comment line, so
+// proxies do not close it and dead clients are noticed.
+const eventsHeartbeat = 15 * time.Second
+
+// swagger:route GET /events events streamEventsEndpoint
+// Stream task changes as Server-Sent Events.
+// Sends task.created, task.updated, task.assigned, task.deleted and comment.created events,
+// optionally filtered by task or assignee. A client that reconnects with the Last-Event-ID
+// header, or the last_event_id query parameter, is sent the events it missed. If some have
+// already left the server's buffer, a reset event is sent first and the client should reload.
+// Only events for tasks in the caller's projects are sent.
+// Produces:
+// - text/event-stream
+// Schemes: http, https
+// responses:
+//
+//	200: eventStreamResponse
+//	400: badRequestError
+//	401: unauthorizedError
+//	500: internalServerError
+func (app *application) streamEventsHandler(w http.ResponseWriter, r *http.Request) {
+	if app.taskFile == nil {
+		if _, ok := app.requireUser(w, r); !ok {
+			return
+		}
+	}
+
+	query := r.URL.Query()
+
+	var filter events.Filter
+	var err error
+	if value := query.Get("task_id"); value != "" {
+		filter.TaskID, err = strconv.Atoi(value)
+		if err != nil || filter.TaskID < 1 {
+			http.Error(w, "Invalid task ID", http.StatusBadRequest)
+			return
+		}
+	}
+	if value := query.Get("assignee_id"); value != "" {
+		filter.AssigneeID, err = strconv.Atoi(value)
+		if err != nil || filter.AssigneeID < 1 {
+			http.Error(w, "Invalid assignee ID", http.StatusBadRequest)
+			return
+		}
+	}
+
+	var lastEventID uint64
+	value := r.Header.Get("Last-Event-ID")
+	if value == "" {
+		// EventSource cannot set headers on its first connection.
+		value = query.Get("last_event_id")
+	}
+	if value != "" {
+		lastEventID, err = strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid Last-Event-ID", http.StatusBadRequest)
+			return
+		}
+	}
+
+	// The stream outlives the server's write timeout, so lift it for this response.
+	rc := http.NewResponseController(w)
+	err = rc.SetWriteDeadline(time.Time{})
+	if err != nil {
+		app.logger.Printf("Failed to lift write deadline for event stream: %v", err)
+		http.Error(w, "Streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	sub, missed, complete := app.hub.Subscribe(filter, lastEventID)
+	defer sub.Close()
+	access := &eventAccess{app: app, r: r, tasks: map[int]bool{}}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	fmt.Fprint(w, "retry: 3000\n\n")
+	if !complete {
+		fmt.Fprint(w, "event: reset\ndata: {}\n\n")
+	}
+	for _, event := range missed {
+		if access.allows(event) {
+			writeEvent(w, event)
+		}
+	}
+	if rc.Flush() != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(eventsHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case event, ok := <-sub.Events():
+			if !ok {
+				// Dropped for falling behind; the client reconnects and resumes.
+				return
+			}
+			if !access.allows(event) {
+				continue
+			}
+			writeEvent(w, event)
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": ping\n\n")
+			access.forget()
+		case <-r.Context().Done():
+			return
+		case <-app.stopping:
+			// Clients reconnect to another instance and resume from there.
+			return
+		}
+
+		if rc.Flush() != nil {
+			return
+		}
+	}
+}
+
+// eventAccess decides which events a stream sends, by whether the caller can
+// access each event's task. Answers are kept until the next heartbeat, so a
+// task moving project or a change of membership soon takes effect.
+type eventAccess struct {
+	app   *application
+	r     *http.Request
+	tasks map[int]bool
+}
+
+func (access *eventAccess) allows(event events.Event) bool {
+	allowed, ok := access.tasks[event.TaskID]
+	if ok {
+		return allowed
+	}
+
+	allowed, err := access.app.canAccessTask(access.r, event.TaskID)
+	if err != nil {
+		// Not kept, so the next event for the task asks again.
+		access.app.logger.Printf("Failed to check access to task %d for event stream: %v", event.TaskID, err)
+		return false
+	}
+	access.tasks[event.TaskID] = allowed
+	return allowed
+}
+
+func (access *eventAccess) forget() {
+	access.tasks = map[int]bool{}
+}
+
+// writeEvent writes one event in the text/event-stream format. Data is
+// compact JSON, so it always fits on a single data line.
+func writeEvent(w http.ResponseWriter, event events.Event) {
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, event.Data)
+}
+
+// publish sends a change to the event stream. A failure is only logged,
+// as the change itself has already been saved.
+func (app *application) publish(eventType string, taskID, assigneeID int, data any) {
+	err := app.hub.Publish(eventType, taskID, assigneeID, data)
+	if err != nil {
+		app.logger.Printf("Failed to publish %s event for task %d: %v", eventType, taskID, err)
+	}
+}
@@ -1,7 +1,14 @@
 package main
 
 import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
 	"net/http"
+	"time"
+
+	"tms.zinkworks.com/model"
 )
 
 // swagger:route GET /healthcheck healthcheck healthcheckEndpoint
@@ -22,3 +29,122 @@ func (app *application) healthcheckHandler(w http.ResponseWriter, r *http.Reques
 		http.Error(w, "The server encountered a problem and could not process your request", http.StatusInternalServerError)
 	}
 }
+
+// readinessTimeout bounds the checks /readyz makes of the database.
+const readinessTimeout = 2 * time.Second
+
+// livenessHandler reports that the process is up and serving. It is for
+// orchestrators deciding whether to restart the process, so it checks
+// nothing else, and is served outside the versioned API.
+func (app *application) livenessHandler(w http.ResponseWriter, r *http.Request) {
+	err := app.writeJSON(w, http.StatusOK, map[string]string{"status": "alive"}, nil)
+	if err != nil {
+		app.logger.Print(err)
+		http.Error(w, "The server encountered a problem and could not process your request", http.StatusInternalServerError)
+	}
+}
+
+// readinessReport is the body of a /readyz response.
+type readinessReport struct {
+	Status  string                    `json:"status"`
+	Checks  map[string]readinessCheck `json:"checks"`
+	DB      *dbStats                  `json:"db,omitempty"`
+	Workers map[string]workerStatus   `json:"workers,omitempty"`
+}
+
+type readinessCheck struct {
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// dbStats is the part of sql.DBStats worth watching, with durations in
+// milliseconds.
+type dbStats struct {
+	MaxOpenConnections int   `json:"max_open_connections"`
+	OpenConnections    int   `json:"open_connections"`
+	InUse              int   `json:"in_use"`
+	Idle               int   `json:"idle"`
+	WaitCount          int64 `json:"wait_count"`
+	WaitDurationMS     int64 `json:"wait_duration_ms"`
+	MaxIdleClosed      int64 `json:"max_idle_closed"`
+	MaxIdleTimeClosed  int64 `json:"max_idle_time_closed"`
+	MaxLifetimeClosed  int64 `json:"max_lifetime_closed"`
+}
+
+// readinessHandler reports whether the instance should be sent traffic:
+// the database answers within readinessTimeout with the schema this code
+// expects, and the server is not shutting down. It responds 503 when not,
+// so load balancers stop routing to it. It is served outside the versioned
+// API.
+func (app *application) readinessHandler(w http.ResponseWriter, r *http.Request) {
+	report := readinessReport{Status: "ready", Checks: map[string]readinessCheck{}}
+	ready := true
+	check := func(name string, err error) {
+		if err != nil {
+			ready = false
+			report.Checks[name] = readinessCheck{Error: err.Error()}
+			return
+		}
+		report.Checks[name] = readinessCheck{OK: true}
+	}
+
+	select {
+	case <-app.stopping:
+		check("shutdown", errors.New("the server is shutting down"))
+	default:
+		check("shutdown", nil)
+	}
+
+	if app.db != nil {
+		ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+		defer cancel()
+
+		err := app.db.PingContext(ctx)
+		check("database", err)
+		if err == nil {
+			check("schema", app.checkSchema(ctx))
+		}
+
+		stats := app.db.Stats()
+		report.DB = &dbStats{
+			MaxOpenConnections: stats.MaxOpenConnections,
+			OpenConnections:    stats.OpenConnections,
+			InUse:              stats.InUse,
+			Idle:               stats.Idle,
+			WaitCount:          stats.WaitCount,
+			WaitDurationMS:     stats.WaitDuration.Milliseconds(),
+			MaxIdleClosed:      stats.MaxIdleClosed,
+			MaxIdleTimeClosed:  stats.MaxIdleTimeClosed,
+			MaxLifetimeClosed:  stats.MaxLifetimeClosed,
+		}
+		report.Workers = app.workers.snapshot()
+	}
+
+	status := http.StatusOK
+	if !ready {
+		report.Status = "not ready"
+		status = http.StatusServiceUnavailable
+	}
+	err := app.writeJSON(w, status, report, nil)
+	if err != nil {
+		app.logger.Print(err)
+		http.Error(w, "The server encountered a problem and could not process your request", http.StatusInternalServerError)
+	}
+}
+
+// checkSchema reports an error unless the database has the schema version
+// this code was written for, fully applied.
+func (app *application) checkSchema(ctx context.Context) error {
+	version, dirty, err := model.SchemaDto{DB: app.db}.GetSchemaVersion(ctx)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return errors.New("no schema version is recorded")
+	case err != nil:
+		return err
+	case dirty:
+		return fmt.Errorf("the migration to schema version %d did not finish", version)
+	case version != model.SchemaVersion:
+		return fmt.Errorf("schema version is %d, expected %d; apply the scripts in migrations/", version, model.SchemaVersion)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+
+	"tms.zinkworks.com/model"
+)
+
+func TestLiveness(t *testing.T) {
+	server := newTestServer(t)
+
+	res, err := http.Get(server.URL + "/healthz")
+	assert.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	// The probes are not part of the API, so they are not deprecated aliases.
+	assert.Empty(t, res.Header.Get("Deprecation"))
+}
+
+func TestReadiness(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	assert.NoError(t, err)
+	defer db.Close()
+
+	app := &application{logger: log.New(io.Discard, "", 0), db: db, stopping: make(chan struct{})}
+	app.workers.start(workerWebhooks)
+	app.workers.ran(workerWebhooks, errors.New("connection refused"))
+
+	ready := func() (int, readinessReport) {
+		res := httptest.NewRecorder()
+		app.routes().ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		var report readinessReport
+		assert.NoError(t, json.Unmarshal(res.Body.Bytes(), &report))
+		return res.Code, report
+	}
+	schemaVersion := func(version int, dirty bool) {
+		mock.ExpectPing()
+		mock.ExpectQuery("SELECT version, dirty FROM schema_migrations").
+			WillReturnRows(sqlmock.NewRows([]string{"version", "dirty"}).AddRow(version, dirty))
+	}
+
+	schemaVersion(model.SchemaVersion, false)
+	status, report := ready()
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "ready", report.Status)
+	assert.Equal(t, readinessCheck{OK: true}, report.Checks["database"])
+	assert.Equal(t, readinessCheck{OK: true}, report.Checks["schema"])
+	assert.NotNil(t, report.DB)
+	assert.True(t, report.Workers[workerWebhooks].Running)
+	assert.Equal(t, "connection refused", report.Workers[workerWebhooks].LastError)
+
+	schemaVersion(model.SchemaVersion+1, false)
+	status, report = ready()
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+	assert.Equal(t, "not ready", report.Status)
+	assert.Contains(t, report.Checks["schema"].Error, "expected")
+
+	mock.ExpectPing().WillReturnError(errors.New("connection refused"))
+	status, report = ready()
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+	assert.Equal(t, readinessCheck{Error: "connection refused"}, report.Checks["database"])
+
+	// Once shutdown starts, load balancers are told to stop sending traffic.
+	close(app.stopping)
+	schemaVersion(model.SchemaVersion, false)
+	status, report = ready()
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+	assert.False(t, report.Checks["shutdown"].OK)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
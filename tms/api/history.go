@@ -0,0 +1,63 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/julienschmidt/httprouter"
+
+	"tms.zinkworks.com/model"
+)
+
+// swagger:route GET /tasks/{id}/history history getTaskHistoryEndpoint
+// Get the change history of a task, newest first.
+// Every event has the acting user, the action and the before and after value of each changed field.
+// The history of a deleted task can still be read.
+// With ?limit, at most that many events are returned, and a Link header with rel="next" gives the next page.
+// Produces:
+// - application/json
+// Schemes: http, https
+// responses:
+//
+//	200: taskHistoryResponse
+//	304: notModifiedResponse
+//	400: invalidTaskIdError
+//	401: unauthorizedError
+//	404: notFoundError
+//	500: internalServerError
+func (app *application) getTaskHistoryHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	// Parse the task ID from the URL parameters.
+	taskID, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	page, err := readPage(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	taskDto := model.TaskDto{DB: app.db}
+
+	list := newListWriter(app.logger, w, r, page, "task history", model.TaskEventCursor)
+	err = taskDto.EachTaskEvent(taskID, list.fetch(), list.add)
+
+	// Tasks created before history was recorded have no events yet.
+	if err == nil && list.count == 0 && page.After == nil {
+		_, err = taskDto.GetTask(taskID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				http.NotFound(w, r)
+			} else {
+				http.Error(w, "Error fetching task", http.StatusInternalServerError)
+			}
+			return
+		}
+	}
+
+	list.close(err)
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+
+	"tms.zinkworks.com/model"
+)
+
+// maxImportSize bounds the body of an import.
+const maxImportSize = 32 << 20
+
+// Import and export formats.
+const (
+	formatCSV    = "csv"
+	formatJSON   = "json"
+	formatNDJSON = "ndjson"
+)
+
+// swagger:route GET /tasks/export tasks exportTasksEndpoint
+// Export all tasks.
+// Streams every task in the caller's projects that is not in the trash, with its items, comments and assignee, as a JSON
+// array (the default), newline-delimited JSON, or CSV with items and comments as JSON arrays.
+// Produces:
+// - application/json
+// - application/x-ndjson
+// - text/csv
+// Schemes: http, https
+// responses:
+//
+//	200: exportTasksResponse
+//	400: badRequestError
+//	401: unauthorizedError
+//	500: internalServerError
+func (app *application) exportTasksHandler(w http.ResponseWriter, r *http.Request) {
+	filter, ok := app.visibleTasks(w, r)
+	if !ok {
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = formatJSON
+	}
+
+	var write func(model.Task) error
+	var finish func() error
+	switch format {
+	case formatJSON:
+		encoder := json.NewEncoder(w)
+		first := true
+		write = func(task model.Task) error {
+			separator := ",\n"
+			if first {
+				separator, first = "[\n", false
+			}
+			_, err := io.WriteString(w, separator)
+			if err != nil {
+				return err
+			}
+			return encoder.Encode(task)
+		}
+		finish = func() error {
+			closing := "]\n"
+			if first {
+				closing = "[]\n"
+			}
+			_, err := io.WriteString(w, closing)
+			return err
+		}
+	case formatNDJSON:
+		encoder := json.NewEncoder(w)
+		write = func(task model.Task) error { return encoder.Encode(task) }
+		finish = func() error { return nil }
+	case formatCSV:
+		writer := csv.NewWriter(w)
+		wroteHeader := false
+		write = func(task model.Task) error {
+			if !wroteHeader {
+				wroteHeader = true
+				err := writer.Write(model.TaskCSVHeader)
+				if err != nil {
+					return err
+				}
+			}
+			record, err := model.TaskCSVRecord(task)
+			if err != nil {
+				return err
+			}
+			return writer.Write(record)
+		}
+		finish = func() error {
+			if !wroteHeader {
+				writer.Write(model.TaskCSVHeader)
+			}
+			writer.Flush()
+			return writer.Error()
+		}
+	default:
+		http.Error(w, "Invalid format, expected csv, json or ndjson", http.StatusBadRequest)
+		return
+	}
+
+	contentTypes := map[string]string{
+		formatJSON:   "application/json",
+		formatNDJSON: "application/x-ndjson",
+		formatCSV:    "text/csv; charset=utf-8",
+	}
+	filename := "tasks-" + time.Now().UTC().Format("20060102T150405Z") + "." + format
+	w.Header().Set("Content-Type", contentTypes[format])
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+
+	taskDto := model.TaskDto{DB: app.db}
+
+	// Headers are only sent with the first task, so a query that fails
+	// straight away can still be reported properly.
+	wrote := false
+	err := taskDto.StreamTasks(filter, func(task model.Task) error {
+		wrote = true
+		return write(task)
+	})
+	if err != nil {
+		app.logger.Printf("Failed to export tasks: %v", err)
+		if !wrote {
+			w.Header().Del("Content-Disposition")
+			http.Error(w, "Error exporting tasks", http.StatusInternalServerError)
+		}
+		// Otherwise the response is cut short, which the client sees as a truncated download.
+		return
+	}
+
+	err = finish()
+	if err != nil {
+		app.logger.Printf("Failed to export tasks: %v", err)
+	}
+}
+
+// swagger:route POST /tasks/import tasks importTasksEndpoint
+// Import tasks.
+// Upserts tasks by ID from CSV, a JSON array or newline-delimited JSON, as written by the export.
+// Rows without an ID, or with one that does not exist, create tasks; rows with an existing ID
+// replace that task's fields and items. The import is all or nothing: if any row is invalid,
+// nothing is saved and every error is reported with its row. With dry_run=true the rows are
+// checked against the database and the report returned without saving anything. Rows can only
+// create or replace tasks in the caller's projects.
+// Consumes:
+// - application/json
+// - application/x-ndjson
+// - text/csv
+// Produces:
+// - application/json
+// Schemes: http, https
+// responses:
+//
+//	200: importReportResponse
+//	400: badRequestError
+//	401: unauthorizedError
+//	413: payloadTooLargeError
+//	422: importReportResponse
+//	500: internalServerError
+func (app *application) importTasksHandler(w http.ResponseWriter, r *http.Request) {
+	if _, ok := app.requireUser(w, r); !ok {
+		return
+	}
+
+	query := r.URL.Query()
+
+	dryRun := false
+	if value := query.Get("dry_run"); value != "" {
+		var err error
+		dryRun, err = strconv.ParseBool(value)
+		if err != nil {
+			http.Error(w, "Invalid dry_run, expected true or false", http.StatusBadRequest)
+			return
+		}
+	}
+
+	format := query.Get("format")
+	if format == "" {
+		format = importFormat(r.Header.Get("Content-Type"))
+	}
+
+	body := http.MaxBytesReader(w, r.Body, maxImportSize)
+
+	var rows []model.ImportRow
+	var err error
+	switch format {
+	case formatCSV:
+		rows, err = model.ParseTasksCSV(body)
+	case formatJSON:
+		rows, err = model.ParseTasksJSON(body)
+	case formatNDJSON:
+		rows, err = model.ParseTasksNDJSON(body)
+	default:
+		http.Error(w, "Invalid format, expected csv, json or ndjson", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, "The import is too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(rows) == 0 {
+		http.Error(w, "The import has no tasks", http.StatusBadRequest)
+		return
+	}
+
+	taskDto := model.TaskDto{DB: app.db, ActorID: app.contextGetUser(r)}
+
+	report, err := taskDto.ImportTasks(rows, dryRun, time.Now())
+	if err != nil {
+		app.logger.Printf("Failed to import tasks: %v", err)
+		http.Error(w, "Error importing tasks", http.StatusInternalServerError)
+		return
+	}
+
+	for _, rowErr := range report.Errors {
+		if rowErr.Err != nil {
+			app.logger.Printf("Failed to import row %d: %v", rowErr.Row, rowErr.Err)
+		}
+	}
+
+	status := http.StatusOK
+	if len(report.Errors) > 0 {
+		status = http.StatusUnprocessableEntity
+	}
+
+	err = app.writeJSON(w, status, report, nil)
+	if err != nil {
+		app.logger.Print(err)
+		http.Error(w, "The server encountered a problem and could not process your request", http.StatusInternalServerError)
+	}
+}
+
+// importFormat picks the format from the request's content type, defaulting to JSON.
+func importFormat(contentType string) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "text/csv":
+		return formatCSV
+	case "application/x-ndjson", "application/jsonl":
+		return formatNDJSON
+	default:
+		return formatJSON
+	}
+}
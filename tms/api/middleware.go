@@ -0,0 +1,292 @@
+package main
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"math"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// authenticate reads the caller's user ID from the X-User-ID header into the
+// request context. The server does not check who callers are itself: an
+// authenticating proxy in front of it does, and sets the header. So the
+// header is only trusted on requests that come straight from one of
+// -trusted-proxies, or from anyone with -insecure-user-header, which is for
+// development only. Requests without the header carry on anonymously.
+func (app *application) authenticate(next http.Handler) http.Handler {
+	// The setting was checked by loadConfig.
+	proxies, _ := parseTrustedProxies(app.config.auth.trustedProxies)
+
+	trusted := func(r *http.Request) bool {
+		if app.config.auth.insecureUserHeader {
+			return true
+		}
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			host = r.RemoteAddr
+		}
+		ip := net.ParseIP(host)
+		for _, proxy := range proxies {
+			if ip != nil && proxy.Contains(ip) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "X-User-ID")
+
+		header := r.Header.Get("X-User-ID")
+		if header == "" {
+			next.ServeHTTP(w, app.contextSetUser(r, 0))
+			return
+		}
+
+		if !trusted(r) {
+			http.Error(w, "X-User-ID is only accepted from trusted proxies", http.StatusUnauthorized)
+			return
+		}
+
+		userID, err := strconv.Atoi(header)
+		if err != nil || userID < 1 {
+			http.Error(w, "Invalid X-User-ID header", http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, app.contextSetUser(r, userID))
+	})
+}
+
+// rateLimit allows each client IP address limiter.rps requests a second,
+// with bursts of up to limiter.burst, and answers the rest with 429.
+func (app *application) rateLimit(next http.Handler) http.Handler {
+	if !app.config.limiter.enabled {
+		return next
+	}
+	limiter := newIPLimiter(app.config.limiter.rps, app.config.limiter.burst)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			ip = r.RemoteAddr
+		}
+
+		wait := limiter.allow(ip, time.Now())
+		if wait > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			http.Error(w, "Rate limit exceeded", http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ipLimiter keeps a token bucket for each client. The buckets of clients
+// that have been quiet for a few minutes are dropped.
+type ipLimiter struct {
+	rate      float64
+	burst     float64
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newIPLimiter(rate float64, burst int) *ipLimiter {
+	return &ipLimiter{rate: rate, burst: float64(burst), buckets: map[string]*bucket{}}
+}
+
+// allow takes a token from the client's bucket, and returns zero if there
+// was one, or otherwise how long until there will be.
+func (l *ipLimiter) allow(client string, now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastSweep) > time.Minute {
+		for key, b := range l.buckets {
+			if now.Sub(b.last) > 3*time.Minute {
+				delete(l.buckets, key)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	b, ok := l.buckets[client]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[client] = b
+	}
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+
+	if b.tokens < 1 {
+		return time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	}
+	b.tokens--
+	return 0
+}
+
+// compress compresses responses with gzip or deflate, whichever the client
+// prefers in its Accept-Encoding header. Only text and JSON-like content is
+// compressed: event streams have to reach clients as each event is written,
+// and images and archives are compressed already.
+func (app *application) compress(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+
+		encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+		if encoding == "" || r.Method == http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cw := &compressWriter{ResponseWriter: w, encoding: encoding}
+		defer cw.close()
+		next.ServeHTTP(cw, r)
+	})
+}
+
+// negotiateEncoding picks gzip or deflate from an Accept-Encoding header by
+// their q-values, preferring gzip on a tie, or returns "" for neither.
+func negotiateEncoding(header string) string {
+	q := map[string]float64{}
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(part, ";")
+		weight := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			weight = parsed
+		}
+		q[strings.ToLower(strings.TrimSpace(coding))] = weight
+	}
+
+	best, bestQ := "", 0.0
+	for _, coding := range []string{"gzip", "deflate"} {
+		weight, ok := q[coding]
+		if !ok {
+			weight, ok = q["*"]
+		}
+		if ok && weight > bestQ {
+			best, bestQ = coding, weight
+		}
+	}
+	return best
+}
+
+// compressible reports whether a response of the content type is worth
+// compressing.
+func compressible(contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case mediaType == "text/event-stream":
+		return false
+	case strings.HasPrefix(mediaType, "text/"),
+		strings.HasSuffix(mediaType, "+json"),
+		strings.HasSuffix(mediaType, "+xml"):
+		return true
+	}
+	switch mediaType {
+	case "application/json", "application/xml", "application/javascript", "application/x-ndjson":
+		return true
+	}
+	return false
+}
+
+// encoder is the part of gzip.Writer and zlib.Writer compressWriter uses.
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// encoders keeps idle compressors for reuse, as each holds a few hundred
+// kilobytes of state. HTTP's deflate coding is zlib framed, not raw deflate.
+var encoders = map[string]*sync.Pool{
+	"gzip":    {New: func() any { return gzip.NewWriter(nil) }},
+	"deflate": {New: func() any { return zlib.NewWriter(nil) }},
+}
+
+// compressWriter decides whether to compress a response once its headers
+// are written, and then passes the body through the encoder if so.
+type compressWriter struct {
+	http.ResponseWriter
+	encoding    string
+	wroteHeader bool
+	encoder     encoder
+}
+
+func (cw *compressWriter) WriteHeader(status int) {
+	// Informational responses come before the real one.
+	if cw.wroteHeader || status < http.StatusOK {
+		cw.ResponseWriter.WriteHeader(status)
+		return
+	}
+	cw.wroteHeader = true
+
+	header := cw.Header()
+	// Partial content is a byte range of the uncompressed body, and some
+	// responses have no body at all.
+	if status != http.StatusNoContent && status != http.StatusPartialContent &&
+		status != http.StatusNotModified && header.Get("Content-Encoding") == "" && compressible(header.Get("Content-Type")) {
+		header.Set("Content-Encoding", cw.encoding)
+		header.Del("Content-Length")
+		cw.encoder = encoders[cw.encoding].Get().(encoder)
+		cw.encoder.Reset(cw.ResponseWriter)
+	}
+	cw.ResponseWriter.WriteHeader(status)
+}
+
+func (cw *compressWriter) Write(p []byte) (int, error) {
+	if !cw.wroteHeader {
+		if cw.Header().Get("Content-Type") == "" {
+			cw.Header().Set("Content-Type", http.DetectContentType(p))
+		}
+		cw.WriteHeader(http.StatusOK)
+	}
+	if cw.encoder == nil {
+		return cw.ResponseWriter.Write(p)
+	}
+	return cw.encoder.Write(p)
+}
+
+// Flush sends what has been compressed so far on to the client.
+func (cw *compressWriter) Flush() {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if cw.encoder != nil {
+		cw.encoder.Flush()
+	}
+	http.NewResponseController(cw.ResponseWriter).Flush()
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// close finishes the compressed stream and returns the encoder to its pool.
+func (cw *compressWriter) close() {
+	if cw.encoder == nil {
+		return
+	}
+	cw.encoder.Close()
+	cw.encoder.Reset(nil)
+	encoders[cw.encoding].Put(cw.encoder)
+	cw.encoder = nil
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIPLimiter(t *testing.T) {
+	limiter := newIPLimiter(2, 3)
+	start := time.Now()
+
+	for i := 0; i < 3; i++ {
+		assert.Zero(t, limiter.allow("10.0.0.1", start))
+	}
+	assert.Equal(t, 500*time.Millisecond, limiter.allow("10.0.0.1", start))
+	// Other clients have their own bucket.
+	assert.Zero(t, limiter.allow("10.0.0.2", start))
+	// Tokens come back at the rate.
+	assert.Zero(t, limiter.allow("10.0.0.1", start.Add(500*time.Millisecond)))
+}
+
+func TestRateLimit(t *testing.T) {
+	app := &application{}
+	app.config.limiter.enabled = true
+	app.config.limiter.rps = 1
+	app.config.limiter.burst = 1
+	handler := app.rateLimit(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/v1/tasks", nil))
+	assert.Equal(t, http.StatusOK, res.Code)
+
+	res = httptest.NewRecorder()
+	handler.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/v1/tasks", nil))
+	assert.Equal(t, http.StatusTooManyRequests, res.Code)
+	assert.Equal(t, "1", res.Header().Get("Retry-After"))
+}
+
+func TestAuthenticate(t *testing.T) {
+	app := &application{}
+	app.config.auth.trustedProxies = []string{"10.0.0.0/8", "192.0.2.7"}
+	handler := app.authenticate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, strconv.Itoa(app.contextGetUser(r)))
+	}))
+
+	request := func(remoteAddr, userID string) (int, string) {
+		req := httptest.NewRequest(http.MethodGet, "/v1/tasks", nil)
+		req.RemoteAddr = remoteAddr
+		if userID != "" {
+			req.Header.Set("X-User-ID", userID)
+		}
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, req)
+		return res.Code, res.Body.String()
+	}
+
+	code, body := request("10.1.2.3:5000", "7")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "7", body)
+	code, body = request("192.0.2.7:5000", "7")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "7", body)
+
+	// Anyone else claiming to be a user is turned away, but may stay anonymous.
+	code, _ = request("192.0.2.8:5000", "7")
+	assert.Equal(t, http.StatusUnauthorized, code)
+	code, body = request("192.0.2.8:5000", "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "0", body)
+
+	app.config.auth.insecureUserHeader = true
+	handler = app.authenticate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, strconv.Itoa(app.contextGetUser(r)))
+	}))
+	code, body = request("192.0.2.8:5000", "7")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "7", body)
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	for header, want := range map[string]string{
+		"":                          "",
+		"gzip, deflate, br":         "gzip",
+		"deflate":                   "deflate",
+		"gzip;q=0.5, deflate;q=0.8": "deflate",
+		"gzip;q=0, *":               "deflate",
+		"*;q=0":                     "",
+		"identity":                  "",
+	} {
+		assert.Equal(t, want, negotiateEncoding(header), header)
+	}
+}
+
+func TestCompress(t *testing.T) {
+	body := strings.Repeat(`{"title":"Renew domain"},`, 100)
+	app := &application{}
+	serve := func(contentType, acceptEncoding string) *httptest.ResponseRecorder {
+		handler := app.compress(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", contentType)
+			io.WriteString(w, body)
+		}))
+		req := httptest.NewRequest(http.MethodGet, "/v1/tasks", nil)
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, req)
+		return res
+	}
+
+	res := serve("application/json", "gzip")
+	assert.Equal(t, "gzip", res.Header().Get("Content-Encoding"))
+	assert.Equal(t, "Accept-Encoding", res.Header().Get("Vary"))
+	assert.Less(t, res.Body.Len(), len(body))
+	reader, err := gzip.NewReader(res.Body)
+	assert.NoError(t, err)
+	data, err := io.ReadAll(reader)
+	assert.NoError(t, err)
+	assert.Equal(t, body, string(data))
+
+	res = serve("text/csv", "deflate")
+	assert.Equal(t, "deflate", res.Header().Get("Content-Encoding"))
+	reader2, err := zlib.NewReader(res.Body)
+	assert.NoError(t, err)
+	data, err = io.ReadAll(reader2)
+	assert.NoError(t, err)
+	assert.Equal(t, body, string(data))
+
+	for contentType, acceptEncoding := range map[string]string{
+		"application/json":  "",
+		"text/event-stream": "gzip",
+		"image/png":         "gzip",
+	} {
+		res = serve(contentType, acceptEncoding)
+		assert.Empty(t, res.Header().Get("Content-Encoding"), contentType)
+		assert.Equal(t, body, res.Body.String(), contentType)
+	}
+}
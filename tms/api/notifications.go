@@ -0,0 +1,161 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	netmail "net/mail"
+	"strconv"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+
+	"tms.zinkworks.com/mail"
+	"tms.zinkworks.com/model"
+)
+
+// notificationData is what the mail templates are rendered with.
+type notificationData struct {
+	ActorID int
+	Task    *model.Task
+	Comment *model.TaskComment
+}
+
+// swagger:route PUT /users/{userID}/email notifications setUserEmailEndpoint
+// Set the address a user is emailed at.
+// Users are emailed when a task is assigned to them, and when a task assigned to them is
+// commented on or completed by someone else. Only the user themselves can set their address.
+// Consumes:
+// - application/json
+// Produces:
+// - application/json
+// Schemes: http, https
+// responses:
+//
+//	200: userEmailResponse
+//	400: badRequestError
+//	401: unauthorizedError
+//	403: forbiddenError
+//	500: internalServerError
+func (app *application) setUserEmailHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	userID, ok := app.requireSelf(w, r, ps)
+	if !ok {
+		return
+	}
+
+	var userEmail model.UserEmail
+	err := json.NewDecoder(r.Body).Decode(&userEmail)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	address, err := netmail.ParseAddress(userEmail.Email)
+	if err != nil {
+		http.Error(w, "Invalid email address", http.StatusBadRequest)
+		return
+	}
+
+	userEmail.UserID = userID
+	userEmail.Email = address.Address
+	userEmail.UpdatedAt = time.Now()
+
+	userDto := model.UserDto{DB: app.db}
+
+	err = userDto.SetUserEmail(&userEmail)
+	if err != nil {
+		http.Error(w, "Error saving email address", http.StatusInternalServerError)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, userEmail, nil)
+	if err != nil {
+		app.logger.Print(err)
+		http.Error(w, "The server encountered a problem and could not process your request", http.StatusInternalServerError)
+	}
+}
+
+// swagger:route DELETE /users/{userID}/email notifications deleteUserEmailEndpoint
+// Stop emailing a user.
+// Produces:
+// - application/json
+// Schemes: http, https
+// Responses:
+//
+//	200: successfullyDeletedResponse
+//	400: invalidIdError
+//	401: unauthorizedError
+//	403: forbiddenError
+//	404: notFoundError
+//	500: internalServerError
+func (app *application) deleteUserEmailHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	userID, ok := app.requireSelf(w, r, ps)
+	if !ok {
+		return
+	}
+
+	userDto := model.UserDto{DB: app.db}
+
+	deleted, err := userDto.DeleteUserEmail(userID)
+	if err != nil {
+		http.Error(w, "Error deleting email address", http.StatusInternalServerError)
+		return
+	}
+	if !deleted {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// requireSelf checks that the :userID in the path is the caller.
+func (app *application) requireSelf(w http.ResponseWriter, r *http.Request, ps httprouter.Params) (int, bool) {
+	userID, err := strconv.Atoi(ps.ByName("userID"))
+	if err != nil || userID < 1 {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return 0, false
+	}
+
+	callerID, ok := app.requireUser(w, r)
+	if !ok {
+		return 0, false
+	}
+	if callerID != userID {
+		http.Error(w, "You can only change your own email address", http.StatusForbidden)
+		return 0, false
+	}
+
+	return userID, true
+}
+
+// notify queues an email to the user about a change made by actorID. Users
+// are not told about their own changes, and nothing is sent when mail is
+// disabled or the user has no address. Failures are only logged, as the
+// change itself has already been saved.
+func (app *application) notify(template string, userID, actorID int, data notificationData) {
+	if app.mail == nil || userID == 0 || userID == actorID {
+		return
+	}
+
+	userDto := model.UserDto{DB: app.db}
+
+	email, err := userDto.GetUserEmail(userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return
+	}
+	if err != nil {
+		app.logger.Printf("Failed to look up email address of user %d: %v", userID, err)
+		return
+	}
+
+	data.ActorID = actorID
+	msg, err := mail.Render(template, []string{email}, data)
+	if err != nil {
+		app.logger.Printf("Failed to render %s email: %v", template, err)
+		return
+	}
+
+	app.mail.Enqueue(msg)
+}
@@ -0,0 +1,524 @@
+package main
+
+import (
+	_ "embed"
+	"net/http"
+
+	"tms.zinkworks.com/model"
+	"tms.zinkworks.com/openapi"
+)
+
+// maxValidatedBody bounds the JSON bodies the validation middleware reads;
+// larger ones are left for the handler to reject.
+const maxValidatedBody = 1 << 20
+
+//go:embed docs.html
+var docsPage []byte
+
+// openAPI describes the routes of version 1 of the API, in the same order
+// and under the same conditions as v1Routes, so a server backed by the task
+// file only documents the core task API.
+func (app *application) openAPI() *openapi.Document {
+	doc := openapi.New(openapi.Info{
+		Title: "Task Management System API",
+		Description: "Manage tasks, their items, comments, assignees, subtasks and dependencies, " +
+			"organised into projects and boards. Callers are identified by the X-User-ID header, which is set by " +
+			"the authenticating proxy in front of the server and not accepted from other clients. " +
+			"Errors are sent as plain text unless noted.",
+		Version: version,
+	})
+	doc.Servers = []openapi.Server{{URL: "/v1", Description: "Version 1. The unversioned paths are deprecated aliases of it."}}
+
+	task := doc.SchemaOf(model.Task{})
+	tasks := openapi.ArrayOf(task)
+	comment := doc.SchemaOf(model.TaskComment{})
+	taskID := openapi.Integer().Min(1).Describe("Task ID")
+
+	op := func(id, tag, summary string) *openapi.Operation {
+		return &openapi.Operation{OperationID: id, Tags: []string{tag}, Summary: summary}
+	}
+	// cached documents the conditional requests of endpoints that send an ETag and Last-Modified.
+	cached := func(o *openapi.Operation) *openapi.Operation {
+		return o.Header("If-None-Match", "Answer 304 if the response would have one of these ETags", openapi.String()).
+			Header("If-Modified-Since", "Answer 304 if nothing in the response has changed since; ignored with If-None-Match", openapi.String()).
+			Respond(http.StatusNotModified, "The client's copy is current", "", nil)
+	}
+	// paged documents the keyset pagination of long lists, which send the
+	// next page's URL in a Link header with rel="next".
+	paged := func(o *openapi.Operation) *openapi.Operation {
+		return o.Query("limit", "Return at most this many items, and a Link to the next page", openapi.Integer().Between(1, maxPageSize)).
+			Query("cursor", "Where the page starts, from the Link of the one before", openapi.String())
+	}
+
+	doc.Add(http.MethodGet, "/healthcheck", op("healthcheck", "health", "Report the server's status and version").
+		JSON(http.StatusOK, "The server is available", doc.SchemaOf(map[string]string{})))
+
+	doc.Add(http.MethodPost, "/tasks", op("createTask", "tasks", "Create a task with its items").
+		Body("application/json", "The task. The project defaults to the default project.", task).
+		JSON(http.StatusCreated, "The created task", task).
+		Error(http.StatusBadRequest, "Invalid body, the project does not exist, or the parent task is not in the project").
+		Error(http.StatusInternalServerError, "The task could not be saved"))
+	doc.Add(http.MethodGet, "/tasks", cached(paged(op("listTasks", "tasks", "List every task not in the trash, least recently updated first").
+		JSON(http.StatusOK, "The tasks", tasks).
+		Error(http.StatusBadRequest, "Invalid limit or cursor").
+		Error(http.StatusInternalServerError, "The tasks could not be fetched"))))
+	doc.Add(http.MethodPost, "/comments", op("createComment", "comments", "Comment on a task").
+		Body("application/json", "", comment).
+		JSON(http.StatusCreated, "The created comment", comment).
+		Error(http.StatusBadRequest, "Invalid body").
+		Error(http.StatusNotFound, "No such task").
+		Error(http.StatusInternalServerError, "The comment could not be saved"))
+	doc.Add(http.MethodGet, "/tasks/{id}", cached(op("getTask", "tasks", "Get a task with its items").
+		JSON(http.StatusOK, "The task", task).
+		Error(http.StatusBadRequest, "Invalid task ID").
+		Error(http.StatusNotFound, "No such task").
+		Error(http.StatusInternalServerError, "The task could not be fetched")))
+	doc.Add(http.MethodPut, "/tasks/{id}", op("updateTask", "tasks", "Replace a task's title, description, completed flag and items").
+		Body("application/json", "", task).
+		JSON(http.StatusOK, "The updated task", task).
+		Error(http.StatusBadRequest, "Invalid task ID or body").
+		Error(http.StatusNotFound, "No such task").
+		Error(http.StatusConflict, "The task cannot be completed while tasks blocking it are open").
+		Error(http.StatusInternalServerError, "The task could not be saved"))
+	doc.Add(http.MethodDelete, "/tasks/{id}", op("deleteTask", "tasks", "Move a task, and by default its subtasks, to the trash").
+		Query("subtasks", "What happens to the subtasks: deleted with the task, or moved up to its parent", openapi.Enum("cascade", "reparent")).
+		Respond(http.StatusOK, "The task was deleted", "", nil).
+		Error(http.StatusBadRequest, "Invalid task ID or subtasks mode").
+		Error(http.StatusNotFound, "No such task").
+		Error(http.StatusInternalServerError, "The task could not be deleted"))
+	doc.Add(http.MethodPatch, "/tasks/{taskID}/assign/{userID}", op("assignTask", "tasks", "Assign a task to a user, or unassign it with user 0").
+		JSON(http.StatusOK, "The assigned task", task).
+		Error(http.StatusBadRequest, "Invalid task or user ID").
+		Error(http.StatusNotFound, "No such task").
+		Error(http.StatusInternalServerError, "The task could not be assigned"))
+	doc.Add(http.MethodGet, "/users/{userID}/tasks/assigned", cached(paged(op("listAssignedTasks", "tasks", "List the tasks assigned to a user, least recently updated first").
+		JSON(http.StatusOK, "The tasks", tasks).
+		Error(http.StatusBadRequest, "Invalid user ID, limit or cursor").
+		Error(http.StatusInternalServerError, "The tasks could not be fetched"))))
+	doc.Add(http.MethodGet, "/comments/{taskID}", cached(paged(op("listComments", "comments", "List a task's comments, oldest first").
+		JSON(http.StatusOK, "The comments", openapi.ArrayOf(comment)).
+		Error(http.StatusBadRequest, "Invalid task ID, limit or cursor").
+		Error(http.StatusNotFound, "No such task").
+		Error(http.StatusInternalServerError, "The comments could not be fetched"))))
+	doc.Add(http.MethodGet, "/events", op("streamEvents", "events", "Stream task changes as Server-Sent Events").
+		Query("task_id", "Only send events about this task", openapi.Integer().Min(1)).
+		Query("assignee_id", "Only send events about tasks assigned to this user", openapi.Integer().Min(1)).
+		Query("last_event_id", "Resume after this event, for clients that cannot set Last-Event-ID", openapi.Integer().Min(0)).
+		Header("Last-Event-ID", "Resume after this event", openapi.Integer().Min(0)).
+		Respond(http.StatusOK, "A stream of task.created, task.updated, task.assigned, task.deleted and comment.created events, "+
+			"preceded by a reset event if some missed events are no longer kept", "text/event-stream", openapi.String()).
+		Error(http.StatusBadRequest, "Invalid filter or Last-Event-ID").
+		Error(http.StatusInternalServerError, "The connection cannot be streamed"))
+	doc.Add(http.MethodPost, "/rpc", &openapi.Operation{
+		OperationID: "rpc",
+		Tags:        []string{"rpc"},
+		Summary:     "Call task operations over JSON-RPC 2.0",
+		Description: "Methods: task.get, task.list, task.create, task.update, task.assign, task.delete, comment.add and comment.list, " +
+			"with named params. Send one request or a batch array; requests without an id are notifications. " +
+			"Problems with the body are reported as JSON-RPC errors, so the body is not validated against this schema.",
+		RawBody: true,
+	})
+	doc.Operation(http.MethodPost, "/rpc").
+		Body("application/json", "A request, or an array of them", &openapi.Schema{OneOf: []*openapi.Schema{
+			doc.SchemaOf(model.RPCRequest{}), openapi.ArrayOf(doc.SchemaOf(model.RPCRequest{})),
+		}}).
+		JSON(http.StatusOK, "The response, or an array of responses for a batch", &openapi.Schema{OneOf: []*openapi.Schema{
+			doc.SchemaOf(model.RPCResponse{}), openapi.ArrayOf(doc.SchemaOf(model.RPCResponse{})),
+		}}).
+		Respond(http.StatusNoContent, "Only notifications were sent", "", nil).
+		Error(http.StatusRequestEntityTooLarge, "The body is too large")
+
+	doc.Add(http.MethodGet, "/openapi.json", op("getOpenAPI", "docs", "Get this document").
+		JSON(http.StatusOK, "The OpenAPI 3 document", &openapi.Schema{Type: "object"}))
+	doc.Add(http.MethodGet, "/docs", op("getDocs", "docs", "Read this document in a browser").
+		Respond(http.StatusOK, "A page that renders the document", "text/html", openapi.String()))
+
+	if app.taskFile != nil {
+		return app.documentUsers(doc)
+	}
+
+	doc.Add(http.MethodPatch, "/tasks/{taskID}/parent/{parentID}", op("setParentTask", "subtasks", "Move a task under another task").
+		JSON(http.StatusOK, "The moved task", task).
+		Error(http.StatusBadRequest, "Invalid task or parent ID").
+		Error(http.StatusNotFound, "No such task, or no such parent in the task's project").
+		Error(http.StatusConflict, "The parent is the task itself or one of its subtasks").
+		Error(http.StatusInternalServerError, "The task could not be moved"))
+	doc.Add(http.MethodGet, "/tasks/{id}/subtasks", cached(op("listSubtasks", "subtasks", "List a task's direct subtasks").
+		JSON(http.StatusOK, "The subtasks", tasks).
+		Error(http.StatusBadRequest, "Invalid task ID").
+		Error(http.StatusNotFound, "No such task").
+		Error(http.StatusInternalServerError, "The subtasks could not be fetched")))
+	doc.Add(http.MethodGet, "/tasks/{id}/tree", op("getTaskTree", "subtasks", "Get a task with all its subtasks, nested, and their progress").
+		JSON(http.StatusOK, "The task tree", doc.SchemaOf(model.TaskNode{})).
+		Error(http.StatusBadRequest, "Invalid task ID").
+		Error(http.StatusNotFound, "No such task").
+		Error(http.StatusInternalServerError, "The tree could not be fetched"))
+
+	dependency := doc.SchemaOf(model.TaskDependency{})
+	doc.Add(http.MethodPost, "/tasks/{id}/dependencies", op("createDependency", "dependencies", "Link a task to another task").
+		Body("application/json", "", openapi.Require(&openapi.Schema{AllOf: []*openapi.Schema{dependency, {
+			Properties: map[string]*openapi.Schema{
+				"type":            openapi.Enum(model.DependencyBlocks, model.DependencyBlockedBy, model.DependencyRelatesTo),
+				"related_task_id": taskID,
+			},
+		}}}, "related_task_id", "type")).
+		JSON(http.StatusCreated, "The dependency, as stored", dependency).
+		Error(http.StatusBadRequest, "Invalid task ID or dependency").
+		Error(http.StatusNotFound, "No such task").
+		Error(http.StatusConflict, "The dependency exists already or would create a cycle").
+		Error(http.StatusInternalServerError, "The dependency could not be saved"))
+	doc.Add(http.MethodGet, "/tasks/{id}/dependencies", op("listDependencies", "dependencies", "List the dependencies a task takes part in").
+		JSON(http.StatusOK, "The dependencies", openapi.ArrayOf(dependency)).
+		Error(http.StatusBadRequest, "Invalid task ID").
+		Error(http.StatusInternalServerError, "The dependencies could not be fetched"))
+	doc.Add(http.MethodDelete, "/tasks/{id}/dependencies/{dependencyID}", op("deleteDependency", "dependencies", "Remove one of a task's dependencies").
+		Respond(http.StatusOK, "The dependency was removed", "", nil).
+		Error(http.StatusBadRequest, "Invalid task or dependency ID").
+		Error(http.StatusNotFound, "No such dependency on the task").
+		Error(http.StatusInternalServerError, "The dependency could not be removed"))
+
+	recurrence := doc.SchemaOf(model.TaskRecurrence{})
+	doc.Add(http.MethodPut, "/tasks/{id}/recurrence", op("setRecurrence", "recurrence", "Make a task recur on an RFC 5545 RRULE").
+		Body("application/json", "", openapi.Require(recurrence, "rrule")).
+		JSON(http.StatusOK, "The schedule", recurrence).
+		Error(http.StatusBadRequest, "Invalid task ID or rule").
+		Error(http.StatusNotFound, "No such task").
+		Error(http.StatusInternalServerError, "The schedule could not be saved"))
+	doc.Add(http.MethodGet, "/tasks/{id}/recurrence", op("getRecurrence", "recurrence", "Get a task's schedule").
+		JSON(http.StatusOK, "The schedule", recurrence).
+		Error(http.StatusBadRequest, "Invalid task ID").
+		Error(http.StatusNotFound, "The task does not recur").
+		Error(http.StatusInternalServerError, "The schedule could not be fetched"))
+	doc.Add(http.MethodDelete, "/tasks/{id}/recurrence", op("deleteRecurrence", "recurrence", "Stop a task recurring").
+		Respond(http.StatusOK, "The task no longer recurs", "", nil).
+		Error(http.StatusBadRequest, "Invalid task ID").
+		Error(http.StatusNotFound, "The task does not recur").
+		Error(http.StatusInternalServerError, "The schedule could not be removed"))
+
+	doc.Add(http.MethodGet, "/tasks/{id}/history", cached(paged(op("listHistory", "history", "List the changes made to a task, newest first").
+		JSON(http.StatusOK, "The changes", openapi.ArrayOf(doc.SchemaOf(model.TaskEvent{}))).
+		Error(http.StatusBadRequest, "Invalid task ID, limit or cursor").
+		Error(http.StatusNotFound, "No such task").
+		Error(http.StatusInternalServerError, "The history could not be fetched"))))
+	doc.Add(http.MethodPost, "/tasks/{id}/restore", op("restoreTask", "trash", "Take a task, and the subtasks deleted with it, out of the trash").
+		JSON(http.StatusOK, "The restored task", task).
+		Error(http.StatusBadRequest, "Invalid task ID").
+		Error(http.StatusNotFound, "No such task in the trash").
+		Error(http.StatusInternalServerError, "The task could not be restored"))
+
+	attachment := doc.SchemaOf(model.TaskAttachment{})
+	doc.Add(http.MethodPost, "/tasks/{id}/attachments", op("uploadAttachment", "attachments", "Attach a file to a task").
+		Body("multipart/form-data", "The file, as the file part", &openapi.Schema{
+			Type:       "object",
+			Properties: map[string]*openapi.Schema{"file": openapi.Binary()},
+			Required:   []string{"file"},
+		}).
+		JSON(http.StatusCreated, "The attachment", attachment).
+		Error(http.StatusBadRequest, "Invalid task ID, or no file part").
+		Error(http.StatusNotFound, "No such task").
+		Error(http.StatusRequestEntityTooLarge, "The file is larger than the server accepts").
+		Error(http.StatusUnsupportedMediaType, "The file's type is not allowed").
+		Error(http.StatusInternalServerError, "The file could not be stored").
+		Error(http.StatusServiceUnavailable, "The file was removed while it was uploaded; try again"))
+	doc.Add(http.MethodGet, "/tasks/{id}/attachments", op("listAttachments", "attachments", "List a task's attachments").
+		JSON(http.StatusOK, "The attachments", openapi.ArrayOf(attachment)).
+		Error(http.StatusBadRequest, "Invalid task ID").
+		Error(http.StatusNotFound, "No such task").
+		Error(http.StatusInternalServerError, "The attachments could not be fetched"))
+	doc.Add(http.MethodGet, "/tasks/{id}/attachments/{attachmentID}", op("downloadAttachment", "attachments", "Download an attachment; Range requests are supported").
+		Header("Range", "Fetch only part of the file, as in bytes=1024-", openapi.String()).
+		Respond(http.StatusOK, "The file, in its detected type", "application/octet-stream", openapi.Binary()).
+		Respond(http.StatusPartialContent, "The requested range of the file", "application/octet-stream", openapi.Binary()).
+		Respond(http.StatusNotModified, "The file matches the ETag given in If-None-Match", "", nil).
+		Error(http.StatusBadRequest, "Invalid task or attachment ID").
+		Error(http.StatusNotFound, "No such attachment on the task").
+		Error(http.StatusRequestedRangeNotSatisfiable, "The range is outside the file").
+		Error(http.StatusInternalServerError, "The file could not be read"))
+	doc.Add(http.MethodDelete, "/tasks/{id}/attachments/{attachmentID}", op("deleteAttachment", "attachments", "Remove an attachment from a task").
+		Respond(http.StatusOK, "The attachment was removed", "", nil).
+		Error(http.StatusBadRequest, "Invalid task or attachment ID").
+		Error(http.StatusNotFound, "No such attachment on the task").
+		Error(http.StatusInternalServerError, "The attachment could not be removed"))
+
+	doc.Add(http.MethodGet, "/trash", op("listTrash", "trash", "List the tasks in the trash").
+		JSON(http.StatusOK, "The deleted tasks", tasks).
+		Error(http.StatusInternalServerError, "The trash could not be fetched"))
+	doc.Add(http.MethodPatch, "/tasks/{taskID}/project/{projectID}", op("moveTaskToProject", "projects", "Move a task, and its subtasks, to another project").
+		JSON(http.StatusOK, "The moved task", task).
+		Error(http.StatusBadRequest, "Invalid task or project ID").
+		Error(http.StatusUnauthorized, "No X-User-ID header").
+		Error(http.StatusNotFound, "No such task, or the caller is not a member of the project").
+		Error(http.StatusConflict, "The task is a subtask; move its top-level task instead").
+		Error(http.StatusInternalServerError, "The task could not be moved"))
+
+	project := doc.SchemaOf(model.Project{})
+	member := doc.SchemaOf(model.ProjectMember{})
+	projectMember := func(o *openapi.Operation) *openapi.Operation {
+		return o.Error(http.StatusUnauthorized, "No X-User-ID header").
+			Error(http.StatusNotFound, "No such project, or the caller is not a member of it")
+	}
+	doc.Add(http.MethodPost, "/projects", op("createProject", "projects", "Create a project owned by the caller").
+		Body("application/json", "", requireNonEmpty(project, "name")).
+		JSON(http.StatusCreated, "The created project", project).
+		Error(http.StatusBadRequest, "Invalid body or no name").
+		Error(http.StatusUnauthorized, "No X-User-ID header").
+		Error(http.StatusInternalServerError, "The project could not be saved"))
+	doc.Add(http.MethodGet, "/projects", op("listProjects", "projects", "List the projects the caller is a member of").
+		JSON(http.StatusOK, "The projects", openapi.ArrayOf(project)).
+		Error(http.StatusUnauthorized, "No X-User-ID header").
+		Error(http.StatusInternalServerError, "The projects could not be fetched"))
+	doc.Add(http.MethodGet, "/projects/{id}", projectMember(op("getProject", "projects", "Get a project").
+		JSON(http.StatusOK, "The project", project).
+		Error(http.StatusBadRequest, "Invalid project ID").
+		Error(http.StatusInternalServerError, "The project could not be fetched")))
+	doc.Add(http.MethodGet, "/projects/{id}/members", projectMember(op("listProjectMembers", "projects", "List a project's members and their roles").
+		JSON(http.StatusOK, "The members", openapi.ArrayOf(member)).
+		Error(http.StatusBadRequest, "Invalid project ID").
+		Error(http.StatusInternalServerError, "The members could not be fetched")))
+	doc.Add(http.MethodPost, "/projects/{id}/members", projectMember(op("addProjectMember", "projects", "Add a user to a project; owners only").
+		Body("application/json", "", openapi.Require(&openapi.Schema{AllOf: []*openapi.Schema{member, {
+			Properties: map[string]*openapi.Schema{"user_id": openapi.Integer().Min(1)},
+		}}}, "user_id")).
+		JSON(http.StatusCreated, "The new member", member).
+		Error(http.StatusBadRequest, "Invalid project ID or body").
+		Error(http.StatusForbidden, "The caller does not own the project").
+		Error(http.StatusInternalServerError, "The member could not be added")))
+	doc.Add(http.MethodDelete, "/projects/{id}/members/{userID}", projectMember(op("removeProjectMember", "projects", "Remove a user from a project; owners only").
+		Respond(http.StatusOK, "The member was removed", "", nil).
+		Error(http.StatusBadRequest, "Invalid project or user ID").
+		Error(http.StatusForbidden, "The caller does not own the project").
+		Error(http.StatusInternalServerError, "The member could not be removed")))
+	doc.Add(http.MethodGet, "/projects/{id}/tasks", cached(paged(projectMember(op("listProjectTasks", "projects", "List the tasks in a project, least recently updated first").
+		JSON(http.StatusOK, "The tasks", tasks).
+		Error(http.StatusBadRequest, "Invalid project ID, limit or cursor").
+		Error(http.StatusInternalServerError, "The tasks could not be fetched")))))
+	doc.Add(http.MethodPost, "/projects/{id}/tasks", projectMember(op("createProjectTask", "projects", "Create a task in a project").
+		Body("application/json", "", task).
+		JSON(http.StatusCreated, "The created task", task).
+		Error(http.StatusBadRequest, "Invalid project ID or body, or the parent task is not in the project").
+		Error(http.StatusInternalServerError, "The task could not be saved")))
+
+	board := doc.SchemaOf(model.Board{})
+	doc.Add(http.MethodPost, "/projects/{id}/boards", projectMember(op("createBoard", "boards", "Create a board with its columns").
+		Body("application/json", "", openapi.Require(&openapi.Schema{AllOf: []*openapi.Schema{board, {
+			Properties: map[string]*openapi.Schema{
+				"name": {MinLength: intPtr(1)},
+				"columns": openapi.ArrayOf(openapi.Require(&openapi.Schema{
+					Type: "object",
+					Properties: map[string]*openapi.Schema{
+						"name":  {Type: "string", MinLength: intPtr(1)},
+						"state": openapi.Enum(model.ColumnStateOpen, model.ColumnStateCompleted),
+					},
+				}, "name", "state")).Between(1, 1000),
+			},
+		}}}, "name", "columns")).
+		JSON(http.StatusCreated, "The created board", board).
+		Error(http.StatusBadRequest, "Invalid project ID or body").
+		Error(http.StatusInternalServerError, "The board could not be saved")))
+	doc.Add(http.MethodGet, "/projects/{id}/boards", projectMember(op("listProjectBoards", "boards", "List a project's boards, without their cards").
+		JSON(http.StatusOK, "The boards", openapi.ArrayOf(board)).
+		Error(http.StatusBadRequest, "Invalid project ID").
+		Error(http.StatusInternalServerError, "The boards could not be fetched")))
+	doc.Add(http.MethodGet, "/boards/{id}", projectMember(op("getBoard", "boards", "Get a board with its columns and cards").
+		JSON(http.StatusOK, "The board", board).
+		Error(http.StatusBadRequest, "Invalid board ID").
+		Error(http.StatusInternalServerError, "The board could not be fetched")))
+	doc.Add(http.MethodPatch, "/boards/{id}/cards/{taskID}", projectMember(op("moveCard", "boards", "Place a task in a column of a board").
+		Body("application/json", "", openapi.Require(doc.SchemaOf(model.CardMove{}), "column_id")).
+		JSON(http.StatusOK, "The card", doc.SchemaOf(model.BoardCard{})).
+		Error(http.StatusBadRequest, "Invalid board or task ID, or the neighbouring task is not in the column").
+		Error(http.StatusConflict, "The task cannot move to a completed column while tasks blocking it are open").
+		Error(http.StatusInternalServerError, "The card could not be moved")))
+	doc.Add(http.MethodDelete, "/boards/{id}/cards/{taskID}", projectMember(op("removeCard", "boards", "Take a task off a board").
+		Respond(http.StatusOK, "The card was removed", "", nil).
+		Error(http.StatusBadRequest, "Invalid board or task ID").
+		Error(http.StatusInternalServerError, "The card could not be removed")))
+
+	webhook := doc.SchemaOf(model.Webhook{})
+	delivery := doc.SchemaOf(model.WebhookDelivery{})
+	doc.Add(http.MethodPost, "/webhooks", op("createWebhook", "webhooks", "Register a URL to be sent the events of a project's tasks").
+		Body("application/json", "", openapi.Require(&openapi.Schema{AllOf: []*openapi.Schema{webhook, {
+			Properties: map[string]*openapi.Schema{
+				"url":    {Type: "string", Format: "uri"},
+				"events": openapi.ArrayOf(openapi.Enum(model.WebhookEvents...)).Between(1, len(model.WebhookEvents)),
+			},
+		}}}, "url", "events")).
+		JSON(http.StatusCreated, "The webhook, with the secret deliveries are signed with", webhook).
+		Error(http.StatusBadRequest, "Invalid body, URL or event, or not a member of the project").
+		Error(http.StatusUnauthorized, "No X-User-ID header").
+		Error(http.StatusInternalServerError, "The webhook could not be saved"))
+	doc.Add(http.MethodGet, "/webhooks", op("listWebhooks", "webhooks", "List the caller's webhooks").
+		JSON(http.StatusOK, "The webhooks", openapi.ArrayOf(webhook)).
+		Error(http.StatusUnauthorized, "No X-User-ID header").
+		Error(http.StatusInternalServerError, "The webhooks could not be fetched"))
+	doc.Add(http.MethodGet, "/webhooks/{id}", op("getWebhook", "webhooks", "Get a webhook").
+		JSON(http.StatusOK, "The webhook", webhook).
+		Error(http.StatusBadRequest, "Invalid webhook ID").
+		Error(http.StatusUnauthorized, "No X-User-ID header").
+		Error(http.StatusNotFound, "No such webhook created by the caller").
+		Error(http.StatusInternalServerError, "The webhook could not be fetched"))
+	doc.Add(http.MethodDelete, "/webhooks/{id}", op("deleteWebhook", "webhooks", "Remove a webhook and its pending deliveries").
+		Respond(http.StatusOK, "The webhook was removed", "", nil).
+		Error(http.StatusBadRequest, "Invalid webhook ID").
+		Error(http.StatusUnauthorized, "No X-User-ID header").
+		Error(http.StatusNotFound, "No such webhook created by the caller").
+		Error(http.StatusInternalServerError, "The webhook could not be removed"))
+	doc.Add(http.MethodGet, "/webhooks/{id}/deliveries", op("listWebhookDeliveries", "webhooks", "List a webhook's most recent deliveries").
+		Query("limit", "How many deliveries to return", openapi.Integer().Between(1, 1000)).
+		JSON(http.StatusOK, "The deliveries, newest first", openapi.ArrayOf(delivery)).
+		Error(http.StatusBadRequest, "Invalid webhook ID or limit").
+		Error(http.StatusUnauthorized, "No X-User-ID header").
+		Error(http.StatusNotFound, "No such webhook created by the caller").
+		Error(http.StatusInternalServerError, "The deliveries could not be fetched"))
+	doc.Add(http.MethodPost, "/webhooks/{id}/deliveries/{deliveryID}/redeliver", op("redeliverWebhook", "webhooks", "Queue a delivery to be sent again").
+		JSON(http.StatusAccepted, "The queued delivery", delivery).
+		Error(http.StatusBadRequest, "Invalid webhook or delivery ID").
+		Error(http.StatusUnauthorized, "No X-User-ID header").
+		Error(http.StatusNotFound, "No such delivery of a webhook created by the caller").
+		Error(http.StatusInternalServerError, "The delivery could not be queued"))
+
+	userEmail := doc.SchemaOf(model.UserEmail{})
+	doc.Add(http.MethodPut, "/users/{userID}/email", op("setUserEmail", "notifications", "Set the address the caller is emailed at").
+		Body("application/json", "", openapi.Require(&openapi.Schema{AllOf: []*openapi.Schema{userEmail, {
+			Properties: map[string]*openapi.Schema{"email": {Type: "string", Format: "email"}},
+		}}}, "email")).
+		JSON(http.StatusOK, "The address", userEmail).
+		Error(http.StatusBadRequest, "Invalid user ID, body or address").
+		Error(http.StatusUnauthorized, "No X-User-ID header").
+		Error(http.StatusForbidden, "The user is not the caller").
+		Error(http.StatusInternalServerError, "The address could not be saved"))
+	doc.Add(http.MethodDelete, "/users/{userID}/email", op("deleteUserEmail", "notifications", "Stop emailing the caller").
+		Respond(http.StatusOK, "The address was removed", "", nil).
+		Error(http.StatusBadRequest, "Invalid user ID").
+		Error(http.StatusUnauthorized, "No X-User-ID header").
+		Error(http.StatusForbidden, "The user is not the caller").
+		Error(http.StatusNotFound, "The caller has no address").
+		Error(http.StatusInternalServerError, "The address could not be removed"))
+
+	doc.Add(http.MethodGet, "/tasks/order", op("getTaskOrder", "dependencies", "List the open tasks in an order that respects their blockers").
+		JSON(http.StatusOK, "The tasks", tasks).
+		Error(http.StatusInternalServerError, "The tasks could not be fetched"))
+	doc.Add(http.MethodPost, "/tasks/bulk", op("bulkTasks", "tasks", "Run many task operations in one request").
+		Body("application/json", "", openapi.Require(doc.SchemaOf(model.BulkRequest{}), "operations")).
+		JSON(http.StatusOK, "The outcome of every operation", doc.SchemaOf(model.BulkResponse{})).
+		Error(http.StatusBadRequest, "Invalid mode or operation").
+		Error(http.StatusInternalServerError, "The operations could not be run"))
+	doc.Add(http.MethodGet, "/tasks/export", op("exportTasks", "tasks", "Export every task in the caller's projects that is not in the trash").
+		Query("format", "Defaults to json", openapi.Enum(formatJSON, formatNDJSON, formatCSV)).
+		JSON(http.StatusOK, "The tasks, as a download", tasks).
+		Respond(http.StatusOK, "", "application/x-ndjson", openapi.String()).
+		Respond(http.StatusOK, "", "text/csv", openapi.String()).
+		Error(http.StatusBadRequest, "Invalid format").
+		Error(http.StatusInternalServerError, "The tasks could not be fetched"))
+	importOp := op("importTasks", "tasks", "Upsert tasks from an export").
+		Query("format", "Defaults to the body's content type", openapi.Enum(formatJSON, formatNDJSON, formatCSV)).
+		Query("dry_run", "Check the import without saving anything", openapi.Boolean()).
+		Body("application/json", "The tasks, as written by the export. Rows are checked one by one and reported in the response.", tasks).
+		Body("application/x-ndjson", "", openapi.String()).
+		Body("application/jsonl", "", openapi.String()).
+		Body("text/csv", "", openapi.String()).
+		JSON(http.StatusOK, "The import report", doc.SchemaOf(model.ImportReport{})).
+		JSON(http.StatusUnprocessableEntity, "Some rows were rejected and nothing was saved", doc.SchemaOf(model.ImportReport{})).
+		Error(http.StatusBadRequest, "Invalid format, dry_run or file").
+		Error(http.StatusRequestEntityTooLarge, "The body is too large").
+		Error(http.StatusInternalServerError, "The tasks could not be saved")
+	importOp.RawBody = true
+	doc.Add(http.MethodPost, "/tasks/import", importOp)
+
+	return app.documentUsers(doc)
+}
+
+// documentUsers adds the X-User-ID header, read by the authenticate
+// middleware, to every operation.
+func (app *application) documentUsers(doc *openapi.Document) *openapi.Document {
+	for _, item := range doc.Paths {
+		for _, op := range *item {
+			op.Header("X-User-ID", "The calling user, set by the authenticating proxy. Some operations require it.", openapi.Integer().Min(1))
+			if op.Responses["401"] == nil {
+				op.Error(http.StatusUnauthorized, "Invalid X-User-ID header, or one not sent by a trusted proxy")
+			}
+		}
+	}
+	return doc
+}
+
+// requireNonEmpty requires the given string properties to be present and non-empty.
+func requireNonEmpty(schema *openapi.Schema, names ...string) *openapi.Schema {
+	properties := map[string]*openapi.Schema{}
+	for _, name := range names {
+		properties[name] = &openapi.Schema{MinLength: intPtr(1)}
+	}
+	return openapi.Require(&openapi.Schema{AllOf: []*openapi.Schema{schema, {Properties: properties}}}, names...)
+}
+
+func intPtr(n int) *int {
+	return &n
+}
+
+// swagger:route GET /openapi.json docs getOpenAPIEndpoint
+// Get the OpenAPI 3 document.
+// Describes every route this server serves, with schemas generated from the types the handlers use.
+// responses:
+//
+//	200: openAPIResponse
+func (app *application) openAPIHandler(doc *openapi.Document) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		err := app.writeJSON(w, http.StatusOK, doc, nil)
+		if err != nil {
+			app.logger.Print(err)
+			http.Error(w, "The server encountered a problem and could not process your request", http.StatusInternalServerError)
+		}
+	}
+}
+
+// swagger:route GET /docs docs getDocsEndpoint
+// Read the API documentation.
+// Serves a page that renders /openapi.json in a browser.
+// Produces:
+// - text/html
+// responses:
+//
+//	200: docsResponse
+func (app *application) docsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(docsPage)
+}
+
+// validateRequests rejects requests that do not match the document before
+// they reach a handler, when validation is enabled: 415 for a body in a
+// type the operation does not accept, 400 for anything else, with every
+// problem listed as JSON.
+func (app *application) validateRequests(doc *openapi.Document, next http.Handler) http.Handler {
+	if !app.config.validation.enabled {
+		return next
+	}
+	validator := openapi.NewValidator(doc, maxValidatedBody)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		problems, err := validator.Validate(r)
+		if err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if len(problems) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		status := http.StatusBadRequest
+		if problems[0].UnsupportedMediaType() {
+			status = http.StatusUnsupportedMediaType
+		}
+		err = app.writeJSON(w, status, validationResponse{Error: "The request does not match the API description", Problems: problems}, nil)
+		if err != nil {
+			app.logger.Print(err)
+			http.Error(w, "The server encountered a problem and could not process your request", http.StatusInternalServerError)
+		}
+	})
+}
+
+// validationResponse is the body of a request rejected by validateRequests.
+type validationResponse struct {
+	Error    string                    `json:"error"`
+	Problems []openapi.ValidationError `json:"problems"`
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"tms.zinkworks.com/events"
+	"tms.zinkworks.com/model"
+	"tms.zinkworks.com/openapi"
+)
+
+var (
+	routerRoute = regexp.MustCompile(`router\.(?:Handle|HandlerFunc)\(http\.Method(\w+), "([^"]+)"`)
+	fixedRoute  = regexp.MustCompile(`\{http\.Method(\w+), "([^"]+)"\}`)
+	routeParam  = regexp.MustCompile(`:(\w+)`)
+)
+
+// registeredRoutes reads the routes v1Routes registers, as "METHOD /path"
+// with OpenAPI path templates, split at the point the task-file server stops.
+func registeredRoutes(t *testing.T) (core, all []string) {
+	t.Helper()
+	source, err := os.ReadFile("routes.go")
+	assert.NoError(t, err)
+
+	// Only v1Routes registers API routes.
+	_, v1Source, found := strings.Cut(string(source), "func (app *application) v1Routes()")
+	assert.True(t, found)
+	coreSource, _, found := strings.Cut(v1Source, "if app.taskFile != nil")
+	assert.True(t, found)
+
+	collect := func(src string) []string {
+		var routes []string
+		for _, pattern := range []*regexp.Regexp{routerRoute, fixedRoute} {
+			for _, m := range pattern.FindAllStringSubmatch(src, -1) {
+				routes = append(routes, strings.ToUpper(m[1])+" "+routeParam.ReplaceAllString(m[2], "{$1}"))
+			}
+		}
+		sort.Strings(routes)
+		return routes
+	}
+	return collect(coreSource), collect(v1Source)
+}
+
+func TestOpenAPI_MatchesRoutes(t *testing.T) {
+	core, all := registeredRoutes(t)
+
+	assert.Equal(t, all, (&application{}).openAPI().Routes())
+	assert.Equal(t, core, (&application{taskFile: &model.FileTaskStore{}}).openAPI().Routes())
+}
+
+func TestFileStore_UnsupportedRoutes(t *testing.T) {
+	core, all := registeredRoutes(t)
+	server := newTestServer(t)
+
+	supported := make(map[string]bool)
+	for _, route := range core {
+		supported[route] = true
+	}
+	for _, route := range all {
+		if supported[route] {
+			continue
+		}
+		method, path, _ := strings.Cut(route, " ")
+		req, err := http.NewRequest(method, server.URL+regexp.MustCompile(`\{\w+\}`).ReplaceAllString(path, "1"), nil)
+		assert.NoError(t, err)
+		res, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		res.Body.Close()
+		assert.Equal(t, http.StatusNotImplemented, res.StatusCode, route)
+	}
+
+	// The core routes next to them are still served.
+	res, err := http.Get(server.URL + "/tasks/1")
+	assert.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusNotFound, res.StatusCode)
+}
+
+func TestOpenAPI_Served(t *testing.T) {
+	server := newTestServer(t)
+
+	res, err := http.Get(server.URL + "/openapi.json")
+	assert.NoError(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	var doc openapi.Document
+	assert.NoError(t, json.NewDecoder(res.Body).Decode(&doc))
+	assert.Equal(t, openapi.Version, doc.OpenAPI)
+	assert.NotNil(t, doc.Operation(http.MethodPost, "/tasks"))
+	assert.Contains(t, doc.Components.Schemas, "Task")
+
+	res, err = http.Get(server.URL + "/docs")
+	assert.NoError(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "text/html; charset=utf-8", res.Header.Get("Content-Type"))
+}
+
+func TestValidateRequests(t *testing.T) {
+	store, err := model.OpenFileTaskStore(filepath.Join(t.TempDir(), "tasks.json"))
+	assert.NoError(t, err)
+	app := &application{
+		logger:   log.New(io.Discard, "", 0),
+		taskFile: store,
+		hub:      events.NewHub(100),
+	}
+	app.config.validation.enabled = true
+	server := httptest.NewServer(app.routes())
+	defer server.Close()
+
+	post := func(path, contentType, body string) (int, string) {
+		res, err := http.Post(server.URL+path, contentType, strings.NewReader(body))
+		assert.NoError(t, err)
+		defer res.Body.Close()
+		data, _ := io.ReadAll(res.Body)
+		return res.StatusCode, string(data)
+	}
+
+	status, _ := post("/tasks", "application/json", `{"title":"Renew domain","items":["billing"]}`)
+	assert.Equal(t, http.StatusCreated, status)
+
+	status, body := post("/tasks", "application/json", `{"title":7,"items":"billing","completed":"no"}`)
+	assert.Equal(t, http.StatusBadRequest, status)
+	var rejected struct {
+		Error    string                    `json:"error"`
+		Problems []openapi.ValidationError `json:"problems"`
+	}
+	assert.NoError(t, json.Unmarshal([]byte(body), &rejected))
+	assert.Equal(t, []openapi.ValidationError{
+		{In: "body", Field: "completed", Message: "must be a boolean, not a string"},
+		{In: "body", Field: "items", Message: "must be an array, not a string"},
+		{In: "body", Field: "title", Message: "must be a string, not a number"},
+	}, rejected.Problems)
+
+	status, _ = post("/tasks", "text/csv", "title\nRenew domain\n")
+	assert.Equal(t, http.StatusUnsupportedMediaType, status)
+
+	res, err := http.Get(server.URL + "/tasks/first")
+	assert.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+
+	// JSON-RPC reports problems with its body itself.
+	status, body = post("/rpc", "application/json", `{"jsonrpc":"2.0","method":"task.get","params":{"id":"x"},"id":1}`)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Contains(t, body, `"code":-32602`)
+}
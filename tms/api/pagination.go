@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"tms.zinkworks.com/model"
+)
+
+const (
+	// maxPageSize is the most items a client can ask for in one page.
+	maxPageSize = 1000
+	// listBufferSize is how much of a list that is not paged is held, so it
+	// can be given validators, before it is streamed instead.
+	listBufferSize = 1 << 20
+)
+
+var (
+	errInvalidLimit  = fmt.Errorf("Invalid limit, expected 1 to %d", maxPageSize)
+	errInvalidCursor = errors.New("Invalid cursor")
+)
+
+// readPage reads the page of a list a request asks for with the limit and
+// cursor query parameters. Without either, it is the whole list.
+func readPage(r *http.Request) (model.Page, error) {
+	var page model.Page
+	query := r.URL.Query()
+
+	if s := query.Get("limit"); s != "" {
+		limit, err := strconv.Atoi(s)
+		if err != nil || limit < 1 || limit > maxPageSize {
+			return page, errInvalidLimit
+		}
+		page.Limit = limit
+	}
+
+	if s := query.Get("cursor"); s != "" {
+		cursor, err := model.ParseCursor(s)
+		if err != nil {
+			return page, errInvalidCursor
+		}
+		page.After = &cursor
+		// A cursor alone carries on with pages of the largest size.
+		if page.Limit == 0 {
+			page.Limit = maxPageSize
+		}
+	}
+
+	return page, nil
+}
+
+// listWriter writes a list as a JSON array an item at a time, as a store
+// reads it. A page, which is bounded, is held until the end so it can be
+// given an ETag, a Last-Modified time and a Link to the next page. A whole
+// list is held up to listBufferSize and then streamed, so however long it
+// is, the memory it takes stays the same.
+type listWriter[T any] struct {
+	logger   *log.Logger
+	w        http.ResponseWriter
+	r        *http.Request
+	page     model.Page
+	name     string
+	position func(T) model.Cursor
+
+	validators *validators
+	buf        bytes.Buffer
+	streaming  bool
+	count      int
+	last       model.Cursor
+	more       bool
+}
+
+// newListWriter returns a writer for the page of a list of items, each of
+// which is at position in it. The name is used in errors.
+func newListWriter[T any](logger *log.Logger, w http.ResponseWriter, r *http.Request, page model.Page, name string, position func(T) model.Cursor) *listWriter[T] {
+	return &listWriter[T]{
+		logger:     logger,
+		w:          w,
+		r:          r,
+		page:       page,
+		name:       name,
+		position:   position,
+		validators: newValidators(),
+	}
+}
+
+// fetch returns the page to read from the store: one item more than is
+// written, to find out whether there is a next page.
+func (list *listWriter[T]) fetch() model.Page {
+	page := list.page
+	if page.Limit > 0 {
+		page.Limit++
+	}
+	return page
+}
+
+// add writes the next item of the list.
+func (list *listWriter[T]) add(item T) error {
+	if list.page.Limit > 0 && list.count == list.page.Limit {
+		list.more = true
+		return nil
+	}
+
+	js, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+
+	if list.count == 0 {
+		list.buf.WriteByte('[')
+	} else {
+		list.buf.WriteByte(',')
+	}
+	list.buf.Write(js)
+	list.count++
+
+	position := list.position(item)
+	list.last = position
+	list.validators.add(position.ID, position.Time)
+
+	if list.page.Limit == 0 && list.buf.Len() >= listBufferSize {
+		return list.flush()
+	}
+	return nil
+}
+
+// flush sends what is held, starting the response the first time.
+func (list *listWriter[T]) flush() error {
+	if !list.streaming {
+		list.streaming = true
+		list.w.Header().Set("Content-Type", "application/json")
+		list.w.WriteHeader(http.StatusOK)
+	}
+	_, err := list.w.Write(list.buf.Bytes())
+	list.buf.Reset()
+	return err
+}
+
+// close ends the list, after the store has read it with the error given.
+func (list *listWriter[T]) close(err error) {
+	if err != nil {
+		list.logger.Printf("Failed to fetch %s: %v", list.name, err)
+		if list.streaming {
+			// The status has been sent, so the client can only be told by
+			// the response being cut short.
+			panic(http.ErrAbortHandler)
+		}
+		http.Error(list.w, "Error fetching "+list.name, http.StatusInternalServerError)
+		return
+	}
+
+	if list.count == 0 {
+		list.buf.WriteByte('[')
+	}
+	list.buf.WriteString("]\n")
+	if list.streaming {
+		list.flush()
+		return
+	}
+
+	if list.more {
+		query := list.r.URL.Query()
+		query.Set("cursor", list.last.String())
+		query.Set("limit", strconv.Itoa(list.page.Limit))
+		// Relative, so it holds whichever path and version the list was asked for under.
+		list.w.Header().Set("Link", fmt.Sprintf(`<?%s>; rel="next"`, query.Encode()))
+		// The same items with and without a next page are different
+		// responses. IDs start at 1, so this is no item's version.
+		list.validators.add(0, time.Time{})
+	}
+	if list.validators.notModified(list.w, list.r) {
+		return
+	}
+
+	list.w.Header().Set("Content-Type", "application/json")
+	list.w.WriteHeader(http.StatusOK)
+	list.w.Write(list.buf.Bytes())
+}
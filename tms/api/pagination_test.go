@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"tms.zinkworks.com/model"
+	"tms.zinkworks.com/tmsclient"
+)
+
+var nextLink = regexp.MustCompile(`^<(.+)>; rel="next"$`)
+
+func TestListPagination(t *testing.T) {
+	server := newTestServer(t)
+	client := tmsclient.New(server.URL, tmsclient.WithUserID(7))
+	ctx := context.Background()
+
+	for _, title := range []string{"Draft", "Review", "Publish", "Announce", "Archive"} {
+		_, err := client.CreateTask(ctx, model.Task{Title: title})
+		assert.NoError(t, err)
+	}
+	// Updating a task moves it to the end of the list.
+	_, err := client.UpdateTask(ctx, 1, model.Task{Title: "Draft again"})
+	assert.NoError(t, err)
+
+	get := func(target, etag string) (*http.Response, []model.Task) {
+		req, err := http.NewRequest(http.MethodGet, target, nil)
+		assert.NoError(t, err)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		res, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		defer res.Body.Close()
+
+		var tasks []model.Task
+		if res.StatusCode == http.StatusOK {
+			assert.NoError(t, json.NewDecoder(res.Body).Decode(&tasks))
+		}
+		return res, tasks
+	}
+	ids := func(tasks []model.Task) []int {
+		ids := make([]int, len(tasks))
+		for i, task := range tasks {
+			ids[i] = task.ID
+		}
+		return ids
+	}
+
+	var pages [][]int
+	target := server.URL + "/v1/tasks?limit=2"
+	for target != "" {
+		res, tasks := get(target, "")
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		pages = append(pages, ids(tasks))
+
+		// A page can be revalidated like the whole list.
+		notModified, _ := get(target, res.Header.Get("ETag"))
+		assert.Equal(t, http.StatusNotModified, notModified.StatusCode)
+
+		target = ""
+		if m := nextLink.FindStringSubmatch(res.Header.Get("Link")); m != nil {
+			next, err := res.Request.URL.Parse(m[1])
+			assert.NoError(t, err)
+			target = next.String()
+		}
+	}
+	assert.Equal(t, [][]int{{2, 3}, {4, 5}, {1}}, pages)
+
+	// Without a limit, the whole list comes in one response with no Link.
+	res, tasks := get(server.URL+"/v1/tasks", "")
+	assert.Equal(t, []int{2, 3, 4, 5, 1}, ids(tasks))
+	assert.Empty(t, res.Header.Get("Link"))
+
+	for _, query := range []string{"limit=0", "limit=1001", "limit=ten", "cursor=bogus"} {
+		res, _ := get(server.URL+"/v1/tasks?"+query, "")
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode, query)
+	}
+
+	// The client follows the Links itself.
+	all, err := client.ListTasks(ctx, tmsclient.ListOptions{Limit: 2}).All()
+	assert.NoError(t, err)
+	assert.Equal(t, []int{2, 3, 4, 5, 1}, ids(all))
+
+	for _, comment := range []string{"First", "Second", "Third"} {
+		_, err := client.AddComment(ctx, 3, comment)
+		assert.NoError(t, err)
+	}
+	comments, err := client.ListComments(ctx, 3, tmsclient.ListOptions{Limit: 2}).All()
+	assert.NoError(t, err)
+	assert.Len(t, comments, 3)
+	assert.Equal(t, "Third", comments[2].Comment)
+}
+
+func TestListWriter_StreamsLongLists(t *testing.T) {
+	res := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/tasks", nil)
+	now := time.Now()
+	list := newListWriter(log.New(io.Discard, "", 0), res, req, model.Page{}, "tasks", model.TaskCursor)
+
+	description := strings.Repeat("x", 1024)
+	count := 2 * listBufferSize / len(description)
+	for id := 1; id <= count; id++ {
+		assert.NoError(t, list.add(model.Task{ID: id, Description: description, UpdatedAt: now}))
+		if id == 1 {
+			assert.Zero(t, res.Body.Len(), "the start of a list is held")
+		}
+	}
+	list.close(nil)
+
+	// Once streaming, the response cannot be given validators.
+	assert.True(t, list.streaming)
+	assert.Empty(t, res.Header().Get("ETag"))
+	var tasks []model.Task
+	assert.NoError(t, json.Unmarshal(res.Body.Bytes(), &tasks))
+	assert.Len(t, tasks, count)
+
+	// An error after the response has started can only cut it short.
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() { list.close(errors.New("connection reset")) })
+}
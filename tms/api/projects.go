@@ -0,0 +1,544 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+
+	"tms.zinkworks.com/model"
+)
+
+// requireUser returns the caller's user ID, or writes a 401 for anonymous callers.
+func (app *application) requireUser(w http.ResponseWriter, r *http.Request) (int, bool) {
+	userID := app.contextGetUser(r)
+	if userID == 0 {
+		http.Error(w, "You must identify yourself with the X-User-ID header", http.StatusUnauthorized)
+		return 0, false
+	}
+
+	return userID, true
+}
+
+// requireProjectMember checks that the caller is a member of the project and
+// returns their role. Non-members get a 404 so they cannot tell which projects exist.
+func (app *application) requireProjectMember(w http.ResponseWriter, r *http.Request, projectID int) (string, bool) {
+	userID, ok := app.requireUser(w, r)
+	if !ok {
+		return "", false
+	}
+
+	projectDto := model.ProjectDto{DB: app.db}
+
+	role, err := projectDto.GetAccessRole(projectID, userID)
+	if err != nil {
+		http.Error(w, "Error fetching project membership", http.StatusInternalServerError)
+		return "", false
+	}
+	if role == "" {
+		http.Error(w, "Project not found", http.StatusNotFound)
+		return "", false
+	}
+
+	return role, true
+}
+
+// requireTaskAccess checks that the caller can access the project the task
+// is filed under, as requireProjectMember does for a project. A task in a
+// project they cannot access gets a 404, like one that does not exist. The
+// task file has no projects or users, so every task in it is open.
+func (app *application) requireTaskAccess(w http.ResponseWriter, r *http.Request, taskID int) bool {
+	if app.taskFile != nil {
+		return true
+	}
+	if _, ok := app.requireUser(w, r); !ok {
+		return false
+	}
+
+	ok, err := app.canAccessTask(r, taskID)
+	if err != nil {
+		app.logger.Printf("Failed to check access to task %d: %v", taskID, err)
+		http.Error(w, "Error fetching project membership", http.StatusInternalServerError)
+		return false
+	}
+	if !ok {
+		http.Error(w, "Task not found", http.StatusNotFound)
+		return false
+	}
+
+	return true
+}
+
+// canAccessTask reports whether the caller can access the project the task
+// is filed under. It is false for a task that does not exist; tasks in the
+// trash are included.
+func (app *application) canAccessTask(r *http.Request, taskID int) (bool, error) {
+	if app.taskFile != nil {
+		return true, nil
+	}
+
+	taskDto := model.TaskDto{DB: app.db}
+
+	projectID, err := taskDto.GetTaskProjectID(taskID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return app.canAccessProject(r, projectID)
+}
+
+// canAccessProject reports whether the caller can access the project's tasks.
+func (app *application) canAccessProject(r *http.Request, projectID int) (bool, error) {
+	if app.taskFile != nil {
+		return true, nil
+	}
+
+	projectDto := model.ProjectDto{DB: app.db}
+
+	role, err := projectDto.GetAccessRole(projectID, app.contextGetUser(r))
+	if err != nil {
+		return false, err
+	}
+
+	return role != "", nil
+}
+
+// taskAccess guards the routes of a single task, whose ID is the named
+// parameter, with requireTaskAccess. An ID that does not parse is left for
+// the handler to reject.
+func (app *application) taskAccess(param string, next httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		taskID, err := strconv.Atoi(ps.ByName(param))
+		if err == nil && !app.requireTaskAccess(w, r, taskID) {
+			return
+		}
+		next(w, r, ps)
+	}
+}
+
+// visibleTasks returns the filter that limits a list to the tasks the caller
+// can access, or writes a 401 for an anonymous caller. Lists from the task
+// file are not limited.
+func (app *application) visibleTasks(w http.ResponseWriter, r *http.Request) (model.TaskFilter, bool) {
+	if app.taskFile != nil {
+		return model.TaskFilter{}, true
+	}
+
+	userID, ok := app.requireUser(w, r)
+	return model.TaskFilter{VisibleTo: userID}, ok
+}
+
+// swagger:route POST /projects projects createProjectEndpoint
+// Create a new project.
+// The caller becomes the project's owner and first member.
+// Consumes:
+// - application/json
+// Produces:
+// - application/json
+// Schemes: http, https
+// responses:
+//
+//	201: projectCreatedResponse
+//	400: badRequestError
+//	401: unauthorizedError
+//	500: internalServerError
+func (app *application) createProjectHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := app.requireUser(w, r)
+	if !ok {
+		return
+	}
+
+	var createProject model.Project
+	err := json.NewDecoder(r.Body).Decode(&createProject)
+	if err != nil || createProject.Name == "" {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	createProject.OwnerUserID = userID
+	createProject.CreatedAt = time.Now()
+	createProject.UpdatedAt = createProject.CreatedAt
+
+	projectDto := model.ProjectDto{DB: app.db}
+
+	err = projectDto.Insert(&createProject)
+	if err != nil {
+		http.Error(w, "Error inserting project", http.StatusInternalServerError)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, createProject, nil)
+	if err != nil {
+		app.logger.Print(err)
+		http.Error(w, "The server encountered a problem and could not process your request", http.StatusInternalServerError)
+	}
+}
+
+// swagger:route GET /projects projects getProjectsEndpoint
+// Get the caller's projects.
+// Returns only the projects the caller is a member of.
+// Produces:
+// - application/json
+// Schemes: http, https
+// responses:
+//
+//	200: allProjectsResponse
+//	401: unauthorizedError
+//	500: internalServerError
+func (app *application) getProjectsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := app.requireUser(w, r)
+	if !ok {
+		return
+	}
+
+	projectDto := model.ProjectDto{DB: app.db}
+
+	projects, err := projectDto.GetProjectsForUser(userID)
+	if err != nil {
+		http.Error(w, "Error fetching projects", http.StatusInternalServerError)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, projects, nil)
+	if err != nil {
+		app.logger.Print(err)
+		http.Error(w, "The server encountered a problem and could not process your request", http.StatusInternalServerError)
+	}
+}
+
+// swagger:route GET /projects/{id} projects getProjectEndpoint
+// Get a project by ID.
+// Produces:
+// - application/json
+// Schemes: http, https
+// responses:
+//
+//	200: projectResponse
+//	400: invalidIdError
+//	401: unauthorizedError
+//	404: notFoundError
+//	500: internalServerError
+func (app *application) getProjectHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	projectID, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid project ID", http.StatusBadRequest)
+		return
+	}
+
+	if _, ok := app.requireProjectMember(w, r, projectID); !ok {
+		return
+	}
+
+	projectDto := model.ProjectDto{DB: app.db}
+
+	project, err := projectDto.GetProject(projectID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.NotFound(w, r)
+		} else {
+			http.Error(w, "Error fetching project", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, project, nil)
+	if err != nil {
+		app.logger.Print(err)
+		http.Error(w, "The server encountered a problem and could not process your request", http.StatusInternalServerError)
+	}
+}
+
+// swagger:route GET /projects/{id}/members projects getProjectMembersEndpoint
+// Get the members of a project.
+// Produces:
+// - application/json
+// Schemes: http, https
+// responses:
+//
+//	200: allProjectMembersResponse
+//	400: invalidIdError
+//	401: unauthorizedError
+//	404: notFoundError
+//	500: internalServerError
+func (app *application) getProjectMembersHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	projectID, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid project ID", http.StatusBadRequest)
+		return
+	}
+
+	if _, ok := app.requireProjectMember(w, r, projectID); !ok {
+		return
+	}
+
+	projectDto := model.ProjectDto{DB: app.db}
+
+	members, err := projectDto.GetMembers(projectID)
+	if err != nil {
+		http.Error(w, "Error fetching project members", http.StatusInternalServerError)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, members, nil)
+	if err != nil {
+		app.logger.Print(err)
+		http.Error(w, "The server encountered a problem and could not process your request", http.StatusInternalServerError)
+	}
+}
+
+// swagger:route POST /projects/{id}/members projects addProjectMemberEndpoint
+// Add a member to a project.
+// Only the project owner can add members.
+// Consumes:
+// - application/json
+// Produces:
+// - application/json
+// Schemes: http, https
+// responses:
+//
+//	201: projectMemberCreatedResponse
+//	400: badRequestError
+//	401: unauthorizedError
+//	403: forbiddenError
+//	404: notFoundError
+//	500: internalServerError
+func (app *application) addProjectMemberHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	projectID, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid project ID", http.StatusBadRequest)
+		return
+	}
+
+	role, ok := app.requireProjectMember(w, r, projectID)
+	if !ok {
+		return
+	}
+	if role != model.ProjectRoleOwner {
+		http.Error(w, "Only the project owner can add members", http.StatusForbidden)
+		return
+	}
+
+	var member model.ProjectMember
+	err = json.NewDecoder(r.Body).Decode(&member)
+	if err != nil || member.UserID < 1 {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	member.ProjectID = projectID
+	member.Role = model.ProjectRoleMember
+	member.CreatedAt = time.Now()
+
+	projectDto := model.ProjectDto{DB: app.db}
+
+	err = projectDto.AddMember(&member)
+	if err != nil {
+		http.Error(w, "Error adding project member", http.StatusInternalServerError)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, member, nil)
+	if err != nil {
+		app.logger.Print(err)
+		http.Error(w, "The server encountered a problem and could not process your request", http.StatusInternalServerError)
+	}
+}
+
+// swagger:route DELETE /projects/{id}/members/{userID} projects removeProjectMemberEndpoint
+// Remove a member from a project.
+// Only the project owner can remove members, and the owner cannot be removed.
+// Produces:
+// - application/json
+// Schemes: http, https
+// Responses:
+//
+//	200: successfullyDeletedResponse
+//	400: invalidIdError
+//	401: unauthorizedError
+//	403: forbiddenError
+//	404: notFoundError
+//	500: internalServerError
+func (app *application) removeProjectMemberHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	projectID, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid project ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, err := strconv.Atoi(ps.ByName("userID"))
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	role, ok := app.requireProjectMember(w, r, projectID)
+	if !ok {
+		return
+	}
+	if role != model.ProjectRoleOwner {
+		http.Error(w, "Only the project owner can remove members", http.StatusForbidden)
+		return
+	}
+
+	projectDto := model.ProjectDto{DB: app.db}
+
+	removed, err := projectDto.RemoveMember(projectID, userID)
+	if err != nil {
+		app.logger.Printf("Failed to remove user %d from project %d: %v", userID, projectID, err)
+		http.Error(w, "Error removing project member", http.StatusInternalServerError)
+		return
+	}
+	if !removed {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// swagger:route GET /projects/{id}/tasks projects getProjectTasksEndpoint
+// Get the tasks in a project.
+// Lists are ordered by when tasks were last updated. With ?limit, at most that many are returned,
+// and a Link header with rel="next" gives the next page; its cursor is opaque.
+// Produces:
+// - application/json
+// Schemes: http, https
+// responses:
+//
+//	200: allTasksResponse
+//	304: notModifiedResponse
+//	400: invalidIdError
+//	401: unauthorizedError
+//	404: notFoundError
+//	500: internalServerError
+func (app *application) getProjectTasksHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	projectID, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid project ID", http.StatusBadRequest)
+		return
+	}
+
+	if _, ok := app.requireProjectMember(w, r, projectID); !ok {
+		return
+	}
+
+	page, err := readPage(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	taskDto := model.TaskDto{DB: app.db}
+
+	list := newListWriter(app.logger, w, r, page, "tasks", model.TaskCursor)
+	list.close(taskDto.EachTask(model.TaskFilter{ProjectID: projectID}, list.fetch(), list.add))
+}
+
+// swagger:route POST /projects/{id}/tasks projects createProjectTaskEndpoint
+// Create a task in a project.
+// Consumes:
+// - application/json
+// Produces:
+// - application/json
+// Schemes: http, https
+// responses:
+//
+//	201: taskCreatedResponse
+//	400: badRequestError
+//	401: unauthorizedError
+//	404: notFoundError
+//	500: internalServerError
+func (app *application) createProjectTaskHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	projectID, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid project ID", http.StatusBadRequest)
+		return
+	}
+
+	if _, ok := app.requireProjectMember(w, r, projectID); !ok {
+		return
+	}
+
+	var createTask model.Task
+	err = json.NewDecoder(r.Body).Decode(&createTask)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	createTask.CreatedAt = time.Now()
+	createTask.UpdatedAt = time.Now()
+	createTask.AssignedUserID = 0
+	createTask.ProjectID = projectID
+
+	app.insertTask(w, r, &createTask)
+}
+
+// swagger:route PATCH /tasks/{taskID}/project/{projectID} projects moveTaskToProjectEndpoint
+// Move a task, and its subtasks, to another project.
+// The caller must be a member of both the task's current project and the target project.
+// Subtasks cannot be moved on their own, and the moved tasks are taken off the old project's boards.
+// Produces:
+// - application/json
+// Schemes: http, https
+// Responses:
+//
+//	200: taskResponse
+//	400: invalidIdError
+//	401: unauthorizedError
+//	404: notFoundError
+//	409: subtaskMoveError
+//	500: internalServerError
+func (app *application) moveTaskToProjectHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+
+	taskDto := model.TaskDto{DB: app.db, ActorID: app.contextGetUser(r)}
+
+	taskID, err := strconv.Atoi(ps.ByName("taskID"))
+	if err != nil {
+		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	projectID, err := strconv.Atoi(ps.ByName("projectID"))
+	if err != nil {
+		http.Error(w, "Invalid project ID", http.StatusBadRequest)
+		return
+	}
+
+	existingTask, err := taskDto.GetTask(taskID)
+	if err != nil {
+		http.Error(w, "Task not found", http.StatusNotFound)
+		return
+	}
+
+	// Access to the task's own project is checked by the route.
+	if _, ok := app.requireProjectMember(w, r, projectID); !ok {
+		return
+	}
+
+	existingTask.ProjectID = projectID
+	existingTask.UpdatedAt = time.Now()
+
+	err = taskDto.MoveTaskToProject(taskID, projectID, existingTask.UpdatedAt)
+	if errors.Is(err, model.ErrSubtaskMove) {
+		http.Error(w, "A subtask stays in its parent's project; move the top-level task instead", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error moving task", http.StatusInternalServerError)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, existingTask, nil)
+	if err != nil {
+		app.logger.Print(err)
+		http.Error(w, "The server encountered a problem and could not process your request", http.StatusInternalServerError)
+	}
+}
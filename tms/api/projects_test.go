@@ -0,0 +1,84 @@
+package main
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+
+	"tms.zinkworks.com/events"
+)
+
+func TestTaskAccess(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	app := &application{logger: log.New(io.Discard, "", 0), db: db, hub: events.NewHub(100)}
+	app.config.auth.trustedProxies = []string{"192.0.2.0/24"}
+	handler := app.routes()
+
+	request := func(method, target, userID, body string) int {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		if userID != "" {
+			req.Header.Set("X-User-ID", userID)
+		}
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, req)
+		return res.Code
+	}
+	taskProject := func(taskID, projectID int) {
+		mock.ExpectQuery("SELECT project_id FROM task WHERE id = \\$1").
+			WithArgs(taskID).
+			WillReturnRows(sqlmock.NewRows([]string{"project_id"}).AddRow(projectID))
+	}
+	notMember := func(projectID int) {
+		mock.ExpectQuery("SELECT role FROM project_member").
+			WithArgs(projectID, 7).
+			WillReturnRows(sqlmock.NewRows([]string{"role"}))
+	}
+
+	// Task routes need to know who is asking.
+	assert.Equal(t, http.StatusUnauthorized, request(http.MethodGet, "/v1/tasks/5", "", ""))
+	assert.Equal(t, http.StatusUnauthorized, request(http.MethodGet, "/v1/tasks", "", ""))
+	assert.Equal(t, http.StatusUnauthorized, request(http.MethodPost, "/v1/rpc", "", `{"jsonrpc":"2.0","method":"task.list","id":1}`))
+
+	// A task in a project the caller is not a member of looks like one that does not exist.
+	taskProject(5, 3)
+	notMember(3)
+	assert.Equal(t, http.StatusNotFound, request(http.MethodGet, "/v1/tasks/5", "7", ""))
+
+	taskProject(5, 3)
+	notMember(3)
+	assert.Equal(t, http.StatusNotFound, request(http.MethodGet, "/v1/comments/5", "7", ""))
+
+	taskProject(5, 3)
+	notMember(3)
+	assert.Equal(t, http.StatusNotFound, request(http.MethodPost, "/v1/comments", "7", `{"task_id":5,"comment":"Hello"}`))
+
+	taskProject(5, 3)
+	notMember(3)
+	req := httptest.NewRequest(http.MethodPost, "/v1/rpc", strings.NewReader(`{"jsonrpc":"2.0","method":"task.get","params":{"id":5},"id":1}`))
+	req.Header.Set("X-User-ID", "7")
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, req)
+	assert.Contains(t, res.Body.String(), `"code":-32001`)
+
+	mock.ExpectQuery("SELECT project_id FROM task WHERE id = \\$1").
+		WithArgs(6).
+		WillReturnRows(sqlmock.NewRows([]string{"project_id"}))
+	assert.Equal(t, http.StatusNotFound, request(http.MethodGet, "/v1/tasks/6", "7", ""))
+
+	// Lists only hold the tasks in the default project and the caller's own.
+	mock.ExpectQuery("WHERE t.deleted_at IS NULL AND \\(project_id = 1 OR project_id IN \\(SELECT pm.project_id FROM project_member pm WHERE pm.user_id = \\$1\\)\\)").
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "description", "completed", "created_at", "updated_at", "assigned_user_id", "parent_task_id", "project_id", "items"}))
+	assert.Equal(t, http.StatusOK, request(http.MethodGet, "/v1/tasks", "7", ""))
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
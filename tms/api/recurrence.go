@@ -0,0 +1,197 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+
+	"tms.zinkworks.com/model"
+)
+
+// swagger:route PUT /tasks/{id}/recurrence recurrence setTaskRecurrenceEndpoint
+// Make a task the template of a recurring series.
+// The rrule supports FREQ (DAILY, WEEKLY, MONTHLY), INTERVAL, BYDAY, COUNT and UNTIL.
+// dtstart defaults to now. Any existing schedule on the task is replaced.
+// Consumes:
+// - application/json
+// Produces:
+// - application/json
+// Schemes: http, https
+// responses:
+//
+//	200: taskRecurrenceResponse
+//	400: badRequestError
+//	401: unauthorizedError
+//	404: notFoundError
+//	500: internalServerError
+func (app *application) setTaskRecurrenceHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	// Parse the task ID from the URL parameters.
+	taskID, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	var rec model.TaskRecurrence
+	err = json.NewDecoder(r.Body).Decode(&rec)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	rule, err := model.ParseRRule(rec.RRule)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	taskDto := model.TaskDto{DB: app.db, ActorID: app.contextGetUser(r)}
+
+	_, err = taskDto.GetTask(taskID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.NotFound(w, r)
+		} else {
+			http.Error(w, "Error fetching task", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	now := time.Now()
+	if rec.DTStart.IsZero() {
+		rec.DTStart = now
+	}
+	rec.TaskID = taskID
+	rec.CreatedAt = now
+	rec.NextRunAt = rec.FirstRun(rule, now)
+	rec.LastRunAt = nil
+
+	err = taskDto.UpsertTaskRecurrence(&rec)
+	if err != nil {
+		app.logger.Printf("Failed to set recurrence of task %d: %v", taskID, err)
+		http.Error(w, "Error saving recurrence", http.StatusInternalServerError)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, rec, nil)
+	if err != nil {
+		app.logger.Print(err)
+		http.Error(w, "The server encountered a problem and could not process your request", http.StatusInternalServerError)
+	}
+}
+
+// swagger:route GET /tasks/{id}/recurrence recurrence getTaskRecurrenceEndpoint
+// Get the recurrence schedule of a template task.
+// Produces:
+// - application/json
+// Schemes: http, https
+// responses:
+//
+//	200: taskRecurrenceResponse
+//	400: invalidTaskIdError
+//	401: unauthorizedError
+//	404: notFoundError
+//	500: internalServerError
+func (app *application) getTaskRecurrenceHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	// Parse the task ID from the URL parameters.
+	taskID, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	taskDto := model.TaskDto{DB: app.db}
+
+	rec, err := taskDto.GetTaskRecurrence(taskID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.NotFound(w, r)
+		} else {
+			http.Error(w, "Error fetching recurrence", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, rec, nil)
+	if err != nil {
+		app.logger.Print(err)
+		http.Error(w, "The server encountered a problem and could not process your request", http.StatusInternalServerError)
+	}
+}
+
+// swagger:route DELETE /tasks/{id}/recurrence recurrence deleteTaskRecurrenceEndpoint
+// Stop a recurring series.
+// Instances that were already created are kept.
+// Produces:
+// - application/json
+// Schemes: http, https
+// Responses:
+//
+//	200: successfullyDeletedResponse
+//	400: invalidTaskIdError
+//	401: unauthorizedError
+//	404: notFoundError
+//	500: internalServerError
+func (app *application) deleteTaskRecurrenceHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	// Parse the task ID from the URL parameters.
+	taskID, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	taskDto := model.TaskDto{DB: app.db, ActorID: app.contextGetUser(r)}
+
+	deleted, err := taskDto.DeleteTaskRecurrence(taskID)
+	if err != nil {
+		app.logger.Printf("Failed to delete recurrence of task %d: %v", taskID, err)
+		http.Error(w, "Error deleting recurrence", http.StatusInternalServerError)
+		return
+	}
+	if !deleted {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// runRecurrenceScheduler generates due recurring task instances straight
+// away and then on every tick of the interval, until the server shuts down.
+// It is started in its own goroutine from main.
+func (app *application) runRecurrenceScheduler(interval time.Duration) {
+	defer func() {
+		if err := recover(); err != nil {
+			app.logger.Printf("recurrence scheduler stopped: %v", err)
+			app.workers.stop(workerRecurrence, err)
+		}
+	}()
+	app.workers.start(workerRecurrence)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		taskDto := model.TaskDto{DB: app.db}
+
+		created, err := taskDto.GenerateDueRecurrences(time.Now())
+		if err != nil {
+			app.logger.Printf("Failed to generate recurring tasks: %v", err)
+		} else if created > 0 {
+			app.logger.Printf("generated %d recurring task(s)", created)
+		}
+		app.workers.ran(workerRecurrence, err)
+
+		select {
+		case <-ticker.C:
+		case <-app.stopping:
+			app.workers.stop(workerRecurrence, stoppedForShutdown)
+			return
+		}
+	}
+}
@@ -6,19 +6,128 @@ import (
 	"github.com/julienschmidt/httprouter"
 )
 
-func (app *application) routes() *httprouter.Router {
+// routes serves each version of the API under its prefix, with the
+// unversioned paths as deprecated aliases of /v1. The probes sit outside
+// the API, and are not rate limited. Responses are compressed for clients
+// that accept it.
+func (app *application) routes() http.Handler {
+	versions := apiVersions{
+		"/v1": app.v1Routes(),
+	}
+	probes := fixedRoutes{
+		{http.MethodGet, "/healthz"}: http.HandlerFunc(app.livenessHandler),
+		{http.MethodGet, "/readyz"}:  http.HandlerFunc(app.readinessHandler),
+	}
+	return probes.then(app.compress(app.rateLimit(app.authenticate(versions.serve("/v1")))))
+}
+
+// v1Routes returns the routes of version 1 of the API, without the prefix.
+func (app *application) v1Routes() http.Handler {
 	router := httprouter.New()
+	spec := app.openAPI()
 
 	router.HandlerFunc(http.MethodGet, "/healthcheck", app.healthcheckHandler)
-	router.HandlerFunc(http.MethodPost, "/tasks", app.createTaskHandler2)
+	router.HandlerFunc(http.MethodPost, "/tasks", app.createTaskHandler)
 	router.HandlerFunc(http.MethodGet, "/tasks", app.getAllTasksHandler)
 	router.HandlerFunc(http.MethodPost, "/comments", app.createTaskCommentsHandler)
-	router.Handle(http.MethodGet, "/tasks/:id", httprouter.Handle(app.getTaskHandler))
-	router.Handle(http.MethodPut, "/tasks/:id", httprouter.Handle(app.updateTaskHandler))
-	router.Handle(http.MethodDelete, "/tasks/:id", httprouter.Handle(app.deleteTaskHandler))
-	router.Handle(http.MethodPatch, "/tasks/:taskID/assign/:userID", httprouter.Handle(app.assignTaskHandler))
+	router.Handle(http.MethodGet, "/tasks/:id", app.taskAccess("id", app.getTaskHandler))
+	router.Handle(http.MethodPut, "/tasks/:id", app.taskAccess("id", app.updateTaskHandler))
+	router.Handle(http.MethodDelete, "/tasks/:id", app.taskAccess("id", app.deleteTaskHandler))
+	router.Handle(http.MethodPatch, "/tasks/:taskID/assign/:userID", app.taskAccess("taskID", app.assignTaskHandler))
 	router.Handle(http.MethodGet, "/users/:userID/tasks/assigned", httprouter.Handle(app.getTasksAssignedToUserHandler))
-	router.Handle(http.MethodGet, "/comments/:taskID", httprouter.Handle(app.getAllTaskCommentsHandler))
+	router.Handle(http.MethodGet, "/comments/:taskID", app.taskAccess("taskID", app.getAllTaskCommentsHandler))
+	router.HandlerFunc(http.MethodGet, "/events", app.streamEventsHandler)
+	router.HandlerFunc(http.MethodPost, "/rpc", app.rpcHandler)
+	router.HandlerFunc(http.MethodGet, "/openapi.json", app.openAPIHandler(spec))
+	router.HandlerFunc(http.MethodGet, "/docs", app.docsHandler)
+
+	// Single-task routes are wrapped in taskAccess, which limits them to the
+	// tasks in projects the caller can access. The task file only backs the
+	// core task API above, so in file mode the routes below are collected
+	// apart from it and answered with 501.
+	core := router
+	if app.taskFile != nil {
+		router = httprouter.New()
+	}
+
+	router.Handle(http.MethodPatch, "/tasks/:taskID/parent/:parentID", app.taskAccess("taskID", app.setParentTaskHandler))
+	router.Handle(http.MethodGet, "/tasks/:id/subtasks", app.taskAccess("id", app.getSubtasksHandler))
+	router.Handle(http.MethodGet, "/tasks/:id/tree", app.taskAccess("id", app.getTaskTreeHandler))
+	router.Handle(http.MethodPost, "/tasks/:id/dependencies", app.taskAccess("id", app.createTaskDependencyHandler))
+	router.Handle(http.MethodGet, "/tasks/:id/dependencies", app.taskAccess("id", app.getTaskDependenciesHandler))
+	router.Handle(http.MethodDelete, "/tasks/:id/dependencies/:dependencyID", app.taskAccess("id", app.deleteTaskDependencyHandler))
+	router.Handle(http.MethodPut, "/tasks/:id/recurrence", app.taskAccess("id", app.setTaskRecurrenceHandler))
+	router.Handle(http.MethodGet, "/tasks/:id/recurrence", app.taskAccess("id", app.getTaskRecurrenceHandler))
+	router.Handle(http.MethodDelete, "/tasks/:id/recurrence", app.taskAccess("id", app.deleteTaskRecurrenceHandler))
+	router.Handle(http.MethodGet, "/tasks/:id/history", app.taskAccess("id", app.getTaskHistoryHandler))
+	router.Handle(http.MethodPost, "/tasks/:id/restore", app.taskAccess("id", app.restoreTaskHandler))
+	router.Handle(http.MethodPost, "/tasks/:id/attachments", app.taskAccess("id", app.uploadTaskAttachmentHandler))
+	router.Handle(http.MethodGet, "/tasks/:id/attachments", app.taskAccess("id", app.getTaskAttachmentsHandler))
+	router.Handle(http.MethodGet, "/tasks/:id/attachments/:attachmentID", app.taskAccess("id", app.downloadTaskAttachmentHandler))
+	router.Handle(http.MethodDelete, "/tasks/:id/attachments/:attachmentID", app.taskAccess("id", app.deleteTaskAttachmentHandler))
+	router.HandlerFunc(http.MethodGet, "/trash", app.getTrashHandler)
+	router.Handle(http.MethodPatch, "/tasks/:taskID/project/:projectID", app.taskAccess("taskID", app.moveTaskToProjectHandler))
+	router.HandlerFunc(http.MethodPost, "/projects", app.createProjectHandler)
+	router.HandlerFunc(http.MethodGet, "/projects", app.getProjectsHandler)
+	router.Handle(http.MethodGet, "/projects/:id", httprouter.Handle(app.getProjectHandler))
+	router.Handle(http.MethodGet, "/projects/:id/members", httprouter.Handle(app.getProjectMembersHandler))
+	router.Handle(http.MethodPost, "/projects/:id/members", httprouter.Handle(app.addProjectMemberHandler))
+	router.Handle(http.MethodDelete, "/projects/:id/members/:userID", httprouter.Handle(app.removeProjectMemberHandler))
+	router.Handle(http.MethodGet, "/projects/:id/tasks", httprouter.Handle(app.getProjectTasksHandler))
+	router.Handle(http.MethodPost, "/projects/:id/tasks", httprouter.Handle(app.createProjectTaskHandler))
+	router.Handle(http.MethodPost, "/projects/:id/boards", httprouter.Handle(app.createBoardHandler))
+	router.Handle(http.MethodGet, "/projects/:id/boards", httprouter.Handle(app.getProjectBoardsHandler))
+	router.Handle(http.MethodGet, "/boards/:id", httprouter.Handle(app.getBoardHandler))
+	router.Handle(http.MethodPatch, "/boards/:id/cards/:taskID", httprouter.Handle(app.moveCardHandler))
+	router.Handle(http.MethodDelete, "/boards/:id/cards/:taskID", httprouter.Handle(app.removeCardHandler))
+	router.HandlerFunc(http.MethodPost, "/webhooks", app.createWebhookHandler)
+	router.HandlerFunc(http.MethodGet, "/webhooks", app.getWebhooksHandler)
+	router.Handle(http.MethodGet, "/webhooks/:id", httprouter.Handle(app.getWebhookHandler))
+	router.Handle(http.MethodDelete, "/webhooks/:id", httprouter.Handle(app.deleteWebhookHandler))
+	router.Handle(http.MethodGet, "/webhooks/:id/deliveries", httprouter.Handle(app.getWebhookDeliveriesHandler))
+	router.Handle(http.MethodPost, "/webhooks/:id/deliveries/:deliveryID/redeliver", httprouter.Handle(app.redeliverWebhookHandler))
+	router.Handle(http.MethodPut, "/users/:userID/email", httprouter.Handle(app.setUserEmailHandler))
+	router.Handle(http.MethodDelete, "/users/:userID/email", httprouter.Handle(app.deleteUserEmailHandler))
+
+	// httprouter cannot hold a static segment next to a wildcard, so these are
+	// matched before the router sees /tasks/:id.
+	fixed := fixedRoutes{
+		{http.MethodGet, "/tasks/order"}:   http.HandlerFunc(app.getTaskOrderHandler),
+		{http.MethodPost, "/tasks/bulk"}:   http.HandlerFunc(app.bulkTasksHandler),
+		{http.MethodGet, "/tasks/export"}:  http.HandlerFunc(app.exportTasksHandler),
+		{http.MethodPost, "/tasks/import"}: http.HandlerFunc(app.importTasksHandler),
+	}
+
+	if app.taskFile != nil {
+		return app.validateRequests(spec, fileStoreUnsupported(fixed, router, core))
+	}
+	return app.validateRequests(spec, fixed.then(router))
+}
+
+// fileStoreUnsupported answers the fixed routes and those of unsupported,
+// which need the database, with 501 Not Implemented, and hands everything
+// else to next.
+func fileStoreUnsupported(fixed fixedRoutes, unsupported *httprouter.Router, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, isFixed := fixed[[2]string{r.Method, r.URL.Path}]
+		if handle, _, _ := unsupported.Lookup(r.Method, r.URL.Path); isFixed || handle != nil {
+			http.Error(w, "Not supported by the file store; run the server with -store postgres", http.StatusNotImplemented)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// fixedRoutes maps an exact method and path to a handler.
+type fixedRoutes map[[2]string]http.Handler
 
-	return router
+// then serves requests matching one of the fixed routes and hands everything else to next.
+func (routes fixedRoutes) then(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if handler, ok := routes[[2]string{r.Method, r.URL.Path}]; ok {
+			handler.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
 }
@@ -0,0 +1,538 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"tms.zinkworks.com/events"
+	"tms.zinkworks.com/mail"
+	"tms.zinkworks.com/model"
+)
+
+const (
+	// maxRPCBatch bounds the number of calls in one batch request.
+	maxRPCBatch = 100
+	// maxRPCBody bounds the size of an RPC request body.
+	maxRPCBody = 1 << 20
+)
+
+// rpcMethod runs one JSON-RPC method. It returns the result to encode, or
+// an error to send in its place.
+type rpcMethod func(app *application, r *http.Request, params json.RawMessage) (any, *model.RPCError)
+
+// rpcMethods are the methods served at POST /rpc. They work on the same
+// task store, and publish the same events, as the REST handlers.
+var rpcMethods = map[string]rpcMethod{
+	"task.get":     (*application).rpcTaskGet,
+	"task.list":    (*application).rpcTaskList,
+	"task.create":  (*application).rpcTaskCreate,
+	"task.update":  (*application).rpcTaskUpdate,
+	"task.assign":  (*application).rpcTaskAssign,
+	"task.delete":  (*application).rpcTaskDelete,
+	"comment.add":  (*application).rpcCommentAdd,
+	"comment.list": (*application).rpcCommentList,
+}
+
+// swagger:route POST /rpc rpc rpcEndpoint
+// Call task operations over JSON-RPC 2.0.
+// The body is a single request or a batch array of them. The methods are task.get, task.list,
+// task.create, task.update, task.assign, task.delete, comment.add and comment.list, with named
+// params. Requests without an id are notifications and get no response; a body holding only
+// notifications is answered with 204. Failed calls carry a standard JSON-RPC error code, or
+// -32001 for a missing task and -32002 for a task with unfinished blockers. As with the REST
+// routes, calls only reach tasks in the caller's projects; others are reported as missing.
+// Consumes:
+// - application/json
+// Produces:
+// - application/json
+// Schemes: http, https
+// responses:
+//
+//	200: rpcResponse
+//	204: rpcNotificationsResponse
+//	401: unauthorizedError
+//	413: payloadTooLargeError
+func (app *application) rpcHandler(w http.ResponseWriter, r *http.Request) {
+	if app.taskFile == nil {
+		if _, ok := app.requireUser(w, r); !ok {
+			return
+		}
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRPCBody))
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 || body[0] != '[' {
+		response := app.rpcCall(r, body)
+		if response == nil {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		app.writeRPC(w, response)
+		return
+	}
+
+	var batch []json.RawMessage
+	err = json.Unmarshal(body, &batch)
+	if err != nil {
+		app.writeRPC(w, rpcFailure(nil, model.RPCParseError, "Parse error"))
+		return
+	}
+	if len(batch) == 0 {
+		app.writeRPC(w, rpcFailure(nil, model.RPCInvalidRequest, "Invalid Request: empty batch"))
+		return
+	}
+	if len(batch) > maxRPCBatch {
+		app.writeRPC(w, rpcFailure(nil, model.RPCInvalidRequest, fmt.Sprintf("Invalid Request: send at most %d calls in a batch", maxRPCBatch)))
+		return
+	}
+
+	responses := make([]*model.RPCResponse, 0, len(batch))
+	for _, call := range batch {
+		if response := app.rpcCall(r, call); response != nil {
+			responses = append(responses, response)
+		}
+	}
+	if len(responses) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	app.writeRPC(w, responses)
+}
+
+// rpcCall runs one call and returns its response, or nil for a notification.
+func (app *application) rpcCall(r *http.Request, raw json.RawMessage) *model.RPCResponse {
+	var req model.RPCRequest
+	err := json.Unmarshal(raw, &req)
+	if err != nil {
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) || len(raw) == 0 {
+			return rpcFailure(nil, model.RPCParseError, "Parse error")
+		}
+		return rpcFailure(nil, model.RPCInvalidRequest, "Invalid Request")
+	}
+
+	if req.JSONRPC != model.RPCVersion || req.Method == "" || !validRPCID(req.ID) {
+		return rpcFailure(validID(req.ID), model.RPCInvalidRequest, "Invalid Request")
+	}
+
+	method, ok := rpcMethods[req.Method]
+	if !ok {
+		if req.IsNotification() {
+			return nil
+		}
+		return rpcFailure(req.ID, model.RPCMethodNotFound, "Method not found: "+req.Method)
+	}
+
+	result, rpcErr := method(app, r, req.Params)
+	if req.IsNotification() {
+		return nil
+	}
+	if rpcErr != nil {
+		return &model.RPCResponse{JSONRPC: model.RPCVersion, Error: rpcErr, ID: req.ID}
+	}
+
+	encoded, err := json.Marshal(result)
+	if err != nil {
+		app.logger.Printf("Failed to encode result of %s: %v", req.Method, err)
+		return rpcFailure(req.ID, model.RPCInternalError, "Internal error")
+	}
+	return &model.RPCResponse{JSONRPC: model.RPCVersion, Result: encoded, ID: req.ID}
+}
+
+func (app *application) writeRPC(w http.ResponseWriter, data any) {
+	err := app.writeJSON(w, http.StatusOK, data, nil)
+	if err != nil {
+		app.logger.Print(err)
+		http.Error(w, "The server encountered a problem and could not process your request", http.StatusInternalServerError)
+	}
+}
+
+func rpcFailure(id json.RawMessage, code int, message string) *model.RPCResponse {
+	return &model.RPCResponse{
+		JSONRPC: model.RPCVersion,
+		Error:   &model.RPCError{Code: code, Message: message},
+		ID:      id,
+	}
+}
+
+// validRPCID reports whether id is absent, a string, a number or null.
+func validRPCID(id json.RawMessage) bool {
+	if id == nil {
+		return true
+	}
+	var value any
+	if json.Unmarshal(id, &value) != nil {
+		return false
+	}
+	switch value.(type) {
+	case string, float64, nil:
+		return true
+	}
+	return false
+}
+
+// validID returns id if it can be echoed back in an error response.
+func validID(id json.RawMessage) json.RawMessage {
+	if validRPCID(id) {
+		return id
+	}
+	return nil
+}
+
+// decodeParams decodes named params into dst, rejecting unknown fields.
+func decodeParams(params json.RawMessage, dst any) *model.RPCError {
+	if len(params) == 0 || params[0] != '{' {
+		return &model.RPCError{Code: model.RPCInvalidParams, Message: "Invalid params: expected an object"}
+	}
+	decoder := json.NewDecoder(bytes.NewReader(params))
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(dst)
+	if err != nil {
+		return &model.RPCError{Code: model.RPCInvalidParams, Message: "Invalid params: " + err.Error()}
+	}
+	return nil
+}
+
+func invalidParams(message string) *model.RPCError {
+	return &model.RPCError{Code: model.RPCInvalidParams, Message: "Invalid params: " + message}
+}
+
+// rpcStoreError turns a task store error into an RPC error, logging the ones
+// that are the server's fault.
+func (app *application) rpcStoreError(method string, err error) *model.RPCError {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return &model.RPCError{Code: model.RPCNotFound, Message: "Task not found"}
+	case errors.Is(err, model.ErrProjectNotFound):
+		return invalidParams("project not found")
+	case errors.Is(err, model.ErrParentNotFound):
+		return invalidParams("parent task not found")
+	}
+	app.logger.Printf("RPC %s failed: %v", method, err)
+	return &model.RPCError{Code: model.RPCInternalError, Message: "Internal error"}
+}
+
+// rpcTaskAccess checks that the caller can access a task, reporting one they
+// cannot like a missing task.
+func (app *application) rpcTaskAccess(r *http.Request, method string, taskID int) *model.RPCError {
+	ok, err := app.canAccessTask(r, taskID)
+	if err != nil {
+		return app.rpcStoreError(method, err)
+	}
+	if !ok {
+		return app.rpcStoreError(method, sql.ErrNoRows)
+	}
+	return nil
+}
+
+func (app *application) rpcTaskGet(r *http.Request, params json.RawMessage) (any, *model.RPCError) {
+	var p struct {
+		ID int `json:"id"`
+	}
+	if rpcErr := decodeParams(params, &p); rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	if rpcErr := app.rpcTaskAccess(r, "task.get", p.ID); rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	task, err := app.taskStore(r).GetTask(p.ID)
+	if err != nil {
+		return nil, app.rpcStoreError("task.get", err)
+	}
+	return task, nil
+}
+
+func (app *application) rpcTaskList(r *http.Request, params json.RawMessage) (any, *model.RPCError) {
+	var p struct {
+		AssignedUserID int `json:"assigned_user_id"`
+	}
+	if params != nil {
+		if rpcErr := decodeParams(params, &p); rpcErr != nil {
+			return nil, rpcErr
+		}
+	}
+
+	var filter model.TaskFilter
+	if app.taskFile == nil {
+		filter.VisibleTo = app.contextGetUser(r)
+	}
+	if p.AssignedUserID != 0 {
+		filter.AssignedUserID = &p.AssignedUserID
+	}
+
+	tasks := []model.Task{}
+	err := app.taskStore(r).EachTask(filter, model.Page{}, func(task model.Task) error {
+		tasks = append(tasks, task)
+		return nil
+	})
+	if err != nil {
+		return nil, app.rpcStoreError("task.list", err)
+	}
+	return tasks, nil
+}
+
+func (app *application) rpcTaskCreate(r *http.Request, params json.RawMessage) (any, *model.RPCError) {
+	var task model.Task
+	if rpcErr := decodeParams(params, &task); rpcErr != nil {
+		return nil, rpcErr
+	}
+	if task.Title == "" {
+		return nil, invalidParams("title is required")
+	}
+
+	task.ID = 0
+	task.CreatedAt = time.Now()
+	task.UpdatedAt = task.CreatedAt
+	task.AssignedUserID = 0
+	if task.ProjectID == 0 {
+		task.ProjectID = model.DefaultProjectID
+	}
+
+	// The file store checks the project itself, as it only has the default one.
+	if app.db != nil {
+		projectDto := model.ProjectDto{DB: app.db}
+		_, err := projectDto.GetProject(task.ProjectID)
+		if err != nil {
+			return nil, invalidParams("project not found")
+		}
+
+		ok, err := app.canAccessProject(r, task.ProjectID)
+		if err != nil {
+			return nil, app.rpcStoreError("task.create", err)
+		}
+		if !ok {
+			return nil, invalidParams("project not found")
+		}
+	}
+
+	taskDto := app.taskStore(r)
+	if task.ParentTaskID != 0 {
+		_, err := taskDto.GetTask(task.ParentTaskID)
+		if err != nil {
+			return nil, invalidParams("parent task not found")
+		}
+
+		ok, err := app.canAccessTask(r, task.ParentTaskID)
+		if err != nil {
+			return nil, app.rpcStoreError("task.create", err)
+		}
+		if !ok {
+			return nil, invalidParams("parent task not found")
+		}
+	}
+
+	err := taskDto.Insert(&task)
+	if err != nil {
+		return nil, app.rpcStoreError("task.create", err)
+	}
+	for _, item := range task.Items {
+		err = taskDto.InsertTaskItem(task.ID, item)
+		if err != nil {
+			return nil, app.rpcStoreError("task.create", err)
+		}
+	}
+
+	app.publish(events.TaskCreated, task.ID, task.AssignedUserID, task)
+	return task, nil
+}
+
+func (app *application) rpcTaskUpdate(r *http.Request, params json.RawMessage) (any, *model.RPCError) {
+	var p struct {
+		ID          int      `json:"id"`
+		Title       *string  `json:"title"`
+		Description *string  `json:"description"`
+		Completed   *bool    `json:"completed"`
+		Items       []string `json:"items"`
+	}
+	if rpcErr := decodeParams(params, &p); rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	if rpcErr := app.rpcTaskAccess(r, "task.update", p.ID); rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	taskDto := app.taskStore(r)
+
+	task, err := taskDto.GetTask(p.ID)
+	if err != nil {
+		return nil, app.rpcStoreError("task.update", err)
+	}
+
+	// Unlike PUT /tasks/{id}, fields left out of the params keep their value.
+	completing := p.Completed != nil && *p.Completed && !task.Completed
+
+	if p.Title != nil {
+		task.Title = *p.Title
+	}
+	if p.Description != nil {
+		task.Description = *p.Description
+	}
+	if p.Completed != nil {
+		task.Completed = *p.Completed
+	}
+	if p.Items != nil {
+		task.Items = p.Items
+	}
+	task.UpdatedAt = time.Now()
+
+	err = taskDto.UpdateTask(task.ID, task)
+	if errors.Is(err, model.ErrTaskBlocked) {
+		return nil, &model.RPCError{Code: model.RPCConflict, Message: "Task has unfinished blockers"}
+	}
+	if err != nil {
+		return nil, app.rpcStoreError("task.update", err)
+	}
+
+	app.publish(events.TaskUpdated, task.ID, task.AssignedUserID, task)
+	if completing {
+		app.notify(mail.TemplateTaskCompleted, task.AssignedUserID, app.contextGetUser(r), notificationData{Task: task})
+	}
+	return task, nil
+}
+
+func (app *application) rpcTaskAssign(r *http.Request, params json.RawMessage) (any, *model.RPCError) {
+	var p struct {
+		ID     int `json:"id"`
+		UserID int `json:"user_id"`
+	}
+	if rpcErr := decodeParams(params, &p); rpcErr != nil {
+		return nil, rpcErr
+	}
+	if p.UserID < 0 {
+		return nil, invalidParams("invalid user_id")
+	}
+
+	if rpcErr := app.rpcTaskAccess(r, "task.assign", p.ID); rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	taskDto := app.taskStore(r)
+
+	task, err := taskDto.GetTask(p.ID)
+	if err != nil {
+		return nil, app.rpcStoreError("task.assign", err)
+	}
+
+	task.AssignedUserID = p.UserID
+	task.UpdatedAt = time.Now()
+
+	err = taskDto.AssignUserToTask(task.ID, p.UserID, task.UpdatedAt)
+	if err != nil {
+		return nil, app.rpcStoreError("task.assign", err)
+	}
+
+	app.publish(events.TaskAssigned, task.ID, p.UserID, task)
+	app.notify(mail.TemplateTaskAssigned, p.UserID, app.contextGetUser(r), notificationData{Task: task})
+	return task, nil
+}
+
+func (app *application) rpcTaskDelete(r *http.Request, params json.RawMessage) (any, *model.RPCError) {
+	var p struct {
+		ID       int    `json:"id"`
+		Subtasks string `json:"subtasks"`
+	}
+	if rpcErr := decodeParams(params, &p); rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	if rpcErr := app.rpcTaskAccess(r, "task.delete", p.ID); rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	taskDto := app.taskStore(r)
+
+	assigneeID := 0
+	if task, err := taskDto.GetTask(p.ID); err == nil {
+		assigneeID = task.AssignedUserID
+	}
+
+	var err error
+	switch p.Subtasks {
+	case "", "cascade":
+		err = taskDto.DeleteTask(p.ID)
+	case "reparent":
+		err = taskDto.DeleteTaskReparent(p.ID)
+	default:
+		return nil, invalidParams("subtasks must be cascade or reparent")
+	}
+	if err != nil {
+		return nil, app.rpcStoreError("task.delete", err)
+	}
+
+	app.publish(events.TaskDeleted, p.ID, assigneeID, map[string]int{"id": p.ID})
+	return map[string]int{"id": p.ID}, nil
+}
+
+func (app *application) rpcCommentAdd(r *http.Request, params json.RawMessage) (any, *model.RPCError) {
+	var comment model.TaskComment
+	if rpcErr := decodeParams(params, &comment); rpcErr != nil {
+		return nil, rpcErr
+	}
+	if comment.Comment == "" {
+		return nil, invalidParams("comment is required")
+	}
+
+	if rpcErr := app.rpcTaskAccess(r, "comment.add", comment.TaskID); rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	taskDto := app.taskStore(r)
+
+	// Unlike POST /comments, the task must exist, so a typo is not silently accepted.
+	task, err := taskDto.GetTask(comment.TaskID)
+	if err != nil {
+		return nil, app.rpcStoreError("comment.add", err)
+	}
+
+	comment.ID = 0
+	comment.CreatedAt = time.Now()
+	err = taskDto.InsertTaskComment(&comment)
+	if err != nil {
+		return nil, app.rpcStoreError("comment.add", err)
+	}
+
+	app.publish(events.CommentCreated, comment.TaskID, task.AssignedUserID, comment)
+	app.notify(mail.TemplateTaskCommented, task.AssignedUserID, app.contextGetUser(r), notificationData{Task: task, Comment: &comment})
+	return comment, nil
+}
+
+func (app *application) rpcCommentList(r *http.Request, params json.RawMessage) (any, *model.RPCError) {
+	var p struct {
+		TaskID int `json:"task_id"`
+	}
+	if rpcErr := decodeParams(params, &p); rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	if rpcErr := app.rpcTaskAccess(r, "comment.list", p.TaskID); rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	comments, err := app.taskStore(r).GetAllTaskCommentsByTaskID(p.TaskID)
+	if err != nil {
+		return nil, app.rpcStoreError("comment.list", err)
+	}
+	if comments == nil {
+		comments = []model.TaskComment{}
+	}
+	return comments, nil
+}
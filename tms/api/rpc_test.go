@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"tms.zinkworks.com/model"
+	"tms.zinkworks.com/tmsclient"
+)
+
+// postRPC posts body to /rpc and returns the status and the response body.
+func postRPC(t *testing.T, url, body string) (int, string) {
+	t.Helper()
+
+	resp, err := http.Post(url+"/rpc", "application/json", strings.NewReader(body))
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	return resp.StatusCode, string(data)
+}
+
+func TestRPC_CreateThenGet(t *testing.T) {
+	server := newTestServer(t)
+
+	status, body := postRPC(t, server.URL, `{"jsonrpc":"2.0","method":"task.create","params":{"title":"Rotate certificates","items":["staging"]},"id":1}`)
+	assert.Equal(t, http.StatusOK, status)
+
+	var created struct {
+		Result model.Task
+		ID     int
+	}
+	assert.NoError(t, json.Unmarshal([]byte(body), &created))
+	assert.Equal(t, 1, created.ID)
+	assert.Equal(t, "Rotate certificates", created.Result.Title)
+
+	status, body = postRPC(t, server.URL, `{"jsonrpc":"2.0","method":"task.get","params":{"id":`+strconv.Itoa(created.Result.ID)+`},"id":"get"}`)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Contains(t, body, `"title":"Rotate certificates"`)
+	assert.Contains(t, body, `"id":"get"`)
+}
+
+func TestRPC_Batch(t *testing.T) {
+	server := newTestServer(t)
+
+	status, body := postRPC(t, server.URL, `[
+		{"jsonrpc":"2.0","method":"task.create","params":{"title":"Notified"}},
+		{"jsonrpc":"2.0","method":"task.list","id":1},
+		{"jsonrpc":"2.0","method":"task.get","params":{"id":999},"id":2},
+		{"jsonrpc":"2.0","method":"task.archive","id":3},
+		{"jsonrpc":"2.0","method":"task.get","params":{"id":"x"},"id":4},
+		{"method":"task.list","id":5},
+		1
+	]`)
+	assert.Equal(t, http.StatusOK, status)
+
+	var responses []model.RPCResponse
+	assert.NoError(t, json.Unmarshal([]byte(body), &responses))
+	if !assert.Len(t, responses, 6) {
+		return
+	}
+
+	var tasks []model.Task
+	assert.NoError(t, json.Unmarshal(responses[0].Result, &tasks))
+	if assert.Len(t, tasks, 1) {
+		assert.Equal(t, "Notified", tasks[0].Title)
+	}
+
+	codes := []int{}
+	ids := []string{}
+	for _, response := range responses[1:] {
+		codes = append(codes, response.Error.Code)
+		ids = append(ids, string(response.ID))
+	}
+	assert.Equal(t, []int{model.RPCNotFound, model.RPCMethodNotFound, model.RPCInvalidParams, model.RPCInvalidRequest, model.RPCInvalidRequest}, codes)
+	assert.Equal(t, []string{"2", "3", "4", "5", "null"}, ids)
+}
+
+func TestRPC_ClientCall(t *testing.T) {
+	server := newTestServer(t)
+	client := tmsclient.New(server.URL, tmsclient.WithUserID(7))
+	ctx := context.Background()
+
+	var task model.Task
+	err := client.Call(ctx, "task.create", map[string]any{"title": "Rotate certificates"}, &task)
+	assert.NoError(t, err)
+
+	var comment model.TaskComment
+	err = client.Call(ctx, "comment.add", map[string]any{"task_id": task.ID, "comment": "Staging done"}, &comment)
+	assert.NoError(t, err)
+	assert.Equal(t, task.ID, comment.TaskID)
+
+	err = client.Call(ctx, "task.assign", map[string]any{"id": task.ID + 1, "user_id": 5}, nil)
+	var rpcErr *model.RPCError
+	assert.True(t, errors.As(err, &rpcErr))
+	assert.Equal(t, model.RPCNotFound, rpcErr.Code)
+}
+
+func TestRPC_ErrorsWithoutCalls(t *testing.T) {
+	server := newTestServer(t)
+
+	status, body := postRPC(t, server.URL, `{"jsonrpc":"2.0","method":`)
+	assert.Equal(t, http.StatusOK, status)
+	assert.JSONEq(t, `{"jsonrpc":"2.0","error":{"code":-32700,"message":"Parse error"},"id":null}`, body)
+
+	status, body = postRPC(t, server.URL, `[]`)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Contains(t, body, `"code":-32600`)
+
+	status, body = postRPC(t, server.URL, `[{"jsonrpc":"2.0","method":"task.list"}]`)
+	assert.Equal(t, http.StatusNoContent, status)
+	assert.Empty(t, body)
+}
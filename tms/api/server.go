@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// serve serves the API over HTTPS when TLS is configured, with a plain
+// HTTP listener redirecting to it if asked for, and over HTTP otherwise.
+// On SIGINT or SIGTERM it shuts down gracefully: /readyz starts failing,
+// and after the drain delay the server stops accepting connections and
+// waits for requests in flight and the background workers. It returns nil
+// once they are done.
+func (app *application) serve(srv *http.Server) error {
+	tlsConfig, err := app.tlsConfig()
+	if err != nil {
+		return err
+	}
+	srv.TLSConfig = tlsConfig
+
+	shutdownErr := make(chan error)
+	go func() {
+		quit := make(chan os.Signal, 1)
+		signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+		s := <-quit
+
+		app.logger.Printf("caught %s, shutting down", s)
+		shutdownErr <- app.shutdown(srv)
+	}()
+
+	if tlsConfig != nil && app.config.tls.redirectPort != 0 {
+		redirect := &http.Server{
+			Addr:         fmt.Sprintf(":%d", app.config.tls.redirectPort),
+			Handler:      redirectToHTTPS(app.config.port),
+			ReadTimeout:  5 * time.Second,
+			WriteTimeout: 5 * time.Second,
+		}
+		srv.RegisterOnShutdown(func() { redirect.Close() })
+		go func() {
+			app.logger.Printf("redirecting HTTP on %s to HTTPS", redirect.Addr)
+			err := redirect.ListenAndServe()
+			if !errors.Is(err, http.ErrServerClosed) {
+				app.logger.Fatal(err)
+			}
+		}()
+	}
+
+	if tlsConfig != nil {
+		app.logger.Printf("starting %s server on %s with TLS", app.config.env, srv.Addr)
+		// The certificate is in TLSConfig already.
+		err = srv.ListenAndServeTLS("", "")
+	} else {
+		app.logger.Printf("starting %s server on %s", app.config.env, srv.Addr)
+		err = srv.ListenAndServe()
+	}
+	if !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	err = <-shutdownErr
+	if err != nil {
+		return err
+	}
+
+	// The workers use the database, which is closed once serve returns.
+	app.background.Wait()
+	app.logger.Printf("stopped server")
+	return nil
+}
+
+// shutdown marks the server as stopping, which fails /readyz and ends event
+// streams, waits for load balancers to notice, then stops the server.
+func (app *application) shutdown(srv *http.Server) error {
+	close(app.stopping)
+	time.Sleep(app.config.shutdown.drainDelay)
+
+	ctx, cancel := context.WithTimeout(context.Background(), app.config.shutdown.timeout)
+	defer cancel()
+	return srv.Shutdown(ctx)
+}
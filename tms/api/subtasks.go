@@ -0,0 +1,180 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/julienschmidt/httprouter"
+
+	"tms.zinkworks.com/model"
+)
+
+// swagger:route GET /tasks/{id}/subtasks tasks getSubtasksEndpoint
+// Get the direct subtasks of a task.
+// Returns the tasks whose parent is the given task.
+// Produces:
+// - application/json
+// Schemes: http, https
+// Responses:
+//
+//	200: allTasksResponse
+//	304: notModifiedResponse
+//	400: invalidTaskIdError
+//	401: unauthorizedError
+//	404: notFoundError
+//	500: internalServerError
+func (app *application) getSubtasksHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	// Parse the task ID from the URL parameters.
+	taskID, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	taskDto := model.TaskDto{DB: app.db}
+
+	_, err = taskDto.GetTask(taskID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.NotFound(w, r)
+		} else {
+			http.Error(w, "Error fetching task", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	tasks, err := taskDto.GetSubtasks(taskID)
+	if err != nil {
+		http.Error(w, "Error fetching subtasks", http.StatusInternalServerError)
+		return
+	}
+	if checkNotModified(w, r, taskVersions(tasks...)) {
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, tasks, nil)
+	if err != nil {
+		app.logger.Print(err)
+		http.Error(w, "The server encountered a problem and could not process your request", http.StatusInternalServerError)
+	}
+}
+
+// swagger:route GET /tasks/{id}/tree tasks getTaskTreeEndpoint
+// Get a task with all of its descendants.
+// Returns the task hierarchy rooted at the given task, with progress rolled up from the leaves.
+// Produces:
+// - application/json
+// Schemes: http, https
+// Responses:
+//
+//	200: taskTreeResponse
+//	400: invalidTaskIdError
+//	401: unauthorizedError
+//	404: notFoundError
+//	500: internalServerError
+func (app *application) getTaskTreeHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	// Parse the task ID from the URL parameters.
+	taskID, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	taskDto := model.TaskDto{DB: app.db}
+
+	tree, err := taskDto.GetTaskTree(taskID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.NotFound(w, r)
+		} else {
+			http.Error(w, "Error fetching task tree", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, tree, nil)
+	if err != nil {
+		app.logger.Print(err)
+		http.Error(w, "The server encountered a problem and could not process your request", http.StatusInternalServerError)
+	}
+}
+
+// swagger:route PATCH /tasks/{taskID}/parent/{parentID} tasks setParentTaskEndpoint
+// Move a task under a new parent.
+// A parentID of 0 detaches the task and makes it a root task.
+// Produces:
+// - application/json
+// Schemes: http, https
+// Responses:
+//
+//	200: taskResponse
+//	400: invalidIdError
+//	401: unauthorizedError
+//	404: notFoundError
+//	409: taskCycleError
+//	500: internalServerError
+func (app *application) setParentTaskHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+
+	taskDto := model.TaskDto{DB: app.db, ActorID: app.contextGetUser(r)}
+
+	// Parse the task ID from the URL parameters.
+	taskID, err := strconv.Atoi(ps.ByName("taskID"))
+	if err != nil {
+		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	parentID, err := strconv.Atoi(ps.ByName("parentID"))
+	if err != nil || parentID < 0 {
+		http.Error(w, "Invalid parent task ID", http.StatusBadRequest)
+		return
+	}
+
+	if parentID != 0 {
+		_, err = taskDto.GetTask(parentID)
+		if err != nil {
+			http.Error(w, "Parent task not found", http.StatusNotFound)
+			return
+		}
+
+		ok, err := app.canAccessTask(r, parentID)
+		if err != nil {
+			app.logger.Print(err)
+			http.Error(w, "Error fetching project membership", http.StatusInternalServerError)
+			return
+		}
+		if !ok {
+			http.Error(w, "Parent task not found", http.StatusNotFound)
+			return
+		}
+	}
+
+	err = taskDto.SetParentTask(taskID, parentID)
+	if err != nil {
+		switch {
+		case errors.Is(err, model.ErrTaskCycle):
+			http.Error(w, "Task cannot be moved under itself or one of its subtasks", http.StatusConflict)
+		case errors.Is(err, model.ErrParentNotFound):
+			http.Error(w, "Parent task not found", http.StatusNotFound)
+		case errors.Is(err, sql.ErrNoRows):
+			http.Error(w, "Task not found", http.StatusNotFound)
+		default:
+			http.Error(w, "Error moving task", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	task, err := taskDto.GetTask(taskID)
+	if err != nil {
+		http.Error(w, "Error fetching task", http.StatusInternalServerError)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, task, nil)
+	if err != nil {
+		app.logger.Print(err)
+		http.Error(w, "The server encountered a problem and could not process your request", http.StatusInternalServerError)
+	}
+}
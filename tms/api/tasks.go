@@ -4,107 +4,17 @@ import (
 	"database/sql"
 	"encoding/json"
 	"errors"
-	"fmt"
-	"io/ioutil"
 	"net/http"
-	"os"
-	"path/filepath"
 	"strconv"
-
 	"time"
 
 	"github.com/julienschmidt/httprouter"
 
+	"tms.zinkworks.com/events"
+	"tms.zinkworks.com/mail"
 	"tms.zinkworks.com/model"
 )
 
-func (app *application) createTaskHandler(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("Fetching All Tasks")
-
-	wd, err := os.Getwd()
-	if err != nil {
-		fmt.Println("Error getting current working directory:", err)
-		return
-	}
-
-	filePath := filepath.Join(wd, "tasks.json")
-
-	jsonData, err := ioutil.ReadFile(filePath)
-	if err != nil {
-		fmt.Println("Error reading JSON file:", err)
-		return
-	}
-
-	var tasks []model.Task
-	err = json.Unmarshal(jsonData, &tasks)
-	if err != nil {
-		fmt.Println("Error parsing JSON:", err)
-		return
-	}
-
-	// Encode the struct to JSON and send it as the HTTP response.
-	err = app.writeJSON(w, http.StatusOK, tasks, nil)
-	if err != nil {
-		app.logger.Print(err)
-		http.Error(w, "The server encountered a problem and could not process your request", http.StatusInternalServerError)
-	}
-}
-
-func (app *application) showTaskHandler(w http.ResponseWriter, r *http.Request) {
-	id, err := app.readIDParam(r)
-	if err != nil {
-		http.NotFound(w, r)
-		return
-	}
-
-	fmt.Println("Fetching Task with ID:", id)
-
-	wd, err := os.Getwd()
-	if err != nil {
-		fmt.Println("Error getting current working directory:", err)
-		return
-	}
-
-	filePath := filepath.Join(wd, "tasks.json")
-
-	jsonData, err := ioutil.ReadFile(filePath)
-	if err != nil {
-		fmt.Println("Error reading JSON file:", err)
-		return
-	}
-
-	var tasks []model.Task
-	err = json.Unmarshal(jsonData, &tasks)
-	if err != nil {
-		fmt.Println("Error parsing JSON:", err)
-		return
-	}
-
-	desiredID := id
-
-	// Find the task with the desired ID
-	var foundTask *model.Task
-	for _, task := range tasks {
-		if int64(task.ID) == desiredID {
-			foundTask = &task
-			break
-		}
-	}
-
-	// Check if the task was found
-	if foundTask == nil {
-		fmt.Printf("Task with ID %d not found\n", desiredID)
-		return
-	}
-
-	// Encode the struct to JSON and send it as the HTTP response.
-	err = app.writeJSON(w, http.StatusOK, foundTask, nil)
-	if err != nil {
-		app.logger.Print(err)
-		http.Error(w, "The server encountered a problem and could not process your request", http.StatusInternalServerError)
-	}
-}
-
 // swagger:route POST /tasks tasks createTaskEndpoint
 // Create a new task.
 // Inserts a new task and its items into the database.
@@ -117,8 +27,14 @@ func (app *application) showTaskHandler(w http.ResponseWriter, r *http.Request)
 //
 //	201: taskCreatedResponse
 //	400: badRequestError
+//	401: unauthorizedError
 //	500: internalServerError
-func (app *application) createTaskHandler2(w http.ResponseWriter, r *http.Request) {
+func (app *application) createTaskHandler(w http.ResponseWriter, r *http.Request) {
+	if app.db != nil {
+		if _, ok := app.requireUser(w, r); !ok {
+			return
+		}
+	}
 
 	var createTask model.Task
 
@@ -132,10 +48,70 @@ func (app *application) createTaskHandler2(w http.ResponseWriter, r *http.Reques
 	createTask.UpdatedAt = time.Now()
 	createTask.AssignedUserID = 0
 
-	taskDto := model.TaskDto{DB: app.db}
+	if createTask.ProjectID == 0 {
+		createTask.ProjectID = model.DefaultProjectID
+	}
+
+	// The file store checks the project itself, as it only has the default one.
+	if app.db != nil {
+		projectDto := model.ProjectDto{DB: app.db}
+
+		_, err = projectDto.GetProject(createTask.ProjectID)
+		if err != nil {
+			http.Error(w, "Project not found", http.StatusBadRequest)
+			return
+		}
+
+		// A project the caller cannot access is reported like one that does not exist.
+		ok, err := app.canAccessProject(r, createTask.ProjectID)
+		if err != nil {
+			app.logger.Print(err)
+			http.Error(w, "Error fetching project membership", http.StatusInternalServerError)
+			return
+		}
+		if !ok {
+			http.Error(w, "Project not found", http.StatusBadRequest)
+			return
+		}
+	}
+
+	app.insertTask(w, r, &createTask)
+}
+
+// insertTask validates the parent of a new task, inserts the task and its items,
+// and writes the created task as the response.
+func (app *application) insertTask(w http.ResponseWriter, r *http.Request, createTask *model.Task) {
+	taskDto := app.taskStore(r)
+
+	if createTask.ParentTaskID != 0 {
+		_, err := taskDto.GetTask(createTask.ParentTaskID)
+		if err != nil {
+			http.Error(w, "Parent task not found", http.StatusBadRequest)
+			return
+		}
+
+		ok, err := app.canAccessTask(r, createTask.ParentTaskID)
+		if err != nil {
+			app.logger.Print(err)
+			http.Error(w, "Error fetching project membership", http.StatusInternalServerError)
+			return
+		}
+		if !ok {
+			http.Error(w, "Parent task not found", http.StatusBadRequest)
+			return
+		}
+	}
 
 	// Call the Insert method to insert the task into the database.
-	err = taskDto.Insert(&createTask)
+	err := taskDto.Insert(createTask)
+	if errors.Is(err, model.ErrProjectNotFound) {
+		http.Error(w, "Project not found", http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, model.ErrParentNotFound) {
+		http.Error(w, "Parent task not found", http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		http.Error(w, "Error inserting task", http.StatusInternalServerError)
 		return
@@ -147,46 +123,56 @@ func (app *application) createTaskHandler2(w http.ResponseWriter, r *http.Reques
 		err = taskDto.InsertTaskItem(createTask.ID, item)
 		if err != nil {
 			http.Error(w, "Error inserting task items", http.StatusInternalServerError)
+			return
 		}
 	}
 
+	app.publish(events.TaskCreated, createTask.ID, createTask.AssignedUserID, createTask)
+
 	// Encode the struct to JSON and send it as the HTTP response.
 	err = app.writeJSON(w, http.StatusCreated, createTask, nil)
 	if err != nil {
 		app.logger.Print(err)
 		http.Error(w, "The server encountered a problem and could not process your request", http.StatusInternalServerError)
 	}
-
 }
 
 // swagger:route GET /tasks tasks getAllTasksEndpoint
 // Get all tasks.
-// Fetches all tasks from the database.
+// Fetches all tasks in the projects the caller can access.
+// Lists are ordered by when tasks were last updated. With ?limit, at most that many are returned,
+// and a Link header with rel="next" gives the next page; its cursor is opaque.
 // Produces:
 // - application/json
 // Schemes: http, https
 // responses:
 //
 //	200: allTasksResponse
+//	304: notModifiedResponse
+//	400: badRequestError
+//	401: unauthorizedError
 //	500: internalServerError
 func (app *application) getAllTasksHandler(w http.ResponseWriter, r *http.Request) {
-	taskDto := model.TaskDto{DB: app.db}
+	filter, ok := app.visibleTasks(w, r)
+	if !ok {
+		return
+	}
 
-	// Fetch all tasks from the database.
-	tasks, err := taskDto.GetAllTasks()
+	page, err := readPage(r)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Error fetching tasks: %v", err), http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	// Encode the tasks slice to JSON and send the response.
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(tasks)
+	// Stream the tasks to the response as they are read.
+	list := newListWriter(app.logger, w, r, page, "tasks", model.TaskCursor)
+	list.close(app.taskStore(r).EachTask(filter, list.fetch(), list.add))
 }
 
 // swagger:route DELETE /tasks/{id} tasks deleteTaskEndpoint
 // Delete a task by ID.
-// Removes a task from the database based on its ID.
+// Moves a task to the trash, from where it can be restored until it is purged. Subtasks are
+// deleted with it unless ?subtasks=reparent is given, in which case they move up to the task's parent.
 // Produces:
 // - application/json
 // Schemes: http, https
@@ -195,6 +181,8 @@ func (app *application) getAllTasksHandler(w http.ResponseWriter, r *http.Reques
 //
 //	200: successfullyDeletedResponse
 //	400: invalidTaskIdError
+//	401: unauthorizedError
+//	404: notFoundError
 //	500: internalServerError
 func (app *application) deleteTaskHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	// Parse the task ID from the URL parameters.
@@ -204,16 +192,36 @@ func (app *application) deleteTaskHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	taskDto := model.TaskDto{DB: app.db}
+	taskDto := app.taskStore(r)
+
+	// Look up the assignee first, so event subscribers filtering on it hear about the deletion.
+	assigneeID := 0
+	if task, err := taskDto.GetTask(taskID); err == nil {
+		assigneeID = task.AssignedUserID
+	}
 
-	// Delete the task from the database.
-	err = taskDto.DeleteTask(taskID)
+	// Delete the task from the database, either taking its subtasks with it or handing them to its parent.
+	switch r.URL.Query().Get("subtasks") {
+	case "", "cascade":
+		err = taskDto.DeleteTask(taskID)
+	case "reparent":
+		err = taskDto.DeleteTaskReparent(taskID)
+	default:
+		http.Error(w, "Invalid subtasks mode, expected cascade or reparent", http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		http.NotFound(w, r)
+		return
+	}
 	if err != nil {
 		app.logger.Printf("Failed to delete task with ID %d: %v", taskID, err) // Log the error for more insight
 		http.Error(w, "Error deleting task", http.StatusInternalServerError)
 		return
 	}
 
+	app.publish(events.TaskDeleted, taskID, assigneeID, map[string]int{"id": taskID})
+
 	w.WriteHeader(http.StatusOK)
 }
 
@@ -227,11 +235,13 @@ func (app *application) deleteTaskHandler(w http.ResponseWriter, r *http.Request
 //
 //	200: taskResponse
 //	400: badRequestError
+//	401: unauthorizedError
 //	404: notFoundError
+//	409: unfinishedBlockersError
 //	500: internalServerError
 func (app *application) updateTaskHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 
-	taskDto := model.TaskDto{DB: app.db}
+	taskDto := app.taskStore(r)
 
 	// Parse the task ID from the URL parameters.
 	taskID, err := strconv.Atoi(ps.ByName("id"))
@@ -254,6 +264,8 @@ func (app *application) updateTaskHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	completing := updateTask.Completed && !existingTask.Completed
+
 	existingTask.Title = updateTask.Title
 	existingTask.Description = updateTask.Description
 	existingTask.Completed = updateTask.Completed
@@ -261,13 +273,23 @@ func (app *application) updateTaskHandler(w http.ResponseWriter, r *http.Request
 
 	existingTask.Items = updateTask.Items
 
-	// Update the task in the database.
+	// Update the task in the database. A task cannot be completed while any
+	// of the tasks blocking it are still open.
 	err = taskDto.UpdateTask(taskID, existingTask)
+	if errors.Is(err, model.ErrTaskBlocked) {
+		http.Error(w, "Task has unfinished blockers", http.StatusConflict)
+		return
+	}
 	if err != nil {
 		http.Error(w, "Error updating task", http.StatusInternalServerError)
 		return
 	}
 
+	app.publish(events.TaskUpdated, taskID, existingTask.AssignedUserID, existingTask)
+	if completing {
+		app.notify(mail.TemplateTaskCompleted, existingTask.AssignedUserID, app.contextGetUser(r), notificationData{Task: existingTask})
+	}
+
 	err = app.writeJSON(w, http.StatusOK, existingTask, nil)
 	if err != nil {
 		app.logger.Print(err)
@@ -285,7 +307,9 @@ func (app *application) updateTaskHandler(w http.ResponseWriter, r *http.Request
 // responses:
 //
 //	200: taskResponse
+//	304: notModifiedResponse
 //	400: invalidTaskIdError
+//	401: unauthorizedError
 //	404: notFoundError
 //	500: internalServerError
 func (app *application) getTaskHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
@@ -296,7 +320,7 @@ func (app *application) getTaskHandler(w http.ResponseWriter, r *http.Request, p
 		return
 	}
 
-	taskDto := model.TaskDto{DB: app.db}
+	taskDto := app.taskStore(r)
 
 	// Fetch the task from the database by its ID.
 	task, err := taskDto.GetTask(taskID)
@@ -308,6 +332,9 @@ func (app *application) getTaskHandler(w http.ResponseWriter, r *http.Request, p
 		}
 		return
 	}
+	if checkNotModified(w, r, taskVersions(*task)) {
+		return
+	}
 
 	// Encode the task to JSON and send the response.
 	w.Header().Set("Content-Type", "application/json")
@@ -325,11 +352,12 @@ func (app *application) getTaskHandler(w http.ResponseWriter, r *http.Request, p
 //
 //	200: taskResponse
 //	400: invalidIdError
+//	401: unauthorizedError
 //	404: notFoundError
 //	500: internalServerError
 func (app *application) assignTaskHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 
-	taskDto := model.TaskDto{DB: app.db}
+	taskDto := app.taskStore(r)
 
 	// Parse the task ID from the URL parameters.
 	taskID, err := strconv.Atoi(ps.ByName("taskID"))
@@ -355,11 +383,18 @@ func (app *application) assignTaskHandler(w http.ResponseWriter, r *http.Request
 
 	// Use the AssignUserToTask method with updatedAt to perform the assignment of the user to the task.
 	err = taskDto.AssignUserToTask(taskID, userID, existingTask.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Task not found", http.StatusNotFound)
+		return
+	}
 	if err != nil {
 		http.Error(w, "Error assigning user to task", http.StatusInternalServerError)
 		return
 	}
 
+	app.publish(events.TaskAssigned, taskID, userID, existingTask)
+	app.notify(mail.TemplateTaskAssigned, userID, app.contextGetUser(r), notificationData{Task: existingTask})
+
 	err = app.writeJSON(w, http.StatusOK, existingTask, nil)
 	if err != nil {
 		app.logger.Print(err)
@@ -369,16 +404,25 @@ func (app *application) assignTaskHandler(w http.ResponseWriter, r *http.Request
 
 // swagger:route GET /users/{userID}/tasks/assigned tasks getUserAssignedTasksEndpoint
 // Get tasks assigned to a specific user.
-// Returns a list of tasks assigned to a user based on their ID.
+// Returns a list of tasks assigned to a user based on their ID, in the projects the caller can access.
+// Lists are ordered by when tasks were last updated. With ?limit, at most that many are returned,
+// and a Link header with rel="next" gives the next page; its cursor is opaque.
 // Produces:
 // - application/json
 // Schemes: http, https
 // Responses:
 //
 //	200: allTasksResponse
+//	304: notModifiedResponse
 //	400: invalidIdError
+//	401: unauthorizedError
 //	500: internalServerError
 func (app *application) getTasksAssignedToUserHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	filter, ok := app.visibleTasks(w, r)
+	if !ok {
+		return
+	}
+
 	// Parse the user ID from the URL parameters.
 	userID, err := strconv.Atoi(ps.ByName("userID"))
 	if err != nil || userID == 0 {
@@ -386,19 +430,15 @@ func (app *application) getTasksAssignedToUserHandler(w http.ResponseWriter, r *
 		return
 	}
 
-	taskDto := model.TaskDto{DB: app.db}
-
-	// Fetch all tasks from the database.
-	tasks, err := taskDto.GetAllTaskByAssignedUserID(userID)
+	page, err := readPage(r)
 	if err != nil {
-		http.Error(w, "Error fetching tasks", http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	// Encode the tasks slice to JSON and send the response.
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(tasks)
-
+	list := newListWriter(app.logger, w, r, page, "tasks", model.TaskCursor)
+	filter.AssignedUserID = &userID
+	list.close(app.taskStore(r).EachTask(filter, list.fetch(), list.add))
 }
 
 // swagger:route POST /comments tasks createTaskCommentsEndpoint
@@ -413,6 +453,8 @@ func (app *application) getTasksAssignedToUserHandler(w http.ResponseWriter, r *
 //
 //	201: taskCommentCreatedResponse
 //	400: badRequestError
+//	401: unauthorizedError
+//	404: notFoundError
 //	500: internalServerError
 func (app *application) createTaskCommentsHandler(w http.ResponseWriter, r *http.Request) {
 
@@ -424,16 +466,34 @@ func (app *application) createTaskCommentsHandler(w http.ResponseWriter, r *http
 		return
 	}
 
+	if !app.requireTaskAccess(w, r, createTaskComment.TaskID) {
+		return
+	}
+
 	createTaskComment.CreatedAt = time.Now()
-	taskDto := model.TaskDto{DB: app.db}
+	taskDto := app.taskStore(r)
 
 	// Call the Insert method to insert the task comment into the database.
 	err = taskDto.InsertTaskComment(&createTaskComment)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Task not found", http.StatusNotFound)
+		return
+	}
 	if err != nil {
 		http.Error(w, "Error inserting task", http.StatusInternalServerError)
 		return
 	}
 
+	assigneeID := 0
+	task, err := taskDto.GetTask(createTaskComment.TaskID)
+	if err == nil {
+		assigneeID = task.AssignedUserID
+	}
+	app.publish(events.CommentCreated, createTaskComment.TaskID, assigneeID, createTaskComment)
+	if task != nil {
+		app.notify(mail.TemplateTaskCommented, assigneeID, app.contextGetUser(r), notificationData{Task: task, Comment: &createTaskComment})
+	}
+
 	// Encode the struct to JSON and send it as the HTTP response.
 	err = app.writeJSON(w, http.StatusCreated, createTaskComment, nil)
 	if err != nil {
@@ -445,14 +505,18 @@ func (app *application) createTaskCommentsHandler(w http.ResponseWriter, r *http
 
 // swagger:route GET /comments/{taskID} tasks getTaskCommentsEndpoint
 // Get comments for a specific task.
-// Returns a list of comments for a task based on its ID.
+// Returns a list of comments for a task based on its ID, oldest first.
+// With ?limit, at most that many are returned, and a Link header with rel="next" gives the next page.
 // Produces:
 // - application/json
 // Schemes: http, https
 // Responses:
 //
 //	200: allCommentsResponse
+//	304: notModifiedResponse
 //	400: invalidIdError
+//	401: unauthorizedError
+//	404: notFoundError
 //	500: internalServerError
 func (app *application) getAllTaskCommentsHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	// Parse the task ID from the URL parameters.
@@ -462,17 +526,27 @@ func (app *application) getAllTaskCommentsHandler(w http.ResponseWriter, r *http
 		return
 	}
 
-	taskDto := model.TaskDto{DB: app.db}
-
-	// Fetch all tasks from the database.
-	tasks, err := taskDto.GetAllTaskCommentsByTaskID(taskID)
+	page, err := readPage(r)
 	if err != nil {
-		http.Error(w, "Error fetching tasks", http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	// Encode the tasks slice to JSON and send the response.
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(tasks)
+	list := newListWriter(app.logger, w, r, page, "comments", model.TaskCommentCursor)
+	err = app.taskStore(r).EachTaskComment(taskID, list.fetch(), list.add)
+	// A missing task is reported before any comment is written.
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Task not found", http.StatusNotFound)
+		return
+	}
+	list.close(err)
+}
 
+// taskStore returns the store behind the core task API: the task file if the
+// server was started with one, otherwise the database, acting as the caller.
+func (app *application) taskStore(r *http.Request) model.TaskStore {
+	if app.taskFile != nil {
+		return app.taskFile
+	}
+	return model.TaskDto{DB: app.db, ActorID: app.contextGetUser(r)}
 }
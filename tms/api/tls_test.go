@@ -0,0 +1,111 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newTLSServer serves a handler reporting the protocol with the app's TLS settings.
+func newTLSServer(t *testing.T, app *application) (*httptest.Server, *x509.CertPool) {
+	t.Helper()
+	app.logger = log.New(io.Discard, "", 0)
+	tlsConfig, err := app.tlsConfig()
+	assert.NoError(t, err)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.Proto)
+	}))
+	server.TLS = tlsConfig
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(tlsConfig.Certificates[0].Leaf)
+	return server, roots
+}
+
+func fetchBody(client *http.Client, url string) (string, error) {
+	res, err := client.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	return string(body), err
+}
+
+func TestTLS_SelfSignedServesHTTP2(t *testing.T) {
+	app := &application{}
+	app.config.tls.selfSigned = true
+	server, roots := newTLSServer(t, app)
+
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig:   &tls.Config{RootCAs: roots},
+		ForceAttemptHTTP2: true,
+	}}
+	proto, err := fetchBody(client, server.URL)
+	assert.NoError(t, err)
+	assert.Equal(t, "HTTP/2.0", proto)
+
+	// Old protocol versions are refused.
+	old := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots, MaxVersion: tls.VersionTLS11}}}
+	_, err = fetchBody(old, server.URL)
+	assert.Error(t, err)
+}
+
+func TestTLS_MutualTLS(t *testing.T) {
+	clientCert, err := selfSignedCertificate(time.Now())
+	assert.NoError(t, err)
+	caFile := filepath.Join(t.TempDir(), "clients.pem")
+	assert.NoError(t, os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: clientCert.Certificate[0]}), 0o600))
+
+	app := &application{}
+	app.config.tls.selfSigned = true
+	app.config.tls.clientCA = caFile
+	app.config.tls.clientAuth = "require"
+	server, roots := newTLSServer(t, app)
+
+	anonymous := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}
+	_, err = fetchBody(anonymous, server.URL)
+	assert.Error(t, err)
+
+	service := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+		RootCAs:      roots,
+		Certificates: []tls.Certificate{clientCert},
+	}}}
+	_, err = fetchBody(service, server.URL)
+	assert.NoError(t, err)
+
+	// Optional client auth lets clients without a certificate in.
+	app = &application{}
+	app.config.tls.selfSigned = true
+	app.config.tls.clientCA = caFile
+	app.config.tls.clientAuth = "optional"
+	server, roots = newTLSServer(t, app)
+	anonymous = &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}
+	_, err = fetchBody(anonymous, server.URL)
+	assert.NoError(t, err)
+}
+
+func TestRedirectToHTTPS(t *testing.T) {
+	res := httptest.NewRecorder()
+	redirectToHTTPS(4443).ServeHTTP(res, httptest.NewRequest(http.MethodPost, "http://tms.example.com:8080/v1/tasks?dry_run=true", nil))
+	assert.Equal(t, http.StatusPermanentRedirect, res.Code)
+	assert.Equal(t, "https://tms.example.com:4443/v1/tasks?dry_run=true", res.Header().Get("Location"))
+
+	res = httptest.NewRecorder()
+	redirectToHTTPS(443).ServeHTTP(res, httptest.NewRequest(http.MethodGet, "http://tms.example.com/v1/tasks", nil))
+	assert.Equal(t, "https://tms.example.com/v1/tasks", res.Header().Get("Location"))
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+
+	"tms.zinkworks.com/model"
+)
+
+// swagger:route GET /trash trash getTrashEndpoint
+// Get the tasks in the trash, most recently deleted first.
+// Deleted tasks are purged for good once the server's retention period has passed.
+// Produces:
+// - application/json
+// Schemes: http, https
+// responses:
+//
+//	200: trashResponse
+//	401: unauthorizedError
+//	500: internalServerError
+func (app *application) getTrashHandler(w http.ResponseWriter, r *http.Request) {
+	filter, ok := app.visibleTasks(w, r)
+	if !ok {
+		return
+	}
+
+	taskDto := model.TaskDto{DB: app.db}
+
+	tasks, err := taskDto.GetDeletedTasks(filter)
+	if err != nil {
+		http.Error(w, "Error fetching trash", http.StatusInternalServerError)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, tasks, nil)
+	if err != nil {
+		app.logger.Print(err)
+		http.Error(w, "The server encountered a problem and could not process your request", http.StatusInternalServerError)
+	}
+}
+
+// swagger:route POST /tasks/{id}/restore trash restoreTaskEndpoint
+// Restore a task from the trash.
+// Subtasks that were deleted together with the task are restored too.
+// If the task's parent is still in the trash, the task comes back as a root task.
+// Produces:
+// - application/json
+// Schemes: http, https
+// responses:
+//
+//	200: taskResponse
+//	400: invalidTaskIdError
+//	401: unauthorizedError
+//	404: notFoundError
+//	500: internalServerError
+func (app *application) restoreTaskHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	// Parse the task ID from the URL parameters.
+	taskID, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	taskDto := model.TaskDto{DB: app.db, ActorID: app.contextGetUser(r)}
+
+	err = taskDto.RestoreTask(taskID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Task not found in the trash", http.StatusNotFound)
+		} else {
+			app.logger.Printf("Failed to restore task %d: %v", taskID, err)
+			http.Error(w, "Error restoring task", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	task, err := taskDto.GetTask(taskID)
+	if err != nil {
+		http.Error(w, "Error fetching task", http.StatusInternalServerError)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, task, nil)
+	if err != nil {
+		app.logger.Print(err)
+		http.Error(w, "The server encountered a problem and could not process your request", http.StatusInternalServerError)
+	}
+}
+
+// runTrashPurger permanently deletes tasks that have been in the trash for
+// longer than the retention period, along with attachment content nothing
+// else uses, straight away and then on every tick of the interval, until the
+// server shuts down. It is started in its own goroutine from main.
+func (app *application) runTrashPurger(retention, interval time.Duration) {
+	defer func() {
+		if err := recover(); err != nil {
+			app.logger.Printf("trash purger stopped: %v", err)
+			app.workers.stop(workerTrash, err)
+		}
+	}()
+	app.workers.start(workerTrash)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		taskDto := model.TaskDto{DB: app.db}
+		cutoff := time.Now().Add(-retention)
+
+		// Note the attachment content first; the rows go with the tasks.
+		blobs, findErr := taskDto.GetPurgeableAttachmentBlobs(cutoff)
+		if findErr != nil {
+			app.logger.Printf("Failed to find attachments of deleted tasks: %v", findErr)
+		}
+
+		purged, err := taskDto.PurgeDeletedTasks(cutoff)
+		if err != nil {
+			app.logger.Printf("Failed to purge deleted tasks: %v", err)
+		} else if purged > 0 {
+			app.logger.Printf("purged %d deleted task(s)", purged)
+		}
+
+		for _, key := range blobs {
+			app.releaseBlob(key)
+		}
+		app.workers.ran(workerTrash, errors.Join(findErr, err))
+
+		select {
+		case <-ticker.C:
+		case <-app.stopping:
+			app.workers.stop(workerTrash, stoppedForShutdown)
+			return
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The unversioned paths are aliases of /v1, kept for clients written before
+// the API was versioned. They were deprecated when /v1 was introduced and
+// stop being served at the sunset.
+var (
+	unversionedDeprecation = time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)
+	unversionedSunset      = time.Date(2027, time.April, 30, 0, 0, 0, 0, time.UTC)
+)
+
+// versionSegment matches a path segment naming an API version, as in /v2.
+var versionSegment = regexp.MustCompile(`^/v[0-9]+(/|$)`)
+
+// apiVersions maps a path prefix, such as /v1, to the routes of that
+// version of the API. Each version's routes see paths without the prefix.
+type apiVersions map[string]http.Handler
+
+// serve routes requests to the version their path starts with. Paths under
+// no version are served by the legacy version, marked deprecated; paths
+// under a version that does not exist are not found.
+func (versions apiVersions) serve(legacy string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if match := versionSegment.FindString(r.URL.Path); match != "" {
+			prefix := strings.TrimSuffix(match, "/")
+			handler, ok := versions[prefix]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			http.StripPrefix(prefix, handler).ServeHTTP(w, r)
+			return
+		}
+
+		header := w.Header()
+		header.Set("Deprecation", "@"+strconv.FormatInt(unversionedDeprecation.Unix(), 10))
+		header.Set("Sunset", unversionedSunset.Format(http.TimeFormat))
+		header.Add("Link", `<`+legacy+r.URL.EscapedPath()+`>; rel="successor-version"`)
+		versions[legacy].ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVersions(t *testing.T) {
+	server := newTestServer(t)
+
+	get := func(path string) *http.Response {
+		res, err := http.Get(server.URL + path)
+		assert.NoError(t, err)
+		res.Body.Close()
+		return res
+	}
+
+	res := get("/v1/tasks")
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Empty(t, res.Header.Get("Deprecation"))
+	assert.Empty(t, res.Header.Get("Sunset"))
+
+	res = get("/v1/tasks/42")
+	assert.Equal(t, http.StatusNotFound, res.StatusCode)
+
+	// The unversioned paths still work, but say they are going away.
+	res = get("/tasks")
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.True(t, strings.HasPrefix(res.Header.Get("Deprecation"), "@"))
+	assert.Equal(t, "Fri, 30 Apr 2027 00:00:00 GMT", res.Header.Get("Sunset"))
+	assert.Equal(t, `</v1/tasks>; rel="successor-version"`, res.Header.Get("Link"))
+
+	res = get("/v2/tasks")
+	assert.Equal(t, http.StatusNotFound, res.StatusCode)
+	assert.Empty(t, res.Header.Get("Deprecation"))
+
+	res = get("/v1/docs")
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+}
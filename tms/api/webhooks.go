@@ -0,0 +1,455 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+
+	"tms.zinkworks.com/model"
+	"tms.zinkworks.com/webhook"
+)
+
+// webhookPayload is the JSON body sent to webhooks. Task is the task as it
+// was when the event was first sent, or null if it has since been deleted.
+type webhookPayload struct {
+	Event      string                       `json:"event"`
+	EventID    int                          `json:"event_id"`
+	OccurredAt time.Time                    `json:"occurred_at"`
+	ActorID    int                          `json:"actor_id"`
+	TaskID     int                          `json:"task_id"`
+	Changes    map[string]model.FieldChange `json:"changes"`
+	Task       *model.Task                  `json:"task"`
+}
+
+// swagger:route POST /webhooks webhooks createWebhookEndpoint
+// Subscribe a URL to the events of a project's tasks.
+// The caller must be a member of the project, which defaults to the default project.
+// Every delivery is signed with HMAC-SHA256 in the X-TMS-Signature header, over the
+// X-TMS-Timestamp header, a dot and the body. A secret is generated if none is given,
+// and it is only ever returned in this response.
+// Consumes:
+// - application/json
+// Produces:
+// - application/json
+// Schemes: http, https
+// responses:
+//
+//	201: webhookCreatedResponse
+//	400: badRequestError
+//	401: unauthorizedError
+//	500: internalServerError
+func (app *application) createWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := app.requireUser(w, r)
+	if !ok {
+		return
+	}
+
+	var createWebhook model.Webhook
+	err := json.NewDecoder(r.Body).Decode(&createWebhook)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	target, err := url.Parse(createWebhook.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		http.Error(w, "The webhook url must be an absolute http or https URL", http.StatusBadRequest)
+		return
+	}
+
+	if len(createWebhook.Events) == 0 {
+		http.Error(w, "Subscribe the webhook to at least one event", http.StatusBadRequest)
+		return
+	}
+	for _, event := range createWebhook.Events {
+		if !model.ValidWebhookEvent(event) {
+			http.Error(w, "Unknown webhook event "+strconv.Quote(event), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if createWebhook.ProjectID == 0 {
+		createWebhook.ProjectID = model.DefaultProjectID
+	}
+	allowed, err := app.canAccessProject(r, createWebhook.ProjectID)
+	if err != nil {
+		http.Error(w, "Error fetching project membership", http.StatusInternalServerError)
+		return
+	}
+	if !allowed {
+		http.Error(w, "Project not found", http.StatusBadRequest)
+		return
+	}
+
+	if createWebhook.Secret == "" {
+		secret := make([]byte, 32)
+		_, err = rand.Read(secret)
+		if err != nil {
+			http.Error(w, "Error generating webhook secret", http.StatusInternalServerError)
+			return
+		}
+		createWebhook.Secret = hex.EncodeToString(secret)
+	}
+
+	createWebhook.Active = true
+	createWebhook.CreatedByID = userID
+	createWebhook.CreatedAt = time.Now()
+
+	webhookDto := model.WebhookDto{DB: app.db}
+
+	err = webhookDto.Insert(&createWebhook)
+	if err != nil {
+		http.Error(w, "Error inserting webhook", http.StatusInternalServerError)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, createWebhook, nil)
+	if err != nil {
+		app.logger.Print(err)
+		http.Error(w, "The server encountered a problem and could not process your request", http.StatusInternalServerError)
+	}
+}
+
+// swagger:route GET /webhooks webhooks getWebhooksEndpoint
+// Get the caller's webhooks.
+// Secrets are not included.
+// Produces:
+// - application/json
+// Schemes: http, https
+// responses:
+//
+//	200: allWebhooksResponse
+//	401: unauthorizedError
+//	500: internalServerError
+func (app *application) getWebhooksHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := app.requireUser(w, r)
+	if !ok {
+		return
+	}
+
+	webhookDto := model.WebhookDto{DB: app.db}
+
+	hooks, err := webhookDto.GetWebhooks(userID)
+	if err != nil {
+		http.Error(w, "Error fetching webhooks", http.StatusInternalServerError)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, hooks, nil)
+	if err != nil {
+		app.logger.Print(err)
+		http.Error(w, "The server encountered a problem and could not process your request", http.StatusInternalServerError)
+	}
+}
+
+// swagger:route GET /webhooks/{id} webhooks getWebhookEndpoint
+// Get a webhook by ID.
+// Only the webhook's creator can see it.
+// Produces:
+// - application/json
+// Schemes: http, https
+// responses:
+//
+//	200: webhookResponse
+//	400: invalidIdError
+//	401: unauthorizedError
+//	404: notFoundError
+//	500: internalServerError
+func (app *application) getWebhookHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	userID, ok := app.requireUser(w, r)
+	if !ok {
+		return
+	}
+
+	webhookID, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid webhook ID", http.StatusBadRequest)
+		return
+	}
+
+	webhookDto := model.WebhookDto{DB: app.db}
+
+	hook, err := webhookDto.GetWebhook(webhookID, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.NotFound(w, r)
+		} else {
+			http.Error(w, "Error fetching webhook", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, hook, nil)
+	if err != nil {
+		app.logger.Print(err)
+		http.Error(w, "The server encountered a problem and could not process your request", http.StatusInternalServerError)
+	}
+}
+
+// swagger:route DELETE /webhooks/{id} webhooks deleteWebhookEndpoint
+// Delete a webhook and its delivery log.
+// Produces:
+// - application/json
+// Schemes: http, https
+// Responses:
+//
+//	200: successfullyDeletedResponse
+//	400: invalidIdError
+//	401: unauthorizedError
+//	404: notFoundError
+//	500: internalServerError
+func (app *application) deleteWebhookHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	userID, ok := app.requireUser(w, r)
+	if !ok {
+		return
+	}
+
+	webhookID, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid webhook ID", http.StatusBadRequest)
+		return
+	}
+
+	webhookDto := model.WebhookDto{DB: app.db}
+
+	deleted, err := webhookDto.DeleteWebhook(webhookID, userID)
+	if err != nil {
+		app.logger.Printf("Failed to delete webhook %d: %v", webhookID, err)
+		http.Error(w, "Error deleting webhook", http.StatusInternalServerError)
+		return
+	}
+	if !deleted {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// swagger:route GET /webhooks/{id}/deliveries webhooks getWebhookDeliveriesEndpoint
+// Get the delivery log of a webhook, newest first.
+// Each delivery shows its status, the number of attempts, and the last response or error.
+// Produces:
+// - application/json
+// Schemes: http, https
+// responses:
+//
+//	200: allWebhookDeliveriesResponse
+//	400: invalidIdError
+//	401: unauthorizedError
+//	404: notFoundError
+//	500: internalServerError
+func (app *application) getWebhookDeliveriesHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	userID, ok := app.requireUser(w, r)
+	if !ok {
+		return
+	}
+
+	webhookID, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid webhook ID", http.StatusBadRequest)
+		return
+	}
+
+	limit := 100
+	if value := r.URL.Query().Get("limit"); value != "" {
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > 1000 {
+			http.Error(w, "Invalid limit, expected 1 to 1000", http.StatusBadRequest)
+			return
+		}
+	}
+
+	webhookDto := model.WebhookDto{DB: app.db}
+
+	_, err = webhookDto.GetWebhook(webhookID, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.NotFound(w, r)
+		} else {
+			http.Error(w, "Error fetching webhook", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	deliveries, err := webhookDto.GetDeliveries(webhookID, limit)
+	if err != nil {
+		http.Error(w, "Error fetching deliveries", http.StatusInternalServerError)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, deliveries, nil)
+	if err != nil {
+		app.logger.Print(err)
+		http.Error(w, "The server encountered a problem and could not process your request", http.StatusInternalServerError)
+	}
+}
+
+// swagger:route POST /webhooks/{id}/deliveries/{deliveryID}/redeliver webhooks redeliverWebhookEndpoint
+// Send an earlier delivery again.
+// A new delivery with the same payload is queued and sent in the background.
+// Produces:
+// - application/json
+// Schemes: http, https
+// responses:
+//
+//	202: webhookDeliveryResponse
+//	400: invalidIdError
+//	401: unauthorizedError
+//	404: notFoundError
+//	500: internalServerError
+func (app *application) redeliverWebhookHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	userID, ok := app.requireUser(w, r)
+	if !ok {
+		return
+	}
+
+	webhookID, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid webhook ID", http.StatusBadRequest)
+		return
+	}
+
+	deliveryID, err := strconv.Atoi(ps.ByName("deliveryID"))
+	if err != nil {
+		http.Error(w, "Invalid delivery ID", http.StatusBadRequest)
+		return
+	}
+
+	webhookDto := model.WebhookDto{DB: app.db}
+
+	delivery, err := webhookDto.Redeliver(webhookID, deliveryID, userID, time.Now())
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.NotFound(w, r)
+		} else {
+			app.logger.Printf("Failed to redeliver delivery %d of webhook %d: %v", deliveryID, webhookID, err)
+			http.Error(w, "Error queueing redelivery", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusAccepted, delivery, nil)
+	if err != nil {
+		app.logger.Print(err)
+		http.Error(w, "The server encountered a problem and could not process your request", http.StatusInternalServerError)
+	}
+}
+
+// runWebhookDispatcher sends due webhook deliveries straight away and then
+// on every tick of the interval, until the server shuts down. Deliveries are queued by the same statement
+// that records a task event, so handlers never wait on a receiver. It is
+// started in its own goroutine from main.
+func (app *application) runWebhookDispatcher(interval time.Duration) {
+	defer func() {
+		if err := recover(); err != nil {
+			app.logger.Printf("webhook dispatcher stopped: %v", err)
+			app.workers.stop(workerWebhooks, err)
+		}
+	}()
+	app.workers.start(workerWebhooks)
+
+	sender := webhook.Sender{Client: webhook.PublicClient(app.config.webhooks.timeout)}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		app.workers.ran(workerWebhooks, app.dispatchWebhooks(sender))
+
+		select {
+		case <-ticker.C:
+		case <-app.stopping:
+			app.workers.stop(workerWebhooks, stoppedForShutdown)
+			return
+		}
+	}
+}
+
+// dispatchWebhooks claims a batch of due deliveries and sends them, a few at
+// a time. Failed deliveries are retried later, so only failing to claim any
+// is an error.
+func (app *application) dispatchWebhooks(sender webhook.Sender) error {
+	const batchSize, concurrency = 50, 8
+
+	webhookDto := model.WebhookDto{DB: app.db}
+
+	// The lease outlasts one send, so a slow receiver is not sent the same delivery twice.
+	due, err := webhookDto.ClaimDueDeliveries(time.Now(), 2*app.config.webhooks.timeout, batchSize)
+	if err != nil {
+		app.logger.Printf("Failed to claim webhook deliveries: %v", err)
+		return err
+	}
+
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, concurrency)
+	for _, delivery := range due {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(delivery model.DueDelivery) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			app.deliverWebhook(sender, delivery)
+		}(delivery)
+	}
+	wg.Wait()
+	return nil
+}
+
+// deliverWebhook makes one attempt at a delivery and records the outcome,
+// scheduling a retry with exponential backoff if attempts remain.
+func (app *application) deliverWebhook(sender webhook.Sender, delivery model.DueDelivery) {
+	webhookDto := model.WebhookDto{DB: app.db}
+
+	payload := []byte(delivery.Payload)
+	if payload == nil {
+		var err error
+		payload, err = app.webhookPayload(delivery)
+		if err != nil {
+			app.logger.Printf("Failed to build payload of webhook delivery %d: %v", delivery.ID, err)
+			return
+		}
+	}
+
+	status, sendErr := sender.Send(context.Background(), delivery.URL, delivery.Secret, delivery.Event, delivery.ID, payload)
+
+	var retryAt *time.Time
+	if sendErr != nil && delivery.Attempts+1 < app.config.webhooks.maxAttempts {
+		next := time.Now().Add(webhook.Backoff(delivery.Attempts + 1))
+		retryAt = &next
+	}
+
+	err := webhookDto.RecordDeliveryAttempt(delivery.ID, payload, status, sendErr, retryAt, time.Now())
+	if err != nil {
+		app.logger.Printf("Failed to record attempt of webhook delivery %d: %v", delivery.ID, err)
+	}
+}
+
+func (app *application) webhookPayload(delivery model.DueDelivery) ([]byte, error) {
+	taskDto := model.TaskDto{DB: app.db}
+
+	task, err := taskDto.GetTask(delivery.TaskEvent.TaskID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	return json.Marshal(webhookPayload{
+		Event:      delivery.Event,
+		EventID:    delivery.EventID,
+		OccurredAt: delivery.TaskEvent.CreatedAt,
+		ActorID:    delivery.TaskEvent.ActorID,
+		TaskID:     delivery.TaskEvent.TaskID,
+		Changes:    delivery.TaskEvent.Changes,
+		Task:       task,
+	})
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+
+	"tms.zinkworks.com/events"
+)
+
+func TestWebhooks_OnlyTheCreatorsAndMembers(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	app := &application{logger: log.New(io.Discard, "", 0), db: db, hub: events.NewHub(100)}
+	app.config.auth.trustedProxies = []string{"192.0.2.0/24"}
+	handler := app.routes()
+
+	request := func(method, target, body string) int {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("X-User-ID", "7")
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, req)
+		return res.Code
+	}
+
+	// Another user's webhook, and its deliveries, look like ones that do not exist.
+	mock.ExpectQuery("FROM webhook WHERE id = \\$1 AND created_by = \\$2").
+		WithArgs(2, 7).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "project_id", "url", "events", "active", "created_by", "created_at"}))
+	assert.Equal(t, http.StatusNotFound, request(http.MethodGet, "/v1/webhooks/2", ""))
+
+	mock.ExpectQuery("FROM webhook WHERE id = \\$1 AND created_by = \\$2").
+		WithArgs(2, 7).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "project_id", "url", "events", "active", "created_by", "created_at"}))
+	assert.Equal(t, http.StatusNotFound, request(http.MethodGet, "/v1/webhooks/2/deliveries", ""))
+
+	mock.ExpectExec("DELETE FROM webhook WHERE id = \\$1 AND created_by = \\$2").
+		WithArgs(2, 7).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	assert.Equal(t, http.StatusNotFound, request(http.MethodDelete, "/v1/webhooks/2", ""))
+
+	// Webhooks can only be added to the caller's projects.
+	mock.ExpectQuery("SELECT role FROM project_member").
+		WithArgs(3, 7).
+		WillReturnRows(sqlmock.NewRows([]string{"role"}))
+	assert.Equal(t, http.StatusBadRequest, request(http.MethodPost, "/v1/webhooks",
+		`{"project_id":3,"url":"https://example.com/hook","events":["task.created"]}`))
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
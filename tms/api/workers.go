@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// The background workers, by the name /readyz reports them under.
+const (
+	workerRecurrence = "recurrence"
+	workerTrash      = "trash"
+	workerWebhooks   = "webhooks"
+	workerMail       = "mail"
+)
+
+// workerStatus is what /readyz reports about a background worker.
+type workerStatus struct {
+	Running bool       `json:"running"`
+	LastRun *time.Time `json:"last_run,omitempty"`
+	// LastError is the error of the last run, if it failed.
+	LastError string `json:"last_error,omitempty"`
+	// Stopped says why a worker that is no longer running stopped.
+	Stopped string `json:"stopped,omitempty"`
+}
+
+// stoppedForShutdown is the reason workers give when they stop because the
+// server is shutting down.
+const stoppedForShutdown = "the server is shutting down"
+
+// startWorker runs a background worker in its own goroutine. The worker must
+// return once app.stopping is closed.
+func (app *application) startWorker(run func()) {
+	app.background.Add(1)
+	go func() {
+		defer app.background.Done()
+		run()
+	}()
+}
+
+// workerRegistry keeps the status of the background workers. The zero
+// value is ready to use.
+type workerRegistry struct {
+	mu      sync.Mutex
+	workers map[string]*workerStatus
+}
+
+func (registry *workerRegistry) status(name string) *workerStatus {
+	if registry.workers == nil {
+		registry.workers = map[string]*workerStatus{}
+	}
+	status := registry.workers[name]
+	if status == nil {
+		status = &workerStatus{}
+		registry.workers[name] = status
+	}
+	return status
+}
+
+// start records that a worker has started.
+func (registry *workerRegistry) start(name string) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	*registry.status(name) = workerStatus{Running: true}
+}
+
+// ran records the outcome of one run of a worker.
+func (registry *workerRegistry) ran(name string, err error) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	status := registry.status(name)
+	now := time.Now()
+	status.LastRun = &now
+	status.LastError = ""
+	if err != nil {
+		status.LastError = err.Error()
+	}
+}
+
+// stop records that a worker has stopped, and why.
+func (registry *workerRegistry) stop(name string, reason any) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	status := registry.status(name)
+	status.Running = false
+	status.Stopped = fmt.Sprint(reason)
+}
+
+// snapshot returns a copy of every worker's status.
+func (registry *workerRegistry) snapshot() map[string]workerStatus {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	snapshot := make(map[string]workerStatus, len(registry.workers))
+	for name, status := range registry.workers {
+		snapshot[name] = *status
+	}
+	return snapshot
+}
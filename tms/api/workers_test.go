@@ -0,0 +1,42 @@
+package main
+
+import (
+	"io"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorkersStopOnShutdown(t *testing.T) {
+	db, _, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	app := &application{logger: log.New(io.Discard, "", 0), db: db, stopping: make(chan struct{})}
+	app.startWorker(func() { app.runRecurrenceScheduler(time.Hour) })
+	app.startWorker(func() { app.runTrashPurger(time.Hour, time.Hour) })
+	app.startWorker(func() { app.runWebhookDispatcher(time.Hour) })
+
+	close(app.stopping)
+
+	done := make(chan struct{})
+	go func() {
+		app.background.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the workers did not stop")
+	}
+
+	for name, status := range app.workers.snapshot() {
+		assert.False(t, status.Running, name)
+		assert.Equal(t, stoppedForShutdown, status.Stopped, name)
+	}
+	assert.Len(t, app.workers.snapshot(), 3)
+}
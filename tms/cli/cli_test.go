@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"tms.zinkworks.com/model"
+)
+
+// run executes the CLI against server with a config file naming user 7.
+func run(t *testing.T, server *httptest.Server, stdin string, args ...string) (string, error) {
+	t.Helper()
+
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(configPath, []byte("url: "+server.URL+"\ntoken: s3cr3t\nuser_id: 7\n"), 0o600)
+	assert.NoError(t, err)
+
+	var stdout, stderr bytes.Buffer
+	root := newRootCmd(strings.NewReader(stdin), &stdout, &stderr)
+	root.SetArgs(append([]string{"--config", configPath}, args...))
+	err = root.Execute()
+	return stdout.String(), err
+}
+
+func TestTasksList_Table(t *testing.T) {
+	updated := time.Date(2023, 6, 10, 9, 0, 0, 0, time.Local)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/users/5/tasks/assigned", r.URL.Path)
+		assert.Equal(t, "7", r.Header.Get("X-User-ID"))
+		assert.Equal(t, "Bearer s3cr3t", r.Header.Get("Authorization"))
+		json.NewEncoder(w).Encode([]model.Task{
+			{ID: 2, Title: "Second", AssignedUserID: 5, ProjectID: 1, UpdatedAt: updated},
+			{ID: 1, Title: "First", Completed: true, AssignedUserID: 5, ProjectID: 1, Items: []string{"a"}, UpdatedAt: updated},
+		})
+	}))
+	defer server.Close()
+
+	out, err := run(t, server, "", "tasks", "list", "--assignee", "5")
+
+	assert.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	assert.Len(t, lines, 3)
+	assert.Equal(t, []string{"ID", "TITLE", "DONE", "ASSIGNEE", "PROJECT", "PARENT", "ITEMS", "UPDATED"}, strings.Fields(lines[0]))
+	assert.Equal(t, []string{"1", "First", "yes", "5", "1", "-", "1", "2023-06-10", "09:00:00"}, strings.Fields(lines[1]))
+	assert.True(t, strings.HasPrefix(lines[2], "2 "))
+}
+
+func TestTasksUpdate_ChangesOnlyGivenFields(t *testing.T) {
+	var put model.Task
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/tasks/3", r.URL.Path)
+		switch r.Method {
+		case http.MethodGet:
+			json.NewEncoder(w).Encode(model.Task{ID: 3, Title: "Old", Description: "Keep me", Items: []string{"one"}})
+		case http.MethodPut:
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&put))
+			json.NewEncoder(w).Encode(put)
+		}
+	}))
+	defer server.Close()
+
+	out, err := run(t, server, "", "tasks", "update", "3", "--title", "New", "--add-item", "two", "--completed", "-o", "json")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "New", put.Title)
+	assert.Equal(t, "Keep me", put.Description)
+	assert.Equal(t, []string{"one", "two"}, put.Items)
+	assert.True(t, put.Completed)
+	assert.Contains(t, out, `"title": "New"`)
+
+	_, err = run(t, server, "", "tasks", "update", "3", "-o", "json")
+	assert.ErrorContains(t, err, "nothing to update")
+}
+
+func TestCommentsAdd_FromStdinAsYAML(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/comments", r.URL.Path)
+		var comment model.TaskComment
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&comment))
+		assert.Equal(t, 4, comment.TaskID)
+		assert.Equal(t, "true", comment.Comment)
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(comment)
+	}))
+	defer server.Close()
+
+	out, err := run(t, server, "true\n", "comments", "add", "4", "-", "-o", "yaml")
+
+	assert.NoError(t, err)
+	assert.Contains(t, out, "task_id: 4\n")
+	// A string that reads as another type stays quoted.
+	assert.Contains(t, out, `comment: "true"`)
+}
+
+func TestClient_ReportsServerErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Task not found", http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	_, err := run(t, server, "", "tasks", "get", "9")
+
+	assert.EqualError(t, err, "GET /tasks/9: 404 Not Found: Task not found")
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// apiPrefix is the version of the API the commands are written against.
+const apiPrefix = "/v1"
+
+// client calls the API on behalf of the configured user.
+type client struct {
+	baseURL string
+	token   string
+	userID  int
+	http    *http.Client
+}
+
+func newClient(cfg config) *client {
+	return &client{
+		baseURL: strings.TrimRight(cfg.URL, "/"),
+		token:   cfg.Token,
+		userID:  cfg.UserID,
+		http:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// do sends body, if any, as JSON and decodes the response into out, if
+// given. A response outside 2xx is returned as an error holding the
+// server's message.
+func (c *client) do(method, path string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequest(method, c.baseURL+apiPrefix+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.userID != 0 {
+		req.Header.Set("X-User-ID", strconv.Itoa(c.userID))
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, strings.TrimSpace(string(message)))
+	}
+
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"tms.zinkworks.com/model"
+)
+
+func (app *cli) commentsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "comments",
+		Aliases: []string{"comment"},
+		Short:   "Add and list task comments",
+	}
+
+	cmd.AddCommand(app.commentsAddCmd(), app.commentsListCmd())
+	return cmd
+}
+
+func (app *cli) commentsAddCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "add TASK_ID [TEXT...]",
+		Short: "Comment on a task",
+		Long: "Comment on a task. Without TEXT the comment is written in $EDITOR;\n" +
+			"with TEXT \"-\" it is read from standard input.",
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			taskID, err := parseID("task", args[0])
+			if err != nil {
+				return err
+			}
+
+			text := strings.Join(args[1:], " ")
+			switch text {
+			case "":
+				text, err = editText("")
+			case "-":
+				var content []byte
+				content, err = io.ReadAll(cmd.InOrStdin())
+				text = strings.TrimRight(string(content), "\r\n")
+			}
+			if err != nil {
+				return err
+			}
+			if strings.TrimSpace(text) == "" {
+				return errors.New("the comment is empty")
+			}
+
+			comment := model.TaskComment{TaskID: taskID, Comment: text}
+			var created model.TaskComment
+			err = app.client.do(http.MethodPost, "/comments", comment, &created)
+			if err != nil {
+				return err
+			}
+
+			return app.print(cmd.OutOrStdout(), created, func(w io.Writer) {
+				fmt.Fprintf(w, "Commented on task %d\n", created.TaskID)
+			})
+		},
+	}
+}
+
+func (app *cli) commentsListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list TASK_ID",
+		Short: "List a task's comments, oldest first",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			taskID, err := parseID("task", args[0])
+			if err != nil {
+				return err
+			}
+
+			var comments []model.TaskComment
+			err = app.client.do(http.MethodGet, fmt.Sprintf("/comments/%d", taskID), nil, &comments)
+			if err != nil {
+				return err
+			}
+
+			sort.Slice(comments, func(i, j int) bool {
+				if !comments[i].CreatedAt.Equal(comments[j].CreatedAt) {
+					return comments[i].CreatedAt.Before(comments[j].CreatedAt)
+				}
+				return comments[i].ID < comments[j].ID
+			})
+			return app.print(cmd.OutOrStdout(), comments, func(w io.Writer) { writeCommentsTable(w, comments) })
+		},
+	}
+}
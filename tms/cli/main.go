@@ -0,0 +1,132 @@
+// Command tms is a command-line client for the Task Management System API.
+//
+// Build it with:
+//
+//	go build -o tms ./tms/cli
+//
+// The server URL, token and user ID are read from a YAML config file,
+// $XDG_CONFIG_HOME/tms/config.yaml by default:
+//
+//	url: http://localhost:4000
+//	token: s3cr3t
+//	user_id: 7
+//
+// The user ID is sent as X-User-ID, the header the API identifies callers
+// by, and the token as a bearer token, for servers behind an authenticating
+// proxy. The --server flag and the TMS_URL and TMS_TOKEN environment
+// variables override the file.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+const defaultServer = "http://localhost:4000"
+
+// config is the content of the config file.
+type config struct {
+	URL    string `yaml:"url"`
+	Token  string `yaml:"token"`
+	UserID int    `yaml:"user_id"`
+}
+
+// cli holds the global flags and, once they are parsed, the API client.
+type cli struct {
+	configPath string
+	server     string
+	output     string
+	stdin      io.Reader
+	client     *client
+}
+
+func main() {
+	err := newRootCmd(os.Stdin, os.Stdout, os.Stderr).Execute()
+	if err != nil {
+		os.Exit(1)
+	}
+}
+
+func newRootCmd(stdin io.Reader, stdout, stderr io.Writer) *cobra.Command {
+	app := &cli{stdin: stdin}
+
+	root := &cobra.Command{
+		Use:          "tms",
+		Short:        "Manage tasks on a Task Management System server",
+		SilenceUsage: true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			switch app.output {
+			case outputTable, outputJSON, outputYAML:
+			default:
+				return fmt.Errorf("invalid output %q, expected table, json or yaml", app.output)
+			}
+
+			cfg, err := loadConfig(app.configPath)
+			if err != nil {
+				return err
+			}
+
+			if app.server != "" {
+				cfg.URL = app.server
+			}
+
+			app.client = newClient(cfg)
+			return nil
+		},
+	}
+	root.SetIn(stdin)
+	root.SetOut(stdout)
+	root.SetErr(stderr)
+
+	flags := root.PersistentFlags()
+	flags.StringVar(&app.configPath, "config", "", "Config file (default $XDG_CONFIG_HOME/tms/config.yaml)")
+	flags.StringVar(&app.server, "server", "", "Server URL, overriding the config file")
+	flags.StringVarP(&app.output, "output", "o", outputTable, "Output format (table|json|yaml)")
+
+	root.AddCommand(app.tasksCmd(), app.commentsCmd())
+	return root
+}
+
+// loadConfig reads the config file, then applies the environment. A
+// missing default config file is not an error; a missing explicit one is.
+func loadConfig(path string) (config, error) {
+	cfg := config{URL: defaultServer}
+
+	explicit := path != ""
+	if !explicit {
+		dir, err := os.UserConfigDir()
+		if err == nil {
+			path = filepath.Join(dir, "tms", "config.yaml")
+		}
+	}
+
+	if path != "" {
+		content, err := os.ReadFile(path)
+		switch {
+		case errors.Is(err, fs.ErrNotExist) && !explicit:
+		case err != nil:
+			return config{}, err
+		default:
+			err = yaml.Unmarshal(content, &cfg)
+			if err != nil {
+				return config{}, fmt.Errorf("reading %s: %w", path, err)
+			}
+		}
+	}
+
+	if url := os.Getenv("TMS_URL"); url != "" {
+		cfg.URL = url
+	}
+	if token := os.Getenv("TMS_TOKEN"); token != "" {
+		cfg.Token = token
+	}
+
+	return cfg, nil
+}
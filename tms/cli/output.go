@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"tms.zinkworks.com/model"
+)
+
+// Output formats.
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+)
+
+// print writes v in the chosen format. table writes its rows for the table format.
+func (app *cli) print(w io.Writer, v any, table func(w io.Writer)) error {
+	switch app.output {
+	case outputJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(v)
+	case outputYAML:
+		return writeYAML(w, v)
+	default:
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		table(tw)
+		return tw.Flush()
+	}
+}
+
+// writeYAML writes v as YAML with the same field names and order as its
+// JSON, by reading the JSON back as a YAML document.
+func writeYAML(w io.Writer, v any) error {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	var node yaml.Node
+	err = yaml.Unmarshal(payload, &node)
+	if err != nil {
+		return err
+	}
+	blockStyle(&node)
+
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	err = encoder.Encode(&node)
+	if err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+// blockStyle drops the flow style the JSON was read with, except for empty
+// collections, which have no block form.
+func blockStyle(node *yaml.Node) {
+	if len(node.Content) > 0 {
+		node.Style &^= yaml.FlowStyle
+	}
+	// Strings stay quoted only where YAML needs it.
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!str" {
+		node.Style &^= yaml.DoubleQuotedStyle
+	}
+	for _, child := range node.Content {
+		blockStyle(child)
+	}
+}
+
+func writeTasksTable(w io.Writer, tasks []model.Task) {
+	fmt.Fprintln(w, "ID\tTITLE\tDONE\tASSIGNEE\tPROJECT\tPARENT\tITEMS\tUPDATED")
+	for _, task := range tasks {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%d\t%s\t%d\t%s\n",
+			task.ID,
+			truncate(task.Title, 48),
+			yesNo(task.Completed),
+			optionalID(task.AssignedUserID),
+			task.ProjectID,
+			optionalID(task.ParentTaskID),
+			len(task.Items),
+			task.UpdatedAt.Local().Format(time.DateTime),
+		)
+	}
+}
+
+func writeTaskDetail(w io.Writer, task model.Task) {
+	fmt.Fprintf(w, "ID:\t%d\n", task.ID)
+	fmt.Fprintf(w, "Title:\t%s\n", task.Title)
+	fmt.Fprintf(w, "Completed:\t%s\n", yesNo(task.Completed))
+	fmt.Fprintf(w, "Assignee:\t%s\n", optionalID(task.AssignedUserID))
+	fmt.Fprintf(w, "Project:\t%d\n", task.ProjectID)
+	fmt.Fprintf(w, "Parent:\t%s\n", optionalID(task.ParentTaskID))
+	fmt.Fprintf(w, "Created:\t%s\n", task.CreatedAt.Local().Format(time.DateTime))
+	fmt.Fprintf(w, "Updated:\t%s\n", task.UpdatedAt.Local().Format(time.DateTime))
+	if task.Description != "" {
+		fmt.Fprintf(w, "Description:\t%s\n", strings.ReplaceAll(task.Description, "\n", "\n\t"))
+	}
+	for i, item := range task.Items {
+		label := ""
+		if i == 0 {
+			label = "Items:"
+		}
+		fmt.Fprintf(w, "%s\t- %s\n", label, item)
+	}
+}
+
+func writeCommentsTable(w io.Writer, comments []model.TaskComment) {
+	fmt.Fprintln(w, "ID\tTASK\tCREATED\tCOMMENT")
+	for _, comment := range comments {
+		fmt.Fprintf(w, "%d\t%d\t%s\t%s\n",
+			comment.ID,
+			comment.TaskID,
+			comment.CreatedAt.Local().Format(time.DateTime),
+			truncate(strings.ReplaceAll(comment.Comment, "\n", " "), 72),
+		)
+	}
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+func optionalID(id int) string {
+	if id == 0 {
+		return "-"
+	}
+	return fmt.Sprint(id)
+}
+
+func truncate(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max-1]) + "…"
+}
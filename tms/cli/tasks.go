@@ -0,0 +1,290 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+
+	"github.com/spf13/cobra"
+
+	"tms.zinkworks.com/model"
+)
+
+func (app *cli) tasksCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "tasks",
+		Aliases: []string{"task"},
+		Short:   "List, create and change tasks",
+	}
+
+	cmd.AddCommand(
+		app.tasksListCmd(),
+		app.tasksGetCmd(),
+		app.tasksCreateCmd(),
+		app.tasksUpdateCmd(),
+		app.tasksDeleteCmd(),
+		app.tasksAssignCmd(),
+		app.tasksCompleteCmd(),
+	)
+	return cmd
+}
+
+func (app *cli) tasksListCmd() *cobra.Command {
+	var assignee int
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List tasks",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := "/tasks"
+			if assignee != 0 {
+				path = fmt.Sprintf("/users/%d/tasks/assigned", assignee)
+			}
+
+			var tasks []model.Task
+			err := app.client.do(http.MethodGet, path, nil, &tasks)
+			if err != nil {
+				return err
+			}
+
+			sort.Slice(tasks, func(i, j int) bool { return tasks[i].ID < tasks[j].ID })
+			return app.print(cmd.OutOrStdout(), tasks, func(w io.Writer) { writeTasksTable(w, tasks) })
+		},
+	}
+
+	cmd.Flags().IntVar(&assignee, "assignee", 0, "Only list tasks assigned to this user")
+	return cmd
+}
+
+func (app *cli) tasksGetCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "get TASK_ID",
+		Short: "Show a task",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			task, err := app.getTask(args[0])
+			if err != nil {
+				return err
+			}
+			return app.printTask(cmd, task)
+		},
+	}
+}
+
+func (app *cli) tasksCreateCmd() *cobra.Command {
+	var task model.Task
+	var edit bool
+
+	cmd := &cobra.Command{
+		Use:   "create",
+		Short: "Create a task",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if task.Title == "" {
+				return errors.New("a title is required")
+			}
+
+			if edit {
+				description, err := editText(task.Description)
+				if err != nil {
+					return err
+				}
+				task.Description = description
+			}
+
+			var created model.Task
+			err := app.client.do(http.MethodPost, "/tasks", task, &created)
+			if err != nil {
+				return err
+			}
+			return app.printTask(cmd, &created)
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringVar(&task.Title, "title", "", "Title of the task")
+	flags.StringVar(&task.Description, "description", "", "Description of the task")
+	flags.StringArrayVar(&task.Items, "item", nil, "A checklist item; repeat for more")
+	flags.IntVar(&task.ProjectID, "project", 0, "Project to create the task in (default the default project)")
+	flags.IntVar(&task.ParentTaskID, "parent", 0, "Parent task")
+	flags.BoolVarP(&edit, "edit", "e", false, "Write the description in $EDITOR")
+	return cmd
+}
+
+func (app *cli) tasksUpdateCmd() *cobra.Command {
+	var title, description string
+	var items, addItems []string
+	var completed, edit bool
+
+	cmd := &cobra.Command{
+		Use:   "update TASK_ID",
+		Short: "Change a task's title, description, items or completion",
+		Long: "Change a task's title, description, items or completion. Only the fields given are changed;\n" +
+			"--item replaces the checklist and --add-item appends to it.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			flags := cmd.Flags()
+			changed := false
+			for _, name := range []string{"title", "description", "item", "add-item", "completed", "edit"} {
+				changed = changed || flags.Changed(name)
+			}
+			if !changed {
+				return errors.New("nothing to update: give at least one of --title, --description, --item, --add-item, --completed or --edit")
+			}
+
+			task, err := app.getTask(args[0])
+			if err != nil {
+				return err
+			}
+
+			if flags.Changed("title") {
+				task.Title = title
+			}
+			if flags.Changed("description") {
+				task.Description = description
+			}
+			if flags.Changed("item") {
+				task.Items = items
+			}
+			task.Items = append(task.Items, addItems...)
+			if flags.Changed("completed") {
+				task.Completed = completed
+			}
+			if edit {
+				task.Description, err = editText(task.Description)
+				if err != nil {
+					return err
+				}
+			}
+
+			return app.putTask(cmd, task)
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringVar(&title, "title", "", "New title")
+	flags.StringVar(&description, "description", "", "New description")
+	flags.StringArrayVar(&items, "item", nil, "Replace the checklist; repeat for more items")
+	flags.StringArrayVar(&addItems, "add-item", nil, "Append a checklist item; repeat for more")
+	flags.BoolVar(&completed, "completed", false, "Mark the task completed, or open with --completed=false")
+	flags.BoolVarP(&edit, "edit", "e", false, "Edit the description in $EDITOR")
+	return cmd
+}
+
+func (app *cli) tasksDeleteCmd() *cobra.Command {
+	var reparent bool
+
+	cmd := &cobra.Command{
+		Use:   "delete TASK_ID",
+		Short: "Move a task and its subtasks to the trash",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := parseID("task", args[0])
+			if err != nil {
+				return err
+			}
+
+			path := fmt.Sprintf("/tasks/%d", id)
+			if reparent {
+				path += "?" + url.Values{"subtasks": {"reparent"}}.Encode()
+			}
+
+			err = app.client.do(http.MethodDelete, path, nil, nil)
+			if err != nil {
+				return err
+			}
+
+			result := map[string]any{"id": id, "deleted": true}
+			return app.print(cmd.OutOrStdout(), result, func(w io.Writer) { fmt.Fprintf(w, "Deleted task %d\n", id) })
+		},
+	}
+
+	cmd.Flags().BoolVar(&reparent, "reparent", false, "Keep the subtasks, moving them up to the task's parent")
+	return cmd
+}
+
+func (app *cli) tasksAssignCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "assign TASK_ID USER_ID",
+		Short: "Assign a task to a user, or unassign it with user 0",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := parseID("task", args[0])
+			if err != nil {
+				return err
+			}
+			userID, err := strconv.Atoi(args[1])
+			if err != nil || userID < 0 {
+				return fmt.Errorf("invalid user ID %q", args[1])
+			}
+
+			var task model.Task
+			err = app.client.do(http.MethodPatch, fmt.Sprintf("/tasks/%d/assign/%d", id, userID), nil, &task)
+			if err != nil {
+				return err
+			}
+			return app.printTask(cmd, &task)
+		},
+	}
+}
+
+func (app *cli) tasksCompleteCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "complete TASK_ID",
+		Short: "Mark a task completed",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			task, err := app.getTask(args[0])
+			if err != nil {
+				return err
+			}
+			if task.Completed {
+				return app.printTask(cmd, task)
+			}
+
+			task.Completed = true
+			return app.putTask(cmd, task)
+		},
+	}
+}
+
+func (app *cli) getTask(arg string) (*model.Task, error) {
+	id, err := parseID("task", arg)
+	if err != nil {
+		return nil, err
+	}
+
+	var task model.Task
+	err = app.client.do(http.MethodGet, fmt.Sprintf("/tasks/%d", id), nil, &task)
+	if err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// putTask saves the task's title, description, completion and items, and prints the result.
+func (app *cli) putTask(cmd *cobra.Command, task *model.Task) error {
+	var updated model.Task
+	err := app.client.do(http.MethodPut, fmt.Sprintf("/tasks/%d", task.ID), task, &updated)
+	if err != nil {
+		return err
+	}
+	return app.printTask(cmd, &updated)
+}
+
+func (app *cli) printTask(cmd *cobra.Command, task *model.Task) error {
+	return app.print(cmd.OutOrStdout(), task, func(w io.Writer) { writeTaskDetail(w, *task) })
+}
+
+func parseID(kind, arg string) (int, error) {
+	id, err := strconv.Atoi(arg)
+	if err != nil || id < 1 {
+		return 0, fmt.Errorf("invalid %s ID %q", kind, arg)
+	}
+	return id, nil
+}
@@ -0,0 +1,98 @@
+package tmsclient
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+
+	"tms.zinkworks.com/model"
+)
+
+// UploadAttachment attaches the content read from r to the task under the
+// given file name. The content is streamed, so the upload is not retried.
+func (c *Client) UploadAttachment(ctx context.Context, taskID int, filename string, r io.Reader) (*model.TaskAttachment, error) {
+	body, writer := io.Pipe()
+	form := multipart.NewWriter(writer)
+
+	go func() {
+		part, err := form.CreateFormFile("file", filename)
+		if err == nil {
+			_, err = io.Copy(part, r)
+		}
+		if err == nil {
+			err = form.Close()
+		}
+		writer.CloseWithError(err)
+	}()
+
+	req := request{
+		method:      http.MethodPost,
+		path:        taskPath(taskID) + "/attachments",
+		raw:         body,
+		contentType: form.FormDataContentType(),
+	}
+
+	var attachment model.TaskAttachment
+	err := c.do(ctx, req, &attachment)
+	// Stop the writer if the request ended before reading the whole body.
+	body.Close()
+	if err != nil {
+		return nil, err
+	}
+	return &attachment, nil
+}
+
+// ListAttachments returns the task's attachments.
+func (c *Client) ListAttachments(ctx context.Context, taskID int) ([]model.TaskAttachment, error) {
+	var attachments []model.TaskAttachment
+	err := c.do(ctx, request{method: http.MethodGet, path: taskPath(taskID) + "/attachments", idempotent: true}, &attachments)
+	return attachments, err
+}
+
+// Download is the content of an attachment. The caller closes Body.
+type Download struct {
+	Body        io.ReadCloser
+	ContentType string
+	Filename    string
+	// Size is the length of Body, or -1 if the server did not say.
+	Size int64
+}
+
+// DownloadAttachment returns the attachment's content from offset onwards;
+// a non-zero offset resumes an interrupted download.
+func (c *Client) DownloadAttachment(ctx context.Context, taskID, attachmentID int, offset int64) (*Download, error) {
+	req := request{method: http.MethodGet, idempotent: true}
+	if offset > 0 {
+		req.header = http.Header{"Range": {fmt.Sprintf("bytes=%d-", offset)}}
+	}
+
+	path := fmt.Sprintf("%s/attachments/%d", taskPath(taskID), attachmentID)
+	resp, err := c.send(ctx, req, c.url(path, nil))
+	if err != nil {
+		return nil, err
+	}
+
+	if offset > 0 && resp.StatusCode != http.StatusPartialContent {
+		resp.Body.Close()
+		return nil, fmt.Errorf("tmsclient: %s: server ignored the range request", path)
+	}
+
+	download := &Download{
+		Body:        resp.Body,
+		ContentType: resp.Header.Get("Content-Type"),
+		Size:        resp.ContentLength,
+	}
+	if _, params, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition")); err == nil {
+		download.Filename = params["filename"]
+	}
+	return download, nil
+}
+
+// DeleteAttachment removes the attachment from the task.
+func (c *Client) DeleteAttachment(ctx context.Context, taskID, attachmentID int) error {
+	path := fmt.Sprintf("%s/attachments/%d", taskPath(taskID), attachmentID)
+	return c.do(ctx, request{method: http.MethodDelete, path: path, idempotent: true}, nil)
+}
@@ -0,0 +1,270 @@
+// Package tmsclient is a Go client for the Task Management System API. It
+// uses the model package's types, so services calling the API share one
+// definition of a task with the server.
+//
+//	client := tmsclient.New("http://localhost:4000", tmsclient.WithUserID(7))
+//	task, err := client.CreateTask(ctx, model.Task{Title: "Rotate certificates"})
+//	if errors.Is(err, tmsclient.ErrNotFound) {
+//		...
+//	}
+//
+// Requests that fail with 429, or with a 5xx on a method that is safe to
+// repeat, are retried with exponential backoff, honouring Retry-After.
+package tmsclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// apiPrefix is the version of the API the client is written against.
+const apiPrefix = "/v1"
+
+// Client calls the API. It is safe for concurrent use.
+type Client struct {
+	baseURL    *url.URL
+	httpClient *http.Client
+	userID     int
+	token      string
+	userAgent  string
+	maxRetries int
+	minBackoff time.Duration
+	maxBackoff time.Duration
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithHTTPClient sets the HTTP client requests are sent with.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) { c.httpClient = httpClient }
+}
+
+// WithUserID makes requests on behalf of the user, through the X-User-ID header.
+func WithUserID(userID int) Option {
+	return func(c *Client) { c.userID = userID }
+}
+
+// WithToken sends the token as a bearer token, for servers behind an
+// authenticating proxy.
+func WithToken(token string) Option {
+	return func(c *Client) { c.token = token }
+}
+
+// WithUserAgent sets the User-Agent header.
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) { c.userAgent = userAgent }
+}
+
+// WithRetries sets how many times a failed request is retried and the
+// bounds of the backoff between attempts. Zero retries turns retrying off.
+func WithRetries(maxRetries int, minBackoff, maxBackoff time.Duration) Option {
+	return func(c *Client) {
+		c.maxRetries = maxRetries
+		c.minBackoff = minBackoff
+		c.maxBackoff = maxBackoff
+	}
+}
+
+// New returns a client for the API at baseURL, such as "http://localhost:4000".
+// Requests go to version 1 of the API under it, as in /v1/tasks.
+// It panics if baseURL is not an absolute URL.
+func New(baseURL string, opts ...Option) *Client {
+	parsed, err := url.Parse(strings.TrimRight(baseURL, "/"))
+	if err != nil || !parsed.IsAbs() {
+		panic(fmt.Sprintf("tmsclient: invalid base URL %q", baseURL))
+	}
+
+	c := &Client{
+		baseURL:    parsed,
+		httpClient: &http.Client{Timeout: time.Minute},
+		userAgent:  "tmsclient",
+		maxRetries: 3,
+		minBackoff: 200 * time.Millisecond,
+		maxBackoff: 5 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// request describes one API call.
+type request struct {
+	method string
+	path   string
+	query  url.Values
+	// body is sent as JSON; raw is sent as is, with contentType. A raw body
+	// that cannot seek is only sent once.
+	body        any
+	raw         io.Reader
+	contentType string
+	header      http.Header
+	// idempotent requests are retried on 5xx as well as 429.
+	idempotent bool
+}
+
+func (c *Client) url(path string, query url.Values) string {
+	u := *c.baseURL
+	u.Path += apiPrefix + path
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// do sends req and decodes a successful response into out, if given. A
+// response outside 2xx is returned as an *Error.
+func (c *Client) do(ctx context.Context, req request, out any) error {
+	resp, err := c.send(ctx, req, c.url(req.path, req.query))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return decode(resp, out)
+}
+
+func decode(resp *http.Response, out any) error {
+	if out == nil {
+		_, err := io.Copy(io.Discard, resp.Body)
+		return err
+	}
+	err := json.NewDecoder(resp.Body).Decode(out)
+	if err != nil {
+		return fmt.Errorf("tmsclient: decoding %s response: %w", resp.Request.URL.Path, err)
+	}
+	return nil
+}
+
+// send sends req to target, retrying as allowed, and returns the first
+// successful response. The caller closes its body.
+func (c *Client) send(ctx context.Context, req request, target string) (*http.Response, error) {
+	var payload []byte
+	if req.body != nil {
+		var err error
+		payload, err = json.Marshal(req.body)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	seeker, rewindable := req.raw.(io.Seeker)
+	if req.raw == nil {
+		rewindable = true
+	}
+
+	for attempt := 0; ; attempt++ {
+		var body io.Reader
+		switch {
+		case req.raw != nil:
+			if attempt > 0 {
+				_, err := seeker.Seek(0, io.SeekStart)
+				if err != nil {
+					return nil, err
+				}
+			}
+			body = req.raw
+		case payload != nil:
+			body = bytes.NewReader(payload)
+		}
+
+		httpReq, err := http.NewRequestWithContext(ctx, req.method, target, body)
+		if err != nil {
+			return nil, err
+		}
+		c.setHeaders(httpReq, req, payload != nil)
+
+		resp, err := c.httpClient.Do(httpReq)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode >= 200 && resp.StatusCode <= 299 {
+			return resp, nil
+		}
+
+		apiErr := newError(resp)
+		resp.Body.Close()
+
+		retryable := resp.StatusCode == http.StatusTooManyRequests || (resp.StatusCode >= 500 && req.idempotent)
+		if !retryable || !rewindable || attempt >= c.maxRetries {
+			return nil, apiErr
+		}
+
+		timer := time.NewTimer(c.backoff(attempt, resp.Header.Get("Retry-After")))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (c *Client) setHeaders(httpReq *http.Request, req request, jsonBody bool) {
+	httpReq.Header.Set("Accept", "application/json")
+	httpReq.Header.Set("User-Agent", c.userAgent)
+	if jsonBody {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+	if req.contentType != "" {
+		httpReq.Header.Set("Content-Type", req.contentType)
+	}
+	for name, values := range req.header {
+		httpReq.Header[name] = values
+	}
+	if c.userID != 0 {
+		httpReq.Header.Set("X-User-ID", strconv.Itoa(c.userID))
+	}
+	if c.token != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+c.token)
+	}
+}
+
+// backoff returns how long to wait before retrying after the given attempt:
+// what Retry-After asks for if it is set, otherwise an exponential delay
+// with full jitter.
+func (c *Client) backoff(attempt int, retryAfter string) time.Duration {
+	if retryAfter != "" {
+		if seconds, err := strconv.Atoi(retryAfter); err == nil && seconds >= 0 {
+			return capDelay(time.Duration(seconds)*time.Second, c.maxBackoff)
+		}
+		if at, err := http.ParseTime(retryAfter); err == nil {
+			return capDelay(time.Until(at), c.maxBackoff)
+		}
+	}
+
+	delay := c.minBackoff << attempt
+	if delay <= 0 || delay > c.maxBackoff {
+		delay = c.maxBackoff
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(delay)) + 1)
+}
+
+// capDelay keeps a delay the server asked for between zero and max.
+func capDelay(delay, max time.Duration) time.Duration {
+	if delay < 0 {
+		return 0
+	}
+	if delay > max {
+		return max
+	}
+	return delay
+}
+
+// Healthcheck returns the server's status, environment and version.
+func (c *Client) Healthcheck(ctx context.Context) (map[string]string, error) {
+	var status map[string]string
+	err := c.do(ctx, request{method: http.MethodGet, path: "/healthcheck", idempotent: true}, &status)
+	return status, err
+}
@@ -0,0 +1,146 @@
+package tmsclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"tms.zinkworks.com/model"
+)
+
+func newTestClient(server *httptest.Server) *Client {
+	return New(server.URL, WithUserID(7), WithRetries(3, time.Millisecond, 10*time.Millisecond))
+}
+
+func TestSend_RetriesIdempotentRequestOnServerError(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			http.Error(w, "Database unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		assert.Equal(t, "7", r.Header.Get("X-User-ID"))
+		json.NewEncoder(w).Encode(model.Task{ID: 4, Title: "Rotate certificates"})
+	}))
+	defer server.Close()
+
+	task, err := newTestClient(server).GetTask(context.Background(), 4)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Rotate certificates", task.Title)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+}
+
+func TestSend_RetriesRateLimitedPostWithBody(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var task model.Task
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&task))
+		assert.Equal(t, "Rotate certificates", task.Title)
+
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.Header().Set("Retry-After", "0")
+			http.Error(w, "Too many requests", http.StatusTooManyRequests)
+			return
+		}
+		task.ID = 9
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(task)
+	}))
+	defer server.Close()
+
+	task, err := newTestClient(server).CreateTask(context.Background(), model.Task{Title: "Rotate certificates"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 9, task.ID)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestSend_DoesNotRetryPostOnServerError(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		http.Error(w, "Failed to create task", http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	_, err := newTestClient(server).CreateTask(context.Background(), model.Task{Title: "Rotate certificates"})
+
+	assert.ErrorIs(t, err, ErrServer)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestError_MatchesSentinelAndKeepsMessage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Task not found", http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	_, err := newTestClient(server).GetTask(context.Background(), 4)
+
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.False(t, errors.Is(err, ErrServer))
+	var apiErr *Error
+	assert.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+	assert.Equal(t, "Task not found", apiErr.Message)
+	assert.Equal(t, "/v1/tasks/4", apiErr.Path)
+}
+
+func TestIterator_FollowsNextLinks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "2", r.URL.Query().Get("limit"))
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		if page < 2 {
+			w.Header().Set("Link", fmt.Sprintf(`</tasks?limit=2&page=%d>; rel="next"`, page+1))
+		}
+		json.NewEncoder(w).Encode([]model.Task{{ID: page*2 + 1}, {ID: page*2 + 2}})
+	}))
+	defer server.Close()
+
+	tasks, err := newTestClient(server).ListTasks(context.Background(), ListOptions{Limit: 2}).All()
+
+	assert.NoError(t, err)
+	ids := make([]int, 0, len(tasks))
+	for _, task := range tasks {
+		ids = append(ids, task.ID)
+	}
+	assert.Equal(t, []int{1, 2, 3, 4, 5, 6}, ids)
+}
+
+func TestEventStream_ParsesEventsAndSkipsComments(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "text/event-stream", r.Header.Get("Accept"))
+		assert.Equal(t, "41", r.Header.Get("Last-Event-ID"))
+		assert.Equal(t, "3", r.URL.Query().Get("task_id"))
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "retry: 3000\n\nevent: reset\ndata: {}\n\n: ping\n\nid: 42\nevent: task.updated\ndata: {\"id\":3}\n\n")
+	}))
+	defer server.Close()
+
+	stream, err := newTestClient(server).StreamEvents(context.Background(), EventFilter{TaskID: 3}, 41)
+	assert.NoError(t, err)
+	defer stream.Close()
+
+	event, err := stream.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, "reset", event.Type)
+
+	event, err = stream.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, Event{ID: 42, Type: "task.updated", Data: json.RawMessage(`{"id":3}`)}, event)
+	assert.Equal(t, uint64(42), stream.LastEventID())
+
+	_, err = stream.Next()
+	assert.ErrorIs(t, err, io.EOF)
+}
@@ -0,0 +1,88 @@
+package tmsclient
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// Errors matched by errors.Is against an *Error, by status code.
+var (
+	ErrBadRequest          = errors.New("bad request")
+	ErrUnauthorized        = errors.New("unauthorized")
+	ErrForbidden           = errors.New("forbidden")
+	ErrNotFound            = errors.New("not found")
+	ErrConflict            = errors.New("conflict")
+	ErrTooLarge            = errors.New("payload too large")
+	ErrUnsupportedType     = errors.New("unsupported media type")
+	ErrUnprocessable       = errors.New("unprocessable entity")
+	ErrRateLimited         = errors.New("rate limited")
+	ErrServer              = errors.New("server error")
+	ErrServiceUnavailable  = errors.New("service unavailable")
+	ErrRangeNotSatisfiable = errors.New("range not satisfiable")
+)
+
+var statusErrors = map[int]error{
+	http.StatusBadRequest:                   ErrBadRequest,
+	http.StatusUnauthorized:                 ErrUnauthorized,
+	http.StatusForbidden:                    ErrForbidden,
+	http.StatusNotFound:                     ErrNotFound,
+	http.StatusConflict:                     ErrConflict,
+	http.StatusRequestEntityTooLarge:        ErrTooLarge,
+	http.StatusUnsupportedMediaType:         ErrUnsupportedType,
+	http.StatusUnprocessableEntity:          ErrUnprocessable,
+	http.StatusTooManyRequests:              ErrRateLimited,
+	http.StatusServiceUnavailable:           ErrServiceUnavailable,
+	http.StatusRequestedRangeNotSatisfiable: ErrRangeNotSatisfiable,
+}
+
+// maxErrorBody bounds how much of an error response is read.
+const maxErrorBody = 64 << 10
+
+// Error is a response outside 2xx. Message is the server's explanation,
+// which the API sends as plain text; Body is the raw response, which for a
+// few routes, such as a rejected import, is a JSON report.
+type Error struct {
+	Method     string
+	Path       string
+	StatusCode int
+	Message    string
+	Body       []byte
+}
+
+func newError(resp *http.Response) *Error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+
+	message := ""
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType != "application/json" {
+		message = strings.TrimSpace(string(body))
+	}
+	if message == "" {
+		message = http.StatusText(resp.StatusCode)
+	}
+
+	return &Error{
+		Method:     resp.Request.Method,
+		Path:       resp.Request.URL.Path,
+		StatusCode: resp.StatusCode,
+		Message:    message,
+		Body:       body,
+	}
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("tmsclient: %s %s: %d %s", e.Method, e.Path, e.StatusCode, e.Message)
+}
+
+// Is matches the sentinel error for the status code, so callers can write
+// errors.Is(err, tmsclient.ErrNotFound).
+func (e *Error) Is(target error) bool {
+	if target == ErrServer {
+		return e.StatusCode >= 500
+	}
+	return statusErrors[e.StatusCode] == target
+}
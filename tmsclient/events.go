@@ -0,0 +1,135 @@
+package tmsclient
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Event is a change sent on the event stream, such as task.updated. Data
+// is the event's JSON payload. A "reset" event means some events were
+// missed and anything cached from earlier events should be reloaded.
+type Event struct {
+	ID   uint64
+	Type string
+	Data json.RawMessage
+}
+
+// EventFilter limits the event stream to one task or assignee. Zero
+// fields do not filter.
+type EventFilter struct {
+	TaskID     int
+	AssigneeID int
+}
+
+// EventStream reads events from an open stream. It is not safe for
+// concurrent use.
+type EventStream struct {
+	body        io.ReadCloser
+	reader      *bufio.Reader
+	lastEventID uint64
+}
+
+// StreamEvents opens the event stream. With a non-zero lastEventID the
+// server first sends the events after it, so a dropped stream can be
+// resumed with EventStream.LastEventID. The stream stays open until Close
+// is called or ctx is done.
+func (c *Client) StreamEvents(ctx context.Context, filter EventFilter, lastEventID uint64) (*EventStream, error) {
+	query := url.Values{}
+	if filter.TaskID != 0 {
+		query.Set("task_id", strconv.Itoa(filter.TaskID))
+	}
+	if filter.AssigneeID != 0 {
+		query.Set("assignee_id", strconv.Itoa(filter.AssigneeID))
+	}
+
+	req := request{
+		method:     http.MethodGet,
+		header:     http.Header{"Accept": {"text/event-stream"}},
+		idempotent: true,
+	}
+	if lastEventID != 0 {
+		req.header.Set("Last-Event-ID", strconv.FormatUint(lastEventID, 10))
+	}
+
+	// The client's timeout covers reading the whole body, which would cut
+	// the stream off, so it is sent without one.
+	httpClient := *c.httpClient
+	httpClient.Timeout = 0
+	streamer := *c
+	streamer.httpClient = &httpClient
+
+	resp, err := streamer.send(ctx, req, c.url("/events", query))
+	if err != nil {
+		return nil, err
+	}
+	return &EventStream{body: resp.Body, reader: bufio.NewReader(resp.Body), lastEventID: lastEventID}, nil
+}
+
+// Next blocks until the next event arrives. It returns io.EOF when the
+// server ends the stream.
+func (s *EventStream) Next() (Event, error) {
+	var event Event
+	var data []string
+	for {
+		line, err := s.reader.ReadString('\n')
+		if err != nil {
+			if err == io.EOF && line == "" {
+				return Event{}, io.EOF
+			}
+			if err != io.EOF {
+				return Event{}, err
+			}
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		if line == "" {
+			// A blank line ends the event; retry-only and comment blocks have no type.
+			if event.Type == "" && data == nil {
+				if err == io.EOF {
+					return Event{}, io.EOF
+				}
+				continue
+			}
+			if event.Type == "" {
+				event.Type = "message"
+			}
+			event.Data = json.RawMessage(strings.Join(data, "\n"))
+			if event.ID != 0 {
+				s.lastEventID = event.ID
+			}
+			return event, nil
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "id":
+			if id, parseErr := strconv.ParseUint(value, 10, 64); parseErr == nil {
+				event.ID = id
+			}
+		case "event":
+			event.Type = value
+		case "data":
+			data = append(data, value)
+		}
+	}
+}
+
+// LastEventID returns the ID of the last event read, to resume from.
+func (s *EventStream) LastEventID() uint64 {
+	return s.lastEventID
+}
+
+// Close closes the stream.
+func (s *EventStream) Close() error {
+	return s.body.Close()
+}
@@ -0,0 +1,140 @@
+package tmsclient
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Iterator walks a list one item at a time, fetching further pages as it
+// goes. Each page is a JSON array; the next one is named by the response's
+// Link header with rel="next", and the list ends on a page without one.
+//
+//	it := client.ListTasks(ctx, tmsclient.ListOptions{})
+//	for it.Next() {
+//		task := it.Value()
+//		...
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+type Iterator[T any] struct {
+	client  *Client
+	ctx     context.Context
+	next    string
+	page    []T
+	current T
+	err     error
+}
+
+// ListOptions tunes a paginated list.
+type ListOptions struct {
+	// Limit is the number of items per page. Zero leaves it to the server.
+	Limit int
+}
+
+func (opts ListOptions) query() url.Values {
+	query := url.Values{}
+	if opts.Limit > 0 {
+		query.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	return query
+}
+
+func newIterator[T any](ctx context.Context, c *Client, path string, query url.Values) *Iterator[T] {
+	return &Iterator[T]{client: c, ctx: ctx, next: c.url(path, query)}
+}
+
+// Next advances to the next item, fetching a page if needed. It returns
+// false at the end of the list or on an error, which Err then returns.
+func (it *Iterator[T]) Next() bool {
+	for len(it.page) == 0 {
+		if it.err != nil || it.next == "" {
+			return false
+		}
+		it.fetch()
+	}
+
+	it.current, it.page = it.page[0], it.page[1:]
+	return true
+}
+
+func (it *Iterator[T]) fetch() {
+	target := it.next
+	it.next = ""
+
+	resp, err := it.client.send(it.ctx, request{method: http.MethodGet, idempotent: true}, target)
+	if err != nil {
+		it.err = err
+		return
+	}
+	defer resp.Body.Close()
+
+	var page []T
+	err = decode(resp, &page)
+	if err != nil {
+		it.err = err
+		return
+	}
+
+	it.page = page
+	if next := nextLink(resp.Header.Values("Link")); next != "" {
+		base := resp.Request.URL
+		ref, err := url.Parse(next)
+		if err != nil {
+			it.err = err
+			return
+		}
+		it.next = base.ResolveReference(ref).String()
+	}
+}
+
+// Value returns the current item.
+func (it *Iterator[T]) Value() T {
+	return it.current
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (it *Iterator[T]) Err() error {
+	return it.err
+}
+
+// All reads the rest of the list into a slice.
+func (it *Iterator[T]) All() ([]T, error) {
+	items := make([]T, 0)
+	for it.Next() {
+		items = append(items, it.Value())
+	}
+	return items, it.Err()
+}
+
+// nextLink returns the target of the rel="next" link in Link headers.
+func nextLink(headers []string) string {
+	for _, header := range headers {
+		for _, link := range strings.Split(header, ",") {
+			parts := strings.Split(link, ";")
+			target := strings.TrimSpace(parts[0])
+			if !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+				continue
+			}
+			for _, param := range parts[1:] {
+				name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+				if strings.EqualFold(name, "rel") && hasToken(strings.Trim(value, `"`), "next") {
+					return target[1 : len(target)-1]
+				}
+			}
+		}
+	}
+	return ""
+}
+
+func hasToken(list, token string) bool {
+	for _, field := range strings.Fields(list) {
+		if strings.EqualFold(field, token) {
+			return true
+		}
+	}
+	return false
+}
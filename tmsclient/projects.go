@@ -0,0 +1,138 @@
+package tmsclient
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"tms.zinkworks.com/model"
+)
+
+// CreateProject creates a project owned by the calling user.
+func (c *Client) CreateProject(ctx context.Context, project model.Project) (*model.Project, error) {
+	var created model.Project
+	err := c.do(ctx, request{method: http.MethodPost, path: "/projects", body: project}, &created)
+	if err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// ListProjects returns the projects the calling user is a member of.
+func (c *Client) ListProjects(ctx context.Context) ([]model.Project, error) {
+	var projects []model.Project
+	err := c.do(ctx, request{method: http.MethodGet, path: "/projects", idempotent: true}, &projects)
+	return projects, err
+}
+
+// GetProject returns a project the calling user is a member of.
+func (c *Client) GetProject(ctx context.Context, id int) (*model.Project, error) {
+	var project model.Project
+	err := c.do(ctx, request{method: http.MethodGet, path: projectPath(id), idempotent: true}, &project)
+	if err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
+
+// ListProjectMembers returns the project's members and their roles.
+func (c *Client) ListProjectMembers(ctx context.Context, projectID int) ([]model.ProjectMember, error) {
+	var members []model.ProjectMember
+	err := c.do(ctx, request{method: http.MethodGet, path: projectPath(projectID) + "/members", idempotent: true}, &members)
+	return members, err
+}
+
+// AddProjectMember adds a user to the project with the given role.
+func (c *Client) AddProjectMember(ctx context.Context, projectID, userID int, role string) (*model.ProjectMember, error) {
+	body := model.ProjectMember{UserID: userID, Role: role}
+	var member model.ProjectMember
+	err := c.do(ctx, request{method: http.MethodPost, path: projectPath(projectID) + "/members", body: body}, &member)
+	if err != nil {
+		return nil, err
+	}
+	return &member, nil
+}
+
+// RemoveProjectMember removes a user from the project.
+func (c *Client) RemoveProjectMember(ctx context.Context, projectID, userID int) error {
+	path := fmt.Sprintf("%s/members/%d", projectPath(projectID), userID)
+	return c.do(ctx, request{method: http.MethodDelete, path: path, idempotent: true}, nil)
+}
+
+// ListProjectTasks lists the tasks in the project.
+func (c *Client) ListProjectTasks(ctx context.Context, projectID int, opts ListOptions) *Iterator[model.Task] {
+	return newIterator[model.Task](ctx, c, projectPath(projectID)+"/tasks", opts.query())
+}
+
+// CreateProjectTask creates a task in the project.
+func (c *Client) CreateProjectTask(ctx context.Context, projectID int, task model.Task) (*model.Task, error) {
+	var created model.Task
+	err := c.do(ctx, request{method: http.MethodPost, path: projectPath(projectID) + "/tasks", body: task}, &created)
+	if err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// MoveTaskToProject moves the task, and its subtasks, to another project.
+func (c *Client) MoveTaskToProject(ctx context.Context, taskID, projectID int) (*model.Task, error) {
+	var task model.Task
+	err := c.do(ctx, request{method: http.MethodPatch, path: fmt.Sprintf("/tasks/%d/project/%d", taskID, projectID), idempotent: true}, &task)
+	if err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// CreateBoard creates a board with the given columns in the project.
+func (c *Client) CreateBoard(ctx context.Context, projectID int, board model.Board) (*model.Board, error) {
+	var created model.Board
+	err := c.do(ctx, request{method: http.MethodPost, path: projectPath(projectID) + "/boards", body: board}, &created)
+	if err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// ListProjectBoards returns the project's boards, without their cards.
+func (c *Client) ListProjectBoards(ctx context.Context, projectID int) ([]model.Board, error) {
+	var boards []model.Board
+	err := c.do(ctx, request{method: http.MethodGet, path: projectPath(projectID) + "/boards", idempotent: true}, &boards)
+	return boards, err
+}
+
+// GetBoard returns the board with its columns and cards.
+func (c *Client) GetBoard(ctx context.Context, id int) (*model.Board, error) {
+	var board model.Board
+	err := c.do(ctx, request{method: http.MethodGet, path: boardPath(id), idempotent: true}, &board)
+	if err != nil {
+		return nil, err
+	}
+	return &board, nil
+}
+
+// MoveCard places the task in a column of the board.
+func (c *Client) MoveCard(ctx context.Context, boardID, taskID int, move model.CardMove) (*model.BoardCard, error) {
+	path := fmt.Sprintf("%s/cards/%d", boardPath(boardID), taskID)
+	var card model.BoardCard
+	err := c.do(ctx, request{method: http.MethodPatch, path: path, body: move, idempotent: true}, &card)
+	if err != nil {
+		return nil, err
+	}
+	return &card, nil
+}
+
+// RemoveCard takes the task off the board.
+func (c *Client) RemoveCard(ctx context.Context, boardID, taskID int) error {
+	path := fmt.Sprintf("%s/cards/%d", boardPath(boardID), taskID)
+	return c.do(ctx, request{method: http.MethodDelete, path: path, idempotent: true}, nil)
+}
+
+func projectPath(id int) string {
+	return "/projects/" + strconv.Itoa(id)
+}
+
+func boardPath(id int) string {
+	return "/boards/" + strconv.Itoa(id)
+}
@@ -0,0 +1,36 @@
+package tmsclient
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"tms.zinkworks.com/model"
+)
+
+// Call makes a JSON-RPC call to POST /rpc and decodes its result into out,
+// if given. A failed call is returned as a *model.RPCError.
+func (c *Client) Call(ctx context.Context, method string, params, out any) error {
+	var encoded json.RawMessage
+	if params != nil {
+		var err error
+		encoded, err = json.Marshal(params)
+		if err != nil {
+			return err
+		}
+	}
+
+	body := model.RPCRequest{JSONRPC: model.RPCVersion, Method: method, Params: encoded, ID: json.RawMessage("1")}
+	var response model.RPCResponse
+	err := c.do(ctx, request{method: http.MethodPost, path: "/rpc", body: body}, &response)
+	if err != nil {
+		return err
+	}
+	if response.Error != nil {
+		return response.Error
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(response.Result, out)
+}
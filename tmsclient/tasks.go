@@ -0,0 +1,273 @@
+package tmsclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"tms.zinkworks.com/model"
+)
+
+// CreateTask creates a task with its items, in the default project unless
+// task.ProjectID is set.
+func (c *Client) CreateTask(ctx context.Context, task model.Task) (*model.Task, error) {
+	var created model.Task
+	err := c.do(ctx, request{method: http.MethodPost, path: "/tasks", body: task}, &created)
+	if err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// ListTasks lists every task not in the trash.
+func (c *Client) ListTasks(ctx context.Context, opts ListOptions) *Iterator[model.Task] {
+	return newIterator[model.Task](ctx, c, "/tasks", opts.query())
+}
+
+// ListAssignedTasks lists the tasks assigned to the user.
+func (c *Client) ListAssignedTasks(ctx context.Context, userID int, opts ListOptions) *Iterator[model.Task] {
+	return newIterator[model.Task](ctx, c, fmt.Sprintf("/users/%d/tasks/assigned", userID), opts.query())
+}
+
+// GetTask returns the task with its items.
+func (c *Client) GetTask(ctx context.Context, id int) (*model.Task, error) {
+	var task model.Task
+	err := c.do(ctx, request{method: http.MethodGet, path: taskPath(id), idempotent: true}, &task)
+	if err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// UpdateTask replaces the task's title, description, completed flag and items.
+func (c *Client) UpdateTask(ctx context.Context, id int, task model.Task) (*model.Task, error) {
+	var updated model.Task
+	err := c.do(ctx, request{method: http.MethodPut, path: taskPath(id), body: task, idempotent: true}, &updated)
+	if err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// DeleteMode says what happens to the subtasks of a deleted task.
+type DeleteMode string
+
+const (
+	// DeleteCascade deletes the subtasks with the task.
+	DeleteCascade DeleteMode = "cascade"
+	// DeleteReparent moves the subtasks up to the task's parent.
+	DeleteReparent DeleteMode = "reparent"
+)
+
+// DeleteTask moves the task to the trash. An empty mode is DeleteCascade.
+func (c *Client) DeleteTask(ctx context.Context, id int, mode DeleteMode) error {
+	query := url.Values{}
+	if mode != "" {
+		query.Set("subtasks", string(mode))
+	}
+	return c.do(ctx, request{method: http.MethodDelete, path: taskPath(id), query: query, idempotent: true}, nil)
+}
+
+// AssignTask assigns the task to the user, or unassigns it for user 0.
+func (c *Client) AssignTask(ctx context.Context, id, userID int) (*model.Task, error) {
+	var task model.Task
+	err := c.do(ctx, request{method: http.MethodPatch, path: fmt.Sprintf("/tasks/%d/assign/%d", id, userID), idempotent: true}, &task)
+	if err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// AddComment comments on a task.
+func (c *Client) AddComment(ctx context.Context, taskID int, comment string) (*model.TaskComment, error) {
+	body := model.TaskComment{TaskID: taskID, Comment: comment}
+	var created model.TaskComment
+	err := c.do(ctx, request{method: http.MethodPost, path: "/comments", body: body}, &created)
+	if err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// ListComments lists the task's comments.
+func (c *Client) ListComments(ctx context.Context, taskID int, opts ListOptions) *Iterator[model.TaskComment] {
+	return newIterator[model.TaskComment](ctx, c, fmt.Sprintf("/comments/%d", taskID), opts.query())
+}
+
+// SetParentTask moves the task under another task.
+func (c *Client) SetParentTask(ctx context.Context, id, parentID int) (*model.Task, error) {
+	var task model.Task
+	err := c.do(ctx, request{method: http.MethodPatch, path: fmt.Sprintf("/tasks/%d/parent/%d", id, parentID), idempotent: true}, &task)
+	if err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// GetSubtasks returns the task's direct subtasks.
+func (c *Client) GetSubtasks(ctx context.Context, id int) ([]model.Task, error) {
+	var tasks []model.Task
+	err := c.do(ctx, request{method: http.MethodGet, path: taskPath(id) + "/subtasks", idempotent: true}, &tasks)
+	return tasks, err
+}
+
+// GetTaskTree returns the task with all of its subtasks, nested, and their progress.
+func (c *Client) GetTaskTree(ctx context.Context, id int) (*model.TaskNode, error) {
+	var tree model.TaskNode
+	err := c.do(ctx, request{method: http.MethodGet, path: taskPath(id) + "/tree", idempotent: true}, &tree)
+	if err != nil {
+		return nil, err
+	}
+	return &tree, nil
+}
+
+// CreateDependency links the task to dep.RelatedTaskID with dep.Type.
+func (c *Client) CreateDependency(ctx context.Context, taskID int, dep model.TaskDependency) (*model.TaskDependency, error) {
+	var created model.TaskDependency
+	err := c.do(ctx, request{method: http.MethodPost, path: taskPath(taskID) + "/dependencies", body: dep}, &created)
+	if err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// GetDependencies returns the dependencies the task takes part in.
+func (c *Client) GetDependencies(ctx context.Context, taskID int) ([]model.TaskDependency, error) {
+	var deps []model.TaskDependency
+	err := c.do(ctx, request{method: http.MethodGet, path: taskPath(taskID) + "/dependencies", idempotent: true}, &deps)
+	return deps, err
+}
+
+// DeleteDependency removes one of the task's dependencies.
+func (c *Client) DeleteDependency(ctx context.Context, taskID, dependencyID int) error {
+	path := fmt.Sprintf("%s/dependencies/%d", taskPath(taskID), dependencyID)
+	return c.do(ctx, request{method: http.MethodDelete, path: path, idempotent: true}, nil)
+}
+
+// GetTaskOrder returns the open tasks in an order that respects their blockers.
+func (c *Client) GetTaskOrder(ctx context.Context) ([]model.Task, error) {
+	var tasks []model.Task
+	err := c.do(ctx, request{method: http.MethodGet, path: "/tasks/order", idempotent: true}, &tasks)
+	return tasks, err
+}
+
+// SetRecurrence makes the task recur on rec.RRule from rec.DTStart,
+// replacing any schedule it had.
+func (c *Client) SetRecurrence(ctx context.Context, taskID int, rec model.TaskRecurrence) (*model.TaskRecurrence, error) {
+	var saved model.TaskRecurrence
+	err := c.do(ctx, request{method: http.MethodPut, path: taskPath(taskID) + "/recurrence", body: rec, idempotent: true}, &saved)
+	if err != nil {
+		return nil, err
+	}
+	return &saved, nil
+}
+
+// GetRecurrence returns the task's schedule.
+func (c *Client) GetRecurrence(ctx context.Context, taskID int) (*model.TaskRecurrence, error) {
+	var rec model.TaskRecurrence
+	err := c.do(ctx, request{method: http.MethodGet, path: taskPath(taskID) + "/recurrence", idempotent: true}, &rec)
+	if err != nil {
+		return nil, err
+	}
+	return &rec, nil
+}
+
+// DeleteRecurrence stops the task recurring.
+func (c *Client) DeleteRecurrence(ctx context.Context, taskID int) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: taskPath(taskID) + "/recurrence", idempotent: true}, nil)
+}
+
+// ListHistory lists the changes made to the task, oldest first.
+func (c *Client) ListHistory(ctx context.Context, taskID int, opts ListOptions) *Iterator[model.TaskEvent] {
+	return newIterator[model.TaskEvent](ctx, c, taskPath(taskID)+"/history", opts.query())
+}
+
+// ListTrash lists the tasks in the trash.
+func (c *Client) ListTrash(ctx context.Context, opts ListOptions) *Iterator[model.Task] {
+	return newIterator[model.Task](ctx, c, "/trash", opts.query())
+}
+
+// RestoreTask takes the task, and the subtasks deleted with it, out of the trash.
+func (c *Client) RestoreTask(ctx context.Context, id int) (*model.Task, error) {
+	var task model.Task
+	err := c.do(ctx, request{method: http.MethodPost, path: taskPath(id) + "/restore"}, &task)
+	if err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// BulkTasks runs a batch of operations. The response reports each
+// operation's outcome, including when an atomic batch was rolled back.
+func (c *Client) BulkTasks(ctx context.Context, bulk model.BulkRequest) (*model.BulkResponse, error) {
+	var response model.BulkResponse
+	err := c.do(ctx, request{method: http.MethodPost, path: "/tasks/bulk", body: bulk}, &response)
+	if err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// Import and export formats.
+const (
+	FormatJSON   = "json"
+	FormatNDJSON = "ndjson"
+	FormatCSV    = "csv"
+)
+
+var formatContentTypes = map[string]string{
+	FormatJSON:   "application/json",
+	FormatNDJSON: "application/x-ndjson",
+	FormatCSV:    "text/csv",
+}
+
+// ExportTasks streams every task in the given format. The caller closes the result.
+func (c *Client) ExportTasks(ctx context.Context, format string) (io.ReadCloser, error) {
+	query := url.Values{"format": {format}}
+	resp, err := c.send(ctx, request{method: http.MethodGet, idempotent: true}, c.url("/tasks/export", query))
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// ImportTasks upserts the tasks read from r, in the given format. If any
+// row is rejected, nothing is saved and the report listing the errors is
+// returned with an error matching ErrUnprocessable.
+func (c *Client) ImportTasks(ctx context.Context, format string, r io.Reader, dryRun bool) (*model.ImportReport, error) {
+	query := url.Values{"format": {format}}
+	if dryRun {
+		query.Set("dry_run", strconv.FormatBool(dryRun))
+	}
+
+	req := request{
+		method:      http.MethodPost,
+		path:        "/tasks/import",
+		query:       query,
+		raw:         r,
+		contentType: formatContentTypes[format],
+	}
+
+	var report model.ImportReport
+	err := c.do(ctx, req, &report)
+
+	var apiErr *Error
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusUnprocessableEntity {
+		if json.Unmarshal(apiErr.Body, &report) == nil {
+			return &report, err
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+func taskPath(id int) string {
+	return "/tasks/" + strconv.Itoa(id)
+}
@@ -0,0 +1,94 @@
+package tmsclient
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"tms.zinkworks.com/model"
+)
+
+// CreateWebhook registers a URL to be sent the given events of the tasks in
+// a project, or in the default project if projectID is 0. The returned
+// webhook holds the secret deliveries are signed with; it is not shown again.
+func (c *Client) CreateWebhook(ctx context.Context, projectID int, targetURL string, events []string) (*model.Webhook, error) {
+	body := model.Webhook{ProjectID: projectID, URL: targetURL, Events: events}
+	var created model.Webhook
+	err := c.do(ctx, request{method: http.MethodPost, path: "/webhooks", body: body}, &created)
+	if err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// ListWebhooks returns the webhooks the caller registered.
+func (c *Client) ListWebhooks(ctx context.Context) ([]model.Webhook, error) {
+	var hooks []model.Webhook
+	err := c.do(ctx, request{method: http.MethodGet, path: "/webhooks", idempotent: true}, &hooks)
+	return hooks, err
+}
+
+// GetWebhook returns a webhook.
+func (c *Client) GetWebhook(ctx context.Context, id int) (*model.Webhook, error) {
+	var hook model.Webhook
+	err := c.do(ctx, request{method: http.MethodGet, path: webhookPath(id), idempotent: true}, &hook)
+	if err != nil {
+		return nil, err
+	}
+	return &hook, nil
+}
+
+// DeleteWebhook removes a webhook and its pending deliveries.
+func (c *Client) DeleteWebhook(ctx context.Context, id int) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: webhookPath(id), idempotent: true}, nil)
+}
+
+// ListWebhookDeliveries returns the webhook's most recent deliveries, at
+// most limit of them, or the server's default for 0.
+func (c *Client) ListWebhookDeliveries(ctx context.Context, id, limit int) ([]model.WebhookDelivery, error) {
+	query := url.Values{}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+
+	var deliveries []model.WebhookDelivery
+	err := c.do(ctx, request{method: http.MethodGet, path: webhookPath(id) + "/deliveries", query: query, idempotent: true}, &deliveries)
+	return deliveries, err
+}
+
+// RedeliverWebhook queues a delivery to be sent again.
+func (c *Client) RedeliverWebhook(ctx context.Context, id, deliveryID int) (*model.WebhookDelivery, error) {
+	path := fmt.Sprintf("%s/deliveries/%d/redeliver", webhookPath(id), deliveryID)
+	var delivery model.WebhookDelivery
+	err := c.do(ctx, request{method: http.MethodPost, path: path}, &delivery)
+	if err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// SetUserEmail sets the address the calling user is emailed at.
+func (c *Client) SetUserEmail(ctx context.Context, userID int, email string) (*model.UserEmail, error) {
+	body := model.UserEmail{Email: email}
+	var saved model.UserEmail
+	err := c.do(ctx, request{method: http.MethodPut, path: userPath(userID) + "/email", body: body, idempotent: true}, &saved)
+	if err != nil {
+		return nil, err
+	}
+	return &saved, nil
+}
+
+// DeleteUserEmail stops emailing the calling user.
+func (c *Client) DeleteUserEmail(ctx context.Context, userID int) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: userPath(userID) + "/email", idempotent: true}, nil)
+}
+
+func webhookPath(id int) string {
+	return "/webhooks/" + strconv.Itoa(id)
+}
+
+func userPath(id int) string {
+	return "/users/" + strconv.Itoa(id)
+}
@@ -0,0 +1,144 @@
+// Package webhook signs and sends webhook requests. Receivers can use
+// Verify to check that a request came from this server.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// Headers sent with every delivery.
+const (
+	HeaderEvent     = "X-TMS-Event"
+	HeaderDelivery  = "X-TMS-Delivery"
+	HeaderTimestamp = "X-TMS-Timestamp"
+	HeaderSignature = "X-TMS-Signature"
+)
+
+// Sign returns the signature of a delivery: "sha256=" followed by the hex
+// HMAC-SHA256, keyed with the subscription's secret, of the timestamp, a
+// dot and the body. Including the timestamp lets receivers reject replays.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether signature is valid for the body and timestamp.
+func Verify(secret string, timestamp int64, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
+
+// Backoff returns how long to wait before retrying after the given number
+// of failed attempts: 10s, 20s, 40s and so on, capped at an hour.
+func Backoff(attempts int) time.Duration {
+	const base, limit = 10 * time.Second, time.Hour
+
+	if attempts < 1 {
+		return base
+	}
+	if attempts > 20 {
+		return limit
+	}
+
+	delay := base << (attempts - 1)
+	if delay > limit {
+		return limit
+	}
+	return delay
+}
+
+// ErrForbiddenAddress is returned, wrapped, when a webhook URL resolves to
+// an address PublicClient will not connect to.
+var ErrForbiddenAddress = errors.New("webhook: address is not publicly routable")
+
+// Sender posts signed deliveries.
+type Sender struct {
+	Client *http.Client
+}
+
+// PublicClient returns a client for Sender that refuses to connect to
+// loopback, private, link-local, multicast and unspecified addresses, so a
+// webhook URL cannot be used to reach the server's own network. The address
+// is checked after the host name is resolved, on every connection,
+// including those made to follow redirects. Proxies are not used, as they
+// would hide the address being connected to.
+func PublicClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout, Control: dialPublicOnly}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			ForceAttemptHTTP2:   true,
+			MaxIdleConns:        100,
+			IdleConnTimeout:     90 * time.Second,
+			TLSHandshakeTimeout: 10 * time.Second,
+		},
+	}
+}
+
+// dialPublicOnly is a net.Dialer Control function, called with the
+// resolved address just before connecting.
+func dialPublicOnly(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, host)
+	}
+	return nil
+}
+
+// Send posts the body to url and returns the response status. Any status
+// outside 2xx is returned as an error along with the status.
+func (sender Sender) Send(ctx context.Context, url, secret, event string, deliveryID int, body []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "tms-webhooks/1.0")
+	req.Header.Set(HeaderEvent, event)
+	req.Header.Set(HeaderDelivery, strconv.Itoa(deliveryID))
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(secret, timestamp, body))
+
+	client := sender.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	// Drain a little of the body so the connection can be reused.
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("receiver responded with %s", resp.Status)
+	}
+
+	return resp.StatusCode, nil
+}
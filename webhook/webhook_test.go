@@ -0,0 +1,82 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	body := []byte(`{"event":"task.created"}`)
+
+	signature := Sign("s3cret", 1700000000, body)
+	assert.Equal(t, "sha256=", signature[:7])
+	assert.Len(t, signature, 7+64)
+
+	assert.True(t, Verify("s3cret", 1700000000, body, signature))
+	assert.False(t, Verify("other", 1700000000, body, signature))
+	assert.False(t, Verify("s3cret", 1700000001, body, signature))
+	assert.False(t, Verify("s3cret", 1700000000, []byte(`{}`), signature))
+}
+
+func TestBackoff(t *testing.T) {
+	assert.Equal(t, 10*time.Second, Backoff(1))
+	assert.Equal(t, 20*time.Second, Backoff(2))
+	assert.Equal(t, 80*time.Second, Backoff(4))
+	assert.Equal(t, time.Hour, Backoff(10))
+	assert.Equal(t, time.Hour, Backoff(100))
+}
+
+func TestSenderSend_SignsRequest(t *testing.T) {
+	var verified bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		timestamp, _ := strconv.ParseInt(r.Header.Get(HeaderTimestamp), 10, 64)
+		verified = Verify("s3cret", timestamp, body, r.Header.Get(HeaderSignature)) &&
+			r.Header.Get(HeaderEvent) == "task.updated" &&
+			r.Header.Get(HeaderDelivery) == "12"
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	status, err := Sender{}.Send(context.Background(), server.URL, "s3cret", "task.updated", 12, []byte(`{"task_id":1}`))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNoContent, status)
+	assert.True(t, verified)
+}
+
+func TestSenderSend_ReportsFailureStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "down", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	status, err := Sender{}.Send(context.Background(), server.URL, "s3cret", "task.deleted", 1, []byte(`{}`))
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+}
+
+func TestPublicClient_RefusesInternalAddresses(t *testing.T) {
+	var reached bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reached = true
+	}))
+	defer server.Close()
+
+	sender := Sender{Client: PublicClient(time.Second)}
+	_, err := sender.Send(context.Background(), server.URL, "s3cret", "task.created", 1, []byte(`{}`))
+	assert.ErrorIs(t, err, ErrForbiddenAddress)
+	assert.False(t, reached)
+
+	for _, address := range []string{"127.0.0.1:80", "[::1]:80", "10.1.2.3:80", "192.168.0.1:443", "169.254.169.254:80", "[fe80::1]:80", "0.0.0.0:80", "[::ffff:127.0.0.1]:80"} {
+		assert.ErrorIs(t, dialPublicOnly("tcp", address, nil), ErrForbiddenAddress, address)
+	}
+	assert.NoError(t, dialPublicOnly("tcp", "93.184.216.34:443", nil))
+	assert.NoError(t, dialPublicOnly("tcp6", "[2606:2800:220:1::1]:443", nil))
+}